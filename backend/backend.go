@@ -16,6 +16,9 @@
 package backend
 
 import (
+	"errors"
+	"time"
+
 	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/logger"
 	"github.com/rs/zerolog"
@@ -57,6 +60,16 @@ type ProverConfig struct {
 	Force         bool                      // defaults to false
 	HintFunctions map[hint.ID]hint.Function // defaults to all built-in hint functions
 	CircuitLogger zerolog.Logger            // defaults to gnark.Logger
+	CheckpointDir string                    // defaults to no checkpoint
+	MemoryBudget  uint64                    // defaults to no budget
+	Stats         *ProverStats              // defaults to no statistics
+}
+
+// ProverStats are the statistics of a proof, filled by the provers which support
+// WithStats.
+type ProverStats struct {
+	Duration time.Duration // time taken by the prover
+	PeakRSS  uint64        // maximum resident set size of the process in bytes, 0 if not reported
 }
 
 // NewProverConfig returns a default ProverConfig with given prover options opts
@@ -67,6 +80,9 @@ func NewProverConfig(opts ...ProverOption) (ProverConfig, error) {
 	for _, v := range hint.GetRegistered() {
 		opt.HintFunctions[hint.UUID(v)] = v
 	}
+	for id, v := range hint.GetAliases() {
+		opt.HintFunctions[id] = v
+	}
 	for _, option := range opts {
 		if err := option(&opt); err != nil {
 			return ProverConfig{}, err
@@ -114,3 +130,72 @@ func WithCircuitLogger(l zerolog.Logger) ProverOption {
 		return nil
 	}
 }
+
+// WithCheckpoint is a prover option that makes the distributed provers write their
+// state to dir after each round, and resume from the last round completed by all the
+// parties when restarted on the same dir. The checkpoint is removed once the proof
+// is done.
+func WithCheckpoint(dir string) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.CheckpointDir = dir
+		return nil
+	}
+}
+
+// WithMemoryBudget is a prover option that bounds, in bytes, the memory the distributed
+// provers hold for the evaluations of the quotient, as estimated from the sizes of the
+// polynomials. Over the budget, the polynomials are evaluated on the cosets one at a
+// time instead of all together, which is slower but keeps a fraction of them in
+// memory. The prover fails before the first round if even that is over the budget. The
+// budget doesn't cover the proving key, the witness and the commitments.
+func WithMemoryBudget(bytes uint64) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.MemoryBudget = bytes
+		return nil
+	}
+}
+
+// WithStats is a prover option that makes the provers which support it fill stats once
+// the proof is done.
+func WithStats(stats *ProverStats) ProverOption {
+	return func(opt *ProverConfig) error {
+		opt.Stats = stats
+		return nil
+	}
+}
+
+// VerifierOption defines option for altering the behaviour of the Verify methods.
+// See the descriptions of functions returning instances of this type for
+// implemented options.
+type VerifierOption func(*VerifierConfig) error
+
+// VerifierConfig is the configuration for the verifier with the options applied.
+type VerifierConfig struct {
+	CircuitFingerprint [32]byte // defaults to zero, the verifying key being then unchecked
+}
+
+// NewVerifierConfig returns a default VerifierConfig with given verifier options opts
+// applied.
+func NewVerifierConfig(opts ...VerifierOption) (VerifierConfig, error) {
+	var opt VerifierConfig
+	for _, option := range opts {
+		if err := option(&opt); err != nil {
+			return VerifierConfig{}, err
+		}
+	}
+	return opt, nil
+}
+
+// WithCircuitFingerprint is a verifier option that checks, before verifying the proofs,
+// that the verifying key was set up for the constraint system of fingerprint fp, as
+// returned by its Fingerprint method. The verification fails with the errors of
+// CheckVerifyingKey otherwise.
+func WithCircuitFingerprint(fp [32]byte) VerifierOption {
+	return func(opt *VerifierConfig) error {
+		if fp == ([32]byte{}) {
+			return errors.New("zero circuit fingerprint")
+		}
+		opt.CircuitFingerprint = fp
+		return nil
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package comm provides the collectives used by the Pianist provers (piano, gpiano)
+// to exchange data between the parties.
+//
+// Rank 0 is the root of every collective. With the Star topology the root talks to
+// every other party, which is what simpleMPI supports. With the BinomialTree topology
+// a broadcast, a reduce or a scatter costs the root log2(M) messages instead of M-1,
+// and every party forwards data to its children; it requires a Transport linking all
+// the parties to each other.
+package comm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Transport sends and receives bytes between the parties.
+//
+// Receive blocks until size bytes were received from the given party; the bytes sent
+// by a party to another one are received in order, regardless of how they were split
+// between the calls to Send.
+type Transport interface {
+	Rank() uint64
+	Size() uint64
+	Send(buf []byte, to uint64) error
+	Receive(size uint64, from uint64) ([]byte, error)
+}
+
+// Topology of the collectives
+type Topology uint8
+
+const (
+	// Star: the root sends to and receives from every other party directly
+	Star Topology = iota
+
+	// BinomialTree: party r receives from r - 2^k, where 2^k is the lowest bit set in r,
+	// and forwards to r + 2^j for every 2^j < 2^k
+	BinomialTree
+)
+
+func (t Topology) String() string {
+	switch t {
+	case Star:
+		return "star"
+	case BinomialTree:
+		return "binomial-tree"
+	default:
+		return fmt.Sprintf("topology(%d)", uint8(t))
+	}
+}
+
+var (
+	errUnknownTopology = errors.New("unknown topology")
+
+	// errStarOnly is returned by the collectives of a Communicator going through
+	// SimpleMPI with another topology than Star, on every party, rather than having
+	// the parties other than the root fail to reach each other while the root waits
+	errStarOnly = errors.New("simpleMPI only supports the star topology")
+)
+
+// Communicator runs the collectives of a Topology on top of a Transport
+type Communicator struct {
+	Transport Transport
+	Topology  Topology
+}
+
+// Default is the communicator of the Pianist provers. It goes through simpleMPI with
+// the Star topology; change it before proving to use another transport or topology.
+// simpleMPI only links rank 0 to the other parties, so that the collectives of a
+// Communicator going through SimpleMPI with another topology fail, see errStarOnly.
+var Default = &Communicator{Transport: SimpleMPI{}, Topology: Star}
+
+// RoundTagger is implemented by the transports tagging each message with the round of
+// the protocol it was sent in, so that a party rejects a message from another round
+type RoundTagger interface {
+	NextRound()
+}
+
+// NextRound ends the current round of the protocol, if the transport tags the messages
+// with their round. The provers end a round with each challenge broadcast by the root.
+func (c *Communicator) NextRound() {
+	if t, ok := c.Transport.(RoundTagger); ok {
+		t.NextRound()
+	}
+}
+
+// check returns errStarOnly if the Transport of c can't run its Topology
+func (c *Communicator) check() error {
+	if _, ok := c.Transport.(SimpleMPI); ok && c.Topology != Star {
+		return errStarOnly
+	}
+	return nil
+}
+
+// Rank returns the rank of this party
+func (c *Communicator) Rank() uint64 {
+	return c.Transport.Rank()
+}
+
+// Size returns the number of parties
+func (c *Communicator) Size() uint64 {
+	return c.Transport.Size()
+}
+
+// Broadcast sends buf from the root to every party and returns it.
+// buf is ignored on the other parties, which expect size bytes.
+func (c *Communicator) Broadcast(buf []byte, size int) ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	rank, n := c.Rank(), c.Size()
+	if rank == 0 && len(buf) != size {
+		return nil, fmt.Errorf("broadcast: got %d bytes, expected %d", len(buf), size)
+	}
+
+	switch c.Topology {
+	case Star:
+		if rank != 0 {
+			return c.Transport.Receive(uint64(size), 0)
+		}
+		for i := uint64(1); i < n; i++ {
+			if err := c.Transport.Send(buf, i); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case BinomialTree:
+		mask := uint64(1)
+		for ; mask < n; mask <<= 1 {
+			if rank&mask != 0 {
+				var err error
+				if buf, err = c.Transport.Receive(uint64(size), rank-mask); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+		for mask >>= 1; mask > 0; mask >>= 1 {
+			if rank+mask < n {
+				if err := c.Transport.Send(buf, rank+mask); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return buf, nil
+	default:
+		return nil, errUnknownTopology
+	}
+}
+
+// Gather sends buf from every party to the root. On the root, it returns the
+// contributions of all the parties ordered by rank, on the other parties it returns nil.
+// Every party must contribute the same number of bytes.
+func (c *Communicator) Gather(buf []byte) ([][]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	rank, n := c.Rank(), c.Size()
+	size := uint64(len(buf))
+
+	var data []byte
+	switch c.Topology {
+	case Star:
+		if rank != 0 {
+			return nil, c.Transport.Send(buf, 0)
+		}
+		data = make([]byte, 0, n*size)
+		data = append(data, buf...)
+		for i := uint64(1); i < n; i++ {
+			recvBuf, err := c.Transport.Receive(size, i)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, recvBuf...)
+		}
+	case BinomialTree:
+		// data holds the contributions of the parties rank, ..., rank+len(data)/size-1
+		data = append([]byte{}, buf...)
+		for mask := uint64(1); mask < n; mask <<= 1 {
+			if rank&mask != 0 {
+				return nil, c.Transport.Send(data, rank-mask)
+			}
+			if src := rank + mask; src < n {
+				recvBuf, err := c.Transport.Receive(minUint64(mask, n-src)*size, src)
+				if err != nil {
+					return nil, err
+				}
+				data = append(data, recvBuf...)
+			}
+		}
+	default:
+		return nil, errUnknownTopology
+	}
+
+	res := make([][]byte, n)
+	for i := range res {
+		res[i] = data[uint64(i)*size : uint64(i+1)*size]
+	}
+	return res, nil
+}
+
+// Scatter sends bufs[i] from the root to party i and returns the part of this party.
+// bufs is ignored on the other parties, and every part must be size bytes long.
+func (c *Communicator) Scatter(bufs [][]byte, size int) ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	rank, n := c.Rank(), c.Size()
+	s := uint64(size)
+
+	var data []byte
+	if rank == 0 {
+		if uint64(len(bufs)) != n {
+			return nil, fmt.Errorf("scatter: got %d parts, expected %d", len(bufs), n)
+		}
+		data = make([]byte, 0, n*s)
+		for _, b := range bufs {
+			if len(b) != size {
+				return nil, fmt.Errorf("scatter: got a part of %d bytes, expected %d", len(b), size)
+			}
+			data = append(data, b...)
+		}
+	}
+
+	switch c.Topology {
+	case Star:
+		if rank != 0 {
+			return c.Transport.Receive(s, 0)
+		}
+		for i := uint64(1); i < n; i++ {
+			if err := c.Transport.Send(data[i*s:(i+1)*s], i); err != nil {
+				return nil, err
+			}
+		}
+		return data[:s], nil
+	case BinomialTree:
+		// data holds the parts of the parties rank, ..., rank+len(data)/size-1
+		mask := uint64(1)
+		for ; mask < n; mask <<= 1 {
+			if rank&mask != 0 {
+				var err error
+				if data, err = c.Transport.Receive(minUint64(mask, n-rank)*s, rank-mask); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+		for mask >>= 1; mask > 0; mask >>= 1 {
+			if dst := rank + mask; dst < n {
+				cnt := minUint64(mask, n-dst)
+				if err := c.Transport.Send(data[mask*s:(mask+cnt)*s], dst); err != nil {
+					return nil, err
+				}
+				data = data[:mask*s]
+			}
+		}
+		return data[:s], nil
+	default:
+		return nil, errUnknownTopology
+	}
+}
+
+// Reduce combines the buf of all the parties with op and returns the result on the
+// root, nil on the other parties. op must be associative and commutative, and return
+// len(a) bytes; it may modify a.
+func (c *Communicator) Reduce(buf []byte, op func(a, b []byte) ([]byte, error)) ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	rank, n := c.Rank(), c.Size()
+	size := uint64(len(buf))
+
+	switch c.Topology {
+	case Star:
+		if rank != 0 {
+			return nil, c.Transport.Send(buf, 0)
+		}
+		acc := append([]byte{}, buf...)
+		for i := uint64(1); i < n; i++ {
+			recvBuf, err := c.Transport.Receive(size, i)
+			if err != nil {
+				return nil, err
+			}
+			if acc, err = op(acc, recvBuf); err != nil {
+				return nil, err
+			}
+		}
+		return acc, nil
+	case BinomialTree:
+		acc := append([]byte{}, buf...)
+		for mask := uint64(1); mask < n; mask <<= 1 {
+			if rank&mask != 0 {
+				return nil, c.Transport.Send(acc, rank-mask)
+			}
+			if src := rank + mask; src < n {
+				recvBuf, err := c.Transport.Receive(size, src)
+				if err != nil {
+					return nil, err
+				}
+				if acc, err = op(acc, recvBuf); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return acc, nil
+	default:
+		return nil, errUnknownTopology
+	}
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,220 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+var (
+	testSizes      = []int{1, 2, 3, 5, 8, 13}
+	testTopologies = []Topology{Star, BinomialTree}
+)
+
+// run calls f on every party of a local world of the given size and topology
+func run(size int, topology Topology, f func(c *Communicator) error) ([]*Local, error) {
+	transports := NewLocal(size)
+	errs := make([]error, size)
+	var wg sync.WaitGroup
+	for i := range transports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = f(&Communicator{Transport: transports[i], Topology: topology})
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("party %d: %w", i, err)
+		}
+	}
+	return transports, nil
+}
+
+// checkCollectives runs every collective on c, for a world of the given size. The tests
+// of the Local, Network and TLS transports share it, so that they check the same
+// collectives.
+func checkCollectives(c *Communicator, size int) error {
+	rank := c.Rank()
+
+	// broadcast
+	var buf []byte
+	if rank == 0 {
+		buf = []byte("challenge")
+	}
+	buf, err := c.Broadcast(buf, len("challenge"))
+	if err != nil {
+		return err
+	}
+	if string(buf) != "challenge" {
+		return fmt.Errorf("broadcast: got %q", buf)
+	}
+
+	// gather
+	parts, err := c.Gather(part(rank))
+	if err != nil {
+		return err
+	}
+	if rank == 0 {
+		for i := range parts {
+			if !bytes.Equal(parts[i], part(uint64(i))) {
+				return fmt.Errorf("gather: got %v from party %d", parts[i], i)
+			}
+		}
+	} else if parts != nil {
+		return fmt.Errorf("gather: got a result out of the root")
+	}
+
+	// scatter
+	if rank == 0 {
+		for i := range parts {
+			parts[i] = append(part(uint64(i)), 0x55)
+		}
+	}
+	buf, err = c.Scatter(parts, 4)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, append(part(rank), 0x55)) {
+		return fmt.Errorf("scatter: got %v", buf)
+	}
+
+	// reduce
+	sum, err := c.Reduce([]byte{byte(rank + 1)}, func(a, b []byte) ([]byte, error) {
+		a[0] += b[0]
+		return a, nil
+	})
+	if err != nil {
+		return err
+	}
+	if rank == 0 && int(sum[0]) != size*(size+1)/2 {
+		return fmt.Errorf("reduce: got %d", sum[0])
+	}
+	return nil
+}
+
+func part(rank uint64) []byte {
+	return []byte{byte(rank), byte(rank >> 8), 0xaa}
+}
+
+func TestCollectives(t *testing.T) {
+	for _, topology := range testTopologies {
+		for _, size := range testSizes {
+			t.Run(fmt.Sprintf("%s/%d", topology, size), func(t *testing.T) {
+				_, err := run(size, topology, func(c *Communicator) error {
+					return checkCollectives(c, size)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
+
+func TestReduceG1(t *testing.T) {
+	_, _, g1, _ := curve.Generators()
+	for _, topology := range testTopologies {
+		for _, size := range testSizes {
+			var expected curve.G1Affine
+			expected.ScalarMultiplication(&g1, big.NewInt(int64(size*(size+1)/2)))
+
+			_, err := run(size, topology, func(c *Communicator) error {
+				var p curve.G1Affine
+				p.ScalarMultiplication(&g1, big.NewInt(int64(c.Rank()+1)))
+				sum, err := c.ReduceG1(p)
+				if err != nil {
+					return err
+				}
+				if c.Rank() == 0 && !sum.Equal(&expected) {
+					return fmt.Errorf("got %s, expected %s", sum.String(), expected.String())
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("%s/%d: %v", topology, size, err)
+			}
+		}
+	}
+}
+
+func TestSimpleMPIStarOnly(t *testing.T) {
+	c := &Communicator{Transport: SimpleMPI{}, Topology: BinomialTree}
+	if _, err := c.Broadcast(nil, 1); !errors.Is(err, errStarOnly) {
+		t.Fatalf("broadcast: got %v, expected %v", err, errStarOnly)
+	}
+	if _, err := c.Gather([]byte{1}); !errors.Is(err, errStarOnly) {
+		t.Fatalf("gather: got %v, expected %v", err, errStarOnly)
+	}
+	if _, err := c.Scatter(nil, 1); !errors.Is(err, errStarOnly) {
+		t.Fatalf("scatter: got %v, expected %v", err, errStarOnly)
+	}
+	if _, err := c.ReduceG1(curve.G1Affine{}); !errors.Is(err, errStarOnly) {
+		t.Fatalf("reduce: got %v, expected %v", err, errStarOnly)
+	}
+}
+
+// The benchmarks report the traffic of the root, which grows linearly with the number
+// of parties for the Star topology and logarithmically for the BinomialTree topology,
+// except for the bytes of a gather which the root receives in any case.
+
+func benchmarkCollective(b *testing.B, f func(c *Communicator) error) {
+	for _, topology := range testTopologies {
+		for _, size := range []int{4, 16, 64, 256} {
+			b.Run(fmt.Sprintf("%s/%d", topology, size), func(b *testing.B) {
+				var root *Local
+				for i := 0; i < b.N; i++ {
+					transports, err := run(size, topology, f)
+					if err != nil {
+						b.Fatal(err)
+					}
+					root = transports[0]
+				}
+				b.ReportMetric(float64(root.BytesSent+root.BytesReceived), "root-B")
+				b.ReportMetric(float64(root.MessagesSent+root.MessagesReceived), "root-msgs")
+			})
+		}
+	}
+}
+
+func BenchmarkBroadcast(b *testing.B) {
+	benchmarkCollective(b, func(c *Communicator) error {
+		_, err := c.Broadcast(make([]byte, 32), 32)
+		return err
+	})
+}
+
+func BenchmarkGather(b *testing.B) {
+	benchmarkCollective(b, func(c *Communicator) error {
+		_, err := c.Gather(make([]byte, 32))
+		return err
+	})
+}
+
+func BenchmarkReduceG1(b *testing.B) {
+	_, _, g1, _ := curve.Generators()
+	benchmarkCollective(b, func(c *Communicator) error {
+		_, err := c.ReduceG1(g1)
+		return err
+	})
+}
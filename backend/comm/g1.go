@@ -0,0 +1,46 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comm
+
+import (
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// ReduceG1 returns, on the root, the sum of the points p of all the parties,
+// such as the partial commitments of a bivariate KZG commitment.
+func (c *Communicator) ReduceG1(p curve.G1Affine) (curve.G1Affine, error) {
+	var res curve.G1Affine
+	buf := p.RawBytes()
+	sum, err := c.Reduce(buf[:], addG1)
+	if err != nil || sum == nil {
+		return res, err
+	}
+	_, err = res.SetBytes(sum)
+	return res, err
+}
+
+// addG1 adds two uncompressed BN254 G1 points
+func addG1(a, b []byte) ([]byte, error) {
+	var p, q curve.G1Affine
+	if _, err := p.SetBytes(a); err != nil {
+		return nil, err
+	}
+	if _, err := q.SetBytes(b); err != nil {
+		return nil, err
+	}
+	p.Add(&p, &q)
+	res := p.RawBytes()
+	return res[:], nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// DialTimeout bounds the time ConnectNetwork waits for the other parties to listen
+var DialTimeout = 30 * time.Second
+
+// Network is a Transport over stream connections, TCP or Unix sockets, linking every
+// pair of parties. A goroutine drains each connection, so that Send doesn't wait for
+// the other party to Receive.
+type Network struct {
+	rank  uint64
+	conns []net.Conn // conns[rank] is nil
+	pipes []*pipe    // pipes[from]
+
+	// BytesSent and BytesReceived count the traffic of this party
+	BytesSent, BytesReceived uint64
+}
+
+// ConnectNetwork links party rank to the other parties, addrs[i] being the address party i
+// listens on with the given network ("tcp", "unix"). The party accepts the parties of
+// higher rank on l, which listens on addrs[rank] and is closed on return, and dials the
+// ones of lower rank. Every party must call it.
+func ConnectNetwork(rank uint64, network string, addrs []string, l net.Listener) (*Network, error) {
+	var hello [8]byte
+	binary.BigEndian.PutUint64(hello[:], rank)
+	dial := func(to uint64, addr string) (net.Conn, error) {
+		conn, err := net.Dial(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := conn.Write(hello[:]); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+	identify := func(conn net.Conn) (uint64, error) {
+		var hello [8]byte
+		if _, err := io.ReadFull(conn, hello[:]); err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(hello[:]), nil
+	}
+
+	conns, err := connectAll(rank, addrs, l, dial, identify)
+	if err != nil {
+		return nil, err
+	}
+	t := &Network{rank: rank, conns: conns, pipes: make([]*pipe, len(conns))}
+	for i, conn := range conns {
+		if conn == nil {
+			continue
+		}
+		t.pipes[i] = newPipe()
+		go drain(conn, t.pipes[i])
+	}
+	return t, nil
+}
+
+// connectAll returns connections from party rank to all the other parties, nil for
+// rank. It accepts the parties of higher rank on l, identify returning the rank of the
+// party of an accepted connection, and dials the parties of lower rank at addrs. l is
+// closed on return.
+func connectAll(rank uint64, addrs []string, l net.Listener, dial func(to uint64, addr string) (net.Conn, error), identify func(conn net.Conn) (uint64, error)) ([]net.Conn, error) {
+	n := uint64(len(addrs))
+	if rank >= n {
+		l.Close()
+		return nil, fmt.Errorf("rank %d out of %d parties", rank, n)
+	}
+	conns := make([]net.Conn, n)
+
+	// accept the parties of higher rank
+	accepted := make(chan error, 1)
+	go func() {
+		for k := rank + 1; k < n; k++ {
+			conn, err := l.Accept()
+			if err != nil {
+				accepted <- err
+				return
+			}
+			// a connection from no party of higher rank, or from a party already
+			// connected, is dropped
+			conn.SetDeadline(time.Now().Add(DialTimeout))
+			from, err := identify(conn)
+			if err != nil || from <= rank || from >= n || conns[from] != nil {
+				conn.Close()
+				k--
+				continue
+			}
+			conn.SetDeadline(time.Time{})
+			conns[from] = conn
+		}
+		accepted <- nil
+	}()
+
+	// dial the parties of lower rank, retrying while they don't listen yet
+	deadline := time.Now().Add(DialTimeout)
+	var err error
+	for to := uint64(0); to < rank && err == nil; to++ {
+		conns[to], err = dial(to, addrs[to])
+		for err != nil && isDialError(err) && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			conns[to], err = dial(to, addrs[to])
+		}
+		if err != nil {
+			err = fmt.Errorf("party %d: %w", to, err)
+		}
+	}
+	if err != nil {
+		// stop accepting
+		l.Close()
+	}
+	if errAccept := <-accepted; err == nil {
+		err = errAccept
+	}
+	l.Close()
+
+	if err != nil {
+		for _, conn := range conns {
+			if conn != nil {
+				conn.Close()
+			}
+		}
+		return nil, err
+	}
+	return conns, nil
+}
+
+// isDialError tells whether err comes from connecting to a party, which may not listen
+// yet, rather than from the exchange which follows
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// drain copies the bytes received on conn to p until conn fails or is closed
+func drain(conn net.Conn, p *pipe) {
+	buf := make([]byte, 1<<16)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			p.write(buf[:n])
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			p.fail(err)
+			return
+		}
+	}
+}
+
+func (t *Network) Rank() uint64 { return t.rank }
+func (t *Network) Size() uint64 { return uint64(len(t.conns)) }
+
+func (t *Network) Send(buf []byte, to uint64) error {
+	if to >= t.Size() || to == t.rank {
+		return fmt.Errorf("party %d can't send to party %d", t.rank, to)
+	}
+	if _, err := t.conns[to].Write(buf); err != nil {
+		return err
+	}
+	t.BytesSent += uint64(len(buf))
+	return nil
+}
+
+func (t *Network) Receive(size uint64, from uint64) ([]byte, error) {
+	if from >= t.Size() || from == t.rank {
+		return nil, fmt.Errorf("party %d can't receive from party %d", t.rank, from)
+	}
+	buf, err := t.pipes[from].read(size)
+	if err != nil {
+		return nil, fmt.Errorf("party %d: %w", from, err)
+	}
+	t.BytesReceived += size
+	return buf, nil
+}
+
+// Close closes the connections to the other parties
+func (t *Network) Close() error {
+	var res error
+	for _, conn := range t.conns {
+		if conn != nil {
+			if err := conn.Close(); err != nil && res == nil {
+				res = err
+			}
+		}
+	}
+	return res
+}
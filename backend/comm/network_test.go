@@ -0,0 +1,113 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comm
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// connect links size parties listening on the given network
+func connect(t *testing.T, network string, size int) []*Network {
+	listeners := make([]net.Listener, size)
+	addrs := make([]string, size)
+	for i := range listeners {
+		addr := "127.0.0.1:0"
+		if network == "unix" {
+			addr = filepath.Join(t.TempDir(), "party.sock")
+		}
+		l, err := net.Listen(network, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listeners[i], addrs[i] = l, l.Addr().String()
+	}
+
+	transports := make([]*Network, size)
+	errs := make([]error, size)
+	var wg sync.WaitGroup
+	for i := range transports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			transports[i], errs[i] = ConnectNetwork(uint64(i), network, addrs, listeners[i])
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("party %d: %v", i, err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, tr := range transports {
+			tr.Close()
+		}
+	})
+	return transports
+}
+
+func TestNetwork(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		for _, topology := range testTopologies {
+			for _, size := range []int{1, 2, 5} {
+				t.Run(fmt.Sprintf("%s/%s/%d", network, topology, size), func(t *testing.T) {
+					transports := connect(t, network, size)
+					errs := make([]error, size)
+					var wg sync.WaitGroup
+					for i := range transports {
+						wg.Add(1)
+						go func(i int) {
+							defer wg.Done()
+							errs[i] = checkCollectives(&Communicator{Transport: transports[i], Topology: topology}, size)
+						}(i)
+					}
+					wg.Wait()
+					for i, err := range errs {
+						if err != nil {
+							t.Fatalf("party %d: %v", i, err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestNetworkClosed(t *testing.T) {
+	transports := connect(t, "tcp", 2)
+
+	// both parties send more than the socket buffers before receiving
+	buf := make([]byte, 1<<22)
+	for _, tr := range transports {
+		if err := tr.Send(buf, 1-tr.Rank()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tr := range transports {
+		if _, err := tr.Receive(uint64(len(buf)), 1-tr.Rank()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a receive fails once the other party left
+	transports[1].Close()
+	if _, err := transports[0].Receive(1, 1); err == nil {
+		t.Fatal("receive from a closed party should fail")
+	}
+}
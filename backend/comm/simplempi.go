@@ -0,0 +1,83 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comm
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+)
+
+// RouteSimpleMPI makes simpleMPI go through t: it sets the rank and the world size of
+// simpleMPI to the ones of t, and replaces its connections between rank 0 and the other
+// parties by connections over t. The code calling simpleMPI directly, such as the
+// distributed KZG of the gnark-crypto fork, then reaches the other parties through t
+// instead of the connections opened by mpi.WorldInit.
+//
+// mpi.WorldInit must have left simpleMPI with a single party, from an ip.txt listing one
+// address, so that it didn't connect to the other parties itself.
+func RouteSimpleMPI(t Transport) {
+	mpi.SelfRank, mpi.WorldSize = t.Rank(), t.Size()
+	if t.Rank() != 0 {
+		var conn net.Conn = &transportConn{t: t, peer: 0}
+		mpi.MasterToSlaveTCPConn, mpi.SlaveToMasterTCPConn = nil, &conn
+		return
+	}
+	conns := make([]*net.Conn, t.Size())
+	for i := uint64(1); i < t.Size(); i++ {
+		var conn net.Conn = &transportConn{t: t, peer: i}
+		conns[i] = &conn
+	}
+	mpi.MasterToSlaveTCPConn, mpi.SlaveToMasterTCPConn = conns, nil
+}
+
+// transportConn is the net.Conn to the party peer over a Transport that RouteSimpleMPI
+// gives to simpleMPI. simpleMPI reads exactly the bytes it expects, which are received
+// as one message of the transport. The deadlines are left to the transport, and the
+// connections are closed with it.
+type transportConn struct {
+	t    Transport
+	peer uint64
+}
+
+func (c *transportConn) Read(b []byte) (int, error) {
+	buf, err := c.t.Receive(uint64(len(b)), c.peer)
+	if err != nil {
+		return 0, err
+	}
+	return copy(b, buf), nil
+}
+
+func (c *transportConn) Write(b []byte) (int, error) {
+	if err := c.t.Send(b, c.peer); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *transportConn) Close() error                       { return nil }
+func (c *transportConn) LocalAddr() net.Addr                { return rankAddr(c.t.Rank()) }
+func (c *transportConn) RemoteAddr() net.Addr               { return rankAddr(c.peer) }
+func (c *transportConn) SetDeadline(t time.Time) error      { return nil }
+func (c *transportConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *transportConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// rankAddr is the address of a party over a Transport, its rank
+type rankAddr uint64
+
+func (a rankAddr) Network() string { return "comm" }
+func (a rankAddr) String() string  { return strconv.FormatUint(uint64(a), 10) }
@@ -0,0 +1,426 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comm
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// ClusterConfig describes the parties linked by ConnectTLS, as read by LoadClusterConfig
+// from a JSON file such as
+//
+//	{
+//		"rank": 1,
+//		"certificate": "party-1.pem",
+//		"key": "party-1.key",
+//		"parties": [
+//			{"address": "10.0.0.1:9000", "certificate": "party-0.pem"},
+//			{"address": "10.0.0.2:9000", "certificate": "party-1.pem"}
+//		]
+//	}
+//
+// Each party authenticates with its certificate, which may be self-signed: a party only
+// accepts the certificate listed for the rank of the other party. The paths are
+// relative to the file.
+type ClusterConfig struct {
+	// Rank of this party, which listens on Parties[Rank].Address
+	Rank uint64 `json:"rank"`
+
+	// PEM certificate and private key of this party
+	Certificate string `json:"certificate"`
+	Key         string `json:"key"`
+
+	// MaxFrameSize is the largest payload of a frame, DefaultMaxFrameSize if zero. The
+	// messages are split into frames of at most this size, and a party fails to read a
+	// larger frame, so that all the parties must have the same.
+	MaxFrameSize uint64 `json:"maxFrameSize,omitempty"`
+
+	Parties []PartyConfig `json:"parties"`
+}
+
+// PartyConfig is the address and the PEM certificate of a party, see ClusterConfig
+type PartyConfig struct {
+	Address     string `json:"address"`
+	Certificate string `json:"certificate"`
+}
+
+// LoadClusterConfig reads a ClusterConfig from the JSON file at path
+func LoadClusterConfig(path string) (*ClusterConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg ClusterConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	resolve(&cfg.Certificate)
+	resolve(&cfg.Key)
+	for i := range cfg.Parties {
+		resolve(&cfg.Parties[i].Certificate)
+	}
+	return &cfg, nil
+}
+
+// TLS is a Transport over mutually authenticated TLS 1.3 connections linking every pair
+// of parties.
+//
+// The messages are framed with the ranks of their sender and receiver, the round of the
+// protocol they were sent in, see RoundTagger, and a sequence number per pair of
+// parties. A party fails to receive a frame which isn't the next one of the link,
+// isn't addressed to it, or comes from another round. TLS protects the frames from
+// changes and from replays across connections, the sequence numbers from replays and
+// drops within a connection.
+type TLS struct {
+	rank  uint64
+	conns []net.Conn
+	links []*link // links[from]
+
+	// maxFrame is the largest payload of a frame sent or received
+	maxFrame uint64
+
+	// round is the current round, incremented by NextRound
+	round uint64
+
+	// mu guards seqs and the counters, and is held while a frame is written so that
+	// the frames to a party are written in the order of their sequence numbers
+	mu sync.Mutex
+
+	// seqs[to] is the sequence number of the next frame to party to
+	seqs []uint64
+
+	// BytesSent and BytesReceived count the payload of the frames of this party
+	BytesSent, BytesReceived uint64
+}
+
+// frameHeaderSize is the size of the header of a frame: the ranks of the sender and
+// the receiver, the round, the sequence number and the size of the payload
+const frameHeaderSize = 5 * 8
+
+// DefaultMaxFrameSize is the largest payload of a frame without ClusterConfig.MaxFrameSize
+const DefaultMaxFrameSize = 1 << 24
+
+// ConnectTLS links this party to the other parties of cfg. It listens on the address of
+// this party until all the parties of higher rank are connected, and dials the ones of
+// lower rank. Every party must call it.
+func ConnectTLS(cfg *ClusterConfig) (*TLS, error) {
+	n := uint64(len(cfg.Parties))
+	if cfg.Rank >= n {
+		return nil, fmt.Errorf("rank %d out of %d parties", cfg.Rank, n)
+	}
+	own, err := tls.LoadX509KeyPair(cfg.Certificate, cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make([][]byte, n)
+	for i, p := range cfg.Parties {
+		if pinned[i], err = readCertificate(p.Certificate); err != nil {
+			return nil, fmt.Errorf("party %d: %w", i, err)
+		}
+		for j := 0; j < i; j++ {
+			if bytes.Equal(pinned[i], pinned[j]) {
+				return nil, fmt.Errorf("parties %d and %d have the same certificate", j, i)
+			}
+		}
+	}
+	if !bytes.Equal(own.Certificate[0], pinned[cfg.Rank]) {
+		return nil, fmt.Errorf("the certificate of party %d isn't the one of the cluster", cfg.Rank)
+	}
+
+	// the peers are authenticated by their pinned certificate instead of a chain
+	rankOf := func(rawCerts [][]byte) (uint64, error) {
+		if len(rawCerts) == 0 {
+			return 0, errors.New("no certificate")
+		}
+		for i := range pinned {
+			if bytes.Equal(rawCerts[0], pinned[i]) {
+				return uint64(i), nil
+			}
+		}
+		return 0, errors.New("unknown certificate")
+	}
+	serverConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{own},
+		ClientAuth:   tls.RequireAnyClientCert,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			from, err := rankOf(rawCerts)
+			if err == nil && from <= cfg.Rank {
+				err = fmt.Errorf("party %d can't dial party %d", from, cfg.Rank)
+			}
+			return err
+		},
+	}
+	dial := func(to uint64, addr string) (net.Conn, error) {
+		clientConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS13,
+			Certificates:       []tls.Certificate{own},
+			InsecureSkipVerify: true,
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				if len(rawCerts) == 0 || !bytes.Equal(rawCerts[0], pinned[to]) {
+					return fmt.Errorf("party %d presented another certificate", to)
+				}
+				return nil
+			},
+		}
+		conn, err := tls.Dial("tcp", addr, clientConfig)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+	identify := func(conn net.Conn) (uint64, error) {
+		c := conn.(*tls.Conn)
+		if err := c.Handshake(); err != nil {
+			return 0, err
+		}
+		return rankOf([][]byte{c.ConnectionState().PeerCertificates[0].Raw})
+	}
+
+	l, err := net.Listen("tcp", cfg.Parties[cfg.Rank].Address)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, n)
+	for i, p := range cfg.Parties {
+		addrs[i] = p.Address
+	}
+	conns, err := connectAll(cfg.Rank, addrs, tls.NewListener(l, serverConfig), dial, identify)
+	if err != nil {
+		return nil, err
+	}
+	maxFrame := cfg.MaxFrameSize
+	if maxFrame == 0 {
+		maxFrame = DefaultMaxFrameSize
+	}
+	return newTLS(cfg.Rank, conns, maxFrame), nil
+}
+
+// newTLS starts reading the frames of the connections of party rank, whose payloads are
+// at most maxFrame bytes
+func newTLS(rank uint64, conns []net.Conn, maxFrame uint64) *TLS {
+	t := &TLS{rank: rank, conns: conns, links: make([]*link, len(conns)), maxFrame: maxFrame, seqs: make([]uint64, len(conns))}
+	for i, conn := range conns {
+		if conn == nil {
+			continue
+		}
+		t.links[i] = newLink()
+		go t.readFrames(uint64(i), conn, t.links[i])
+	}
+	return t
+}
+
+// readCertificate returns the DER encoding of the first certificate of the PEM file
+// at path
+func readCertificate(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return nil, fmt.Errorf("%s: no certificate", path)
+		}
+		if block.Type == "CERTIFICATE" {
+			return block.Bytes, nil
+		}
+	}
+}
+
+func (t *TLS) Rank() uint64 { return t.rank }
+func (t *TLS) Size() uint64 { return uint64(len(t.conns)) }
+
+// NextRound implements RoundTagger
+func (t *TLS) NextRound() {
+	atomic.AddUint64(&t.round, 1)
+}
+
+// Send sends buf in frames of at most the maximal frame size, which Receive reads back
+// as a whole
+func (t *TLS) Send(buf []byte, to uint64) error {
+	if to >= t.Size() || to == t.rank {
+		return fmt.Errorf("party %d can't send to party %d", t.rank, to)
+	}
+	round := atomic.LoadUint64(&t.round)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for len(buf) != 0 {
+		n := len(buf)
+		if uint64(n) > t.maxFrame {
+			n = int(t.maxFrame)
+		}
+		frame := make([]byte, frameHeaderSize+n)
+		binary.BigEndian.PutUint64(frame[0:], t.rank)
+		binary.BigEndian.PutUint64(frame[8:], to)
+		binary.BigEndian.PutUint64(frame[16:], round)
+		binary.BigEndian.PutUint64(frame[24:], t.seqs[to])
+		binary.BigEndian.PutUint64(frame[32:], uint64(n))
+		copy(frame[frameHeaderSize:], buf[:n])
+		if _, err := t.conns[to].Write(frame); err != nil {
+			return err
+		}
+		t.seqs[to]++
+		t.BytesSent += uint64(n)
+		buf = buf[n:]
+	}
+	return nil
+}
+
+func (t *TLS) Receive(size uint64, from uint64) ([]byte, error) {
+	if from >= t.Size() || from == t.rank {
+		return nil, fmt.Errorf("party %d can't receive from party %d", t.rank, from)
+	}
+	buf, err := t.links[from].read(size, atomic.LoadUint64(&t.round))
+	if err != nil {
+		return nil, fmt.Errorf("party %d: %w", from, err)
+	}
+	t.mu.Lock()
+	t.BytesReceived += size
+	t.mu.Unlock()
+	return buf, nil
+}
+
+// Close closes the connections to the other parties
+func (t *TLS) Close() error {
+	var res error
+	for _, conn := range t.conns {
+		if conn != nil {
+			if err := conn.Close(); err != nil && res == nil {
+				res = err
+			}
+		}
+	}
+	return res
+}
+
+// readFrames checks the frames sent by party from on r and queues them on l, until r
+// fails or a frame is rejected
+func (t *TLS) readFrames(from uint64, r io.Reader, l *link) {
+	var header [frameHeaderSize]byte
+	for seq := uint64(0); ; seq++ {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			l.fail(err)
+			return
+		}
+		switch {
+		case binary.BigEndian.Uint64(header[0:]) != from:
+			l.fail(fmt.Errorf("frame from party %d on the link of party %d", binary.BigEndian.Uint64(header[0:]), from))
+			return
+		case binary.BigEndian.Uint64(header[8:]) != t.rank:
+			l.fail(fmt.Errorf("frame to party %d received by party %d", binary.BigEndian.Uint64(header[8:]), t.rank))
+			return
+		case binary.BigEndian.Uint64(header[24:]) != seq:
+			l.fail(fmt.Errorf("frame %d received instead of frame %d, replayed or dropped", binary.BigEndian.Uint64(header[24:]), seq))
+			return
+		case binary.BigEndian.Uint64(header[32:]) > t.maxFrame:
+			l.fail(fmt.Errorf("frame of %d bytes, larger than %d", binary.BigEndian.Uint64(header[32:]), t.maxFrame))
+			return
+		}
+		f := frame{round: binary.BigEndian.Uint64(header[16:])}
+		f.data = make([]byte, binary.BigEndian.Uint64(header[32:]))
+		if _, err := io.ReadFull(r, f.data); err != nil {
+			l.fail(io.ErrUnexpectedEOF)
+			return
+		}
+		l.push(f)
+	}
+}
+
+// frame is the payload of a frame received by TLS, with the round it was sent in
+type frame struct {
+	round uint64
+	data  []byte
+}
+
+// link is the queue of the frames received from a party, which fails once the party
+// can't be read anymore
+type link struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	frames []frame
+	err    error
+}
+
+func newLink() *link {
+	l := &link{}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *link) push(f frame) {
+	l.mu.Lock()
+	l.frames = append(l.frames, f)
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+func (l *link) fail(err error) {
+	l.mu.Lock()
+	l.err = err
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// read returns the next size bytes of the frames, which must all be from the given
+// round
+func (l *link) read(size uint64, round uint64) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	res := make([]byte, 0, size)
+	for uint64(len(res)) < size {
+		if len(l.frames) == 0 {
+			if l.err != nil {
+				return nil, l.err
+			}
+			l.cond.Wait()
+			continue
+		}
+		f := &l.frames[0]
+		if f.round != round {
+			return nil, fmt.Errorf("message of round %d received in round %d", f.round, round)
+		}
+		k := copy(res[len(res):size], f.data)
+		res = res[:len(res)+k]
+		if f.data = f.data[k:]; len(f.data) == 0 {
+			l.frames = l.frames[1:]
+		}
+	}
+	return res, nil
+}
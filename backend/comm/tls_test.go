@@ -0,0 +1,402 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+)
+
+// writeCertificate writes a self-signed certificate and its key to dir/name.pem and
+// dir/name.key
+func writeCertificate(t *testing.T, dir, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// freeAddrs returns size loopback addresses nothing listens on
+func freeAddrs(t *testing.T, size int) []string {
+	res := make([]string, size)
+	for i := range res {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res[i] = l.Addr().String()
+		l.Close()
+	}
+	return res
+}
+
+// writeCluster writes the certificates and the configuration files of size parties to
+// dir, and returns the paths of the configuration files
+func writeCluster(t *testing.T, dir string, size int) []string {
+	addrs := freeAddrs(t, size)
+	parties := make([]PartyConfig, size)
+	for i := range parties {
+		writeCertificate(t, dir, fmt.Sprintf("party-%d", i))
+		parties[i] = PartyConfig{Address: addrs[i], Certificate: fmt.Sprintf("party-%d.pem", i)}
+	}
+	paths := make([]string, size)
+	for i := range paths {
+		data, err := json.Marshal(ClusterConfig{
+			Rank:        uint64(i),
+			Certificate: fmt.Sprintf("party-%d.pem", i),
+			Key:         fmt.Sprintf("party-%d.key", i),
+			Parties:     parties,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[i] = filepath.Join(dir, fmt.Sprintf("party-%d.json", i))
+		if err := os.WriteFile(paths[i], data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+// connectTLS links the parties of the given configuration files
+func connectTLS(t *testing.T, paths []string) ([]*TLS, []error) {
+	transports := make([]*TLS, len(paths))
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cfg, err := LoadClusterConfig(paths[i])
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			transports[i], errs[i] = ConnectTLS(cfg)
+		}(i)
+	}
+	wg.Wait()
+	t.Cleanup(func() {
+		for _, tr := range transports {
+			if tr != nil {
+				tr.Close()
+			}
+		}
+	})
+	return transports, errs
+}
+
+func TestTLS(t *testing.T) {
+	for _, topology := range testTopologies {
+		const size = 3
+		transports, errs := connectTLS(t, writeCluster(t, t.TempDir(), size))
+		for i, err := range errs {
+			if err != nil {
+				t.Fatalf("%s: party %d: %v", topology, i, err)
+			}
+		}
+
+		var wg sync.WaitGroup
+		for i := range transports {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				c := &Communicator{Transport: transports[i], Topology: topology}
+				errs[i] = checkCollectives(c, size)
+				c.NextRound()
+				if errs[i] == nil {
+					errs[i] = checkCollectives(c, size)
+				}
+			}(i)
+		}
+		wg.Wait()
+		for i, err := range errs {
+			if err != nil {
+				t.Fatalf("%s: party %d: %v", topology, i, err)
+			}
+		}
+	}
+}
+
+func TestTLSImpostor(t *testing.T) {
+	dir := t.TempDir()
+	paths := writeCluster(t, dir, 2)
+	cfgs := make([]*ClusterConfig, 2)
+	for i := range cfgs {
+		cfg, err := LoadClusterConfig(paths[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfgs[i] = cfg
+	}
+
+	// party 0 waits for party 1
+	connected := make(chan error, 1)
+	var party0 *TLS
+	go func() {
+		var err error
+		party0, err = ConnectTLS(cfgs[0])
+		connected <- err
+	}()
+
+	// an impostor claims rank 1 with a certificate of its own
+	writeCertificate(t, dir, "impostor")
+	impostorCfg := *cfgs[1]
+	impostorCfg.Certificate, impostorCfg.Key = filepath.Join(dir, "impostor.pem"), filepath.Join(dir, "impostor.key")
+	impostorCfg.Parties = append([]PartyConfig(nil), cfgs[1].Parties...)
+	impostorCfg.Parties[1] = PartyConfig{Address: freeAddrs(t, 1)[0], Certificate: impostorCfg.Certificate}
+	impostor, err := ConnectTLS(&impostorCfg)
+	if err == nil {
+		_, err = impostor.Receive(1, 0)
+		impostor.Close()
+	}
+	if err == nil {
+		t.Fatal("the impostor should be rejected")
+	}
+
+	// party 0 still accepts the genuine party 1
+	party1, err := ConnectTLS(cfgs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer party1.Close()
+	if err := <-connected; err != nil {
+		t.Fatal(err)
+	}
+	defer party0.Close()
+	if err := party1.Send([]byte{1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := party0.Receive(1, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSFrames(t *testing.T) {
+	// frame returns a frame from party 0 to party 1
+	frame := func(round, seq uint64, data []byte) []byte {
+		var header [frameHeaderSize]byte
+		binary.BigEndian.PutUint64(header[8:], 1)
+		binary.BigEndian.PutUint64(header[16:], round)
+		binary.BigEndian.PutUint64(header[24:], seq)
+		binary.BigEndian.PutUint64(header[32:], uint64(len(data)))
+		return append(header[:], data...)
+	}
+
+	t.Run("split", func(t *testing.T) {
+		sender, receiver := net.Pipe()
+		tr := newTLS(1, []net.Conn{receiver, nil}, DefaultMaxFrameSize)
+		defer tr.Close()
+		go sender.Write(append(frame(0, 0, []byte{1, 2, 3}), frame(0, 1, []byte{4})...))
+		buf, err := tr.Receive(2, 0)
+		if err != nil || buf[0] != 1 || buf[1] != 2 {
+			t.Fatal(buf, err)
+		}
+		if buf, err = tr.Receive(2, 0); err != nil || buf[0] != 3 || buf[1] != 4 {
+			t.Fatal(buf, err)
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		sender, receiver := net.Pipe()
+		tr := newTLS(1, []net.Conn{receiver, nil}, DefaultMaxFrameSize)
+		defer tr.Close()
+		go sender.Write(frame(0, 0, []byte{1}))
+		if _, err := tr.Receive(1, 0); err != nil {
+			t.Fatal(err)
+		}
+		go sender.Write(frame(0, 0, []byte{1}))
+		if _, err := tr.Receive(1, 0); err == nil || !strings.Contains(err.Error(), "replayed") {
+			t.Fatalf("got %v, want a replayed frame", err)
+		}
+	})
+
+	t.Run("round", func(t *testing.T) {
+		sender, receiver := net.Pipe()
+		tr := newTLS(1, []net.Conn{receiver, nil}, DefaultMaxFrameSize)
+		defer tr.Close()
+		go sender.Write(frame(0, 0, []byte{1}))
+		tr.NextRound()
+		if _, err := tr.Receive(1, 0); err == nil || !strings.Contains(err.Error(), "round") {
+			t.Fatalf("got %v, want a frame of another round", err)
+		}
+	})
+
+	t.Run("oversized", func(t *testing.T) {
+		sender, receiver := net.Pipe()
+		tr := newTLS(1, []net.Conn{receiver, nil}, 4)
+		defer tr.Close()
+
+		// the header alone claims more than the receiver accepts
+		var header [frameHeaderSize]byte
+		binary.BigEndian.PutUint64(header[8:], 1)
+		binary.BigEndian.PutUint64(header[32:], 1<<62)
+		go sender.Write(header[:])
+		if _, err := tr.Receive(1, 0); err == nil || !strings.Contains(err.Error(), "larger than") {
+			t.Fatalf("got %v, want an oversized frame", err)
+		}
+	})
+
+	t.Run("send in frames", func(t *testing.T) {
+		a, b := net.Pipe()
+		sender := newTLS(0, []net.Conn{nil, a}, 2)
+		receiver := newTLS(1, []net.Conn{b, nil}, 2)
+		defer sender.Close()
+		defer receiver.Close()
+		errs := make(chan error, 1)
+		go func() { errs <- sender.Send([]byte{1, 2, 3, 4, 5}, 1) }()
+		buf, err := receiver.Receive(5, 0)
+		if err != nil || string(buf) != string([]byte{1, 2, 3, 4, 5}) {
+			t.Fatal(buf, err)
+		}
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+		if sender.seqs[1] != 3 {
+			t.Fatalf("sent %d frames, want 3", sender.seqs[1])
+		}
+	})
+}
+
+// TestTLSConcurrentSends sends from party 0 to the other parties from several
+// goroutines at once, which the race detector checks with go test -race
+func TestTLSConcurrentSends(t *testing.T) {
+	const size, nbSenders, nbMessages = 3, 4, 16
+	transports, errs := connectTLS(t, writeCluster(t, t.TempDir(), size))
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("party %d: %v", i, err)
+		}
+	}
+
+	sendErrs := make([]error, size*nbSenders)
+	receiveErrs := make([]error, size)
+	var wg sync.WaitGroup
+	for to := uint64(1); to < size; to++ {
+		for k := 0; k < nbSenders; k++ {
+			wg.Add(1)
+			go func(to uint64, k int) {
+				defer wg.Done()
+				for m := 0; m < nbMessages; m++ {
+					if err := transports[0].Send(part(to), to); err != nil {
+						sendErrs[int(to)*nbSenders+k] = err
+						return
+					}
+				}
+			}(to, k)
+		}
+
+		// the frames are received in the order of their sequence numbers
+		wg.Add(1)
+		go func(to uint64) {
+			defer wg.Done()
+			for m := 0; m < nbSenders*nbMessages; m++ {
+				buf, err := transports[to].Receive(uint64(len(part(to))), 0)
+				if err != nil {
+					receiveErrs[to] = err
+					return
+				}
+				if string(buf) != string(part(to)) {
+					receiveErrs[to] = fmt.Errorf("got %v", buf)
+					return
+				}
+			}
+		}(to)
+	}
+	wg.Wait()
+	for i, err := range sendErrs {
+		if err != nil {
+			t.Fatalf("send to party %d: %v", i/nbSenders, err)
+		}
+	}
+	for i, err := range receiveErrs {
+		if err != nil {
+			t.Fatalf("party %d: %v", i, err)
+		}
+	}
+	if want := uint64((size - 1) * nbSenders * nbMessages * len(part(0))); transports[0].BytesSent != want {
+		t.Fatalf("sent %d bytes, want %d", transports[0].BytesSent, want)
+	}
+}
+
+// TestTLSRouteSimpleMPI checks that the calls to simpleMPI of party 0, such as the ones
+// of the distributed KZG, go through the TLS links once routed
+func TestTLSRouteSimpleMPI(t *testing.T) {
+	const size = 2
+	transports, errs := connectTLS(t, writeCluster(t, t.TempDir(), size))
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("party %d: %v", i, err)
+		}
+	}
+
+	defer func(rank, size uint64, master []*net.Conn, slave *net.Conn) {
+		mpi.SelfRank, mpi.WorldSize, mpi.MasterToSlaveTCPConn, mpi.SlaveToMasterTCPConn = rank, size, master, slave
+	}(mpi.SelfRank, mpi.WorldSize, mpi.MasterToSlaveTCPConn, mpi.SlaveToMasterTCPConn)
+	RouteSimpleMPI(transports[0])
+
+	if err := mpi.SendBytes(part(1), 1); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := transports[1].Receive(uint64(len(part(1))), 0)
+	if err != nil || string(buf) != string(part(1)) {
+		t.Fatal(buf, err)
+	}
+	if err := transports[1].Send(part(0), 0); err != nil {
+		t.Fatal(err)
+	}
+	if buf, err = mpi.ReceiveBytes(uint64(len(part(0))), 1); err != nil || string(buf) != string(part(0)) {
+		t.Fatal(buf, err)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package comm
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+)
+
+// SimpleMPI is the Transport of github.com/sunblaze-ucb/simpleMPI. simpleMPI only links
+// rank 0 to the other parties, so it only supports the Star topology.
+type SimpleMPI struct{}
+
+func (SimpleMPI) Rank() uint64 { return mpi.SelfRank }
+func (SimpleMPI) Size() uint64 { return mpi.WorldSize }
+
+func (SimpleMPI) Send(buf []byte, to uint64) error {
+	if mpi.SelfRank != 0 && to != 0 {
+		return fmt.Errorf("simpleMPI can't send from party %d to party %d, use the star topology", mpi.SelfRank, to)
+	}
+	return mpi.SendBytes(buf, to)
+}
+
+func (SimpleMPI) Receive(size uint64, from uint64) ([]byte, error) {
+	if mpi.SelfRank != 0 && from != 0 {
+		return nil, fmt.Errorf("simpleMPI can't receive on party %d from party %d, use the star topology", mpi.SelfRank, from)
+	}
+	return mpi.ReceiveBytes(size, from)
+}
+
+// Local is an in-process Transport, the parties being goroutines, which counts the
+// traffic of its party.
+type Local struct {
+	rank  uint64
+	pipes [][]*pipe // pipes[from][to]
+
+	// BytesSent, BytesReceived, MessagesSent and MessagesReceived count the traffic of
+	// this party
+	BytesSent, BytesReceived       uint64
+	MessagesSent, MessagesReceived uint64
+}
+
+// NewLocal returns the transports of size parties linked to each other in memory
+func NewLocal(size int) []*Local {
+	pipes := make([][]*pipe, size)
+	for i := range pipes {
+		pipes[i] = make([]*pipe, size)
+		for j := range pipes[i] {
+			pipes[i][j] = newPipe()
+		}
+	}
+	res := make([]*Local, size)
+	for i := range res {
+		res[i] = &Local{rank: uint64(i), pipes: pipes}
+	}
+	return res
+}
+
+func (t *Local) Rank() uint64 { return t.rank }
+func (t *Local) Size() uint64 { return uint64(len(t.pipes)) }
+
+func (t *Local) Send(buf []byte, to uint64) error {
+	if to >= t.Size() || to == t.rank {
+		return fmt.Errorf("party %d can't send to party %d", t.rank, to)
+	}
+	t.pipes[t.rank][to].write(buf)
+	t.BytesSent += uint64(len(buf))
+	t.MessagesSent++
+	return nil
+}
+
+func (t *Local) Receive(size uint64, from uint64) ([]byte, error) {
+	if from >= t.Size() || from == t.rank {
+		return nil, fmt.Errorf("party %d can't receive from party %d", t.rank, from)
+	}
+	buf, err := t.pipes[from][t.rank].read(size)
+	if err != nil {
+		return nil, err
+	}
+	t.BytesReceived += size
+	t.MessagesReceived++
+	return buf, nil
+}
+
+// pipe is an unbounded in-memory byte stream, which fails once its writer stopped with
+// an error
+type pipe struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  bytes.Buffer
+	err  error
+}
+
+func newPipe() *pipe {
+	p := &pipe{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+func (p *pipe) write(b []byte) {
+	p.mu.Lock()
+	p.buf.Write(b)
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+// fail makes the reads waiting for more bytes than buffered return err
+func (p *pipe) fail(err error) {
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+func (p *pipe) read(size uint64) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for uint64(p.buf.Len()) < size {
+		if p.err != nil {
+			return nil, p.err
+		}
+		p.cond.Wait()
+	}
+	res := make([]byte, size)
+	copy(res, p.buf.Next(int(size)))
+	return res, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"errors"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+
+	gpiano_bn254 "github.com/consensys/gnark/internal/backend/bn254/gpiano"
+	witness_bn254 "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+// The circuits described below skip the frontend: every row is an instance of the
+// custom gate of gpiano on NbWitnesses wires with NbSelectors selectors, and the
+// witness is given as columns. Only BN254 is supported.
+const (
+	NbWitnesses = gpiano_bn254.NUM_WITNESSES
+	NbSelectors = gpiano_bn254.NUM_SELECTORS
+)
+
+// Wire identifies a wire of a row held by a party, see CircuitPart
+type Wire = gpiano_bn254.Wire
+
+// CircuitPart describes the selectors and the copy constraints of the rows held by a
+// party, for SetupDirect
+type CircuitPart = gpiano_bn254.CircuitPart
+
+// RandomCircuit describes a synthetic circuit generated by SetupRandom
+type RandomCircuit = gpiano_bn254.RandomCircuit
+
+// SetupDirect prepares the public data of the circuit described by the parts of all the
+// parties. It is collective, each party passing the rows it holds, and the number of
+// rows of each party decides the size of its domain, so backend.WithPartyWeights
+// doesn't apply.
+func SetupDirect(curveID ecc.ID, circuit *CircuitPart, opts ...backend.SetupOption) (ProvingKey, VerifyingKey, error) {
+	opt, err := backend.NewSetupConfig(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opt.PartyWeights != nil {
+		return nil, nil, errors.New("party weights don't apply to a circuit split by the caller")
+	}
+
+	switch curveID {
+	case ecc.BN254:
+		srs, err := srsBN254(opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		pk, vk, err := gpiano_bn254.SetupDirect(curveID, circuit, srs)
+		if err != nil {
+			return nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, nil
+	default:
+		panic("not implemented")
+	}
+}
+
+// SetupRandom generates a satisfiable synthetic circuit as described by circuit, for
+// benchmarks, and returns its public data with the witness columns of this party.
+// It is collective.
+func SetupRandom(curveID ecc.ID, circuit RandomCircuit, opts ...backend.SetupOption) (ProvingKey, VerifyingKey, [][]fr.Element, error) {
+	opt, err := backend.NewSetupConfig(opts...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	switch curveID {
+	case ecc.BN254:
+		srs, err := srsBN254(opt)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		pk, vk, witnesses, err := gpiano_bn254.SetupRandomCircuit(curveID, circuit, srs, opt.PartyWeights...)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, witnesses, nil
+	default:
+		panic("not implemented")
+	}
+}
+
+// ProveDirect generates a gpiano proof from the witness columns of the rows held by this
+// party, for a circuit set up by SetupDirect or SetupRandom. publicInputs are the first
+// entries of the first column on party 0.
+func ProveDirect(pk ProvingKey, witnesses [][]fr.Element, publicInputs []fr.Element, opts ...backend.ProverOption) (Proof, error) {
+	opt, err := backend.NewProverConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch _pk := pk.(type) {
+	case *gpiano_bn254.ProvingKey:
+		return gpiano_bn254.ProveDirect(_pk, witnesses, publicInputs, opt)
+	default:
+		panic("not implemented")
+	}
+}
+
+// NewPublicWitness returns the public witness of a proof generated by ProveDirect, to
+// pass to Verify.
+func NewPublicWitness(curveID ecc.ID, publicInputs []fr.Element) *witness.Witness {
+	switch curveID {
+	case ecc.BN254:
+		w := witness_bn254.New(publicInputs)
+		return &witness.Witness{Vector: &w, CurveID: curveID}
+	default:
+		panic("not implemented")
+	}
+}
@@ -1,12 +1,17 @@
 package gpiano
 
 import (
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/consensys/gnark-crypto/dkzg"
 	"github.com/consensys/gnark-crypto/ecc"
+	dkzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
 
 	"github.com/consensys/gnark/backend/witness"
 	cs_bn254 "github.com/consensys/gnark/internal/backend/bn254/cs"
@@ -16,12 +21,15 @@ import (
 	witness_bn254 "github.com/consensys/gnark/internal/backend/bn254/witness"
 )
 
+var errSRSCurve = errors.New("the SRS isn't of the curve of the constraint system")
+
 // Proof represents a gpiano proof generated by gpiano.Prove
 //
 // it's underlying implementation is curve specific (see gnark/internal/backend)
 type Proof interface {
 	io.WriterTo
 	io.ReaderFrom
+	WriteRawTo(w io.Writer) (int64, error)
 }
 
 // ProvingKey represents a gpiano ProvingKey
@@ -32,6 +40,12 @@ type ProvingKey interface {
 	io.ReaderFrom
 	InitKZG(srs dkzg.SRS) error
 	VerifyingKey() interface{}
+
+	// WritePolynomialsTo writes the polynomials of the key in a raw encoding, which
+	// MapPolynomials memory-maps in place of the ones in memory
+	WritePolynomialsTo(w io.Writer) (int64, error)
+	MapPolynomials(path string) error
+	UnmapPolynomials() error
 }
 
 // VerifyingKey represents a gpiano VerifyingKey
@@ -42,10 +56,19 @@ type VerifyingKey interface {
 	io.ReaderFrom
 	InitKZG(srs dkzg.SRS) error
 	NbPublicWitness() int // number of elements expected in the public witness
+
+	// Fingerprint returns the fingerprint of the constraint system the key was set up for,
+	// see CheckVerifyingKey
+	Fingerprint() compiled.Fingerprint
 }
 
 // Setup prepares the public data associated to a circuit + public inputs.
-func Setup(ccs frontend.CompiledConstraintSystem, publicWitness *witness.Witness) (ProvingKey, VerifyingKey, error) {
+func Setup(ccs frontend.CompiledConstraintSystem, publicWitness *witness.Witness, opts ...backend.SetupOption) (ProvingKey, VerifyingKey, error) {
+	// apply options
+	opt, err := backend.NewSetupConfig(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	switch tccs := ccs.(type) {
 	case *cs_bn254.SparseR1CS:
@@ -53,13 +76,38 @@ func Setup(ccs frontend.CompiledConstraintSystem, publicWitness *witness.Witness
 		if !ok {
 			return nil, nil, witness.ErrInvalidWitness
 		}
-		return gpiano_bn254.Setup(tccs, *w)
+		srs, err := srsBN254(opt)
+		if err != nil {
+			return nil, nil, err
+		}
+		pk, vk, err := gpiano_bn254.Setup(tccs, *w, srs, opt.PartyWeights...)
+		if err != nil {
+			return nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, nil
 	default:
 		panic("unimplemented")
 	}
 
 }
 
+// srsBN254 returns the SRS of the backend.WithSRS option, the zero SRS without it
+func srsBN254(opt backend.SetupConfig) (gpiano_bn254.SRS, error) {
+	if opt.DKZGSRS == nil {
+		return gpiano_bn254.SRS{}, nil
+	}
+	dsrs, ok := opt.DKZGSRS.(*dkzg_bn254.SRS)
+	if !ok {
+		return gpiano_bn254.SRS{}, errSRSCurve
+	}
+	srs, ok := opt.KZGSRS.(*kzg_bn254.SRS)
+	if !ok && opt.KZGSRS != nil {
+		return gpiano_bn254.SRS{}, errSRSCurve
+	}
+	return gpiano_bn254.SRS{X: dsrs, Y: srs}, nil
+}
+
 // Prove generates gpiano proof from a circuit, associated preprocessed public data, and the witness
 // if the force flag is set:
 // 	will executes all the prover computations, even if the witness is invalid
@@ -87,7 +135,11 @@ func Prove(ccs frontend.CompiledConstraintSystem, pk ProvingKey, fullWitness *wi
 }
 
 // Verify verifies a gpiano proof, from the proof, preprocessed public data, and public witness.
-func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness) error {
+// backend.WithCircuitFingerprint checks first that vk was set up for the circuit.
+func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness, opts ...backend.VerifierOption) error {
+	if err := checkFingerprint(vk, opts); err != nil {
+		return err
+	}
 
 	switch _proof := proof.(type) {
 
@@ -103,6 +155,71 @@ func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness) error
 	}
 }
 
+// CheckVerifyingKey returns nil if vk was set up for the compiled circuit ccs. It returns
+// an error wrapping compiled.ErrFingerprintMismatch if vk comes from another constraint
+// system, and compiled.ErrNoFingerprint if vk doesn't record the fingerprint of its own.
+func CheckVerifyingKey(vk VerifyingKey, ccs frontend.CompiledConstraintSystem) error {
+	return vk.Fingerprint().Check(ccs.Fingerprint())
+}
+
+// checkFingerprint checks vk against the circuit fingerprint of the verifier options, if
+// any
+func checkFingerprint(vk VerifyingKey, opts []backend.VerifierOption) error {
+	opt, err := backend.NewVerifierConfig(opts...)
+	if err != nil {
+		return err
+	}
+	if opt.CircuitFingerprint == ([32]byte{}) {
+		return nil
+	}
+	return vk.Fingerprint().Check(opt.CircuitFingerprint)
+}
+
+// BatchVerify verifies several gpiano proofs generated for the same verifying key, folding
+// all their openings into a constant number of pairings.
+//
+// If the batch doesn't pass, the returned error is a *gpiano_bn254.BatchVerifyError
+// holding the index of the failing proof. backend.WithCircuitFingerprint checks first
+// that vk was set up for the circuit.
+func BatchVerify(proofs []Proof, vk VerifyingKey, publicWitnesses []*witness.Witness, opts ...backend.VerifierOption) error {
+	if err := checkFingerprint(vk, opts); err != nil {
+		return err
+	}
+	switch _vk := vk.(type) {
+
+	case *gpiano_bn254.VerifyingKey:
+		_proofs, ws, err := toBN254(proofs, publicWitnesses)
+		if err != nil {
+			return err
+		}
+		return gpiano_bn254.BatchVerify(_proofs, _vk, ws)
+
+	default:
+		panic("unimplemented")
+	}
+}
+
+// toBN254 converts proofs and public witnesses to their bn254 implementation
+func toBN254(proofs []Proof, publicWitnesses []*witness.Witness) ([]*gpiano_bn254.Proof, []witness_bn254.Witness, error) {
+	if len(proofs) != len(publicWitnesses) {
+		return nil, nil, fmt.Errorf("got %d proofs but %d public witnesses", len(proofs), len(publicWitnesses))
+	}
+	_proofs := make([]*gpiano_bn254.Proof, len(proofs))
+	ws := make([]witness_bn254.Witness, len(publicWitnesses))
+	for i := range proofs {
+		var ok bool
+		if _proofs[i], ok = proofs[i].(*gpiano_bn254.Proof); !ok {
+			return nil, nil, fmt.Errorf("proof %d: unexpected type %T", i, proofs[i])
+		}
+		w, ok := publicWitnesses[i].Vector.(*witness_bn254.Witness)
+		if !ok {
+			return nil, nil, witness.ErrInvalidWitness
+		}
+		ws[i] = *w
+	}
+	return _proofs, ws, nil
+}
+
 // NewCS instantiate a concrete curved-typed SparseR1CS and return a ConstraintSystem interface
 // This method exists for (de)serialization purposes
 func NewCS(curveID ecc.ID) frontend.CompiledConstraintSystem {
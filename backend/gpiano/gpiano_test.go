@@ -0,0 +1,136 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	gpiano_bn254 "github.com/consensys/gnark/internal/backend/bn254/gpiano"
+)
+
+func TestVerifyCircuitFingerprint(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	const nbPublicInputs = 4
+	pk, vk, witnesses, err := SetupRandom(ecc.BN254, RandomCircuit{
+		NbConstraints:  1 << 6,
+		NbPublicInputs: nbPublicInputs,
+		GateDensity:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicInputs := witnesses[0][:nbPublicInputs]
+	proof, err := ProveDirect(pk, witnesses, publicInputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := NewPublicWitness(ecc.BN254, publicInputs)
+
+	// a synthetic circuit has no constraint system, so no fingerprint to check
+	fingerprint := compiled.Fingerprint{1}
+	err = Verify(proof, vk, publicWitness, backend.WithCircuitFingerprint(fingerprint))
+	if !errors.Is(err, compiled.ErrNoFingerprint) {
+		t.Fatalf("got %v, expected %v", err, compiled.ErrNoFingerprint)
+	}
+	if err := Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	vk.(*gpiano_bn254.VerifyingKey).CircuitFingerprint = fingerprint
+	if err := Verify(proof, vk, publicWitness, backend.WithCircuitFingerprint(fingerprint)); err != nil {
+		t.Fatal(err)
+	}
+	other := compiled.Fingerprint{2}
+	err = Verify(proof, vk, publicWitness, backend.WithCircuitFingerprint(other))
+	if !errors.Is(err, compiled.ErrFingerprintMismatch) {
+		t.Fatalf("got %v, expected %v", err, compiled.ErrFingerprintMismatch)
+	}
+	err = BatchVerify([]Proof{proof}, vk, []*witness.Witness{publicWitness}, backend.WithCircuitFingerprint(other))
+	if !errors.Is(err, compiled.ErrFingerprintMismatch) {
+		t.Fatalf("batch: got %v, expected %v", err, compiled.ErrFingerprintMismatch)
+	}
+}
+
+// rangeCircuit checks that its inputs are below 16 by looking them up in a table
+type rangeCircuit struct {
+	X   [4]frontend.Variable
+	Sum frontend.Variable `gnark:",public"`
+}
+
+func (c *rangeCircuit) Define(api frontend.API) error {
+	l := api.Compiler().(frontend.Lookuper)
+	entries := make([]frontend.Variable, 16)
+	for i := range entries {
+		entries[i] = i
+	}
+	l.SetLookupTable(entries...)
+	for _, x := range c.X {
+		l.Lookup(x)
+	}
+	api.AssertIsEqual(api.Add(c.X[0], c.X[1], c.X[2], c.X[3]), c.Sum)
+	return nil
+}
+
+func TestLookupCircuit(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &rangeCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	witnesses := func(x [4]frontend.Variable, sum int) (*witness.Witness, *witness.Witness) {
+		assignment := &rangeCircuit{X: x, Sum: sum}
+		full, err := frontend.NewWitness(assignment, ecc.BN254)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public, err := frontend.NewWitness(assignment, ecc.BN254, frontend.PublicOnly())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return full, public
+	}
+
+	fullWitness, publicWitness := witnesses([4]frontend.Variable{1, 5, 9, 15}, 30)
+	pk, vk, err := Setup(ccs, publicWitness)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := Prove(ccs, pk, fullWitness)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(proof, vk, publicWitness, backend.WithCircuitFingerprint(ccs.Fingerprint())); err != nil {
+		t.Fatal(err)
+	}
+
+	// 16 satisfies the gates but isn't in the table
+	fullWitness, _ = witnesses([4]frontend.Variable{16, 5, 9, 0}, 30)
+	if _, err := Prove(ccs, pk, fullWitness); err == nil {
+		t.Fatal("looking up a value out of the table should fail")
+	}
+}
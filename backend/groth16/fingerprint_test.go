@@ -0,0 +1,97 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groth16
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// refactoredCircuit is snarkJSCircuit after a refactor which changed a constant
+type refactoredCircuit snarkJSCircuit
+
+func (c *refactoredCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 6))
+	return nil
+}
+
+func TestCheckVerifyingKey(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &snarkJSCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refactored, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &refactoredCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vk, err := Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckVerifyingKey(vk, ccs); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckVerifyingKey(vk, refactored); !errors.Is(err, compiled.ErrFingerprintMismatch) {
+		t.Fatal(err)
+	}
+
+	// the fingerprint survives the versioned serialization
+	var buf bytes.Buffer
+	if _, err := vk.WriteVersionedTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	vk2 := NewVerifyingKey(ecc.BN254)
+	if _, err := vk2.ReadVersionedFrom(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckVerifyingKey(vk2, ccs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vk2.ReadVersionedFrom(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Fatal("reading a truncated fingerprint should fail")
+	}
+
+	// WriteTo writes the bellman format alone, which the versioned encoding wraps, and
+	// ReadFrom reads it back without fingerprint, leaving the bytes after it unread
+	var plain bytes.Buffer
+	if _, err := vk.WriteTo(&plain); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain.Bytes(), data[8:len(data)-len(compiled.Fingerprint{})]) {
+		t.Fatal("the versioned encoding doesn't wrap the bellman one")
+	}
+	r := bytes.NewReader(append(plain.Bytes(), 1, 2, 3))
+	vk3 := NewVerifyingKey(ecc.BN254)
+	if _, err := vk3.ReadFrom(r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Len() != 3 {
+		t.Fatalf("%d bytes left after the key, expected 3", r.Len())
+	}
+	if err := CheckVerifyingKey(vk3, ccs); !errors.Is(err, compiled.ErrNoFingerprint) {
+		t.Fatal(err)
+	}
+	if _, err := vk3.ReadVersionedFrom(bytes.NewReader(plain.Bytes())); err == nil {
+		t.Fatal("reading the bellman format as versioned should fail")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/backend/witness"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
 	backend_bls12377 "github.com/consensys/gnark/internal/backend/bls12-377/cs"
 	backend_bls12381 "github.com/consensys/gnark/internal/backend/bls12-381/cs"
 	backend_bls24315 "github.com/consensys/gnark/internal/backend/bls24-315/cs"
@@ -103,10 +104,23 @@ type VerifyingKey interface {
 	ExportSolidity(w io.Writer) error
 
 	IsDifferent(interface{}) bool
+
+	// Fingerprint returns the fingerprint of the constraint system the key was set up for,
+	// see CheckVerifyingKey
+	Fingerprint() compiled.Fingerprint
+
+	// WriteVersionedTo writes the key with its fingerprint, which the bellman format of
+	// WriteTo and WriteRawTo leaves out, in a versioned encoding ReadVersionedFrom reads
+	WriteVersionedTo(w io.Writer) (int64, error)
+	ReadVersionedFrom(r io.Reader) (int64, error)
 }
 
 // Verify runs the groth16.Verify algorithm on provided proof with given witness
-func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness) error {
+// backend.WithCircuitFingerprint checks first that vk was set up for the circuit.
+func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness, opts ...backend.VerifierOption) error {
+	if err := checkFingerprint(vk, opts); err != nil {
+		return err
+	}
 
 	switch _proof := proof.(type) {
 	case *groth16_bls12377.Proof:
@@ -150,6 +164,26 @@ func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness) error
 	}
 }
 
+// CheckVerifyingKey returns nil if vk was set up for the compiled circuit ccs. It returns
+// an error wrapping compiled.ErrFingerprintMismatch if vk comes from another constraint
+// system, and compiled.ErrNoFingerprint if vk doesn't record the fingerprint of its own.
+func CheckVerifyingKey(vk VerifyingKey, ccs frontend.CompiledConstraintSystem) error {
+	return vk.Fingerprint().Check(ccs.Fingerprint())
+}
+
+// checkFingerprint checks vk against the circuit fingerprint of the verifier options, if
+// any
+func checkFingerprint(vk VerifyingKey, opts []backend.VerifierOption) error {
+	opt, err := backend.NewVerifierConfig(opts...)
+	if err != nil {
+		return err
+	}
+	if opt.CircuitFingerprint == ([32]byte{}) {
+		return nil
+	}
+	return vk.Fingerprint().Check(opt.CircuitFingerprint)
+}
+
 // Prove runs the groth16.Prove algorithm.
 //
 // if the force flag is set:
@@ -0,0 +1,78 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groth16
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
+	groth16_bn254 "github.com/consensys/gnark/internal/backend/bn254/groth16"
+	witness_bn254 "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+// ErrSnarkJS is wrapped by the errors of ImportSnarkJS on malformed files
+var ErrSnarkJS = groth16_bn254.ErrSnarkJS
+
+var errSnarkJSCurve = errors.New("snarkjs files are implemented for BN254 only")
+
+// ExportSnarkJS writes v in the JSON layout of snarkjs, so that snarkjs verifies the
+// proofs of gnark: a Proof as proof.json, a VerifyingKey as verification_key.json, and
+// a public witness as public.json. Only BN254 is supported.
+func ExportSnarkJS(w io.Writer, v interface{}) error {
+	switch t := v.(type) {
+	case *groth16_bn254.Proof:
+		return t.ExportSnarkJS(w)
+	case *groth16_bn254.VerifyingKey:
+		return t.ExportSnarkJS(w)
+	case *witness.Witness:
+		vector, ok := t.Vector.(*witness_bn254.Witness)
+		if !ok {
+			return errSnarkJSCurve
+		}
+		return groth16_bn254.ExportPublicSignals(w, *vector)
+	case Proof, VerifyingKey:
+		return errSnarkJSCurve
+	default:
+		return fmt.Errorf("can't export %T to snarkjs", v)
+	}
+}
+
+// ImportSnarkJS reads v, as ExportSnarkJS lists them, from a file of snarkjs, so that
+// gnark verifies the proofs of snarkjs. The proof and the verifying key must come from
+// NewProof(ecc.BN254) and NewVerifyingKey(ecc.BN254); the public witness is set to a
+// BN254 one.
+func ImportSnarkJS(r io.Reader, v interface{}) error {
+	switch t := v.(type) {
+	case *groth16_bn254.Proof:
+		return t.ImportSnarkJS(r)
+	case *groth16_bn254.VerifyingKey:
+		return t.ImportSnarkJS(r)
+	case *witness.Witness:
+		vector, err := groth16_bn254.ImportPublicSignals(r)
+		if err != nil {
+			return err
+		}
+		t.CurveID = ecc.BN254
+		t.Vector = &vector
+		return nil
+	case Proof, VerifyingKey:
+		return errSnarkJSCurve
+	default:
+		return fmt.Errorf("can't import %T from snarkjs", v)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groth16
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// the fixtures are x³ + x + 5 = y with y = 35, in the layout of snarkjs
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "snarkjs", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSnarkJSFixtures(t *testing.T) {
+	proof, vk := NewProof(ecc.BN254), NewVerifyingKey(ecc.BN254)
+	var public witness.Witness
+	if err := ImportSnarkJS(bytes.NewReader(readFixture(t, "proof.json")), proof); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportSnarkJS(bytes.NewReader(readFixture(t, "verification_key.json")), vk); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportSnarkJS(bytes.NewReader(readFixture(t, "public.json")), &public); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(proof, vk, &public); err != nil {
+		t.Fatal(err)
+	}
+
+	var wrong witness.Witness
+	if err := ImportSnarkJS(strings.NewReader(`["36"]`), &wrong); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(proof, vk, &wrong); err == nil {
+		t.Fatal("verifying with a wrong public signal should fail")
+	}
+
+	// exported back as snarkjs wrote them
+	for name, v := range map[string]interface{}{"proof.json": proof, "public.json": &public} {
+		var buf bytes.Buffer
+		if err := ExportSnarkJS(&buf, v); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), readFixture(t, name)) {
+			t.Fatalf("%s isn't exported as snarkjs writes it", name)
+		}
+	}
+
+	// vk_alphabeta_12 aside, which snarkjs doesn't read
+	var buf bytes.Buffer
+	if err := ExportSnarkJS(&buf, vk); err != nil {
+		t.Fatal(err)
+	}
+	var got, want map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(readFixture(t, "verification_key.json"), &want); err != nil {
+		t.Fatal(err)
+	}
+	delete(got, "vk_alphabeta_12")
+	delete(want, "vk_alphabeta_12")
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("verification_key.json isn't exported as snarkjs writes it")
+	}
+}
+
+type snarkJSCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *snarkJSCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	return nil
+}
+
+func TestSnarkJSRoundTrip(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, &snarkJSCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full, err := frontend.NewWitness(&snarkJSCircuit{X: 3, Y: 35}, ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := full.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := Prove(ccs, pk, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof2, vk2 := NewProof(ecc.BN254), NewVerifyingKey(ecc.BN254)
+	var public2 witness.Witness
+	for _, v := range [][2]interface{}{{proof, proof2}, {vk, vk2}, {public, &public2}} {
+		var buf bytes.Buffer
+		if err := ExportSnarkJS(&buf, v[0]); err != nil {
+			t.Fatal(err)
+		}
+		if err := ImportSnarkJS(&buf, v[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(proof, proof2) {
+		t.Fatal("the proof imported isn't the one exported")
+	}
+	if err := Verify(proof2, vk2, &public2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnarkJSMalformed(t *testing.T) {
+	replace := func(name, old, new string) []byte {
+		data := string(readFixture(t, name))
+		if !strings.Contains(data, old) {
+			t.Fatalf("%s doesn't contain %s", name, old)
+		}
+		return []byte(strings.Replace(data, old, new, 1))
+	}
+	const y = "21571353503273893883861117131706134589534979625533467587360737182030781517891"
+	for name, test := range map[string]struct {
+		data []byte
+		v    interface{}
+	}{
+		"other protocol":    {replace("proof.json", `"groth16"`, `"plonk"`), NewProof(ecc.BN254)},
+		"other curve":       {replace("proof.json", `"bn128"`, `"bls12381"`), NewProof(ecc.BN254)},
+		"point off curve":   {replace("proof.json", y, "1"), NewProof(ecc.BN254)},
+		"projective point":  {replace("proof.json", `"1"`+"\n ],\n \"pi_b\"", `"2"`+"\n ],\n \"pi_b\""), NewProof(ecc.BN254)},
+		"not a number":      {replace("proof.json", y, "0x10"), NewProof(ecc.BN254)},
+		"missing IC":        {replace("verification_key.json", `"nPublic": 1`, `"nPublic": 2`), NewVerifyingKey(ecc.BN254)},
+		"signal not in Fr":  {[]byte(`["21888242871839275222246405745257275088548364400416034343698204186575808495617"]`), &witness.Witness{}},
+		"negative signal":   {[]byte(`["-1"]`), &witness.Witness{}},
+		"truncated file":    {readFixture(t, "proof.json")[:100], NewProof(ecc.BN254)},
+		"proof, not the vk": {readFixture(t, "proof.json"), NewVerifyingKey(ecc.BN254)},
+	} {
+		if err := ImportSnarkJS(bytes.NewReader(test.data), test.v); !errors.Is(err, ErrSnarkJS) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	if err := ExportSnarkJS(ioutil.Discard, NewProof(ecc.BLS12_381)); err == nil {
+		t.Fatal("expected an error")
+	}
+}
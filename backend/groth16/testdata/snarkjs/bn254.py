@@ -0,0 +1,194 @@
+# minimal BN254 arithmetic and optimal ate pairing, after py_ecc
+p = 21888242871839275222246405745257275088696311157297823662689037894645226208583
+r = 21888242871839275222246405745257275088548364400416034343698204186575808495617
+
+def inv(a, n=p):
+    return pow(a, n - 2, n)
+
+class FQP:
+    def __init__(self, coeffs):
+        self.coeffs = [c % p for c in coeffs]
+    def __add__(self, o):
+        return type(self)([a + b for a, b in zip(self.coeffs, o.coeffs)])
+    def __sub__(self, o):
+        return type(self)([a - b for a, b in zip(self.coeffs, o.coeffs)])
+    def __neg__(self):
+        return type(self)([-a for a in self.coeffs])
+    def __mul__(self, o):
+        if isinstance(o, int):
+            return type(self)([c * o for c in self.coeffs])
+        deg = self.degree
+        b = [0] * (deg * 2 - 1)
+        for i in range(deg):
+            for j in range(deg):
+                b[i + j] += self.coeffs[i] * o.coeffs[j]
+        while len(b) > deg:
+            exp, top = len(b) - deg - 1, b.pop()
+            for i in range(deg):
+                b[exp + i] -= top * self.mc[i]
+        return type(self)(b)
+    __rmul__ = __mul__
+    def __eq__(self, o):
+        return self.coeffs == o.coeffs
+    def __pow__(self, e):
+        o = type(self).one()
+        t = self
+        while e > 0:
+            if e & 1:
+                o = o * t
+            e >>= 1
+            t = t * t
+        return o
+    def inv(self):
+        lm, hm = [1] + [0] * self.degree, [0] * (self.degree + 1)
+        low, high = self.coeffs + [0], self.mc + [1]
+        def deg(x):
+            d = len(x) - 1
+            while x[d] == 0 and d:
+                d -= 1
+            return d
+        def div(a, b):
+            dega, degb = deg(a), deg(b)
+            temp = [x for x in a]
+            o = [0 for x in a]
+            for i in range(dega - degb, -1, -1):
+                o[i] += temp[degb + i] * inv(b[degb])
+                for c in range(degb + 1):
+                    temp[c + i] -= o[c]
+            return [x % p for x in o[:deg(o) + 1]]
+        while deg(low):
+            rr = div(high, low)
+            rr += [0] * (self.degree + 1 - len(rr))
+            nm = [x for x in hm]
+            new = [x for x in high]
+            for i in range(self.degree + 1):
+                for j in range(self.degree + 1 - i):
+                    nm[i + j] -= lm[i] * rr[j]
+                    new[i + j] -= low[i] * rr[j]
+            nm = [x % p for x in nm]
+            new = [x % p for x in new]
+            lm, low, hm, high = nm, new, lm, low
+        return type(self)(lm[:self.degree]) * inv(low[0])
+    def __truediv__(self, o):
+        if isinstance(o, int):
+            return self * inv(o)
+        return self * o.inv()
+    @classmethod
+    def one(cls):
+        return cls([1] + [0] * (cls.degree - 1))
+    @classmethod
+    def zero(cls):
+        return cls([0] * cls.degree)
+
+class FQ2(FQP):
+    degree = 2
+    mc = [1, 0]
+
+class FQ12(FQP):
+    degree = 12
+    mc = [82, 0, 0, 0, 0, 0, -18, 0, 0, 0, 0, 0]
+
+class FQ(int):
+    pass
+
+# points are affine tuples, None at infinity; fields: int mod p, FQ2, FQ12
+def fadd(a, b): return (a + b) % p if isinstance(a, int) else a + b
+def fsub(a, b): return (a - b) % p if isinstance(a, int) else a - b
+def fmul(a, b): return (a * b) % p if isinstance(a, int) and isinstance(b, int) else a * b
+def fdiv(a, b): return (a * inv(b)) % p if isinstance(a, int) else a / b
+
+def double(P):
+    if P is None: return None
+    x, y = P
+    m = fdiv(fmul(3, fmul(x, x)), fmul(2, y))
+    nx = fsub(fmul(m, m), fmul(2, x))
+    ny = fsub(fmul(m, fsub(x, nx)), y)
+    return (nx, ny)
+
+def add(P, Q):
+    if P is None: return Q
+    if Q is None: return P
+    (x1, y1), (x2, y2) = P, Q
+    if x1 == x2:
+        if y1 == y2: return double(P)
+        return None
+    m = fdiv(fsub(y2, y1), fsub(x2, x1))
+    nx = fsub(fsub(fmul(m, m), x1), x2)
+    ny = fsub(fmul(m, fsub(x1, nx)), y1)
+    return (nx, ny)
+
+def mul(P, n):
+    R = None
+    while n:
+        if n & 1: R = add(R, P)
+        P = double(P)
+        n >>= 1
+    return R
+
+def neg(P):
+    if P is None: return None
+    x, y = P
+    return (x, (-y) % p if isinstance(y, int) else -y)
+
+G1 = (1, 2)
+G2 = (FQ2([10857046999023057135944570762232829481370756359578518086990519993285655852781, 11559732032986387107991004021392285783925812861821192530917403151452391805634]),
+      FQ2([8495653923123431417604973247489272438418190587263600148770280649306958101930, 4082367875863433681332203403145435568316851327593401208105741076214120093531]))
+b2 = FQ2([3, 0]) / FQ2([9, 1])
+
+def on_g1(P):
+    x, y = P
+    return (y * y - x * x * x - 3) % p == 0
+
+def on_g2(P):
+    x, y = P
+    return y * y - x * x * x == b2
+
+ate_loop_count = 29793968203157093288
+log_ate_loop_count = 63
+w = FQ12([0, 1] + [0] * 10)
+
+def twist(P):
+    x, y = P
+    xc = [x.coeffs[0] - x.coeffs[1] * 9, x.coeffs[1]]
+    yc = [y.coeffs[0] - y.coeffs[1] * 9, y.coeffs[1]]
+    nx = FQ12([xc[0]] + [0] * 5 + [xc[1]] + [0] * 5)
+    ny = FQ12([yc[0]] + [0] * 5 + [yc[1]] + [0] * 5)
+    return (nx * w ** 2, ny * w ** 3)
+
+def cast(P):
+    return (FQ12([P[0]] + [0] * 11), FQ12([P[1]] + [0] * 11))
+
+def linefunc(P1, P2, T):
+    (x1, y1), (x2, y2), (xt, yt) = P1, P2, T
+    if not (x1 == x2):
+        m = (y2 - y1) / (x2 - x1)
+        return m * (xt - x1) - (yt - y1)
+    elif y1 == y2:
+        m = (x1 * x1 * 3) / (y1 * 2)
+        return m * (xt - x1) - (yt - y1)
+    return xt - x1
+
+def miller_loop(Q, P):
+    R = Q
+    f = FQ12.one()
+    for i in range(log_ate_loop_count, -1, -1):
+        f = f * f * linefunc(R, R, P)
+        R = double(R)
+        if ate_loop_count & (2 ** i):
+            f = f * linefunc(R, Q, P)
+            R = add(R, Q)
+    Q1 = (Q[0] ** p, Q[1] ** p)
+    nQ2 = (Q1[0] ** p, -(Q1[1] ** p))
+    f = f * linefunc(R, Q1, P)
+    R = add(R, Q1)
+    f = f * linefunc(R, nQ2, P)
+    return f
+
+def pairing_ml(Q, P):
+    return miller_loop(twist(Q), cast(P))
+
+def final_exp(f):
+    return f ** ((p ** 12 - 1) // r)
+
+def pairing(Q, P):
+    return final_exp(pairing_ml(Q, P))
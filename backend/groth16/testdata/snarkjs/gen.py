@@ -0,0 +1,45 @@
+# Writes proof.json, public.json and verification_key.json, in the layout of snarkjs, for
+# x³ + x + 5 = y with y = 35, from known trapdoors: see README.md
+import json, os, random
+from bn254 import *
+rnd = random.Random(20230401)
+s = lambda: rnd.randrange(1, r)
+alpha, beta, gamma, delta = s(), s(), s(), s()
+ic = [s(), s()]
+pub = [35]
+a, b = s(), s()
+vkx = (ic[0] + pub[0] * ic[1]) % r
+c = (a * b - alpha * beta - vkx * gamma) * inv(delta, r) % r
+
+A, B, C = mul(G1, a), mul(G2, b), mul(G1, c)
+Alpha, Beta, Gamma, Delta = mul(G1, alpha), mul(G2, beta), mul(G2, gamma), mul(G2, delta)
+IC = [mul(G1, x) for x in ic]
+
+def g1(P): return [str(P[0]), str(P[1]), "1"]
+def g2(P): return [[str(P[0].coeffs[0]), str(P[0].coeffs[1])], [str(P[1].coeffs[0]), str(P[1].coeffs[1])], ["1", "0"]]
+
+# pairing check, as snarkjs verifies: e(-A, B) e(α, β) e(vk_x, γ) e(C, δ) == 1
+VKX = add(IC[0], mul(IC[1], pub[0]))
+f = pairing_ml(B, neg(A)) * pairing_ml(Beta, Alpha) * pairing_ml(Gamma, VKX) * pairing_ml(Delta, C)
+assert final_exp(f) == FQ12.one()
+bad = add(IC[0], mul(IC[1], 36))
+f = pairing_ml(B, neg(A)) * pairing_ml(Beta, Alpha) * pairing_ml(Gamma, bad) * pairing_ml(Delta, C)
+assert final_exp(f) != FQ12.one()
+
+# e(α, β) in the tower Fp2[v]/(v³-(9+u)), Fp6[w]/(w²-v)
+e = pairing(Beta, Alpha).coeffs
+tower = [[None] * 3 for _ in range(2)]
+for i in range(2):
+    for j in range(3):
+        k = 2 * j + i
+        bb = e[k + 6]
+        aa = (e[k] + 9 * bb) % p
+        tower[i][j] = [str(aa), str(bb)]
+
+proof = {"pi_a": g1(A), "pi_b": g2(B), "pi_c": g1(C), "protocol": "groth16", "curve": "bn128"}
+vk = {"protocol": "groth16", "curve": "bn128", "nPublic": 1, "vk_alpha_1": g1(Alpha), "vk_beta_2": g2(Beta),
+      "vk_gamma_2": g2(Gamma), "vk_delta_2": g2(Delta), "vk_alphabeta_12": tower, "IC": [g1(P) for P in IC]}
+for name, o in [("proof", proof), ("verification_key", vk), ("public", [str(x) for x in pub])]:
+    with open(os.path.join(os.path.dirname(os.path.abspath(__file__)), "%s.json" % name), "w") as fh:
+        fh.write(json.dumps(o, indent=1))
+print("ok")
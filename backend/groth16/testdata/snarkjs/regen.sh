@@ -0,0 +1,38 @@
+#!/bin/sh
+# Replaces proof.json, public.json and verification_key.json with the outputs of
+# circom 2.1.x and snarkjs 0.7.x for the circuit of README.md, and removes gen.py.
+set -eu
+cd "$(dirname "$0")"
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+cat > "$tmp/cube.circom" <<'CIRCOM'
+pragma circom 2.0.0;
+
+template Cube() {
+    signal input x;
+    signal input y;
+    signal x2;
+    signal x3;
+
+    x2 <== x * x;
+    x3 <== x2 * x;
+    y === x3 + x + 5;
+}
+
+component main {public [y]} = Cube();
+CIRCOM
+
+circom "$tmp/cube.circom" --r1cs --wasm -o "$tmp"
+snarkjs powersoftau new bn128 4 "$tmp/pot_0.ptau"
+snarkjs powersoftau contribute "$tmp/pot_0.ptau" "$tmp/pot_1.ptau" --name=first -e=random
+snarkjs powersoftau prepare phase2 "$tmp/pot_1.ptau" "$tmp/pot_final.ptau"
+snarkjs groth16 setup "$tmp/cube.r1cs" "$tmp/pot_final.ptau" "$tmp/cube_0.zkey"
+snarkjs zkey contribute "$tmp/cube_0.zkey" "$tmp/cube.zkey" --name=first -e=random
+snarkjs zkey export verificationkey "$tmp/cube.zkey" verification_key.json
+echo '{"x": "3", "y": "35"}' > "$tmp/input.json"
+node "$tmp/cube_js/generate_witness.js" "$tmp/cube_js/cube.wasm" "$tmp/input.json" "$tmp/witness.wtns"
+snarkjs groth16 prove "$tmp/cube.zkey" "$tmp/witness.wtns" proof.json public.json
+snarkjs groth16 verify verification_key.json public.json proof.json
+
+rm -f gen.py
@@ -7,7 +7,7 @@ import (
 )
 
 func init() {
-	Register(IsZero)
+	RegisterNamed("github.com/consensys/gnark/backend/hint.IsZero", 1, IsZero)
 }
 
 // IsZero computes the value 1 - a^(modulus-1) for the single input a. This
@@ -61,14 +61,38 @@ proving option.
 
 In the init() method of the gadget, call the method Register(hintFn) method on
 the hint function hintFn to register a hint function in the package registry.
+
+Naming hint functions
+
+The compiled constraint systems refer to the hint functions by their ID, which
+derives from their name. By default, the name is the one of the Go function,
+which changes when the function is renamed or moved to another package, so that
+the constraint systems serialized before can't be solved anymore. To avoid it,
+name the hint function with SetName(hintFn, name, version), or register it with
+RegisterNamed(name, version, hintFn), in the init() method: the name then stays
+as long as the call does. The version tells apart the functions which compute
+different results under the same name, the constraint systems compiled with one
+not being solved with the other.
+
+The hint functions of gnark are named after their Go function. Those which are
+variables, as bits.NNAF or fields_bls12377.InverseE2Hint, were named after the
+function they hold before, package.glob..funcN or package.init.funcN for a
+function literal depending on the Go compiler, and are now named after the
+variable. RegisterAlias keeps the names they had, so that the constraint systems
+compiled with them are still solved.
 */
 package hint
 
 import (
+	"errors"
+	"fmt"
 	"hash/fnv"
 	"math/big"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/consensys/gnark-crypto/ecc"
 )
@@ -87,17 +111,81 @@ type Function func(curveID ecc.ID, inputs []*big.Int, outputs []*big.Int) error
 
 // UUID is a reference function for computing the hint ID based on a function name
 func UUID(fn Function) ID {
-	hf := fnv.New32a()
-	name := Name(fn)
+	// TODO relying on the name of the Go function to derive UUID is risky; if fn is an anonymous
+	// func, wil be package.glob..funcN and if new anonymous functions are added in the package, N
+	// may change, so will UUID. SetName gives fn a name which doesn't.
+	return NameID(Name(fn))
+}
 
-	// TODO relying on name to derive UUID is risky; if fn is an anonymous func, wil be package.glob..funcN
-	// and if new anonymous functions are added in the package, N may change, so will UUID.
+// NameID returns the ID of the hint functions of the given name, as returned by Name
+func NameID(name string) ID {
+	hf := fnv.New32a()
 	hf.Write([]byte(name)) // #nosec G104 -- does not err
-
 	return ID(hf.Sum32())
 }
 
+// Name returns the name of the hint function: the one given with SetName or RegisterNamed,
+// followed by /vN for the versions N from 2, or the one of the Go function otherwise.
 func Name(fn Function) string {
 	fnptr := reflect.ValueOf(fn).Pointer()
+	namesM.RLock()
+	name, ok := names[fnptr]
+	namesM.RUnlock()
+	if ok {
+		return name
+	}
 	return runtime.FuncForPC(fnptr).Name()
 }
+
+var (
+	names  = make(map[uintptr]string) // stable names of the hint functions
+	named  = make(map[string]uintptr) // hint functions of the stable names
+	namesM sync.RWMutex
+)
+
+// SetName gives the hint function the stable name, at version, from which its ID derives
+// rather than from the name of the Go function. The versions start at 1, and the name of
+// the version 1 is name alone, so that naming a function after its Go function, e.g.
+// "github.com/consensys/gnark/std/math/bits.NBits", keeps its ID.
+//
+// It panics if the function is already named otherwise, or if another function has the
+// name. As the closures of a function literal share their code, they share their name.
+func SetName(fn Function, name string, version uint) {
+	if name == "" || version == 0 {
+		panic("hint functions are named with a non-empty name and a version from 1")
+	}
+	if version > 1 {
+		name = fmt.Sprintf("%s/v%d", name, version)
+	}
+	fnptr := reflect.ValueOf(fn).Pointer()
+
+	namesM.Lock()
+	defer namesM.Unlock()
+	if n, ok := names[fnptr]; ok && n != name {
+		panic(fmt.Sprintf("hint function %s named %s twice", n, name))
+	}
+	if p, ok := named[name]; ok && p != fnptr {
+		panic(fmt.Sprintf("hint functions %s and %s both named %s", runtime.FuncForPC(p).Name(), runtime.FuncForPC(fnptr).Name(), name))
+	}
+	names[fnptr] = name
+	named[name] = fnptr
+}
+
+// ErrMissing is wrapped by the errors of the solvers when hint functions are missing
+var ErrMissing = errors.New("missing hint functions")
+
+// CheckMissing returns an error wrapping ErrMissing, which lists the names of the hints
+// of dependencies, the ones a constraint system needs, missing from functions
+func CheckMissing(dependencies map[ID]string, functions map[ID]Function) error {
+	var missing []string
+	for id, name := range dependencies {
+		if _, ok := functions[id]; !ok {
+			missing = append(missing, fmt.Sprintf("%s (ID %d)", name, id))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("%w: %s; they must be registered, or given with backend.WithHints, under the names the constraint system was compiled with", ErrMissing, strings.Join(missing, ", "))
+}
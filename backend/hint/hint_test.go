@@ -0,0 +1,113 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hint
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+func double(_ ecc.ID, inputs []*big.Int, results []*big.Int) error {
+	results[0].Lsh(inputs[0], 1)
+	return nil
+}
+
+func triple(_ ecc.ID, inputs []*big.Int, results []*big.Int) error {
+	results[0].Mul(inputs[0], big.NewInt(3))
+	return nil
+}
+
+func quadruple(_ ecc.ID, inputs []*big.Int, results []*big.Int) error {
+	results[0].Lsh(inputs[0], 2)
+	return nil
+}
+
+func unnamed(_ ecc.ID, inputs []*big.Int, results []*big.Int) error {
+	return nil
+}
+
+func TestName(t *testing.T) {
+	if name := Name(unnamed); name != "github.com/consensys/gnark/backend/hint.unnamed" {
+		t.Fatalf("unnamed hint named %s", name)
+	}
+
+	SetName(double, "test.double", 1)
+	SetName(triple, "test.triple", 2)
+	if name := Name(double); name != "test.double" {
+		t.Fatalf("version 1 named %s", name)
+	}
+	if name := Name(triple); name != "test.triple/v2" {
+		t.Fatalf("version 2 named %s", name)
+	}
+
+	// the ID derives from the stable name
+	hf := fnv.New32a()
+	hf.Write([]byte("test.triple/v2"))
+	if UUID(triple) != ID(hf.Sum32()) || NameID("test.triple/v2") != ID(hf.Sum32()) {
+		t.Fatal("the ID doesn't derive from the stable name")
+	}
+
+	// naming again the same way is harmless
+	SetName(double, "test.double", 1)
+
+	for name, f := range map[string]func(){
+		"renaming":        func() { SetName(double, "test.double", 2) },
+		"name taken":      func() { SetName(unnamed, "test.double", 1) },
+		"empty name":      func() { SetName(unnamed, "", 1) },
+		"version 0":       func() { SetName(unnamed, "test.unnamed", 0) },
+		"registered name": func() { RegisterNamed("test.triple", 2, unnamed) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected a panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestRegisterAlias(t *testing.T) {
+	RegisterNamed("test.quadruple", 1, quadruple)
+	RegisterAlias(quadruple, "test.glob..func1", "test.quadruple")
+	aliases := GetAliases()
+	if fn, ok := aliases[NameID("test.glob..func1")]; !ok || Name(fn) != "test.quadruple" {
+		t.Fatal("the alias doesn't resolve to the function")
+	}
+	// the ID of a registered function isn't taken over
+	if _, ok := aliases[NameID("test.quadruple")]; ok {
+		t.Fatal("alias of a registered function")
+	}
+}
+
+func TestCheckMissing(t *testing.T) {
+	dependencies := map[ID]string{1: "b.hint", 2: "a.hint/v2", 3: "c.hint"}
+	if err := CheckMissing(dependencies, map[ID]Function{1: double, 2: double, 3: double}); err != nil {
+		t.Fatal(err)
+	}
+	err := CheckMissing(dependencies, map[ID]Function{3: double})
+	if !errors.Is(err, ErrMissing) {
+		t.Fatal(err)
+	}
+	if !strings.Contains(err.Error(), "a.hint/v2 (ID 2), b.hint (ID 1)") || strings.Contains(err.Error(), "c.hint") {
+		t.Fatalf("%q doesn't list the missing hints", err)
+	}
+}
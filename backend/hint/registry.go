@@ -7,6 +7,7 @@ import (
 )
 
 var registry = make(map[ID]Function)
+var aliases = make(map[ID]Function)
 var registryM sync.RWMutex
 
 // Register registers an hint function in the global registry.
@@ -23,6 +24,42 @@ func Register(hintFn Function) {
 	registry[key] = hintFn
 }
 
+// RegisterNamed names the hint function with SetName, and registers it in the global
+// registry.
+func RegisterNamed(name string, version uint, hintFn Function) {
+	SetName(hintFn, name, version)
+	Register(hintFn)
+}
+
+// RegisterAlias registers the hint function in the global registry under the IDs of the
+// given names as well, the ones it had before being named, so that the constraint systems
+// compiled then are still solved.
+func RegisterAlias(hintFn Function, names ...string) {
+	registryM.Lock()
+	defer registryM.Unlock()
+	for _, name := range names {
+		key := NameID(name)
+		if _, ok := registry[key]; ok {
+			log := logger.Logger()
+			log.Warn().Str("name", name).Msg("alias of a registered function")
+			continue
+		}
+		aliases[key] = hintFn
+	}
+}
+
+// GetAliases returns the hint functions registered with RegisterAlias, by the IDs of
+// their aliases.
+func GetAliases() map[ID]Function {
+	registryM.RLock()
+	defer registryM.RUnlock()
+	ret := make(map[ID]Function, len(aliases))
+	for k, v := range aliases {
+		ret[k] = v
+	}
+	return ret
+}
+
 // GetRegistered returns all registered hint functions.
 func GetRegistered() []Function {
 	registryM.RLock()
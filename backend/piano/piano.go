@@ -1,12 +1,17 @@
 package piano
 
 import (
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/consensys/gnark-crypto/dkzg"
 	"github.com/consensys/gnark-crypto/ecc"
+	dkzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
 
 	"github.com/consensys/gnark/backend/witness"
 	cs_bn254 "github.com/consensys/gnark/internal/backend/bn254/cs"
@@ -16,6 +21,11 @@ import (
 	witness_bn254 "github.com/consensys/gnark/internal/backend/bn254/witness"
 )
 
+var (
+	errLookupsUnsupported = errors.New("piano doesn't support table lookups, use gpiano")
+	errSRSCurve           = errors.New("the SRS isn't of the curve of the constraint system")
+)
+
 // Proof represents a piano proof generated by piano.Prove
 //
 // it's underlying implementation is curve specific (see gnark/internal/backend)
@@ -42,10 +52,22 @@ type VerifyingKey interface {
 	io.ReaderFrom
 	InitKZG(srs dkzg.SRS) error
 	NbPublicWitness() int // number of elements expected in the public witness
+
+	// Fingerprint returns the fingerprint of the constraint system the key was set up for,
+	// see CheckVerifyingKey
+	Fingerprint() compiled.Fingerprint
 }
 
 // Setup prepares the public data associated to a circuit + public inputs.
-func Setup(ccs frontend.CompiledConstraintSystem, publicWitness *witness.Witness) (ProvingKey, VerifyingKey, error) {
+func Setup(ccs frontend.CompiledConstraintSystem, publicWitness *witness.Witness, opts ...backend.SetupOption) (ProvingKey, VerifyingKey, error) {
+	// apply options
+	opt, err := backend.NewSetupConfig(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tccs, ok := ccs.(interface{ HasLookups() bool }); ok && tccs.HasLookups() {
+		return nil, nil, errLookupsUnsupported
+	}
 
 	switch tccs := ccs.(type) {
 	case *cs_bn254.SparseR1CS:
@@ -53,7 +75,23 @@ func Setup(ccs frontend.CompiledConstraintSystem, publicWitness *witness.Witness
 		if !ok {
 			return nil, nil, witness.ErrInvalidWitness
 		}
-		return piano_bn254.Setup(tccs, *w)
+		var pk *piano_bn254.ProvingKey
+		var vk *piano_bn254.VerifyingKey
+		if opt.DKZGSRS == nil {
+			pk, vk, err = piano_bn254.Setup(tccs, *w)
+		} else {
+			dsrs, ok := opt.DKZGSRS.(*dkzg_bn254.SRS)
+			srs, ok2 := opt.KZGSRS.(*kzg_bn254.SRS)
+			if !ok || (!ok2 && opt.KZGSRS != nil) {
+				return nil, nil, errSRSCurve
+			}
+			pk, vk, err = piano_bn254.SetupSRS(tccs, *w, dsrs, srs)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, nil
 	default:
 		panic("unimplemented")
 	}
@@ -86,8 +124,73 @@ func Prove(ccs frontend.CompiledConstraintSystem, pk ProvingKey, fullWitness *wi
 	}
 }
 
+// ProveResult is a proof of ProveMany, or the error which prevented it
+type ProveResult struct {
+	Proof Proof
+	Err   error
+}
+
+// ProveMany generates the piano proofs of a stream of witnesses of the same circuit,
+// pipelining consecutive instances, and sends them in order on the returned channel,
+// which is closed once fullWitnesses is closed and the last proof was sent. A witness
+// of another curve gets a ProveResult with witness.ErrInvalidWitness in its position.
+// See piano_bn254.ProveMany for the constraints on the stream.
+func ProveMany(ccs frontend.CompiledConstraintSystem, pk ProvingKey, fullWitnesses <-chan *witness.Witness, opts ...backend.ProverOption) (<-chan ProveResult, error) {
+
+	// apply options
+	opt, err := backend.NewProverConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tccs := ccs.(type) {
+	case *cs_bn254.SparseR1CS:
+		// valid records whether each witness was handed to the prover, in the order of
+		// the stream, so that an invalid witness gets its error in its own position. It is
+		// buffered so that the instances in flight aren't held back by the results.
+		ws := make(chan witness_bn254.Witness)
+		valid := make(chan bool, 16)
+		go func() {
+			defer close(ws)
+			defer close(valid)
+			for fullWitness := range fullWitnesses {
+				w, ok := fullWitness.Vector.(*witness_bn254.Witness)
+				valid <- ok
+				if ok {
+					ws <- *w
+				}
+			}
+		}()
+
+		results := make(chan ProveResult)
+		go func() {
+			defer close(results)
+			proofs := piano_bn254.ProveMany(tccs, pk.(*piano_bn254.ProvingKey), ws, opt)
+			for ok := range valid {
+				if !ok {
+					results <- ProveResult{Err: witness.ErrInvalidWitness}
+					continue
+				}
+				if res := <-proofs; res.Err != nil {
+					results <- ProveResult{Err: res.Err}
+				} else {
+					results <- ProveResult{Proof: res.Proof}
+				}
+			}
+		}()
+		return results, nil
+
+	default:
+		panic("unimplemented")
+	}
+}
+
 // Verify verifies a piano proof, from the proof, preprocessed public data, and public witness.
-func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness) error {
+// backend.WithCircuitFingerprint checks first that vk was set up for the circuit.
+func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness, opts ...backend.VerifierOption) error {
+	if err := checkFingerprint(vk, opts); err != nil {
+		return err
+	}
 
 	switch _proof := proof.(type) {
 
@@ -103,6 +206,63 @@ func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness) error
 	}
 }
 
+// CheckVerifyingKey returns nil if vk was set up for the compiled circuit ccs. It returns
+// an error wrapping compiled.ErrFingerprintMismatch if vk comes from another constraint
+// system, and compiled.ErrNoFingerprint if vk doesn't record the fingerprint of its own.
+func CheckVerifyingKey(vk VerifyingKey, ccs frontend.CompiledConstraintSystem) error {
+	return vk.Fingerprint().Check(ccs.Fingerprint())
+}
+
+// checkFingerprint checks vk against the circuit fingerprint of the verifier options, if
+// any
+func checkFingerprint(vk VerifyingKey, opts []backend.VerifierOption) error {
+	opt, err := backend.NewVerifierConfig(opts...)
+	if err != nil {
+		return err
+	}
+	if opt.CircuitFingerprint == ([32]byte{}) {
+		return nil
+	}
+	return vk.Fingerprint().Check(opt.CircuitFingerprint)
+}
+
+// BatchVerify verifies several piano proofs generated for the same verifying key, folding
+// all their openings into a constant number of pairings.
+//
+// If the batch doesn't pass, the returned error is a *piano_bn254.BatchVerifyError
+// holding the index of the failing proof. backend.WithCircuitFingerprint checks first
+// that vk was set up for the circuit.
+func BatchVerify(proofs []Proof, vk VerifyingKey, publicWitnesses []*witness.Witness, opts ...backend.VerifierOption) error {
+	if err := checkFingerprint(vk, opts); err != nil {
+		return err
+	}
+	if len(proofs) != len(publicWitnesses) {
+		return fmt.Errorf("got %d proofs but %d public witnesses", len(proofs), len(publicWitnesses))
+	}
+
+	switch _vk := vk.(type) {
+
+	case *piano_bn254.VerifyingKey:
+		_proofs := make([]*piano_bn254.Proof, len(proofs))
+		ws := make([]witness_bn254.Witness, len(publicWitnesses))
+		for i := range proofs {
+			var ok bool
+			if _proofs[i], ok = proofs[i].(*piano_bn254.Proof); !ok {
+				return fmt.Errorf("proof %d: unexpected type %T", i, proofs[i])
+			}
+			w, ok := publicWitnesses[i].Vector.(*witness_bn254.Witness)
+			if !ok {
+				return witness.ErrInvalidWitness
+			}
+			ws[i] = *w
+		}
+		return piano_bn254.BatchVerify(_proofs, _vk, ws)
+
+	default:
+		panic("unimplemented")
+	}
+}
+
 // NewCS instantiate a concrete curved-typed SparseR1CS and return a ConstraintSystem interface
 // This method exists for (de)serialization purposes
 func NewCS(curveID ecc.ID) frontend.CompiledConstraintSystem {
@@ -0,0 +1,161 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package piano
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+)
+
+// cubicCircuit has no public input, piano binding them in the proving key, so that its
+// witnesses can be proven with the same key
+type cubicCircuit struct {
+	X, Y frontend.Variable
+}
+
+func (c *cubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 5))
+	return nil
+}
+
+// otherCubicCircuit differs from cubicCircuit by its constant only
+type otherCubicCircuit struct {
+	X, Y frontend.Variable
+}
+
+func (c *otherCubicCircuit) Define(api frontend.API) error {
+	x3 := api.Mul(c.X, c.X, c.X)
+	api.AssertIsEqual(c.Y, api.Add(x3, c.X, 6))
+	return nil
+}
+
+func TestProveManyInvalidWitness(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the witnesses 1 and 3 are of another curve
+	curves := []ecc.ID{ecc.BN254, ecc.BLS12_381, ecc.BN254, ecc.BLS12_381, ecc.BN254}
+	fullWitnesses := make([]*witness.Witness, len(curves))
+	publicWitnesses := make([]*witness.Witness, len(curves))
+	for i, curve := range curves {
+		x := uint64(i + 2)
+		assignment := &cubicCircuit{X: x, Y: x*x*x + x + 5}
+		if fullWitnesses[i], err = frontend.NewWitness(assignment, curve); err != nil {
+			t.Fatal(err)
+		}
+		if publicWitnesses[i], err = frontend.NewWitness(assignment, curve, frontend.PublicOnly()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pk, vk, err := Setup(ccs, publicWitnesses[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	witnesses := make(chan *witness.Witness)
+	go func() {
+		for _, w := range fullWitnesses {
+			witnesses <- w
+		}
+		close(witnesses)
+	}()
+	results, err := ProveMany(ccs, pk, witnesses)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := 0
+	for res := range results {
+		if curves[i] != ecc.BN254 {
+			if !errors.Is(res.Err, witness.ErrInvalidWitness) {
+				t.Fatalf("witness %d: got %v, expected %v", i, res.Err, witness.ErrInvalidWitness)
+			}
+		} else {
+			if res.Err != nil {
+				t.Fatalf("witness %d: %v", i, res.Err)
+			}
+			if err := Verify(res.Proof, vk, publicWitnesses[i]); err != nil {
+				t.Fatalf("proof %d: %v", i, err)
+			}
+		}
+		i++
+	}
+	if i != len(curves) {
+		t.Fatalf("got %d results, expected %d", i, len(curves))
+	}
+}
+
+func TestVerifyCircuitFingerprint(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &cubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &otherCubicCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assignment := &cubicCircuit{X: 3, Y: 35}
+	fullWitness, err := frontend.NewWitness(assignment, ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness, err := frontend.NewWitness(assignment, ecc.BN254, frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pk, vk, err := Setup(ccs, publicWitness)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := Prove(ccs, pk, fullWitness)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Verify(proof, vk, publicWitness, backend.WithCircuitFingerprint(ccs.Fingerprint())); err != nil {
+		t.Fatal(err)
+	}
+	err = Verify(proof, vk, publicWitness, backend.WithCircuitFingerprint(other.Fingerprint()))
+	if !errors.Is(err, compiled.ErrFingerprintMismatch) {
+		t.Fatalf("got %v, expected %v", err, compiled.ErrFingerprintMismatch)
+	}
+	err = BatchVerify([]Proof{proof}, vk, []*witness.Witness{publicWitness}, backend.WithCircuitFingerprint(other.Fingerprint()))
+	if !errors.Is(err, compiled.ErrFingerprintMismatch) {
+		t.Fatalf("batch: got %v, expected %v", err, compiled.ErrFingerprintMismatch)
+	}
+	if err := Verify(proof, vk, publicWitness, backend.WithCircuitFingerprint([32]byte{})); err == nil {
+		t.Fatal("zero circuit fingerprint accepted")
+	}
+}
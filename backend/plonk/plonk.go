@@ -20,12 +20,14 @@
 package plonk
 
 import (
+	"errors"
 	"io"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/kzg"
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
 
 	"github.com/consensys/gnark/backend/witness"
 	cs_bls12377 "github.com/consensys/gnark/internal/backend/bls12-377/cs"
@@ -57,6 +59,8 @@ import (
 	kzg_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/kzg"
 )
 
+var errLookupsUnsupported = errors.New("PlonK doesn't support table lookups, use gpiano")
+
 // Proof represents a Plonk proof generated by plonk.Prove
 //
 // it's underlying implementation is curve specific (see gnark/internal/backend)
@@ -83,24 +87,68 @@ type VerifyingKey interface {
 	io.ReaderFrom
 	InitKZG(srs kzg.SRS) error
 	NbPublicWitness() int // number of elements expected in the public witness
+
+	// Fingerprint returns the fingerprint of the constraint system the key was set up for,
+	// see CheckVerifyingKey
+	Fingerprint() compiled.Fingerprint
 }
 
 // Setup prepares the public data associated to a circuit + public inputs.
-func Setup(ccs frontend.CompiledConstraintSystem, kzgSRS kzg.SRS) (ProvingKey, VerifyingKey, error) {
+// The transcript hash is SHA256 unless changed, see backend.WithTranscriptHash.
+func Setup(ccs frontend.CompiledConstraintSystem, kzgSRS kzg.SRS, opts ...backend.SetupOption) (ProvingKey, VerifyingKey, error) {
+	if tccs, ok := ccs.(interface{ HasLookups() bool }); ok && tccs.HasLookups() {
+		return nil, nil, errLookupsUnsupported
+	}
+
+	// apply options
+	opt, err := backend.NewSetupConfig(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	switch tccs := ccs.(type) {
 	case *cs_bn254.SparseR1CS:
-		return plonk_bn254.Setup(tccs, kzgSRS.(*kzg_bn254.SRS))
+		pk, vk, err := plonk_bn254.Setup(tccs, kzgSRS.(*kzg_bn254.SRS))
+		if err != nil {
+			return nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, nil
 	case *cs_bls12381.SparseR1CS:
-		return plonk_bls12381.Setup(tccs, kzgSRS.(*kzg_bls12381.SRS))
+		pk, vk, err := plonk_bls12381.Setup(tccs, kzgSRS.(*kzg_bls12381.SRS))
+		if err != nil {
+			return nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, nil
 	case *cs_bls12377.SparseR1CS:
-		return plonk_bls12377.Setup(tccs, kzgSRS.(*kzg_bls12377.SRS))
+		pk, vk, err := plonk_bls12377.Setup(tccs, kzgSRS.(*kzg_bls12377.SRS))
+		if err != nil {
+			return nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, nil
 	case *cs_bw6761.SparseR1CS:
-		return plonk_bw6761.Setup(tccs, kzgSRS.(*kzg_bw6761.SRS))
+		pk, vk, err := plonk_bw6761.Setup(tccs, kzgSRS.(*kzg_bw6761.SRS))
+		if err != nil {
+			return nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, nil
 	case *cs_bls24315.SparseR1CS:
-		return plonk_bls24315.Setup(tccs, kzgSRS.(*kzg_bls24315.SRS))
+		pk, vk, err := plonk_bls24315.Setup(tccs, kzgSRS.(*kzg_bls24315.SRS))
+		if err != nil {
+			return nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, nil
 	case *cs_bw6633.SparseR1CS:
-		return plonk_bw6633.Setup(tccs, kzgSRS.(*kzg_bw6633.SRS))
+		pk, vk, err := plonk_bw6633.Setup(tccs, kzgSRS.(*kzg_bw6633.SRS))
+		if err != nil {
+			return nil, nil, err
+		}
+		vk.TranscriptHash = opt.TranscriptHash
+		return pk, vk, nil
 	default:
 		panic("unrecognized SparseR1CS curve type")
 	}
@@ -169,7 +217,11 @@ func Prove(ccs frontend.CompiledConstraintSystem, pk ProvingKey, fullWitness *wi
 }
 
 // Verify verifies a PLONK proof, from the proof, preprocessed public data, and public witness.
-func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness) error {
+// backend.WithCircuitFingerprint checks first that vk was set up for the circuit.
+func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness, opts ...backend.VerifierOption) error {
+	if err := checkFingerprint(vk, opts); err != nil {
+		return err
+	}
 
 	switch _proof := proof.(type) {
 
@@ -220,6 +272,26 @@ func Verify(proof Proof, vk VerifyingKey, publicWitness *witness.Witness) error
 	}
 }
 
+// CheckVerifyingKey returns nil if vk was set up for the compiled circuit ccs. It returns
+// an error wrapping compiled.ErrFingerprintMismatch if vk comes from another constraint
+// system, and compiled.ErrNoFingerprint if vk doesn't record the fingerprint of its own.
+func CheckVerifyingKey(vk VerifyingKey, ccs frontend.CompiledConstraintSystem) error {
+	return vk.Fingerprint().Check(ccs.Fingerprint())
+}
+
+// checkFingerprint checks vk against the circuit fingerprint of the verifier options, if
+// any
+func checkFingerprint(vk VerifyingKey, opts []backend.VerifierOption) error {
+	opt, err := backend.NewVerifierConfig(opts...)
+	if err != nil {
+		return err
+	}
+	if opt.CircuitFingerprint == ([32]byte{}) {
+		return nil
+	}
+	return vk.Fingerprint().Check(opt.CircuitFingerprint)
+}
+
 // NewCS instantiate a concrete curved-typed SparseR1CS and return a ConstraintSystem interface
 // This method exists for (de)serialization purposes
 func NewCS(curveID ecc.ID) frontend.CompiledConstraintSystem {
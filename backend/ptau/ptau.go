@@ -0,0 +1,370 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ptau reads the KZG SRS of BN254 from the .ptau powers of tau files of snarkjs
+// (https://github.com/iden3/snarkjs).
+//
+// The elements of the files are written in little-endian Montgomery form, x·R, on 32
+// bytes, and the points as their affine coordinates, x then y, the point at infinity
+// being (0, 0).
+package ptau
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+)
+
+// section types of the .ptau format read
+const (
+	sectionPtauHeader = 1
+	sectionPtauTauG1  = 2
+	sectionPtauTauG2  = 3
+)
+
+const (
+	magic   = "ptau"
+	n8BN254 = 32 // size of the field elements of BN254 in bytes
+
+	sizeG1BN254 = 2 * n8BN254
+	sizeG2BN254 = 4 * n8BN254
+)
+
+// ErrInvalid is wrapped by the errors of ReadSRS on malformed files, and on files whose
+// powers of tau aren't consistent
+var ErrInvalid = errors.New("invalid snarkjs ptau")
+
+// ReadSRS reads the KZG SRS of BN254 made of the size first powers of tau in G1, and of
+// τ in G2, from a .ptau file of snarkjs, such as the ones of the Perpetual Powers of Tau
+// ceremony, for plonk.Setup. The Pianist backends can take it as their univariate SRS on
+// Y through backend.WithSRS, but their distributed SRS on X is computed from its τ, which
+// the ceremony doesn't reveal: their setups aren't backed by the ceremony.
+//
+// The file is read sequentially and only the points of the SRS are kept, so that size
+// bounds the memory rather than the file. The points are checked to be on the curve and
+// in the subgroups, and the powers to start from the generators. With check, they are
+// also checked to be the successive powers of the same τ by a pairing equation on a
+// random linear combination, which costs two multi-exponentiations of size size.
+func ReadSRS(r io.Reader, size uint64, check bool) (*kzg_bn254.SRS, error) {
+	br := bufio.NewReader(r)
+	head, err := readPtau(br, 12)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	d := &decoder{buf: head}
+	if !bytes.Equal(d.bytes(4), []byte(magic)) {
+		return nil, fmt.Errorf("%w: not a ptau file", ErrInvalid)
+	}
+	if version := d.uint32(); version != 1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalid, version)
+	}
+	nbSections := d.uint32()
+
+	// the sections come in order in the files of snarkjs: the header first, which
+	// gives the number of points of the others
+	var power uint32
+	srs := new(kzg_bn254.SRS)
+	read := make(map[uint32]bool)
+	for i := uint32(0); i < nbSections && !(read[sectionPtauTauG1] && read[sectionPtauTauG2]); i++ {
+		s, err := readPtau(br, 12)
+		if err != nil {
+			return nil, fmt.Errorf("%w: section %d: %v", ErrInvalid, i, err)
+		}
+		d := &decoder{buf: s}
+		typ, sectionSize := d.uint32(), d.uint64()
+		if read[typ] {
+			return nil, fmt.Errorf("%w: duplicated section %d", ErrInvalid, typ)
+		}
+		if (typ == sectionPtauTauG1 || typ == sectionPtauTauG2) && !read[sectionPtauHeader] {
+			return nil, fmt.Errorf("%w: section %d before the header", ErrInvalid, typ)
+		}
+		read[typ] = true
+
+		switch typ {
+		case sectionPtauHeader:
+			if power, err = readPtauHeader(br, sectionSize); err != nil {
+				return nil, fmt.Errorf("%w: header: %v", ErrInvalid, err)
+			}
+		case sectionPtauTauG1:
+			// τⁱ for i < 2·2ᵖᵒʷᵉʳ - 1
+			nbPoints := uint64(2)<<power - 1
+			if size == 0 || size > nbPoints {
+				return nil, fmt.Errorf("%w: %d powers of tau requested, the file has %d", ErrInvalid, size, nbPoints)
+			}
+			srs.G1 = make([]curve.G1Affine, size)
+			if err := readPtauPoints(br, sectionSize, nbPoints*sizeG1BN254, size*sizeG1BN254, func(d *decoder) error {
+				for i := range srs.G1 {
+					if d.g1(&srs.G1[i]); d.err != nil {
+						return fmt.Errorf("point %d: %w", i, d.err)
+					}
+				}
+				return nil
+			}); err != nil {
+				return nil, fmt.Errorf("%w: tau G1: %v", ErrInvalid, err)
+			}
+		case sectionPtauTauG2:
+			// τⁱ for i < 2ᵖᵒʷᵉʳ
+			nbPoints := uint64(1) << power
+			if nbPoints < 2 {
+				return nil, fmt.Errorf("%w: no τ in G2", ErrInvalid)
+			}
+			if err := readPtauPoints(br, sectionSize, nbPoints*sizeG2BN254, 2*sizeG2BN254, func(d *decoder) error {
+				for i := range srs.G2 {
+					if d.g2(&srs.G2[i]); d.err != nil {
+						return fmt.Errorf("point %d: %w", i, d.err)
+					}
+				}
+				return nil
+			}); err != nil {
+				return nil, fmt.Errorf("%w: tau G2: %v", ErrInvalid, err)
+			}
+		default:
+			if err := skipPtau(br, sectionSize); err != nil {
+				return nil, fmt.Errorf("%w: section %d: %v", ErrInvalid, typ, err)
+			}
+		}
+	}
+	for _, typ := range []uint32{sectionPtauTauG1, sectionPtauTauG2} {
+		if !read[typ] {
+			return nil, fmt.Errorf("%w: missing section %d", ErrInvalid, typ)
+		}
+	}
+
+	_, _, g1, g2 := curve.Generators()
+	if !srs.G1[0].Equal(&g1) || !srs.G2[0].Equal(&g2) {
+		return nil, fmt.Errorf("%w: the powers of tau don't start from the generators", ErrInvalid)
+	}
+	if check {
+		if err := checkPowersOfTau(srs); err != nil {
+			return nil, err
+		}
+	}
+	return srs, nil
+}
+
+// readPtauHeader reads the header section, of sectionSize bytes, and returns the power
+// of the file: it holds the powers of tau for the domains of size up to 2ᵖᵒʷᵉʳ
+func readPtauHeader(br *bufio.Reader, sectionSize uint64) (uint32, error) {
+	// n8, q, power and the power of the ceremony
+	if sectionSize != 4+n8BN254+4+4 {
+		return 0, fmt.Errorf("%d bytes, expected the ones of BN254", sectionSize)
+	}
+	s, err := readPtau(br, sectionSize)
+	if err != nil {
+		return 0, err
+	}
+	d := &decoder{buf: s}
+	n8, q := d.field()
+	power := d.uint32()
+	_ = d.uint32()
+	if err := d.end(); err != nil {
+		return 0, err
+	}
+	if n8 != n8BN254 || q.Cmp(fp.Modulus()) != 0 {
+		return 0, fmt.Errorf("prime %s, not the one of BN254", q)
+	}
+	// the domains of fr go up to 2²⁸
+	if power > 28 {
+		return 0, fmt.Errorf("power %d above 28", power)
+	}
+	return power, nil
+}
+
+// readPtauPoints checks that the section has sectionSize bytes as expected, decodes its
+// first n bytes and skips the others
+func readPtauPoints(br *bufio.Reader, sectionSize, expected, n uint64, decode func(*decoder) error) error {
+	if sectionSize != expected {
+		return fmt.Errorf("%d bytes, expected %d", sectionSize, expected)
+	}
+	s, err := readPtau(br, n)
+	if err != nil {
+		return err
+	}
+	d := &decoder{buf: s}
+	if err := decode(d); err != nil {
+		return err
+	}
+	return skipPtau(br, sectionSize-n)
+}
+
+func readPtau(br *bufio.Reader, n uint64) ([]byte, error) {
+	res := make([]byte, n)
+	if _, err := io.ReadFull(br, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func skipPtau(br *bufio.Reader, n uint64) error {
+	if n > 1<<62 {
+		return fmt.Errorf("%d bytes", n)
+	}
+	_, err := io.CopyN(ioutil.Discard, br, int64(n))
+	return err
+}
+
+// checkPowersOfTau checks that the points of srs are [τⁱ]1 and [τ]2 for the same τ, with
+// e(Σ ρⁱ·[τⁱ⁺¹]1, [1]2) = e(Σ ρⁱ·[τⁱ]1, [τ]2) for a random ρ
+func checkPowersOfTau(srs *kzg_bn254.SRS) error {
+	n := len(srs.G1) - 1
+	if n == 0 {
+		return nil
+	}
+	var r fr.Element
+	if _, err := r.SetRandom(); err != nil {
+		return err
+	}
+	rho := make([]fr.Element, n)
+	rho[0].SetOne()
+	for i := 1; i < n; i++ {
+		rho[i].Mul(&rho[i-1], &r)
+	}
+
+	var low, high curve.G1Affine
+	config := ecc.MultiExpConfig{ScalarsMont: true}
+	if _, err := low.MultiExp(srs.G1[:n], rho, config); err != nil {
+		return err
+	}
+	if _, err := high.MultiExp(srs.G1[1:], rho, config); err != nil {
+		return err
+	}
+	low.Neg(&low)
+	ok, err := curve.PairingCheck([]curve.G1Affine{high, low}, []curve.G2Affine{srs.G2[0], srs.G2[1]})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: the points aren't the powers of the same tau", ErrInvalid)
+	}
+	return nil
+}
+
+// decoder reads little-endian values from buf. The first error sticks, and the reads
+// which follow return zero values.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.buf)) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	res := d.buf[:n]
+	d.buf = d.buf[n:]
+	return res
+}
+
+func (d *decoder) uint32() uint32 {
+	b := d.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// element reads a field element of n8 bytes into res and checks it is lower than prime
+// unless prime is nil
+func (d *decoder) element(n8 uint32, prime *big.Int, res *big.Int) {
+	b := d.bytes(uint64(n8))
+	if b == nil {
+		return
+	}
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	res.SetBytes(be)
+	if prime != nil && res.Cmp(prime) >= 0 {
+		d.err = fmt.Errorf("field element %s out of the field", res)
+	}
+}
+
+// field reads the size in bytes of the field elements, a multiple of 8, and the modulus
+// of the field
+func (d *decoder) field() (uint32, *big.Int) {
+	n8 := d.uint32()
+	if d.err == nil && (n8 == 0 || n8%8 != 0) {
+		d.err = fmt.Errorf("invalid field element size %d", n8)
+	}
+	q := new(big.Int)
+	d.element(n8, nil, q)
+	return n8, q
+}
+
+// montgomery reads an element of the field of modulus prime in Montgomery form into
+// limbs, which hold it in Montgomery form as well
+func (d *decoder) montgomery(prime *big.Int, limbs []uint64) {
+	var x big.Int
+	if d.element(n8BN254, prime, &x); d.err != nil {
+		return
+	}
+	var b [n8BN254]byte
+	x.FillBytes(b[:])
+	for i := range limbs {
+		limbs[i] = binary.BigEndian.Uint64(b[n8BN254-8*(i+1):])
+	}
+}
+
+func (d *decoder) g1(p *curve.G1Affine) {
+	d.montgomery(fp.Modulus(), p.X[:])
+	d.montgomery(fp.Modulus(), p.Y[:])
+	if d.err == nil && !p.IsInfinity() && !(p.IsOnCurve() && p.IsInSubGroup()) {
+		d.err = errors.New("G1 point not on the curve")
+	}
+}
+
+func (d *decoder) g2(p *curve.G2Affine) {
+	d.montgomery(fp.Modulus(), p.X.A0[:])
+	d.montgomery(fp.Modulus(), p.X.A1[:])
+	d.montgomery(fp.Modulus(), p.Y.A0[:])
+	d.montgomery(fp.Modulus(), p.Y.A1[:])
+	if d.err == nil && !p.IsInfinity() && !(p.IsOnCurve() && p.IsInSubGroup()) {
+		d.err = errors.New("G2 point not on the curve or not in the subgroup")
+	}
+}
+
+// end returns the error of the decoder, or an error if bytes are left
+func (d *decoder) end() error {
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.buf) != 0 {
+		return fmt.Errorf("%d trailing bytes", len(d.buf))
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ptau
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	dkzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/piano"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+)
+
+// cubeCircuit is out = a·b³ + 5, a and out being public
+type cubeCircuit struct {
+	A   frontend.Variable `gnark:",public"`
+	B   frontend.Variable
+	Out frontend.Variable `gnark:",public"`
+}
+
+func (c *cubeCircuit) Define(api frontend.API) error {
+	b3 := api.Mul(c.B, c.B, c.B)
+	api.AssertIsEqual(c.Out, api.Add(api.Mul(c.A, b3), 5))
+	return nil
+}
+
+// cubeWitnesses returns the full and public witnesses of out = 3·2³ + 5
+func cubeWitnesses(t *testing.T) (full, public *witness.Witness) {
+	t.Helper()
+	assignment := &cubeCircuit{A: 3, B: 2, Out: 29}
+	full, err := frontend.NewWitness(assignment, ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err = frontend.NewWitness(assignment, ecc.BN254, frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return full, public
+}
+
+// testdata/tau3.ptau holds the powers of this τ for the domains up to 2³, laid out as
+// snarkjs powersoftau writes them
+const ptauTau = "14527920257043495044140164776547771266149027174760467282230237695072651680867"
+
+// offset of the tau G1 section in testdata/tau3.ptau, after the file header and the
+// header section
+const offsetTauG1 = 12 + 12 + 44 + 12
+
+func tau3Ptau(t *testing.T) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "tau3.ptau"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReadSRS(t *testing.T) {
+	data := tau3Ptau(t)
+	tau, _ := new(big.Int).SetString(ptauTau, 10)
+	for _, size := range []uint64{2, 10, 15} {
+		srs, err := ReadSRS(bytes.NewReader(data), size, true)
+		if err != nil {
+			t.Fatalf("%d powers: %v", size, err)
+		}
+		want, err := kzg_bn254.NewSRS(size, tau)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(srs, want) {
+			t.Fatalf("%d powers: not the SRS of the ptau secret", size)
+		}
+	}
+}
+
+func TestReadSRSPlonk(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &cubeCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srs, err := ReadSRS(bytes.NewReader(tau3Ptau(t)), 15, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := plonk.Setup(ccs, srs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full, public := cubeWitnesses(t)
+	proof, err := plonk.Prove(ccs, pk, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plonk.Verify(proof, vk, public); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSRSPiano(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &cubeCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srs, err := ReadSRS(bytes.NewReader(tau3Ptau(t)), mpi.WorldSize, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full, public := cubeWitnesses(t)
+
+	// the distributed SRS on X of the τ of the ptau on Y
+	_, _, nbPublic := ccs.GetNbVariables()
+	sizeX := ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints()+nbPublic)) + 3
+	tau, _ := new(big.Int).SetString(ptauTau, 10)
+	dsrs, err := dkzg_bn254.NewSRS(sizeX, []*big.Int{tau, big.NewInt(42)}, &fft.NewDomain(mpi.WorldSize).Generator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, vk, err := piano.Setup(ccs, public, backend.WithSRS(dsrs, srs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := piano.Prove(ccs, pk, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := piano.Verify(proof, vk, public); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadSRSMalformed(t *testing.T) {
+	data := tau3Ptau(t)
+
+	// [τ³]1 and [τ⁴]1 swapped are points of the curve, but not successive powers
+	swapped := append([]byte(nil), data...)
+	p3 := swapped[offsetTauG1+3*sizeG1BN254 : offsetTauG1+4*sizeG1BN254]
+	p4 := swapped[offsetTauG1+4*sizeG1BN254 : offsetTauG1+5*sizeG1BN254]
+	for i := range p3 {
+		p3[i], p4[i] = p4[i], p3[i]
+	}
+	if _, err := ReadSRS(bytes.NewReader(swapped), 8, false); err != nil {
+		t.Fatal(err)
+	}
+
+	notPtau := append([]byte(nil), data...)
+	notPtau[0] ^= 1
+	for name, test := range map[string]struct {
+		data []byte
+		size uint64
+	}{
+		"not a ptau":      {notPtau, 8},
+		"truncated":       {data[:offsetTauG1+5*sizeG1BN254], 8},
+		"too many powers": {data, 16},
+		"no power":        {data, 0},
+		"swapped powers":  {swapped, 8},
+		"no points":       {data[:12+12+44], 8},
+	} {
+		if _, err := ReadSRS(bytes.NewReader(test.data), test.size, true); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
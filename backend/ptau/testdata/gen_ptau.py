@@ -0,0 +1,29 @@
+# Writes tau3.ptau, a .ptau of power 3 laid out as snarkjs powersoftau writes them, of a
+# known tau: see README.md
+import os, sys, struct, random
+here = os.path.dirname(os.path.abspath(__file__))
+sys.path.insert(0, os.path.join(here, '..', '..', 'groth16', 'testdata', 'snarkjs'))
+from bn254 import p, r, G1, G2, mul
+R = 1 << 256
+rnd = random.Random(20230403)
+tau, alpha, beta = [rnd.randrange(1, r) for _ in range(3)]
+power = 3
+u32 = lambda x: struct.pack('<I', x)
+le = lambda x: x.to_bytes(32, 'little')
+mont = lambda x: le(x * R % p)
+g1 = lambda s: (lambda P: mont(int(P[0])) + mont(int(P[1])))(mul(G1, s))
+g2 = lambda s: (lambda P: b''.join(mont(c) for c in P[0].coeffs + P[1].coeffs))(mul(G2, s))
+n = 1 << power
+sec = {
+    1: u32(32) + le(p) + u32(power) + u32(power),
+    2: b''.join(g1(pow(tau, i, r)) for i in range(2 * n - 1)),
+    3: b''.join(g2(pow(tau, i, r)) for i in range(n)),
+    4: b''.join(g1(alpha * pow(tau, i, r) % r) for i in range(n)),
+    5: b''.join(g1(beta * pow(tau, i, r) % r) for i in range(n)),
+    6: g2(beta),
+    7: u32(0),
+}
+out = b'ptau' + u32(1) + u32(len(sec))
+for t in sorted(sec):
+    out += u32(t) + struct.pack('<Q', len(sec[t])) + sec[t]
+open(os.path.join(here, 'tau3.ptau'), 'wb').write(out)
@@ -0,0 +1,176 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	gohash "hash"
+
+	"github.com/consensys/gnark-crypto/dkzg"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark-crypto/kzg"
+	"golang.org/x/crypto/sha3"
+)
+
+// TranscriptHash identifies the hash function used by the Fiat-Shamir transcripts of
+// the PlonK, piano and gpiano provers and verifiers. It is recorded in the verifying key.
+type TranscriptHash uint8
+
+const (
+	// SHA256 is the default transcript hash
+	SHA256 TranscriptHash = iota
+
+	// KECCAK256 is the legacy Keccak-256 of the EVM
+	KECCAK256
+
+	// MIMC is MiMC over the scalar field of the curve, which std/fiat-shamir mirrors
+	// in circuits. Every value written to the transcript (challenge name, previous
+	// challenge, binding) is left-padded to a multiple of the size of a field element,
+	// so that it is absorbed as the field elements a circuit would write, and the
+	// challenges are the hash outputs, already in the field.
+	MIMC
+)
+
+func (h TranscriptHash) String() string {
+	switch h {
+	case SHA256:
+		return "sha256"
+	case KECCAK256:
+		return "keccak256"
+	case MIMC:
+		return "mimc"
+	default:
+		return fmt.Sprintf("transcript-hash(%d)", uint8(h))
+	}
+}
+
+var mimcs = map[ecc.ID]hash.Hash{
+	ecc.BN254:     hash.MIMC_BN254,
+	ecc.BLS12_377: hash.MIMC_BLS12_377,
+	ecc.BLS12_381: hash.MIMC_BLS12_381,
+	ecc.BLS24_315: hash.MIMC_BLS24_315,
+	ecc.BW6_761:   hash.MIMC_BW6_761,
+	ecc.BW6_633:   hash.MIMC_BW6_633,
+}
+
+// New returns a new instance of the hash function, for the scalar field of curve
+// if it is a SNARK-friendly hash
+func (h TranscriptHash) New(curve ecc.ID) (gohash.Hash, error) {
+	switch h {
+	case SHA256:
+		return sha256.New(), nil
+	case KECCAK256:
+		return sha3.NewLegacyKeccak256(), nil
+	case MIMC:
+		m, ok := mimcs[curve]
+		if !ok {
+			return nil, fmt.Errorf("no MiMC transcript hash for curve %s", curve)
+		}
+		hFunc := m.New()
+		return &fieldWriter{Hash: hFunc, size: hFunc.BlockSize()}, nil
+	default:
+		return nil, fmt.Errorf("unknown transcript hash %s", h)
+	}
+}
+
+// fieldWriter left-pads every write to a multiple of size bytes
+type fieldWriter struct {
+	gohash.Hash
+	size int
+}
+
+func (w *fieldWriter) Write(p []byte) (int, error) {
+	if r := len(p) % w.size; r != 0 {
+		padded := make([]byte, len(p)+w.size-r)
+		copy(padded[w.size-r:], p)
+		if _, err := w.Hash.Write(padded); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return w.Hash.Write(p)
+}
+
+// SetupOption defines option for altering the behaviour of the Setup methods.
+// See the descriptions of functions returning instances of this type for
+// implemented options.
+type SetupOption func(*SetupConfig) error
+
+// SetupConfig is the configuration for the setup with the options applied.
+type SetupConfig struct {
+	TranscriptHash TranscriptHash // defaults to SHA256
+	PartyWeights   []uint64       // defaults to an equal share of the circuit per party
+	DKZGSRS        dkzg.SRS       // defaults to the SRS of trapdoors drawn by the setup
+	KZGSRS         kzg.SRS        // defaults to the SRS of trapdoors drawn by the setup
+}
+
+// NewSetupConfig returns a default SetupConfig with given setup options opts
+// applied.
+func NewSetupConfig(opts ...SetupOption) (SetupConfig, error) {
+	var opt SetupConfig
+	for _, option := range opts {
+		if err := option(&opt); err != nil {
+			return SetupConfig{}, err
+		}
+	}
+	return opt, nil
+}
+
+// WithTranscriptHash is a setup option that selects the hash function of the
+// Fiat-Shamir transcript. It is recorded in the verifying key, so that the prover
+// and the verifier use the same one.
+func WithTranscriptHash(h TranscriptHash) SetupOption {
+	return func(opt *SetupConfig) error {
+		if h > MIMC {
+			return fmt.Errorf("unknown transcript hash %s", h)
+		}
+		opt.TranscriptHash = h
+		return nil
+	}
+}
+
+// WithPartyWeights is a setup option for the distributed backends that splits the
+// circuit between the parties proportionally to the weights, one per party, instead
+// of in equal shares. A party with a larger share gets a larger domain.
+func WithPartyWeights(weights ...uint64) SetupOption {
+	return func(opt *SetupConfig) error {
+		for i, w := range weights {
+			if w == 0 {
+				return fmt.Errorf("party %d has a weight of 0", i)
+			}
+		}
+		opt.PartyWeights = weights
+		return nil
+	}
+}
+
+// WithSRS is a setup option for the Pianist backends that commits with the given SRS
+// instead of drawing the trapdoors: dsrs the distributed SRS of the party on X, and srs
+// the univariate SRS on Y, which only the first party needs. dsrs must be of the
+// trapdoor of srs on Y, so that it can only be computed knowing that trapdoor: srs can
+// be read from the file of a Powers of Tau ceremony, which doesn't reveal it, but dsrs
+// can't, and the setup is no more trusted than whoever computed dsrs.
+func WithSRS(dsrs dkzg.SRS, srs kzg.SRS) SetupOption {
+	return func(opt *SetupConfig) error {
+		if dsrs == nil {
+			return errors.New("the distributed SRS is required")
+		}
+		opt.DKZGSRS, opt.KZGSRS = dsrs, srs
+		return nil
+	}
+}
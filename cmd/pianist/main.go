@@ -0,0 +1,236 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command pianist runs a gpiano proof end-to-end with several parties on this machine.
+//
+// It starts -n worker processes of itself, linked to each other over loopback TCP or
+// Unix sockets (comm.Network), which set up, prove and verify the chosen circuit:
+//
+//	pianist -n 4 -circuit random -size 16
+//	pianist -n 2 -network unix -circuit exponentiate
+//
+// The workers get their rank, the world size and the addresses of the parties from the
+// PIANIST_RANK, PIANIST_WORLD_SIZE, PIANIST_NETWORK and PIANIST_PEERS environment
+// variables, which -rank and -peers override to start a party by hand. A party of a
+// cluster spanning several machines rather starts with -cluster, linked to the other
+// parties over mutual TLS (comm.TLS):
+//
+//	pianist -cluster party-1.json -circuit random -size 20
+//
+// The distributed KZG of the gnark-crypto fork bootstraps simpleMPI from ip.txt in its
+// init(). The launcher starts the workers in a directory whose ip.txt lists a single
+// party, so that simpleMPI doesn't connect to the other parties itself, and the workers
+// route simpleMPI through their links to the other parties (comm.RouteSimpleMPI). A
+// party started by hand or with -cluster must be started in such a directory too.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/consensys/gnark/backend/comm"
+)
+
+// Environment of the workers
+const (
+	envRank       = "PIANIST_RANK"
+	envWorldSize  = "PIANIST_WORLD_SIZE"
+	envNetwork    = "PIANIST_NETWORK"
+	envPeers      = "PIANIST_PEERS"
+	envListenerFD = "PIANIST_LISTENER_FD"
+)
+
+// listenerFD is the descriptor of the listener the launcher passes to a worker, the
+// first one after stdin, stdout and stderr
+const listenerFD = 3
+
+type config struct {
+	nbParties int
+	network   string
+	topology  string
+
+	circuit  string
+	size     int
+	density  float64
+	wiring   float64
+	nbProofs int
+
+	// set on the workers
+	rank    int
+	peers   []string
+	cluster *comm.ClusterConfig
+}
+
+func main() {
+	var cfg config
+	var peers, cluster string
+	flag.IntVar(&cfg.nbParties, "n", 2, "number of parties")
+	flag.StringVar(&cfg.network, "network", "tcp", "network linking the parties: tcp or unix")
+	flag.StringVar(&cfg.topology, "topology", "star", "topology of the collectives: star or tree")
+	flag.StringVar(&cfg.circuit, "circuit", "random", "circuit to prove: random or exponentiate")
+	flag.IntVar(&cfg.size, "size", 10, "log2 of the number of constraints of the random circuit")
+	flag.Float64Var(&cfg.density, "density", 1, "fraction of the rows of the random circuit holding a gate")
+	flag.Float64Var(&cfg.wiring, "wiring", 0, "fraction of the wires of the random circuit copied to the next party")
+	flag.IntVar(&cfg.nbProofs, "proofs", 1, "number of proofs to generate")
+	flag.IntVar(&cfg.rank, "rank", -1, "rank of this party, to start a worker by hand")
+	flag.StringVar(&peers, "peers", "", "comma-separated addresses of the parties, to start a worker by hand")
+	flag.StringVar(&cluster, "cluster", "", "configuration file of a TLS cluster, to start one of its parties")
+	flag.Parse()
+
+	if cluster != "" {
+		var err error
+		if cfg.cluster, err = comm.LoadClusterConfig(cluster); err != nil {
+			log.Fatal(err)
+		}
+		cfg.rank = int(cfg.cluster.Rank)
+		if err := work(cfg); err != nil {
+			log.Fatalf("rank %d: %v", cfg.rank, err)
+		}
+		return
+	}
+
+	if cfg.rank < 0 {
+		if s, ok := os.LookupEnv(envRank); ok {
+			var err error
+			if cfg.rank, err = strconv.Atoi(s); err != nil {
+				log.Fatalf("%s: %v", envRank, err)
+			}
+			if s := os.Getenv(envNetwork); s != "" {
+				cfg.network = s
+			}
+			peers = os.Getenv(envPeers)
+		}
+	}
+	if cfg.rank < 0 {
+		if err := launch(cfg, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg.peers = strings.Split(peers, ",")
+	if err := work(cfg); err != nil {
+		log.Fatalf("rank %d: %v", cfg.rank, err)
+	}
+}
+
+// singlePartyIPs is the ip.txt of the workers, which leaves simpleMPI with a single party
+const singlePartyIPs = "localhost:0\n"
+
+// launch starts the workers with the arguments args and waits for them, stopping all of
+// them as soon as one fails
+func launch(cfg config, args []string) error {
+	if cfg.nbParties < 1 {
+		return fmt.Errorf("got %d parties", cfg.nbParties)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "pianist")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "ip.txt"), []byte(singlePartyIPs), 0o644); err != nil {
+		return err
+	}
+
+	// listen for all the workers first, so that none of them dials a party which
+	// isn't listening yet
+	files := make([]*os.File, cfg.nbParties)
+	addrs := make([]string, cfg.nbParties)
+	for i := range files {
+		addr := "127.0.0.1:0"
+		if cfg.network == "unix" {
+			addr = filepath.Join(dir, fmt.Sprintf("party-%d.sock", i))
+		}
+		l, err := net.Listen(cfg.network, addr)
+		if err != nil {
+			return err
+		}
+		if ul, ok := l.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+		addrs[i] = l.Addr().String()
+		f, err := l.(interface{ File() (*os.File, error) }).File()
+		l.Close()
+		if err != nil {
+			return err
+		}
+		files[i] = f
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make([]error, cfg.nbParties)
+	var wg sync.WaitGroup
+	for i := range files {
+		cmd := exec.CommandContext(ctx, self, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("%s=%d", envRank, i),
+			fmt.Sprintf("%s=%d", envWorldSize, cfg.nbParties),
+			fmt.Sprintf("%s=%s", envNetwork, cfg.network),
+			fmt.Sprintf("%s=%s", envPeers, strings.Join(addrs, ",")),
+			fmt.Sprintf("%s=%d", envListenerFD, listenerFD),
+		)
+		cmd.ExtraFiles = []*os.File{files[i]}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		cmd.Stderr = cmd.Stdout
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		files[i].Close()
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prefix(os.Stdout, stdout, fmt.Sprintf("rank %d ", i))
+			if errs[i] = cmd.Wait(); errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("rank %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// prefix copies the lines of r to w, each one starting with p
+func prefix(w io.Writer, r io.Reader, p string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fmt.Fprintf(w, "%s%s\n", p, scanner.Text())
+	}
+}
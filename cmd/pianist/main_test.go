@@ -0,0 +1,58 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestMain runs the workers started by the tests of the launcher, which are processes
+// of the test binary
+func TestMain(m *testing.M) {
+	if _, ok := os.LookupEnv(envRank); ok {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestLaunch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts several provers")
+	}
+	for _, tt := range []struct {
+		network, topology string
+		nbParties         int
+	}{
+		{"tcp", "star", 2},
+		{"unix", "tree", 3},
+	} {
+		t.Run(fmt.Sprintf("%s/%s/%d", tt.network, tt.topology, tt.nbParties), func(t *testing.T) {
+			cfg := config{nbParties: tt.nbParties, network: tt.network}
+			args := []string{
+				"-network", tt.network,
+				"-topology", tt.topology,
+				"-circuit", "random",
+				"-size", "8",
+				"-wiring", "0.5",
+			}
+			if err := launch(cfg, args); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/comm"
+	"github.com/consensys/gnark/backend/gpiano"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+)
+
+// work connects this party to the other ones and proves the circuit
+func work(cfg config) error {
+	topology := comm.Star
+	switch cfg.topology {
+	case "star":
+	case "tree":
+		topology = comm.BinomialTree
+	default:
+		return fmt.Errorf("unknown topology %q", cfg.topology)
+	}
+
+	t, traffic, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	// the distributed KZG calls simpleMPI, which goes through the transport too
+	comm.RouteSimpleMPI(t)
+	comm.Default = &comm.Communicator{Transport: t, Topology: topology}
+
+	var prove func() (gpiano.Proof, error)
+	var vk gpiano.VerifyingKey
+	var publicWitness *witness.Witness
+	start := time.Now()
+	switch cfg.circuit {
+	case "random":
+		prove, vk, publicWitness, err = setupRandom(cfg)
+	case "exponentiate":
+		prove, vk, publicWitness, err = setupExponentiate()
+	default:
+		err = fmt.Errorf("unknown circuit %q", cfg.circuit)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("setup: %s\n", time.Since(start))
+
+	for k := 0; k < cfg.nbProofs; k++ {
+		start = time.Now()
+		proof, err := prove()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("prove: %s\n", time.Since(start))
+
+		if mpi.SelfRank == 0 {
+			start = time.Now()
+			if err := gpiano.Verify(proof, vk, publicWitness); err != nil {
+				return err
+			}
+			fmt.Printf("verify: %s\n", time.Since(start))
+		}
+	}
+	sent, received := traffic()
+	fmt.Printf("sent %d bytes, received %d bytes\n", sent, received)
+	return nil
+}
+
+// transport is a comm.Transport holding connections
+type transport interface {
+	comm.Transport
+	Close() error
+}
+
+// connect links this party to the other ones, over TLS for a cluster, and returns the
+// transport and a function counting the bytes it sent and received
+func connect(cfg config) (transport, func() (uint64, uint64), error) {
+	if cfg.cluster != nil {
+		t, err := comm.ConnectTLS(cfg.cluster)
+		if err != nil {
+			return nil, nil, err
+		}
+		return t, func() (uint64, uint64) { return t.BytesSent, t.BytesReceived }, nil
+	}
+
+	if cfg.rank >= len(cfg.peers) {
+		return nil, nil, fmt.Errorf("rank %d out of %d parties", cfg.rank, len(cfg.peers))
+	}
+	if s := os.Getenv(envWorldSize); s != "" && s != strconv.Itoa(len(cfg.peers)) {
+		return nil, nil, fmt.Errorf("%s=%s but got %d peers", envWorldSize, s, len(cfg.peers))
+	}
+	l, err := listen(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	t, err := comm.ConnectNetwork(uint64(cfg.rank), cfg.network, cfg.peers, l)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, func() (uint64, uint64) { return t.BytesSent, t.BytesReceived }, nil
+}
+
+// listen returns the listener passed by the launcher, or listens on the address of this
+// party for a worker started by hand
+func listen(cfg config) (net.Listener, error) {
+	if s := os.Getenv(envListenerFD); s != "" {
+		fd, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", envListenerFD, err)
+		}
+		f := os.NewFile(uintptr(fd), "listener")
+		defer f.Close()
+		return net.FileListener(f)
+	}
+	return net.Listen(cfg.network, cfg.peers[cfg.rank])
+}
+
+func setupRandom(cfg config) (func() (gpiano.Proof, error), gpiano.VerifyingKey, *witness.Witness, error) {
+	const nbPublicInputs = 4
+	pk, vk, witnesses, err := gpiano.SetupRandom(ecc.BN254, gpiano.RandomCircuit{
+		NbConstraints:    1 << cfg.size,
+		NbPublicInputs:   nbPublicInputs,
+		GateDensity:      cfg.density,
+		CrossPartyWiring: cfg.wiring,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	publicInputs := witnesses[0][:nbPublicInputs]
+	prove := func() (gpiano.Proof, error) {
+		return gpiano.ProveDirect(pk, witnesses, publicInputs)
+	}
+	return prove, vk, gpiano.NewPublicWitness(ecc.BN254, publicInputs), nil
+}
+
+// exponentiateCircuit is y == x**e, as in examples/piano, each party proving its own
+// instance
+type exponentiateCircuit struct {
+	X frontend.Variable `gnark:",public"`
+	Y frontend.Variable `gnark:",public"`
+
+	E frontend.Variable
+}
+
+func (circuit *exponentiateCircuit) Define(api frontend.API) error {
+	const bitSize = 64
+
+	output := frontend.Variable(1)
+	bits := api.ToBinary(circuit.E, bitSize)
+	for i := 0; i < len(bits); i++ {
+		if i != 0 {
+			output = api.Mul(output, output)
+		}
+		multiply := api.Mul(output, circuit.X)
+		output = api.Select(bits[len(bits)-1-i], multiply, output)
+	}
+	api.AssertIsEqual(circuit.Y, output)
+	return nil
+}
+
+func setupExponentiate() (func() (gpiano.Proof, error), gpiano.VerifyingKey, *witness.Witness, error) {
+	var circuit exponentiateCircuit
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &circuit)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	w := exponentiateCircuit{X: 12, E: 2, Y: 144}
+	witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pk, vk, err := gpiano.Setup(ccs, witnessPublic)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	prove := func() (gpiano.Proof, error) {
+		return gpiano.Prove(ccs, pk, witnessFull)
+	}
+	return prove, vk, witnessPublic, nil
+}
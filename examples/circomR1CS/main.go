@@ -6,9 +6,8 @@ import (
 	"os"
 	"runtime"
 
-	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/piano"
-	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/circom"
 	"github.com/sunblaze-ucb/simpleMPI/mpi"
 )
 
@@ -16,7 +15,16 @@ func main() {
 	runtime.GOMAXPROCS(4)
 	dir, _ := os.Getwd()
 	fmt.Println("working directory: ", dir)
-	ccs, err := ReadR1CS("r1cs")
+	f, err := os.Open("r1cs")
+	if err != nil {
+		panic(err)
+	}
+	cs, err := circom.ReadR1CS(f)
+	f.Close()
+	if err != nil {
+		panic(err)
+	}
+	ccs, err := circom.CompileSparse(cs)
 	if err != nil {
 		panic(err)
 	}
@@ -24,20 +32,16 @@ func main() {
 	fmt.Println(a, b, c)
 
 	{
-		// Witnesses instantiation. Witness is known only by the prover,
-		// while public w is a public data known by the verifier.
-		var w R1CSCircuit
-		w.Witness = make([]frontend.Variable, 260275)
-		for i := 0; i < len(w.Witness); i++ {
-			w.Witness[i] = frontend.Variable(0)
-		}
-
-		witnessFull, err := frontend.NewWitness(&w, ecc.BN254)
+		// The witness of the circuit for in.json, computed by the witness calculator
+		// of circom, e.g. with snarkjs wtns calculate circuit.wasm in.json wtns.
+		// Witness is known only by the prover, while public w is a public data
+		// known by the verifier.
+		f, err := os.Open("wtns")
 		if err != nil {
 			log.Fatal(err)
 		}
-
-		witnessPublic, err := frontend.NewWitness(&w, ecc.BN254, frontend.PublicOnly())
+		witnessFull, witnessPublic, err := circom.ReadWitness(f, cs)
+		f.Close()
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -28,12 +28,7 @@ import (
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark/backend/gpiano"
-	"github.com/consensys/gnark/backend/witness"
 	"github.com/sunblaze-ucb/simpleMPI/mpi"
-
-	"github.com/consensys/gnark/backend"
-	gpiano_bn254 "github.com/consensys/gnark/internal/backend/bn254/gpiano"
-	witness_bn254 "github.com/consensys/gnark/internal/backend/bn254/witness"
 )
 
 // In this example we show how to use PLONK with KZG commitments. The circuit that is
@@ -45,6 +40,23 @@ func main() {
 		log.Fatal(err)
 	}
 	numPublicInput := 4
+
+	// optional gate density and cross-party wiring ratio of the synthetic circuit
+	circuit := gpiano.RandomCircuit{
+		NbConstraints:  1 << nv,
+		NbPublicInputs: numPublicInput,
+		GateDensity:    1,
+	}
+	if len(os.Args) > 2 {
+		if circuit.GateDensity, err = strconv.ParseFloat(os.Args[2], 64); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if len(os.Args) > 3 {
+		if circuit.CrossPartyWiring, err = strconv.ParseFloat(os.Args[3], 64); err != nil {
+			log.Fatal(err)
+		}
+	}
 	var repetitions int
 	size := nv - int(math.Round(math.Log2(float64(mpi.WorldSize))))
 	if size <= 20 {
@@ -58,7 +70,7 @@ func main() {
 	// Correct data: the proof passes
 	{
 		start := time.Now()
-		pk, vk, witnesses, err := gpiano_bn254.SetupRandom(ecc.BN254, 1<<nv, numPublicInput)
+		pk, vk, witnesses, err := gpiano.SetupRandom(ecc.BN254, circuit)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -73,10 +85,9 @@ func main() {
 			mpi.ReceiveBytes(1, 0)
 		}
 
-		opt, err := backend.NewProverConfig()
 		start = time.Now()
 		for i := 0; i < repetitions; i++ {
-			_, err := gpiano_bn254.ProveDirect(pk, witnesses, witnesses[0][:numPublicInput], opt)
+			_, err := gpiano.ProveDirect(pk, witnesses, witnesses[0][:numPublicInput])
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -84,7 +95,7 @@ func main() {
 		fmt.Printf("prove for %d variables: %d\n", nv, int(time.Since(start).Microseconds())/repetitions)
 
 		bytesSentStart, bytesReceivedStart := mpi.BytesSent, mpi.BytesReceived
-		proof, err := gpiano_bn254.ProveDirect(pk, witnesses, witnesses[0][:numPublicInput], opt)
+		proof, err := gpiano.ProveDirect(pk, witnesses, witnesses[0][:numPublicInput])
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -109,12 +120,10 @@ func main() {
 		}
 
 		if mpi.SelfRank == 0 {
-			publicWitness := witness_bn254.New(witnesses[0][:numPublicInput])
+			publicWitness := gpiano.NewPublicWitness(ecc.BN254, witnesses[0][:numPublicInput])
 			start = time.Now()
 			for i := 0; i < repetitions*10; i++ {
-				err = gpiano.Verify(proof, vk, &witness.Witness{
-					Vector: &publicWitness,
-				})
+				err = gpiano.Verify(proof, vk, publicWitness)
 				if err != nil {
 					log.Fatal(err)
 				}
@@ -0,0 +1,269 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acir
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/test"
+)
+
+func readProgram(t *testing.T, name string) *Program {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p, err := ReadProgram(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func values(vs ...int64) map[Witness]*big.Int {
+	res := make(map[Witness]*big.Int, len(vs))
+	for i, v := range vs {
+		res[Witness(i+1)] = big.NewInt(v)
+	}
+	return res
+}
+
+// checkSolved checks that the witness map solves p, or doesn't
+func checkSolved(t *testing.T, p *Program, values map[Witness]*big.Int, solved bool) {
+	t.Helper()
+	assignment := NewCircuit(p)
+	if err := assignment.Assign(values); err != nil {
+		t.Fatal(err)
+	}
+	err := test.IsSolved(NewCircuit(p), assignment, ecc.BN254, backend.PLONK)
+	if solved && err != nil {
+		t.Fatal(err)
+	}
+	if !solved && err == nil {
+		t.Fatalf("%v shouldn't solve the program", values)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	p := readProgram(t, "arithmetic.json")
+	if len(p.Opcodes) != 4 || p.Opcodes[0].Name != "Brillig" || p.Opcodes[2].Name != "BlackBoxFuncCall RANGE" {
+		t.Fatal("unexpected opcodes")
+	}
+
+	f, err := os.Open(filepath.Join("testdata", "arithmetic.witness.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	witnessMap, err := ReadWitnessMap(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// z = x·y + 2·b + 3
+	checkSolved(t, p, witnessMap, true)
+	checkSolved(t, p, values(5, 7, 1, 41), false)
+	checkSolved(t, p, values(5, 7, 2, 42), false)
+	checkSolved(t, p, values(300, 7, 1, 2105), false)
+
+	// the witnesses y and z are public, in this order
+	assignment := NewCircuit(p)
+	if err := assignment.Assign(witnessMap); err != nil {
+		t.Fatal(err)
+	}
+	for _, newBuilder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := Compile(p, newBuilder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nbPublic := ccs.GetSchema().NbPublic; nbPublic != 2 {
+			t.Fatalf("%d public variables, expected 2", nbPublic)
+		}
+		witness, err := frontend.NewWitness(assignment, ecc.BN254)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ccs.IsSolved(witness); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	delete(witnessMap, 3)
+	if err := assignment.Assign(witnessMap); err == nil {
+		t.Fatal("assigning without witness 3 should fail")
+	}
+}
+
+func TestNargo(t *testing.T) {
+	p := readProgram(t, filepath.Join("nargo", "target", "arithmetic.json"))
+	names := []string{"BlackBoxFuncCall RANGE", "BlackBoxFuncCall RANGE", "BrilligCall", "AssertZero", "AssertZero"}
+	if len(p.Opcodes) != len(names) {
+		t.Fatalf("%d opcodes, expected %d", len(p.Opcodes), len(names))
+	}
+	for i := range names {
+		if p.Opcodes[i].Name != names[i] {
+			t.Fatalf("opcode %d is %s, expected %s", i, p.Opcodes[i].Name, names[i])
+		}
+	}
+
+	f, err := os.Open(filepath.Join("testdata", "nargo", "target", "arithmetic.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	witnessMap, err := ReadWitnessMap(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// y ≠ 0, z = x·y + 2·b + 3
+	checkSolved(t, p, witnessMap, true)
+	inverse := witnessMap[4]
+	checkSolved(t, p, map[Witness]*big.Int{1: big.NewInt(5), 2: big.NewInt(7), 3: big.NewInt(1), 4: inverse, 5: big.NewInt(41)}, false)
+	checkSolved(t, p, map[Witness]*big.Int{1: big.NewInt(5), 2: big.NewInt(0), 3: big.NewInt(1), 4: inverse, 5: big.NewInt(5)}, false)
+	checkSolved(t, p, map[Witness]*big.Int{1: big.NewInt(5), 2: big.NewInt(7), 3: big.NewInt(2), 4: inverse, 5: big.NewInt(42)}, false)
+
+	// the witnesses y and z are public
+	ccs, err := Compile(p, scs.NewBuilder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nbPublic := ccs.GetSchema().NbPublic; nbPublic != 2 {
+		t.Fatalf("%d public variables, expected 2", nbPublic)
+	}
+}
+
+// bytecode returns the artifact of nargo of the bincode of a program
+func bytecode(t *testing.T, program ...interface{}) string {
+	t.Helper()
+	var raw, zipped bytes.Buffer
+	for _, v := range program {
+		if err := binary.Write(&raw, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := gzip.NewWriter(&zipped)
+	if _, err := w.Write(raw.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return `{"bytecode": "` + base64.StdEncoding.EncodeToString(zipped.Bytes()) + `"}`
+}
+
+func TestReadBytecodeMalformed(t *testing.T) {
+	// a program of one function, with current witness index 1, and one opcode
+	header := []interface{}{uint64(1), uint32(1), uint64(1)}
+	for name, program := range map[string]string{
+		"not base64":      `{"bytecode": "!"}`,
+		"not gzip":        `{"bytecode": "` + base64.StdEncoding.EncodeToString([]byte("bincode")) + `"}`,
+		"truncated":       bytecode(t, append(header, uint32(0))...),
+		"unknown opcode":  bytecode(t, append(header, uint32(7))...),
+		"long sequence":   bytecode(t, uint64(1), uint32(1), uint64(1<<40)),
+		"invalid element": bytecode(t, append(header, uint32(0), uint64(0), uint64(0), uint64(2), [2]byte{'0', 'g'})...),
+	} {
+		if _, err := ReadProgram(strings.NewReader(program)); !errors.Is(err, ErrInvalidProgram) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	for name, program := range map[string]string{
+		"two functions":      bytecode(t, uint64(2), [16]byte{}),
+		"black box function": bytecode(t, append(header, uint32(1), uint32(4))...),
+	} {
+		if _, err := ReadProgram(strings.NewReader(program)); !errors.Is(err, ErrUnsupported) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	p := readProgram(t, "memory.json")
+
+	// a = [10, 20, 30]; a[i]; a[1] = 99; a[i]
+	checkSolved(t, p, values(10, 20, 30, 1, 20, 99, 99), true)
+	checkSolved(t, p, values(10, 20, 30, 2, 30, 99, 30), true)
+	checkSolved(t, p, values(10, 20, 30, 1, 30, 99, 99), false)
+	checkSolved(t, p, values(10, 20, 30, 1, 20, 99, 20), false)
+	checkSolved(t, p, values(10, 20, 30, 3, 0, 99, 0), false)
+}
+
+func TestBitwise(t *testing.T) {
+	p := readProgram(t, "bitwise.json")
+
+	// 0b11001010 & 0b10100110 = 0b10000010, 0b11001010 ^ 0b10100110 = 0b01101100
+	checkSolved(t, p, values(202, 166, 130, 108), true)
+	checkSolved(t, p, values(202, 166, 131, 108), false)
+	checkSolved(t, p, values(202, 166, 130, 109), false)
+	checkSolved(t, p, values(458, 166, 130, 108), false)
+}
+
+func TestUnsupported(t *testing.T) {
+	p := readProgram(t, "unsupported.json")
+	err := p.Supported()
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"opcode 1 (BlackBoxFuncCall SHA256)", "opcode 2 (Call)", "opcode 4 (MemoryOp with a witness predicate)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q doesn't list %s", err, want)
+		}
+	}
+	for _, skipped := range []string{"opcode 0", "opcode 3", "opcode 5"} {
+		if strings.Contains(err.Error(), skipped) {
+			t.Fatalf("%q lists %s", err, skipped)
+		}
+	}
+	if _, err := Compile(p, scs.NewBuilder); !errors.Is(err, ErrUnsupported) {
+		t.Fatal(err)
+	}
+}
+
+func TestReadProgramMalformed(t *testing.T) {
+	const minusOne = "30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000000"
+	for name, program := range map[string]string{
+		"not json":          `{"opcodes": [`,
+		"two tags":          `{"opcodes": [{"AssertZero": {}, "MemoryInit": {}}]}`,
+		"witness too large": `{"current_witness_index": 1, "private_parameters": [2]}`,
+		"not hexadecimal":   `{"opcodes": [{"AssertZero": {"mul_terms": [], "linear_combinations": [], "q_c": "0xg"}}]}`,
+		"out of the field":  `{"opcodes": [{"AssertZero": {"mul_terms": [], "linear_combinations": [], "q_c": "` + minusOne[:63] + `1"}}]}`,
+		"short term":        `{"opcodes": [{"AssertZero": {"mul_terms": [["01", 1]], "linear_combinations": [], "q_c": "0"}}]}`,
+	} {
+		if _, err := ReadProgram(strings.NewReader(program)); !errors.Is(err, ErrInvalidProgram) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	if _, err := ReadProgram(strings.NewReader(`{"functions": [{}, {}]}`)); !errors.Is(err, ErrUnsupported) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acir
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+)
+
+// variants of the enums of ACIR, in the order of their declaration, which numbers them in
+// the bincode of nargo
+const (
+	opcodeAssertZero = iota
+	opcodeBlackBoxFuncCall
+	opcodeDirective
+	opcodeMemoryOp
+	opcodeMemoryInit
+	opcodeBrilligCall
+	opcodeCall
+)
+
+const (
+	blackBoxAND   = 1
+	blackBoxXOR   = 2
+	blackBoxRANGE = 3
+)
+
+const (
+	brilligInputsSingle = iota
+	brilligInputsArray
+	brilligInputsMemoryArray
+)
+
+const (
+	brilligOutputsSimple = iota
+	brilligOutputsArray
+)
+
+const (
+	expressionWidthUnbounded = iota
+	expressionWidthBounded
+)
+
+// readBytecode reads the program of the bytecode of a nargo artifact: the base64 of the
+// gzip of its bincode
+func readBytecode(bytecode string) (*Program, error) {
+	data, err := base64.StdEncoding.DecodeString(bytecode)
+	if err != nil {
+		return nil, err
+	}
+	data, err = gunzip(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Program {functions, unconstrained_functions}, of which the Brillig functions only
+	// compute witnesses, and aren't read
+	d := &decoder{buf: data}
+	nbFunctions := d.length()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if nbFunctions != 1 {
+		return nil, fmt.Errorf("%w: programs of %d functions", ErrUnsupported, nbFunctions)
+	}
+	p := d.circuit()
+	if d.err != nil {
+		return nil, d.err
+	}
+	return p, nil
+}
+
+// readWitnessStack reads the witness map of the main function from the witness stack
+// nargo execute writes, the gzip of its bincode
+func readWitnessStack(r io.Reader) (map[Witness]*big.Int, error) {
+	data, err := gunzip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// WitnessStack {stack: [StackItem {index, witness}]}, the main function being the
+	// function 0
+	d := &decoder{buf: data}
+	var res map[Witness]*big.Int
+	for n := d.length(); n > 0 && d.err == nil; n-- {
+		index := d.uint32()
+		m := d.witnessMap()
+		if index == 0 {
+			res = m
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if res == nil {
+		return nil, fmt.Errorf("no witness map of the main function")
+	}
+	return res, nil
+}
+
+func gunzip(r io.Reader) ([]byte, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
+
+// decoder reads the bincode of the serde types of ACIR, in the default configuration of
+// bincode: integers in little endian on their size, lengths and usize on 8 bytes, enum
+// variants on 4 and options after a byte
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.buf)) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	res := d.buf[:n]
+	d.buf = d.buf[n:]
+	return res
+}
+
+func (d *decoder) uint8() uint8 {
+	b := d.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) uint32() uint32 {
+	b := d.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// length reads the length of a sequence, checked against the bytes left so that a
+// corrupted one doesn't allocate more than the input
+func (d *decoder) length() int {
+	n := d.uint64()
+	if d.err == nil && n > uint64(len(d.buf)) {
+		d.err = fmt.Errorf("sequence of %d elements in %d bytes", n, len(d.buf))
+	}
+	if d.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// option returns true if the option that follows is set
+func (d *decoder) option() bool {
+	switch tag := d.uint8(); tag {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("invalid option tag %d", tag)
+		}
+		return false
+	}
+}
+
+// element reads a field element, serialized as its hexadecimal string
+func (d *decoder) element() Element {
+	var e Element
+	b := d.bytes(uint64(d.length()))
+	if d.err == nil {
+		d.err = e.setString(string(b))
+	}
+	return e
+}
+
+func (d *decoder) witness() Witness {
+	return Witness(d.uint32())
+}
+
+func (d *decoder) witnesses() []Witness {
+	res := make([]Witness, d.length())
+	for i := range res {
+		res[i] = d.witness()
+	}
+	return res
+}
+
+func (d *decoder) expression() Expression {
+	var e Expression
+	e.MulTerms = make([]MulTerm, d.length())
+	for i := range e.MulTerms {
+		e.MulTerms[i] = MulTerm{Coeff: d.element(), Left: d.witness(), Right: d.witness()}
+	}
+	e.LinearCombinations = make([]LinearTerm, d.length())
+	for i := range e.LinearCombinations {
+		e.LinearCombinations[i] = LinearTerm{Coeff: d.element(), Witness: d.witness()}
+	}
+	e.QC = d.element()
+	return e
+}
+
+func (d *decoder) optionalExpression() *Expression {
+	if !d.option() {
+		return nil
+	}
+	e := d.expression()
+	return &e
+}
+
+func (d *decoder) functionInput() FunctionInput {
+	return FunctionInput{Witness: d.witness(), NbBits: d.uint32()}
+}
+
+// circuit reads the fields of a Circuit up to its return values, the others, the
+// expression width aside, coming after them
+func (d *decoder) circuit() *Program {
+	p := &Program{CurrentWitnessIndex: d.uint32()}
+	p.Opcodes = make([]Opcode, d.length())
+	for i := range p.Opcodes {
+		p.Opcodes[i] = d.opcode()
+		if d.err != nil {
+			d.err = fmt.Errorf("opcode %d: %w", i, d.err)
+			return nil
+		}
+	}
+	switch width := d.uint32(); width {
+	case expressionWidthUnbounded:
+	case expressionWidthBounded:
+		d.uint64()
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("invalid expression width variant %d", width)
+		}
+	}
+	p.PrivateParameters = d.witnesses()
+	p.PublicParameters = d.witnesses()
+	p.ReturnValues = d.witnesses()
+	return p
+}
+
+func (d *decoder) opcode() Opcode {
+	var op Opcode
+	switch v := d.uint32(); v {
+	case opcodeAssertZero:
+		op.Name = "AssertZero"
+		e := d.expression()
+		op.AssertZero = &e
+	case opcodeBlackBoxFuncCall:
+		op.Name, op.BlackBox = d.blackBoxFuncCall()
+	case opcodeDirective:
+		// ToLeRadix {a, b, radix}, the only directive
+		op.Name = "Directive"
+		if v := d.uint32(); v != 0 && d.err == nil {
+			d.err = fmt.Errorf("invalid directive variant %d", v)
+		}
+		d.expression()
+		d.witnesses()
+		d.uint32()
+	case opcodeMemoryOp:
+		op.Name = "MemoryOp"
+		op.MemoryOp = &MemoryOp{BlockID: d.uint32()}
+		op.MemoryOp.Op.Operation = d.expression()
+		op.MemoryOp.Op.Index = d.expression()
+		op.MemoryOp.Op.Value = d.expression()
+		op.MemoryOp.Predicate = d.optionalExpression()
+	case opcodeMemoryInit:
+		op.Name = "MemoryInit"
+		op.MemoryInit = &MemoryInit{BlockID: d.uint32(), Init: d.witnesses()}
+		d.uint32() // the type of block, of no use to the constraints
+	case opcodeBrilligCall:
+		op.Name = "BrilligCall"
+		d.uint32()
+		for n := d.length(); n > 0 && d.err == nil; n-- {
+			switch v := d.uint32(); v {
+			case brilligInputsSingle:
+				d.expression()
+			case brilligInputsArray:
+				for m := d.length(); m > 0 && d.err == nil; m-- {
+					d.expression()
+				}
+			case brilligInputsMemoryArray:
+				d.uint32()
+			default:
+				if d.err == nil {
+					d.err = fmt.Errorf("invalid Brillig input variant %d", v)
+				}
+			}
+		}
+		for n := d.length(); n > 0 && d.err == nil; n-- {
+			switch v := d.uint32(); v {
+			case brilligOutputsSimple:
+				d.witness()
+			case brilligOutputsArray:
+				d.witnesses()
+			default:
+				if d.err == nil {
+					d.err = fmt.Errorf("invalid Brillig output variant %d", v)
+				}
+			}
+		}
+		d.optionalExpression()
+	case opcodeCall:
+		op.Name = "Call"
+		d.uint32()
+		d.witnesses()
+		d.witnesses()
+		d.optionalExpression()
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("invalid opcode variant %d", v)
+		}
+	}
+	return op
+}
+
+// blackBoxFuncCall reads the calls to RANGE, AND and XOR. The layouts of the other
+// functions aren't read, so that they can't be skipped.
+func (d *decoder) blackBoxFuncCall() (string, *BlackBoxFuncCall) {
+	switch v := d.uint32(); v {
+	case blackBoxAND, blackBoxXOR:
+		name := "BlackBoxFuncCall AND"
+		if v == blackBoxXOR {
+			name = "BlackBoxFuncCall XOR"
+		}
+		return name, &BlackBoxFuncCall{Lhs: d.functionInput(), Rhs: d.functionInput(), Output: d.witness()}
+	case blackBoxRANGE:
+		return "BlackBoxFuncCall RANGE", &BlackBoxFuncCall{Input: d.functionInput()}
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("%w: black box function %d", ErrUnsupported, v)
+		}
+		return "", nil
+	}
+}
+
+// witnessMap reads a WitnessMap, a map from the witnesses to their values
+func (d *decoder) witnessMap() map[Witness]*big.Int {
+	n := d.length()
+	res := make(map[Witness]*big.Int, n)
+	for ; n > 0 && d.err == nil; n-- {
+		w := d.witness()
+		v := d.element()
+		res[w] = &v.Int
+	}
+	return res
+}
@@ -0,0 +1,260 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acir
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/bits"
+)
+
+// Circuit is the circuit of an ACIR program. Public holds the public parameters and the
+// return values, Secret the other witnesses the program refers to, both in the order of
+// the witnesses.
+type Circuit struct {
+	Public []frontend.Variable `gnark:",public"`
+	Secret []frontend.Variable `gnark:",secret"`
+
+	program   *Program
+	witnesses []Witness       // witnesses of Public then Secret
+	index     map[Witness]int // index of the witnesses in witnesses
+}
+
+// NewCircuit returns the circuit of p, with its variables allocated. It serves both to
+// compile p and to assign a witness map.
+func NewCircuit(p *Program) *Circuit {
+	public := make(map[Witness]bool)
+	for _, ws := range [][]Witness{p.PublicParameters, p.ReturnValues} {
+		for _, w := range ws {
+			public[w] = true
+		}
+	}
+	c := &Circuit{program: p, index: make(map[Witness]int)}
+	var secret []Witness
+	for _, w := range p.witnesses() {
+		if public[w] {
+			c.witnesses = append(c.witnesses, w)
+		} else {
+			secret = append(secret, w)
+		}
+	}
+	c.Public = make([]frontend.Variable, len(c.witnesses))
+	c.Secret = make([]frontend.Variable, len(secret))
+	c.witnesses = append(c.witnesses, secret...)
+	for i, w := range c.witnesses {
+		c.index[w] = i
+	}
+	return c
+}
+
+// Assign assigns the values of the witnesses, as ReadWitnessMap returns them, to the
+// variables of the circuit
+func (c *Circuit) Assign(values map[Witness]*big.Int) error {
+	for i, w := range c.witnesses {
+		v, ok := values[w]
+		if !ok {
+			return fmt.Errorf("no value for witness %d", w)
+		}
+		if i < len(c.Public) {
+			c.Public[i] = v
+		} else {
+			c.Secret[i-len(c.Public)] = v
+		}
+	}
+	return nil
+}
+
+// Compile compiles p on BN254 with newBuilder. ACIR programs may leave parameters
+// unconstrained, so that the option frontend.IgnoreUnconstrainedInputs is added to opts.
+func Compile(p *Program, newBuilder frontend.NewBuilder, opts ...frontend.CompileOption) (frontend.CompiledConstraintSystem, error) {
+	if err := p.Supported(); err != nil {
+		return nil, err
+	}
+	opts = append(opts, frontend.IgnoreUnconstrainedInputs())
+	return frontend.Compile(ecc.BN254, newBuilder, NewCircuit(p), opts...)
+}
+
+// Define asserts the opcodes of the ACIR program
+func (c *Circuit) Define(api frontend.API) error {
+	if c.program == nil {
+		return errors.New("the circuit of an ACIR program must come from NewCircuit")
+	}
+	if err := c.program.Supported(); err != nil {
+		return err
+	}
+	blocks := make(map[uint32][]frontend.Variable)
+	for i, op := range c.program.Opcodes {
+		var err error
+		switch {
+		case op.AssertZero != nil:
+			c.assertZero(api, op.AssertZero)
+		case op.BlackBox != nil:
+			err = c.blackBox(api, op.Name, op.BlackBox)
+		case op.MemoryInit != nil:
+			if _, ok := blocks[op.MemoryInit.BlockID]; ok {
+				err = fmt.Errorf("memory block %d initialized twice", op.MemoryInit.BlockID)
+				break
+			}
+			block := make([]frontend.Variable, len(op.MemoryInit.Init))
+			for j, w := range op.MemoryInit.Init {
+				block[j] = c.witness(w)
+			}
+			blocks[op.MemoryInit.BlockID] = block
+		case op.MemoryOp != nil:
+			block, ok := blocks[op.MemoryOp.BlockID]
+			if !ok {
+				err = fmt.Errorf("memory block %d not initialized", op.MemoryOp.BlockID)
+				break
+			}
+			err = c.memoryOp(api, block, op.MemoryOp)
+		}
+		if err != nil {
+			return fmt.Errorf("opcode %d (%s): %w", i, op.Name, err)
+		}
+	}
+	return nil
+}
+
+// witness returns the variable of witness w
+func (c *Circuit) witness(w Witness) frontend.Variable {
+	i := c.index[w]
+	if i < len(c.Public) {
+		return c.Public[i]
+	}
+	return c.Secret[i-len(c.Public)]
+}
+
+// assertZero asserts e = 0, with api.AssertIsBoolean for the k·w·w - k·w Noir writes for
+// the booleans
+func (c *Circuit) assertZero(api frontend.API, e *Expression) {
+	if len(e.MulTerms) == 1 && len(e.LinearCombinations) == 1 && e.QC.Sign() == 0 {
+		m, l := &e.MulTerms[0], &e.LinearCombinations[0]
+		var sum big.Int
+		sum.Add(&m.Coeff.Int, &l.Coeff.Int)
+		if m.Left == m.Right && m.Left == l.Witness && m.Coeff.Sign() != 0 && sum.Cmp(ecc.BN254.ScalarField()) == 0 {
+			api.AssertIsBoolean(c.witness(l.Witness))
+			return
+		}
+	}
+	api.AssertIsEqual(c.expression(api, e), 0)
+}
+
+func (c *Circuit) expression(api frontend.API, e *Expression) frontend.Variable {
+	terms := make([]frontend.Variable, 0, len(e.MulTerms)+len(e.LinearCombinations)+1)
+	for i := range e.MulTerms {
+		t := &e.MulTerms[i]
+		terms = append(terms, api.Mul(&t.Coeff.Int, c.witness(t.Left), c.witness(t.Right)))
+	}
+	for i := range e.LinearCombinations {
+		t := &e.LinearCombinations[i]
+		terms = append(terms, api.Mul(&t.Coeff.Int, c.witness(t.Witness)))
+	}
+	return sum(api, append(terms, &e.QC.Int))
+}
+
+func sum(api frontend.API, terms []frontend.Variable) frontend.Variable {
+	switch len(terms) {
+	case 0:
+		return 0
+	case 1:
+		return terms[0]
+	default:
+		return api.Add(terms[0], terms[1], terms[2:]...)
+	}
+}
+
+// blackBox asserts the black box call, RANGE, AND or XOR
+func (c *Circuit) blackBox(api frontend.API, name string, call *BlackBoxFuncCall) error {
+	bitLen := api.Compiler().Curve().ScalarField().BitLen()
+	if name == "BlackBoxFuncCall RANGE" {
+		v, n := c.witness(call.Input.Witness), int(call.Input.NbBits)
+		switch {
+		case n == 0:
+			api.AssertIsEqual(v, 0)
+		case n < bitLen:
+			bits.ToBinary(api, v, bits.WithNbDigits(n))
+		}
+		return nil
+	}
+
+	// the ACVM takes the number of bits of lhs for both inputs
+	n := int(call.Lhs.NbBits)
+	if n == 0 || n >= bitLen {
+		return fmt.Errorf("inputs of %d bits", n)
+	}
+	lhs := bits.ToBinary(api, c.witness(call.Lhs.Witness), bits.WithNbDigits(n))
+	rhs := bits.ToBinary(api, c.witness(call.Rhs.Witness), bits.WithNbDigits(n))
+	res := make([]frontend.Variable, n)
+	for i := range res {
+		if name == "BlackBoxFuncCall AND" {
+			res[i] = api.And(lhs[i], rhs[i])
+		} else {
+			res[i] = api.Xor(lhs[i], rhs[i])
+		}
+	}
+	api.AssertIsEqual(bits.FromBinary(api, res, bits.WithUnconstrainedInputs()), c.witness(call.Output))
+	return nil
+}
+
+// memoryOp reads or writes block at the index of op. A witness index i selects the
+// element j for which i - j is zero, and i is in the block if exactly one is.
+func (c *Circuit) memoryOp(api frontend.API, block []frontend.Variable, op *MemoryOp) error {
+	if op.Predicate != nil {
+		if predicate, _ := op.Predicate.constant(); predicate.Sign() == 0 {
+			return nil
+		}
+	}
+	operation, _ := op.Op.Operation.constant()
+	if !operation.IsUint64() || operation.Uint64() > 1 {
+		return fmt.Errorf("operation %s", operation)
+	}
+	write := operation.Uint64() == 1
+	value := c.expression(api, &op.Op.Value)
+
+	if index, ok := op.Op.Index.constant(); ok {
+		if !index.IsUint64() || index.Uint64() >= uint64(len(block)) {
+			return fmt.Errorf("index %s out of a block of %d elements", index, len(block))
+		}
+		if write {
+			block[index.Uint64()] = value
+		} else {
+			api.AssertIsEqual(block[index.Uint64()], value)
+		}
+		return nil
+	}
+
+	index := c.expression(api, &op.Op.Index)
+	selectors := make([]frontend.Variable, len(block))
+	for j := range block {
+		selectors[j] = api.IsZero(api.Sub(index, j))
+	}
+	api.AssertIsEqual(sum(api, selectors), 1)
+	if write {
+		for j := range block {
+			block[j] = api.Select(selectors[j], value, block[j])
+		}
+		return nil
+	}
+	read := make([]frontend.Variable, len(block))
+	for j := range block {
+		read[j] = api.Mul(selectors[j], block[j])
+	}
+	api.AssertIsEqual(sum(api, read), value)
+	return nil
+}
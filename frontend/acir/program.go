@@ -0,0 +1,385 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package acir imports the circuits of Noir, compiled to ACIR, the abstract circuit
+// intermediate representation of the ACVM, to the gnark frontend.
+//
+// ReadProgram reads the artifacts nargo compile writes, whose bytecode is the base64 of
+// the gzip of the bincode of the program, in the layout of ACIR of nargo 0.31: bincode
+// numbers the variants of the enums, so that the artifacts of other versions of nargo,
+// whose variants differ, are misread or rejected. It also reads the JSON serialization of
+// the serde types of ACIR, where the opcodes are tagged with the names of their variants,
+// which doesn't depend on the version. Field elements are hexadecimal strings, with or
+// without the 0x prefix, and witnesses their indices. NewCircuit turns the circuit into a
+// gnark circuit, which compiles for any backend on BN254, the field of ACIR, and
+// ReadWitnessMap reads the witnesses solved by the ACVM, from the witness stack nargo
+// execute writes or from JSON, to assign it.
+//
+// The opcodes compiled are the arithmetic expressions, the memory blocks and their
+// reads and writes, and the black box functions RANGE, AND and XOR, on the gadgets of
+// std/math/bits. The Brillig calls and the directives only compute witnesses, and are
+// skipped: the witness map must hold the witnesses they compute. The calls to other ACIR
+// functions aren't supported, and Program.Supported lists them.
+//
+// The black box functions other than RANGE, AND and XOR aren't supported: the hashes
+// (SHA-256, Blake2s, Blake3, Keccak, Poseidon2, Pedersen), the signatures (Schnorr,
+// ECDSA), the operations on the embedded curve and on big integers, AES and the recursive
+// aggregation have no gadget in std. Program.Supported lists them in the JSON
+// serialization, and ReadProgram rejects the bytecode with the first of them, as bincode
+// can't skip a variant without knowing its layout.
+package acir
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+var (
+	// ErrInvalidProgram is wrapped by the errors of ReadProgram and ReadWitnessMap on
+	// malformed files
+	ErrInvalidProgram = errors.New("invalid ACIR program")
+
+	// ErrUnsupported is wrapped by the errors listing the opcodes which can't be compiled
+	ErrUnsupported = errors.New("unsupported ACIR opcodes")
+)
+
+// Witness is the index of a witness of an ACIR circuit
+type Witness uint32
+
+// Program is an ACIR circuit
+type Program struct {
+	CurrentWitnessIndex uint32    `json:"current_witness_index"`
+	Opcodes             []Opcode  `json:"opcodes"`
+	PrivateParameters   []Witness `json:"private_parameters"`
+	PublicParameters    []Witness `json:"public_parameters"`
+	ReturnValues        []Witness `json:"return_values"`
+}
+
+// Opcode is an opcode of an ACIR circuit. Name is the name of its variant, followed by the
+// name of the function for the black box calls, and the field of the variant is set for
+// the opcodes compiled.
+type Opcode struct {
+	Name       string
+	AssertZero *Expression
+	BlackBox   *BlackBoxFuncCall
+	MemoryInit *MemoryInit
+	MemoryOp   *MemoryOp
+}
+
+// Expression is Σ qₘ·wₗ·wᵣ + Σ qₗ·w + q_c
+type Expression struct {
+	MulTerms           []MulTerm    `json:"mul_terms"`
+	LinearCombinations []LinearTerm `json:"linear_combinations"`
+	QC                 Element      `json:"q_c"`
+}
+
+// MulTerm is a term qₘ·wₗ·wᵣ of an expression, serialized as [qₘ, wₗ, wᵣ]
+type MulTerm struct {
+	Coeff       Element
+	Left, Right Witness
+}
+
+// LinearTerm is a term qₗ·w of an expression, serialized as [qₗ, w]
+type LinearTerm struct {
+	Coeff   Element
+	Witness Witness
+}
+
+// FunctionInput is an input of a black box function, of NbBits bits
+type FunctionInput struct {
+	Witness Witness `json:"witness"`
+	NbBits  uint32  `json:"num_bits"`
+}
+
+// BlackBoxFuncCall is a call to RANGE, with Input, or to AND or XOR, with Lhs, Rhs and
+// Output
+type BlackBoxFuncCall struct {
+	Input  FunctionInput `json:"input"`
+	Lhs    FunctionInput `json:"lhs"`
+	Rhs    FunctionInput `json:"rhs"`
+	Output Witness       `json:"output"`
+}
+
+// MemoryInit initializes the memory block BlockID with the witnesses Init
+type MemoryInit struct {
+	BlockID uint32    `json:"block_id"`
+	Init    []Witness `json:"init"`
+}
+
+// MemoryOp reads the value at index in the memory block BlockID, when the operation is
+// 0, or writes it there, when the operation is 1, if the predicate isn't zero
+type MemoryOp struct {
+	BlockID uint32 `json:"block_id"`
+	Op      struct {
+		Operation Expression `json:"operation"`
+		Index     Expression `json:"index"`
+		Value     Expression `json:"value"`
+	} `json:"op"`
+	Predicate *Expression `json:"predicate"`
+}
+
+// Element is an element of the field of BN254, serialized in hexadecimal
+type Element struct {
+	big.Int
+}
+
+// ReadProgram reads the JSON serialization of an ACIR circuit. The programs of a single
+// function, {"functions": [circuit]}, are read as their circuit.
+func ReadProgram(r io.Reader) (*Program, error) {
+	var p struct {
+		Program
+		Functions []Program `json:"functions"`
+		Bytecode  string    `json:"bytecode"`
+	}
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProgram, err)
+	}
+	res := &p.Program
+	switch {
+	case p.Bytecode != "":
+		var err error
+		if res, err = readBytecode(p.Bytecode); err != nil {
+			if errors.Is(err, ErrUnsupported) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: bytecode: %v", ErrInvalidProgram, err)
+		}
+	case len(p.Functions) == 1:
+		res = &p.Functions[0]
+	case len(p.Functions) > 1:
+		return nil, fmt.Errorf("%w: programs of %d functions", ErrUnsupported, len(p.Functions))
+	}
+	for _, w := range res.witnesses() {
+		if uint32(w) > res.CurrentWitnessIndex {
+			return nil, fmt.Errorf("%w: witness %d above the current witness index %d", ErrInvalidProgram, w, res.CurrentWitnessIndex)
+		}
+	}
+	return res, nil
+}
+
+// ReadWitnessMap reads the JSON serialization of a witness map of the ACVM, an object
+// from the indices of the witnesses, in decimal, to their values, or the witness map of
+// the main function in the gzipped witness stack nargo execute writes
+func ReadWitnessMap(r io.Reader) (map[Witness]*big.Int, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		res, err := readWitnessStack(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: witness stack: %v", ErrInvalidProgram, err)
+		}
+		return res, nil
+	}
+	var m map[Witness]Element
+	if err := json.NewDecoder(br).Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProgram, err)
+	}
+	res := make(map[Witness]*big.Int, len(m))
+	for w, v := range m {
+		res[w] = new(big.Int).Set(&v.Int)
+	}
+	return res, nil
+}
+
+// Supported returns an error wrapping ErrUnsupported which lists the opcodes of p which
+// can't be compiled, if any
+func (p *Program) Supported() error {
+	var unsupported []string
+	for i := range p.Opcodes {
+		if reason := p.Opcodes[i].unsupported(); reason != "" {
+			unsupported = append(unsupported, fmt.Sprintf("opcode %d (%s)", i, reason))
+		}
+	}
+	if len(unsupported) != 0 {
+		return fmt.Errorf("%w: %s", ErrUnsupported, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// unsupported describes op if it can't be compiled
+func (op *Opcode) unsupported() string {
+	switch {
+	case op.AssertZero != nil, op.BlackBox != nil, op.MemoryInit != nil:
+		return ""
+	case op.MemoryOp != nil:
+		if _, ok := op.MemoryOp.Op.Operation.constant(); !ok {
+			return op.Name + " with a witness operation"
+		}
+		if op.MemoryOp.Predicate != nil {
+			if _, ok := op.MemoryOp.Predicate.constant(); !ok {
+				return op.Name + " with a witness predicate"
+			}
+		}
+		return ""
+	case op.Name == "Brillig", op.Name == "BrilligCall", op.Name == "Directive":
+		return ""
+	}
+	return op.Name
+}
+
+// witnesses returns the sorted witnesses the opcodes compiled and the parameters refer to
+func (p *Program) witnesses() []Witness {
+	set := make(map[Witness]bool)
+	for _, ws := range [][]Witness{p.PrivateParameters, p.PublicParameters, p.ReturnValues} {
+		for _, w := range ws {
+			set[w] = true
+		}
+	}
+	expression := func(e *Expression) {
+		for _, t := range e.MulTerms {
+			set[t.Left], set[t.Right] = true, true
+		}
+		for _, t := range e.LinearCombinations {
+			set[t.Witness] = true
+		}
+	}
+	for _, op := range p.Opcodes {
+		switch {
+		case op.AssertZero != nil:
+			expression(op.AssertZero)
+		case op.BlackBox != nil && op.Name == "BlackBoxFuncCall RANGE":
+			set[op.BlackBox.Input.Witness] = true
+		case op.BlackBox != nil:
+			set[op.BlackBox.Lhs.Witness], set[op.BlackBox.Rhs.Witness], set[op.BlackBox.Output] = true, true, true
+		case op.MemoryInit != nil:
+			for _, w := range op.MemoryInit.Init {
+				set[w] = true
+			}
+		case op.MemoryOp != nil:
+			expression(&op.MemoryOp.Op.Operation)
+			expression(&op.MemoryOp.Op.Index)
+			expression(&op.MemoryOp.Op.Value)
+			if op.MemoryOp.Predicate != nil {
+				expression(op.MemoryOp.Predicate)
+			}
+		}
+	}
+	res := make([]Witness, 0, len(set))
+	for w := range set {
+		res = append(res, w)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+// constant returns q_c if e has no term
+func (e *Expression) constant() (*big.Int, bool) {
+	if len(e.MulTerms) != 0 || len(e.LinearCombinations) != 0 {
+		return nil, false
+	}
+	return &e.QC.Int, true
+}
+
+// UnmarshalJSON reads the opcode from its variant, tagged with its name
+func (op *Opcode) UnmarshalJSON(data []byte) error {
+	// the variants without field are serialized as their name
+	if err := json.Unmarshal(data, &op.Name); err == nil {
+		return nil
+	}
+	name, content, err := variant(data)
+	if err != nil {
+		return err
+	}
+	op.Name = name
+	switch name {
+	case "AssertZero", "Arithmetic":
+		op.AssertZero = new(Expression)
+		return json.Unmarshal(content, op.AssertZero)
+	case "BlackBoxFuncCall":
+		function, content, err := variant(content)
+		if err != nil {
+			return err
+		}
+		op.Name += " " + function
+		switch function {
+		case "RANGE", "AND", "XOR":
+			op.BlackBox = new(BlackBoxFuncCall)
+			return json.Unmarshal(content, op.BlackBox)
+		}
+		return nil
+	case "MemoryInit":
+		op.MemoryInit = new(MemoryInit)
+		return json.Unmarshal(content, op.MemoryInit)
+	case "MemoryOp":
+		op.MemoryOp = new(MemoryOp)
+		return json.Unmarshal(content, op.MemoryOp)
+	}
+	return nil
+}
+
+// variant returns the name and the content of the enum variant {name: content}
+func variant(data []byte) (string, json.RawMessage, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", nil, err
+	}
+	if len(m) != 1 {
+		return "", nil, fmt.Errorf("variant with %d tags", len(m))
+	}
+	for name, content := range m {
+		return name, content, nil
+	}
+	panic("unreachable")
+}
+
+// UnmarshalJSON reads the term from [qₘ, wₗ, wᵣ]
+func (t *MulTerm) UnmarshalJSON(data []byte) error {
+	return unmarshalTuple(data, &t.Coeff, &t.Left, &t.Right)
+}
+
+// UnmarshalJSON reads the term from [qₗ, w]
+func (t *LinearTerm) UnmarshalJSON(data []byte) error {
+	return unmarshalTuple(data, &t.Coeff, &t.Witness)
+}
+
+func unmarshalTuple(data []byte, fields ...interface{}) error {
+	var tuple []json.RawMessage
+	if err := json.Unmarshal(data, &tuple); err != nil {
+		return err
+	}
+	if len(tuple) != len(fields) {
+		return fmt.Errorf("%d fields in a tuple of %d", len(tuple), len(fields))
+	}
+	for i := range tuple {
+		if err := json.Unmarshal(tuple[i], fields[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON reads the element from its hexadecimal string
+func (e *Element) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return e.setString(s)
+}
+
+// setString sets e to the element of hexadecimal string s, with or without the 0x prefix
+func (e *Element) setString(s string) error {
+	s = strings.TrimPrefix(s, "0x")
+	if _, ok := e.SetString(s, 16); !ok || e.Sign() < 0 || e.Cmp(ecc.BN254.ScalarField()) >= 0 {
+		return fmt.Errorf("%q isn't an element of the field", s)
+	}
+	return nil
+}
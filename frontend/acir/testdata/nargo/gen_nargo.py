@@ -0,0 +1,57 @@
+# Writes target/arithmetic.json and target/arithmetic.gz, the artifact and the witness
+# stack nargo 0.31 writes for src/main.nr, hand-encoded in the bincode of ACIR: see
+# README.md
+import base64, gzip, json, os, struct
+
+here = os.path.dirname(os.path.abspath(__file__))
+r = 21888242871839275222246405745257275088548364400416034343698204186575808495617
+
+u8 = lambda x: struct.pack('<B', x)
+u32 = lambda x: struct.pack('<I', x)
+u64 = lambda x: struct.pack('<Q', x)
+def seq(items): return u64(len(items)) + b''.join(items)
+def field(x): s = '%064x' % (x % r); return u64(len(s)) + s.encode()
+def expr(mul, lin, qc):
+    return seq([field(q) + u32(a) + u32(b) for q, a, b in mul]) + seq([field(q) + u32(w) for q, w in lin]) + field(qc)
+def some(x): return u8(1) + x
+none = u8(0)
+
+# witnesses: x = 1, y = 2, b = 3, 1/y = 4, the return value = 5
+def range_(w, bits): return u32(1) + u32(3) + u32(w) + u32(bits)
+opcodes = [
+    range_(1, 8),
+    range_(3, 1),
+    # BrilligCall {id: 0, inputs: [Single(y)], outputs: [Simple(1/y)], predicate: Some(1)}
+    u32(5) + u32(0) + seq([u32(0) + expr([], [(1, 2)], 0)]) + seq([u32(0) + u32(4)]) + some(expr([], [], 1)),
+    # y·(1/y) - 1 = 0
+    u32(0) + expr([(1, 2, 4)], [], -1),
+    # x·y + 2·b - z + 3 = 0
+    u32(0) + expr([(1, 1, 2)], [(2, 3), (-1, 5)], 3),
+]
+circuit = u32(5) + seq(opcodes) + u32(1) + u64(4) + seq([u32(1), u32(3)]) + seq([u32(2)]) + seq([u32(5)]) \
+    + seq([]) + u8(0)  # no assert message, not recursive
+# the Brillig function computing 1/y is left out
+program = seq([circuit]) + seq([])
+
+artifact = {
+    "noir_version": "0.31.0",
+    "abi": {
+        "parameters": [
+            {"name": "x", "type": {"kind": "integer", "sign": "unsigned", "width": 8}, "visibility": "private"},
+            {"name": "y", "type": {"kind": "field"}, "visibility": "public"},
+            {"name": "b", "type": {"kind": "boolean"}, "visibility": "private"},
+        ],
+        "return_type": {"abi_type": {"kind": "field"}, "visibility": "public"},
+        "error_types": {},
+    },
+    "bytecode": base64.b64encode(gzip.compress(program, mtime=0)).decode(),
+    "names": ["main"],
+}
+with open(os.path.join(here, 'target', 'arithmetic.json'), 'w') as f:
+    json.dump(artifact, f)
+
+# x = 5, y = 7, b = true
+values = {1: 5, 2: 7, 3: 1, 4: pow(7, r - 2, r), 5: 5 * 7 + 2 + 3}
+stack = seq([u32(0) + seq([u32(w) + field(v) for w, v in sorted(values.items())])])
+with open(os.path.join(here, 'target', 'arithmetic.gz'), 'wb') as f:
+    f.write(gzip.compress(stack, mtime=0))
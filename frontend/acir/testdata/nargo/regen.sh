@@ -0,0 +1,11 @@
+#!/bin/sh
+# Replaces the artifact and the witness stack in target with the ones of nargo 0.31.x
+# for src/main.nr and Prover.toml, and removes gen_nargo.py.
+set -eu
+cd "$(dirname "$0")"
+
+nargo --version
+nargo compile
+nargo execute
+
+rm -f gen_nargo.py
@@ -60,6 +60,20 @@ type Compiler interface {
 	Backend() backend.ID
 }
 
+// Lookuper is implemented by the compilers supporting table lookups, it can be
+// reached from a circuit with api.Compiler().(frontend.Lookuper).
+//
+// The table is fixed when the circuit is compiled and committed by the backend at
+// Setup. Backends without a lookup argument refuse circuits declaring lookups.
+type Lookuper interface {
+	// SetLookupTable sets the entries of the table. The entries must be constants and
+	// the table can be set only once, before the first call to Lookup.
+	SetLookupTable(entries ...Variable)
+
+	// Lookup constrains v to be one of the entries of the table.
+	Lookup(v Variable)
+}
+
 // Builder represents a constraint system builder
 type Builder interface {
 	API
@@ -48,4 +48,13 @@ type CompiledConstraintSystem interface {
 
 	// GetConstraints return a human readable representation of the constraints
 	GetConstraints() [][]string
+
+	// WriteJSON and ReadJSON write and read the constraint system in the portable JSON
+	// layout documented in frontend/compiled, for the tools other than gnark
+	WriteJSON(w io.Writer) error
+	ReadJSON(r io.Reader) error
+
+	// Fingerprint returns the canonical hash of the constraints, coefficients, variable
+	// counts and schema, which the verifying keys record
+	Fingerprint() compiled.Fingerprint
 }
@@ -0,0 +1,165 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark"
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// readSections splits a file of the binary formats of circom, .r1cs and .wtns, into
+// its sections by type, checking its magic and version
+func readSections(data []byte, magic string, versions ...uint32) (map[uint32][]byte, error) {
+	d := &decoder{buf: data}
+	if m := d.bytes(4); d.err == nil && !bytes.Equal(m, []byte(magic)) {
+		return nil, fmt.Errorf("not a %s file", magic)
+	}
+	version := d.uint32()
+	supported := false
+	for _, v := range versions {
+		supported = supported || v == version
+	}
+	if d.err == nil && !supported {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+
+	// the sections may come in any order
+	nbSections := d.uint32()
+	sections := make(map[uint32][]byte)
+	for i := uint32(0); i < nbSections && d.err == nil; i++ {
+		typ, size := d.uint32(), d.uint64()
+		content := d.bytes(size)
+		if d.err != nil {
+			break
+		}
+		if _, ok := sections[typ]; ok {
+			return nil, fmt.Errorf("duplicated section %d", typ)
+		}
+		sections[typ] = content
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("sections: %w", d.err)
+	}
+	return sections, nil
+}
+
+// curveOf returns the curve gnark supports whose scalar field has modulus prime
+func curveOf(prime *big.Int) (ecc.ID, error) {
+	for _, curve := range gnark.Curves() {
+		if curve.ScalarField().Cmp(prime) == 0 {
+			return curve, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("the field of prime %s isn't the scalar field of a supported curve", prime)
+}
+
+// decoder reads little-endian values from buf. The first error sticks, and the reads
+// which follow return zero values.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.buf)) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	res := d.buf[:n]
+	d.buf = d.buf[n:]
+	return res
+}
+
+func (d *decoder) uint32() uint32 {
+	b := d.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (d *decoder) uint64() uint64 {
+	b := d.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// element reads a field element of n8 bytes into res, allocated if nil, and checks
+// it is lower than prime unless prime is nil
+func (d *decoder) element(n8 uint32, prime *big.Int, res *big.Int) *big.Int {
+	if res == nil {
+		res = new(big.Int)
+	}
+	b := d.bytes(uint64(n8))
+	if b == nil {
+		return res
+	}
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	res.SetBytes(be)
+	if prime != nil && res.Cmp(prime) >= 0 {
+		d.err = fmt.Errorf("field element %s out of the field", res)
+	}
+	return res
+}
+
+// field reads the size in bytes of the field elements, a multiple of 8, and the modulus
+// of the field
+func (d *decoder) field() (uint32, *big.Int) {
+	n8 := d.uint32()
+	if d.err == nil && (n8 == 0 || n8%8 != 0) {
+		d.err = fmt.Errorf("invalid field element size %d", n8)
+	}
+	return n8, d.element(n8, nil, nil)
+}
+
+// string reads a NUL-terminated string
+func (d *decoder) string() string {
+	if d.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(d.buf, 0)
+	if i < 0 {
+		d.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	res := string(d.buf[:i])
+	d.buf = d.buf[i+1:]
+	return res
+}
+
+// end returns the error of the decoder, or an error if bytes are left
+func (d *decoder) end() error {
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.buf) != 0 {
+		return fmt.Errorf("%d trailing bytes", len(d.buf))
+	}
+	return nil
+}
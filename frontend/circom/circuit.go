@@ -0,0 +1,114 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circom
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// Circuit is the circuit of a circom constraint system. Public holds the wires of the
+// public outputs then of the public inputs, Secret the wires which follow, wire 0, the
+// constant 1, apart.
+type Circuit struct {
+	Public []frontend.Variable `gnark:",public"`
+	Secret []frontend.Variable `gnark:",secret"`
+
+	r1cs *R1CS
+}
+
+// NewCircuit returns the circuit of r1cs, with its variables allocated. It serves both
+// to compile r1cs and to assign a witness.
+func NewCircuit(r1cs *R1CS) *Circuit {
+	nbPublic := r1cs.NbPublic()
+	return &Circuit{
+		Public: make([]frontend.Variable, nbPublic),
+		Secret: make([]frontend.Variable, int(r1cs.NbWires)-1-nbPublic),
+		r1cs:   r1cs,
+	}
+}
+
+// Assign assigns the values of the wires, as ReadWtns returns them, to the variables of
+// the circuit
+func (c *Circuit) Assign(values []big.Int) error {
+	if len(values) != 1+len(c.Public)+len(c.Secret) {
+		return fmt.Errorf("%d values for %d wires", len(values), 1+len(c.Public)+len(c.Secret))
+	}
+	for i := range c.Public {
+		c.Public[i] = values[1+i]
+	}
+	for i := range c.Secret {
+		c.Secret[i] = values[1+len(c.Public)+i]
+	}
+	return nil
+}
+
+// Compile compiles r1cs on its curve with newBuilder. Circom constraint systems may
+// leave wires unconstrained, so that the option frontend.IgnoreUnconstrainedInputs is
+// added to opts.
+func Compile(r1cs *R1CS, newBuilder frontend.NewBuilder, opts ...frontend.CompileOption) (frontend.CompiledConstraintSystem, error) {
+	opts = append(opts, frontend.IgnoreUnconstrainedInputs())
+	return frontend.Compile(r1cs.Curve, newBuilder, NewCircuit(r1cs), opts...)
+}
+
+// Define asserts the constraints of the circom constraint system
+func (c *Circuit) Define(api frontend.API) error {
+	if c.r1cs == nil {
+		return errors.New("the circuit of a circom constraint system must come from NewCircuit")
+	}
+	if len(c.r1cs.CustomGateUses) != 0 {
+		return errors.New("the custom gates of circom aren't supported")
+	}
+	for _, r1c := range c.r1cs.Constraints {
+		a := c.linearCombination(api, r1c.A)
+		b := c.linearCombination(api, r1c.B)
+		api.AssertIsEqual(api.Mul(a, b), c.linearCombination(api, r1c.C))
+	}
+	return nil
+}
+
+// wire returns the variable of wire i
+func (c *Circuit) wire(i uint32) frontend.Variable {
+	switch {
+	case i == 0:
+		return 1
+	case int(i) <= len(c.Public):
+		return c.Public[i-1]
+	default:
+		return c.Secret[int(i)-1-len(c.Public)]
+	}
+}
+
+func (c *Circuit) linearCombination(api frontend.API, lc LinearCombination) frontend.Variable {
+	terms := make([]frontend.Variable, len(lc))
+	for i := range lc {
+		if lc[i].Wire == 0 {
+			terms[i] = &lc[i].Coeff
+		} else {
+			terms[i] = api.Mul(&lc[i].Coeff, c.wire(lc[i].Wire))
+		}
+	}
+	switch len(terms) {
+	case 0:
+		return 0
+	case 1:
+		return terms[0]
+	default:
+		return api.Add(terms[0], terms[1], terms[2:]...)
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package circom imports the constraint systems compiled by circom.
+//
+// ReadR1CS parses the .r1cs binary format
+// (https://github.com/iden3/r1csfile/blob/master/doc/r1cs_bin_format.md), and NewCircuit
+// turns the constraint system into a circuit the gnark frontend compiles for any
+// backend. ReadZKey reads the Groth16 proving keys snarkjs sets up for them on BN254.
+// The KZG SRS of the powers of tau files of snarkjs are read by backend/ptau.
+//
+// The wires of circom are numbered as follows: wire 0 is the constant 1, then come
+// the public outputs, the public inputs, the private inputs and the internal signals.
+// The public outputs and inputs are the public variables of the circuit, in this
+// order, and the other wires its secret variables.
+package circom
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// section types of the .r1cs format
+const (
+	sectionHeader         = 1
+	sectionConstraints    = 2
+	sectionWireToLabel    = 3
+	sectionCustomGates    = 4
+	sectionCustomGateUses = 5
+)
+
+// ErrInvalidR1CS is wrapped by the errors of ReadR1CS on malformed files
+var ErrInvalidR1CS = errors.New("invalid circom r1cs")
+
+// R1CS is a constraint system compiled by circom
+type R1CS struct {
+	// Curve is the curve whose scalar field is the field of the constraint system
+	Curve ecc.ID
+
+	// NbWires counts the wires, the constant wire 0 included
+	NbWires uint32
+
+	NbPublicOutputs, NbPublicInputs, NbPrivateInputs uint32
+
+	// NbLabels counts the signals of the circuit before circom optimized some away
+	NbLabels uint64
+
+	// Constraints are the constraints A·B = C
+	Constraints []Constraint
+
+	// WireToLabel maps the wires to the signals of the circuit, empty if the file
+	// omits it
+	WireToLabel []uint64
+
+	// CustomGates and CustomGateUses are the custom gates of circom's PLONK
+	// extension, empty for plain R1CS
+	CustomGates    []CustomGate
+	CustomGateUses []CustomGateUse
+}
+
+// Term is the product of a coefficient and a wire
+type Term struct {
+	Wire  uint32
+	Coeff big.Int
+}
+
+// LinearCombination is the sum of its terms
+type LinearCombination []Term
+
+// Constraint is the constraint A·B = C
+type Constraint struct {
+	A, B, C LinearCombination
+}
+
+// CustomGate is a custom gate template and its parameters
+type CustomGate struct {
+	Template   string
+	Parameters []big.Int
+}
+
+// CustomGateUse applies custom gate Gate to signals
+type CustomGateUse struct {
+	Gate    uint32
+	Signals []uint64
+}
+
+// NbPublic returns the number of public variables, outputs and inputs
+func (r *R1CS) NbPublic() int {
+	return int(r.NbPublicOutputs) + int(r.NbPublicInputs)
+}
+
+// ReadR1CS reads a .r1cs file. The field of the file must be the scalar field of one
+// of the curves gnark supports.
+func ReadR1CS(r io.Reader) (*R1CS, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := readSections(data, "r1cs", 1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidR1CS, err)
+	}
+	for _, typ := range []uint32{sectionHeader, sectionConstraints} {
+		if _, ok := sections[typ]; !ok {
+			return nil, fmt.Errorf("%w: missing section %d", ErrInvalidR1CS, typ)
+		}
+	}
+
+	res := new(R1CS)
+	h, err := res.readHeader(sections[sectionHeader])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidR1CS, err)
+	}
+	if err := res.readConstraints(sections[sectionConstraints], h); err != nil {
+		return nil, fmt.Errorf("%w: constraints: %v", ErrInvalidR1CS, err)
+	}
+	if s, ok := sections[sectionWireToLabel]; ok {
+		if err := res.readWireToLabel(s); err != nil {
+			return nil, fmt.Errorf("%w: wire to label map: %v", ErrInvalidR1CS, err)
+		}
+	}
+	if s, ok := sections[sectionCustomGates]; ok {
+		if err := res.readCustomGates(s, h); err != nil {
+			return nil, fmt.Errorf("%w: custom gates: %v", ErrInvalidR1CS, err)
+		}
+	}
+	if s, ok := sections[sectionCustomGateUses]; ok {
+		if err := res.readCustomGateUses(s); err != nil {
+			return nil, fmt.Errorf("%w: custom gate uses: %v", ErrInvalidR1CS, err)
+		}
+	}
+	return res, nil
+}
+
+// header holds what the sections after the header need
+type header struct {
+	n8            uint32 // size of the field elements in bytes
+	prime         *big.Int
+	nbConstraints uint32
+}
+
+func (r *R1CS) readHeader(s []byte) (header, error) {
+	d := &decoder{buf: s}
+	var h header
+	h.n8, h.prime = d.field()
+	r.NbWires = d.uint32()
+	r.NbPublicOutputs = d.uint32()
+	r.NbPublicInputs = d.uint32()
+	r.NbPrivateInputs = d.uint32()
+	r.NbLabels = d.uint64()
+	h.nbConstraints = d.uint32()
+	err := d.end()
+	if err != nil {
+		return h, err
+	}
+
+	if r.Curve, err = curveOf(h.prime); err != nil {
+		return h, err
+	}
+	if r.NbWires == 0 {
+		return h, errors.New("no constant wire")
+	}
+	if uint64(r.NbPublicOutputs)+uint64(r.NbPublicInputs)+uint64(r.NbPrivateInputs) >= uint64(r.NbWires) {
+		return h, fmt.Errorf("%d outputs and inputs for %d wires", uint64(r.NbPublicOutputs)+uint64(r.NbPublicInputs)+uint64(r.NbPrivateInputs), r.NbWires)
+	}
+	return h, nil
+}
+
+func (r *R1CS) readConstraints(s []byte, h header) error {
+	d := &decoder{buf: s}
+	// each constraint takes 12 bytes at least, which bounds the allocation
+	if uint64(h.nbConstraints)*12 > uint64(len(s)) {
+		return fmt.Errorf("%d constraints in %d bytes", h.nbConstraints, len(s))
+	}
+	r.Constraints = make([]Constraint, h.nbConstraints)
+	for i := range r.Constraints {
+		c := &r.Constraints[i]
+		for _, lc := range []*LinearCombination{&c.A, &c.B, &c.C} {
+			*lc = r.readLinearCombination(d, h)
+		}
+		if d.err != nil {
+			return fmt.Errorf("constraint %d: %w", i, d.err)
+		}
+	}
+	return d.end()
+}
+
+func (r *R1CS) readLinearCombination(d *decoder, h header) LinearCombination {
+	nbTerms := d.uint32()
+	if d.err != nil {
+		return nil
+	}
+	if uint64(nbTerms)*(4+uint64(h.n8)) > uint64(len(d.buf)) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	res := make(LinearCombination, nbTerms)
+	for i := range res {
+		res[i].Wire = d.uint32()
+		if d.err == nil && res[i].Wire >= r.NbWires {
+			d.err = fmt.Errorf("wire %d out of %d", res[i].Wire, r.NbWires)
+		}
+		d.element(h.n8, h.prime, &res[i].Coeff)
+	}
+	return res
+}
+
+func (r *R1CS) readWireToLabel(s []byte) error {
+	if uint64(len(s)) != 8*uint64(r.NbWires) {
+		return fmt.Errorf("%d bytes for %d wires", len(s), r.NbWires)
+	}
+	d := &decoder{buf: s}
+	r.WireToLabel = make([]uint64, r.NbWires)
+	for i := range r.WireToLabel {
+		r.WireToLabel[i] = d.uint64()
+		if d.err == nil && r.WireToLabel[i] >= r.NbLabels {
+			return fmt.Errorf("wire %d: label %d out of %d", i, r.WireToLabel[i], r.NbLabels)
+		}
+	}
+	return d.end()
+}
+
+func (r *R1CS) readCustomGates(s []byte, h header) error {
+	d := &decoder{buf: s}
+	nbGates := d.uint32()
+	// each gate takes 5 bytes at least
+	if uint64(nbGates)*5 > uint64(len(s)) {
+		return fmt.Errorf("%d custom gates in %d bytes", nbGates, len(s))
+	}
+	r.CustomGates = make([]CustomGate, nbGates)
+	for i := range r.CustomGates {
+		g := &r.CustomGates[i]
+		g.Template = d.string()
+		nbParameters := d.uint32()
+		if d.err == nil && uint64(nbParameters)*uint64(h.n8) > uint64(len(d.buf)) {
+			d.err = io.ErrUnexpectedEOF
+		}
+		if d.err != nil {
+			return fmt.Errorf("custom gate %d: %w", i, d.err)
+		}
+		g.Parameters = make([]big.Int, nbParameters)
+		for j := range g.Parameters {
+			d.element(h.n8, h.prime, &g.Parameters[j])
+		}
+		if d.err != nil {
+			return fmt.Errorf("custom gate %d: %w", i, d.err)
+		}
+	}
+	return d.end()
+}
+
+func (r *R1CS) readCustomGateUses(s []byte) error {
+	d := &decoder{buf: s}
+	nbUses := d.uint32()
+	// each use takes 8 bytes at least
+	if uint64(nbUses)*8 > uint64(len(s)) {
+		return fmt.Errorf("%d custom gate uses in %d bytes", nbUses, len(s))
+	}
+	r.CustomGateUses = make([]CustomGateUse, nbUses)
+	for i := range r.CustomGateUses {
+		u := &r.CustomGateUses[i]
+		u.Gate = d.uint32()
+		if d.err == nil && int(u.Gate) >= len(r.CustomGates) {
+			d.err = fmt.Errorf("custom gate %d out of %d", u.Gate, len(r.CustomGates))
+		}
+		nbSignals := d.uint32()
+		if d.err == nil && uint64(nbSignals)*8 > uint64(len(d.buf)) {
+			d.err = io.ErrUnexpectedEOF
+		}
+		if d.err != nil {
+			return fmt.Errorf("custom gate use %d: %w", i, d.err)
+		}
+		u.Signals = make([]uint64, nbSignals)
+		for j := range u.Signals {
+			u.Signals[j] = d.uint64()
+			if d.err == nil && u.Signals[j] >= r.NbLabels {
+				d.err = fmt.Errorf("signal %d out of %d", u.Signals[j], r.NbLabels)
+			}
+		}
+		if d.err != nil {
+			return fmt.Errorf("custom gate use %d: %w", i, d.err)
+		}
+	}
+	return d.end()
+}
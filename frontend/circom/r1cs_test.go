@@ -0,0 +1,280 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/consensys/gnark"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/test"
+)
+
+// section is a section of an .r1cs file
+type section struct {
+	typ     uint32
+	content []byte
+}
+
+// encode returns the .r1cs file of sections
+func encode(sections ...section) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("r1cs")
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(sections)))
+	for _, s := range sections {
+		binary.Write(&buf, binary.LittleEndian, s.typ)
+		binary.Write(&buf, binary.LittleEndian, uint64(len(s.content)))
+		buf.Write(s.content)
+	}
+	return buf.Bytes()
+}
+
+// element returns the little-endian encoding of x on n8 bytes
+func element(x *big.Int, n8 int) []byte {
+	res := make([]byte, n8)
+	b := x.Bytes()
+	for i := range b {
+		res[i] = b[len(b)-1-i]
+	}
+	return res
+}
+
+// sections returns the sections of r, its field elements taking n8 bytes
+func (r *R1CS) sections(n8 int) []section {
+	prime := r.Curve.ScalarField()
+	le := binary.LittleEndian
+
+	var h bytes.Buffer
+	binary.Write(&h, le, uint32(n8))
+	h.Write(element(prime, n8))
+	for _, v := range []uint32{r.NbWires, r.NbPublicOutputs, r.NbPublicInputs, r.NbPrivateInputs} {
+		binary.Write(&h, le, v)
+	}
+	binary.Write(&h, le, r.NbLabels)
+	binary.Write(&h, le, uint32(len(r.Constraints)))
+
+	var c bytes.Buffer
+	for _, r1c := range r.Constraints {
+		for _, lc := range []LinearCombination{r1c.A, r1c.B, r1c.C} {
+			binary.Write(&c, le, uint32(len(lc)))
+			for i := range lc {
+				binary.Write(&c, le, lc[i].Wire)
+				c.Write(element(&lc[i].Coeff, n8))
+			}
+		}
+	}
+
+	var w bytes.Buffer
+	for _, label := range r.WireToLabel {
+		binary.Write(&w, le, label)
+	}
+
+	res := []section{{sectionHeader, h.Bytes()}, {sectionConstraints, c.Bytes()}, {sectionWireToLabel, w.Bytes()}}
+	if len(r.CustomGates) == 0 {
+		return res
+	}
+
+	var g bytes.Buffer
+	binary.Write(&g, le, uint32(len(r.CustomGates)))
+	for _, gate := range r.CustomGates {
+		g.WriteString(gate.Template)
+		g.WriteByte(0)
+		binary.Write(&g, le, uint32(len(gate.Parameters)))
+		for i := range gate.Parameters {
+			g.Write(element(&gate.Parameters[i], n8))
+		}
+	}
+	var u bytes.Buffer
+	binary.Write(&u, le, uint32(len(r.CustomGateUses)))
+	for _, use := range r.CustomGateUses {
+		binary.Write(&u, le, use.Gate)
+		binary.Write(&u, le, uint32(len(use.Signals)))
+		for _, s := range use.Signals {
+			binary.Write(&u, le, s)
+		}
+	}
+	return append(res, section{sectionCustomGates, g.Bytes()}, section{sectionCustomGateUses, u.Bytes()})
+}
+
+func term(wire uint32, coeff int64) Term {
+	var res Term
+	res.Wire = wire
+	res.Coeff.SetInt64(coeff)
+	return res
+}
+
+// cube is the constraint system of out = a·b³ + 5, a being a public input and b a
+// private one:
+//
+//	wire 0: one, 1: out, 2: a, 3: b, 4: b², 5: b³
+func cube(curve ecc.ID) *R1CS {
+	return &R1CS{
+		Curve:           curve,
+		NbWires:         6,
+		NbPublicOutputs: 1,
+		NbPublicInputs:  1,
+		NbPrivateInputs: 1,
+		NbLabels:        7,
+		Constraints: []Constraint{
+			{A: LinearCombination{term(3, 1)}, B: LinearCombination{term(3, 1)}, C: LinearCombination{term(4, 1)}},
+			{A: LinearCombination{term(4, 1)}, B: LinearCombination{term(3, 1)}, C: LinearCombination{term(5, 1)}},
+			{A: LinearCombination{term(2, 1)}, B: LinearCombination{term(5, 1)}, C: LinearCombination{term(1, 1), term(0, -5)}},
+		},
+		WireToLabel: []uint64{0, 1, 2, 3, 4, 6},
+	}
+}
+
+// reduce returns r with its coefficients reduced modulo the field
+func (r *R1CS) reduce() *R1CS {
+	q := r.Curve.ScalarField()
+	for i := range r.Constraints {
+		for _, lc := range []LinearCombination{r.Constraints[i].A, r.Constraints[i].B, r.Constraints[i].C} {
+			for j := range lc {
+				lc[j].Coeff.Mod(&lc[j].Coeff, q)
+			}
+		}
+	}
+	return r
+}
+
+func TestReadR1CS(t *testing.T) {
+	for _, curve := range gnark.Curves() {
+		want := cube(curve).reduce()
+		n8 := (curve.ScalarField().BitLen() + 63) / 64 * 8
+		got, err := ReadR1CS(bytes.NewReader(encode(want.sections(n8)...)))
+		if err != nil {
+			t.Fatalf("%s: %v", curve, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: the constraint system read isn't the one written", curve)
+		}
+	}
+}
+
+func TestCircuit(t *testing.T) {
+	cs := cube(ecc.BN254).reduce()
+
+	// out = 3·2³ + 5
+	assignment := NewCircuit(cs)
+	assignment.Public[0], assignment.Public[1] = 29, 3
+	assignment.Secret[0], assignment.Secret[1], assignment.Secret[2] = 2, 4, 8
+	if err := test.IsSolved(NewCircuit(cs), assignment, ecc.BN254, backend.PLONK); err != nil {
+		t.Fatal(err)
+	}
+	assignment.Public[0] = 30
+	if err := test.IsSolved(NewCircuit(cs), assignment, ecc.BN254, backend.PLONK); err == nil {
+		t.Fatal("expected an error")
+	}
+	assignment.Public[0] = 29
+
+	for _, newBuilder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := Compile(cs, newBuilder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nbPublic := ccs.GetSchema().NbPublic; nbPublic != 2 {
+			t.Fatalf("%d public variables, expected 2", nbPublic)
+		}
+		witness, err := frontend.NewWitness(assignment, ecc.BN254)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ccs.IsSolved(witness); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// custom gates are parsed but can't be compiled
+	cs.CustomGates = []CustomGate{{Template: "CMul", Parameters: []big.Int{*big.NewInt(3)}}}
+	cs.CustomGateUses = []CustomGateUse{{Gate: 0, Signals: []uint64{2, 3, 4}}}
+	got, err := ReadR1CS(bytes.NewReader(encode(cs.sections(32)...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cs) {
+		t.Fatal("the custom gates read aren't the ones written")
+	}
+	if _, err := Compile(got, scs.NewBuilder); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestReadR1CSMalformed(t *testing.T) {
+	cs := cube(ecc.BN254).reduce()
+	valid := encode(cs.sections(32)...)
+
+	// every truncation fails
+	for n := 0; n < len(valid); n++ {
+		if _, err := ReadR1CS(bytes.NewReader(valid[:n])); !errors.Is(err, ErrInvalidR1CS) {
+			t.Fatalf("truncated at %d bytes: %v", n, err)
+		}
+	}
+
+	corrupt := func(name string, f func(r *R1CS) []section) {
+		t.Helper()
+		r := cube(ecc.BN254).reduce()
+		if _, err := ReadR1CS(bytes.NewReader(encode(f(r)...))); !errors.Is(err, ErrInvalidR1CS) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	corrupt("unknown prime", func(r *R1CS) []section {
+		s := r.sections(32)
+		copy(s[0].content[4:], element(big.NewInt(101), 32))
+		return s
+	})
+	corrupt("wire out of range", func(r *R1CS) []section {
+		r.Constraints[0].A[0].Wire = r.NbWires
+		return r.sections(32)
+	})
+	corrupt("coefficient out of the field", func(r *R1CS) []section {
+		r.Constraints[0].A[0].Coeff.Set(r.Curve.ScalarField())
+		return r.sections(32)
+	})
+	corrupt("too many inputs", func(r *R1CS) []section {
+		r.NbPrivateInputs = r.NbWires
+		return r.sections(32)
+	})
+	corrupt("missing constraints", func(r *R1CS) []section {
+		s := r.sections(32)
+		return []section{s[0], s[2]}
+	})
+	corrupt("duplicated section", func(r *R1CS) []section {
+		s := r.sections(32)
+		return append(s, s[1])
+	})
+	corrupt("trailing bytes", func(r *R1CS) []section {
+		s := r.sections(32)
+		s[1].content = append(s[1].content, 0)
+		return s
+	})
+	corrupt("unknown custom gate", func(r *R1CS) []section {
+		r.CustomGates = []CustomGate{{Template: "CMul"}}
+		r.CustomGateUses = []CustomGateUse{{Gate: 1}}
+		return r.sections(32)
+	})
+	if _, err := ReadR1CS(bytes.NewReader(append([]byte("wtns"), valid[4:]...))); !errors.Is(err, ErrInvalidR1CS) {
+		t.Fatalf("wrong magic: %v", err)
+	}
+}
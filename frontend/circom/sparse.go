@@ -0,0 +1,97 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circom
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
+	"github.com/consensys/gnark/frontend/cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/frontend/schema"
+)
+
+var tVariable = reflect.TypeOf((*frontend.Variable)(nil)).Elem()
+
+// CompileSparse compiles r1cs into a sparse constraint system, for plonk and the
+// Pianist backends, converting its constraints directly with scs.FromR1CS rather than
+// through the API as Compile does. The witnesses are the ones of NewCircuit.
+func CompileSparse(r1cs *R1CS) (frontend.CompiledConstraintSystem, error) {
+	res, coefficients, err := r1cs.compiled()
+	if err != nil {
+		return nil, err
+	}
+	return scs.FromR1CS(res, coefficients)
+}
+
+// compiled returns the rank-1 constraint system of gnark for r1cs, and its coefficient
+// table. The wires keep their numbering, the constant wire 0 of circom being the one of
+// gnark, and the wires which aren't public are secret.
+func (r *R1CS) compiled() (compiled.R1CS, []big.Int, error) {
+	if len(r.CustomGateUses) != 0 {
+		return compiled.R1CS{}, nil, errors.New("the custom gates of circom aren't supported")
+	}
+
+	res := compiled.R1CS{
+		ConstraintSystem: compiled.ConstraintSystem{
+			MDebug:             make(map[int]int),
+			MHints:             make(map[int]*compiled.Hint),
+			MHintsDependencies: make(map[hint.ID]string),
+			CurveID:            r.Curve,
+		},
+		Constraints: make([]compiled.R1C, len(r.Constraints)),
+	}
+	res.Public = []string{"one"}
+	s, err := schema.Parse(NewCircuit(r), tVariable, func(visibility schema.Visibility, name string, _ reflect.Value) error {
+		if visibility == schema.Public {
+			res.Public = append(res.Public, name)
+		} else {
+			res.Secret = append(res.Secret, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return compiled.R1CS{}, nil, err
+	}
+	res.Schema = s
+	res.NbPublicVariables = len(res.Public)
+	res.NbSecretVariables = len(res.Secret)
+
+	st := cs.NewCoeffTable()
+	nbPublic := r.NbPublic()
+	linearExpression := func(lc LinearCombination) compiled.LinearExpression {
+		l := make(compiled.LinearExpression, len(lc))
+		for i := range lc {
+			visibility := schema.Secret
+			if int(lc[i].Wire) <= nbPublic {
+				visibility = schema.Public
+			}
+			l[i] = compiled.Pack(int(lc[i].Wire), st.CoeffID(&lc[i].Coeff), visibility)
+		}
+		return l
+	}
+	for i, r1c := range r.Constraints {
+		res.Constraints[i] = compiled.R1C{
+			L: linearExpression(r1c.A),
+			R: linearExpression(r1c.B),
+			O: linearExpression(r1c.C),
+		}
+	}
+	return res, st.Coeffs, nil
+}
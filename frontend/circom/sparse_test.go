@@ -0,0 +1,116 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circom
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+func TestCompileSparse(t *testing.T) {
+	for _, curve := range gnark.Curves() {
+		cs := cube(curve).reduce()
+		ccs, err := CompileSparse(cs)
+		if err != nil {
+			t.Fatalf("%s: %v", curve, err)
+		}
+		if nbPublic := ccs.GetSchema().NbPublic; nbPublic != 2 {
+			t.Fatalf("%s: %d public variables, expected 2", curve, nbPublic)
+		}
+		if nbConstraints := ccs.GetNbConstraints(); nbConstraints != 3 {
+			t.Fatalf("%s: %d constraints, expected one per R1C", curve, nbConstraints)
+		}
+
+		// out = 3·2³ + 5
+		for out, valid := range map[int64]bool{29: true, 30: false} {
+			assignment := NewCircuit(cs)
+			if err := assignment.Assign(values(1, out, 3, 2, 4, 8)); err != nil {
+				t.Fatal(err)
+			}
+			full, err := frontend.NewWitness(assignment, curve)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ccs.IsSolved(full); (err == nil) != valid {
+				t.Fatalf("%s: out = %d: %v", curve, out, err)
+			}
+		}
+	}
+}
+
+func values(v ...int64) []big.Int {
+	res := make([]big.Int, len(v))
+	for i := range v {
+		res[i].SetInt64(v[i])
+	}
+	return res
+}
+
+func TestCompileSparseSharing(t *testing.T) {
+	// (a + b + c + d)·e = f, (2a + 2b + 2c + 2d)·f = g and (a + b + c + d + 1)·e = f + e,
+	// whose sums are computed once
+	sum := func(k int64, wires ...uint32) LinearCombination {
+		lc := make(LinearCombination, len(wires))
+		for i, w := range wires {
+			lc[i] = term(w, k)
+		}
+		return lc
+	}
+	cs := &R1CS{
+		Curve:           ecc.BN254,
+		NbWires:         8,
+		NbPrivateInputs: 7,
+		NbLabels:        8,
+		Constraints: []Constraint{
+			{A: sum(1, 1, 2, 3, 4), B: sum(1, 5), C: sum(1, 6)},
+			{A: sum(2, 1, 2, 3, 4), B: sum(1, 6), C: sum(1, 7)},
+			{A: append(sum(1, 1, 2, 3, 4), term(0, 1)), B: sum(1, 5), C: sum(1, 6, 5)},
+		},
+		WireToLabel: []uint64{0, 1, 2, 3, 4, 5, 6, 7},
+	}
+
+	sparse, err := CompileSparse(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3 additions for the sum, 1 for f + e, 1 product per R1C
+	if nbConstraints := sparse.GetNbConstraints(); nbConstraints != 7 {
+		t.Fatalf("%d constraints, expected 7", nbConstraints)
+	}
+	ccs, err := Compile(cs, scs.NewBuilder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sparse.GetNbConstraints() >= ccs.GetNbConstraints() {
+		t.Fatalf("%d constraints converted, %d compiled", sparse.GetNbConstraints(), ccs.GetNbConstraints())
+	}
+
+	// a + b + c + d = 10, e = 5: f = 50, g = 1000
+	for g, valid := range map[int64]bool{1000: true, 1001: false} {
+		full, _, err := ReadWitness(bytes.NewReader(wtns(ecc.BN254, 1, 1, 2, 3, 4, 5, 50, g)), cs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sparse.IsSolved(full); (err == nil) != valid {
+			t.Fatalf("g = %d: %v", g, err)
+		}
+	}
+}
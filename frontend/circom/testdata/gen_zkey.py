@@ -0,0 +1,65 @@
+# Writes cube.zkey, a Groth16 .zkey of the cube constraint system of r1cs_test.go, laid
+# out as snarkjs writes them after a phase-2 contribution, from known trapdoors: gamma = 1,
+# delta random. See README.md
+import os, sys, struct, random
+here = os.path.dirname(os.path.abspath(__file__))
+sys.path.insert(0, os.path.join(here, '..', '..', '..', 'backend', 'groth16', 'testdata', 'snarkjs'))
+from bn254 import p, r, G1, G2, mul, add, on_g1, on_g2
+R = 1 << 256
+w28 = 19103219067921713944291392827692070036145651957329286315305642004821462161904
+def root(n): return pow(w28, 1 << (28 - (n.bit_length() - 1)), r)
+inv = lambda a: pow(a, r - 2, r)
+def lag(k, m, tau):
+    w = pow(root(m), k, r)
+    return w * inv(m) % r * (pow(tau, m, r) - 1) % r * inv((tau - w) % r) % r
+
+cons = [({3: 1}, {3: 1}, {4: 1}), ({4: 1}, {3: 1}, {5: 1}), ({2: 1}, {5: 1}, {1: 1, 0: r - 5})]
+nPub, nVars = 2, 6
+rows = cons + [({s: 1}, {}, {}) for s in range(nPub + 1)]
+n = 8
+rnd = random.Random(20230402)
+tau, alpha, beta, delta = [rnd.randrange(1, r) for _ in range(4)]
+L = [lag(i, n, tau) for i in range(n)]
+A = [0] * nVars; B = [0] * nVars; C = [0] * nVars
+for i, (a, b, c) in enumerate(rows):
+    for s, v in a.items(): A[s] = (A[s] + v * L[i]) % r
+    for s, v in b.items(): B[s] = (B[s] + v * L[i]) % r
+    for s, v in c.items(): C[s] = (C[s] + v * L[i]) % r
+IC = [(beta * A[s] + alpha * B[s] + C[s]) % r for s in range(nPub + 1)]
+K = [(beta * A[s] + alpha * B[s] + C[s]) * inv(delta) % r for s in range(nPub + 1, nVars)]
+H = [lag(2 * i + 1, 2 * n, tau) * inv(delta) % r for i in range(n)]
+
+u32 = lambda x: struct.pack('<I', x)
+def le(x): return x.to_bytes(32, 'little')
+def mont(x): return le(x * R % p)
+def g1(s):
+    P = mul(G1, s) if s else None
+    if P is None: return bytes(64)
+    assert on_g1(P)
+    return mont(int(P[0])) + mont(int(P[1]))
+def g2(s):
+    P = mul(G2, s) if s else None
+    if P is None: return bytes(128)
+    return b''.join(mont(c) for c in P[0].coeffs + P[1].coeffs)
+
+sec = {}
+sec[1] = u32(1)
+sec[2] = u32(32) + le(p) + u32(32) + le(r) + u32(nVars) + u32(nPub) + u32(n) + \
+    g1(alpha) + g1(beta) + g2(beta) + g2(1) + g1(delta) + g2(delta)
+sec[3] = b''.join(g1(x) for x in IC)
+coefs = []
+for i, (a, b, c) in enumerate(rows):
+    for m, lc in enumerate((a, b)):
+        for s, v in sorted(lc.items()):
+            coefs.append(u32(m) + u32(i) + u32(s) + le(v * R * R % r))
+sec[4] = u32(len(coefs)) + b''.join(coefs)
+sec[5] = b''.join(g1(x) for x in A)
+sec[6] = b''.join(g1(x) for x in B)
+sec[7] = b''.join(g2(x) for x in B)
+sec[8] = b''.join(g1(x) for x in K)
+sec[9] = b''.join(g1(x) for x in H)
+sec[10] = bytes(64) + u32(0)
+out = b'zkey' + u32(1) + u32(len(sec))
+for t in sorted(sec):
+    out += u32(t) + struct.pack('<Q', len(sec[t])) + sec[t]
+open(os.path.join(here, 'cube.zkey'), 'wb').write(out)
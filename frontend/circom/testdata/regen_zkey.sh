@@ -0,0 +1,36 @@
+#!/bin/sh
+# Replaces cube.zkey with the one circom 2.1.x and snarkjs 0.7.x set up for the circuit
+# of README.md, checks cube.r1cs in along with it, and removes gen_zkey.py.
+set -eu
+cd "$(dirname "$0")"
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+cat > "$tmp/cube.circom" <<'CIRCOM'
+pragma circom 2.0.0;
+
+template Cube() {
+    signal input a;
+    signal input x;
+    signal output out;
+    signal x2;
+    signal x3;
+
+    x2 <== x * x;
+    x3 <== x2 * x;
+    out <== a * x3 + 5;
+}
+
+component main {public [a]} = Cube();
+CIRCOM
+
+circom "$tmp/cube.circom" --r1cs -o "$tmp"
+snarkjs powersoftau new bn128 3 "$tmp/pot_0.ptau"
+snarkjs powersoftau contribute "$tmp/pot_0.ptau" "$tmp/pot_1.ptau" --name=first -e=random
+snarkjs powersoftau prepare phase2 "$tmp/pot_1.ptau" "$tmp/pot_final.ptau"
+snarkjs groth16 setup "$tmp/cube.r1cs" "$tmp/pot_final.ptau" "$tmp/cube_0.zkey"
+snarkjs zkey contribute "$tmp/cube_0.zkey" cube.zkey --name=first -e=random
+snarkjs zkey verify "$tmp/cube.r1cs" "$tmp/pot_final.ptau" cube.zkey
+cp "$tmp/cube.r1cs" cube.r1cs
+
+rm -f gen_zkey.py
@@ -0,0 +1,104 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circom
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+)
+
+// section types of the .wtns format
+const (
+	sectionWtnsHeader = 1
+	sectionWtnsValues = 2
+)
+
+// ErrInvalidWtns is wrapped by the errors of ReadWtns on malformed files
+var ErrInvalidWtns = errors.New("invalid circom wtns")
+
+// ReadWtns reads the values of the wires from a .wtns file, as the witness calculators
+// of circom write them, and checks them against r1cs: same field and one value per
+// wire, the first being 1.
+func ReadWtns(r io.Reader, r1cs *R1CS) ([]big.Int, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := readSections(data, "wtns", 1, 2)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWtns, err)
+	}
+	for _, typ := range []uint32{sectionWtnsHeader, sectionWtnsValues} {
+		if _, ok := sections[typ]; !ok {
+			return nil, fmt.Errorf("%w: missing section %d", ErrInvalidWtns, typ)
+		}
+	}
+
+	d := &decoder{buf: sections[sectionWtnsHeader]}
+	n8, prime := d.field()
+	nbValues := d.uint32()
+	if err := d.end(); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidWtns, err)
+	}
+	if prime.Cmp(r1cs.Curve.ScalarField()) != 0 {
+		return nil, fmt.Errorf("%w: prime %s, but the field of the constraint system is the one of %s", ErrInvalidWtns, prime, r1cs.Curve)
+	}
+	if nbValues != r1cs.NbWires {
+		return nil, fmt.Errorf("%w: %d values for %d wires", ErrInvalidWtns, nbValues, r1cs.NbWires)
+	}
+
+	s := sections[sectionWtnsValues]
+	if uint64(len(s)) != uint64(nbValues)*uint64(n8) {
+		return nil, fmt.Errorf("%w: %d bytes for %d values", ErrInvalidWtns, len(s), nbValues)
+	}
+	d = &decoder{buf: s}
+	values := make([]big.Int, nbValues)
+	for i := range values {
+		d.element(n8, prime, &values[i])
+		if d.err != nil {
+			return nil, fmt.Errorf("%w: value %d: %v", ErrInvalidWtns, i, d.err)
+		}
+	}
+	if values[0].Cmp(big.NewInt(1)) != 0 {
+		return nil, fmt.Errorf("%w: the constant wire is %s", ErrInvalidWtns, &values[0])
+	}
+	return values, nil
+}
+
+// ReadWitness reads a .wtns file of r1cs and returns the full and the public witnesses
+// of the circuit of r1cs, for any backend
+func ReadWitness(r io.Reader, r1cs *R1CS) (full, public *witness.Witness, err error) {
+	values, err := ReadWtns(r, r1cs)
+	if err != nil {
+		return nil, nil, err
+	}
+	assignment := NewCircuit(r1cs)
+	if err := assignment.Assign(values); err != nil {
+		return nil, nil, err
+	}
+	if full, err = frontend.NewWitness(assignment, r1cs.Curve); err != nil {
+		return nil, nil, err
+	}
+	if public, err = frontend.NewWitness(assignment, r1cs.Curve, frontend.PublicOnly()); err != nil {
+		return nil, nil, err
+	}
+	return full, public, nil
+}
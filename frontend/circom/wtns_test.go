@@ -0,0 +1,98 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+// wtns returns the .wtns file of values on the field of curve
+func wtns(curve ecc.ID, values ...int64) []byte {
+	le := binary.LittleEndian
+	var h, v bytes.Buffer
+	binary.Write(&h, le, uint32(32))
+	h.Write(element(curve.ScalarField(), 32))
+	binary.Write(&h, le, uint32(len(values)))
+	for _, x := range values {
+		v.Write(element(big.NewInt(x), 32))
+	}
+	res := encode(section{sectionWtnsHeader, h.Bytes()}, section{sectionWtnsValues, v.Bytes()})
+	copy(res, "wtns")
+	binary.LittleEndian.PutUint32(res[4:], 2)
+	return res
+}
+
+func TestReadWitness(t *testing.T) {
+	cs := cube(ecc.BN254).reduce()
+	ccs, err := Compile(cs, scs.NewBuilder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// out = 3·2³ + 5
+	full, public, err := ReadWitness(bytes.NewReader(wtns(ecc.BN254, 1, 29, 3, 2, 4, 8)), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.Vector.Len() != 5 || public.Vector.Len() != 2 {
+		t.Fatalf("%d values in the full witness and %d in the public one, expected 5 and 2", full.Vector.Len(), public.Vector.Len())
+	}
+	if err := ccs.IsSolved(full); err != nil {
+		t.Fatal(err)
+	}
+
+	full, _, err = ReadWitness(bytes.NewReader(wtns(ecc.BN254, 1, 30, 3, 2, 4, 8)), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ccs.IsSolved(full); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestReadWtnsMalformed(t *testing.T) {
+	cs := cube(ecc.BN254).reduce()
+	valid := wtns(ecc.BN254, 1, 29, 3, 2, 4, 8)
+	for n := 0; n < len(valid); n++ {
+		if _, err := ReadWtns(bytes.NewReader(valid[:n]), cs); !errors.Is(err, ErrInvalidWtns) {
+			t.Fatalf("truncated at %d bytes: %v", n, err)
+		}
+	}
+
+	outOfField := append([]byte{}, valid...)
+	for i := len(outOfField) - 32; i < len(outOfField); i++ {
+		outOfField[i] = 0xff
+	}
+
+	for name, file := range map[string][]byte{
+		"value out of the field": outOfField,
+		"other field":            wtns(ecc.BLS12_381, 1, 29, 3, 2, 4, 8),
+		"missing value":          wtns(ecc.BN254, 1, 29, 3, 2, 4),
+		"extra value":            wtns(ecc.BN254, 1, 29, 3, 2, 4, 8, 0),
+		"no constant":            wtns(ecc.BN254, 0, 29, 3, 2, 4, 8),
+		"r1cs, not witness":      encode(cs.sections(32)...),
+	} {
+		if _, err := ReadWtns(bytes.NewReader(file), cs); !errors.Is(err, ErrInvalidWtns) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
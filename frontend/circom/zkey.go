@@ -0,0 +1,380 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
+	"github.com/consensys/gnark/frontend/schema"
+	bn254r1cs "github.com/consensys/gnark/internal/backend/bn254/cs"
+	groth16_bn254 "github.com/consensys/gnark/internal/backend/bn254/groth16"
+)
+
+// section types of the .zkey format
+const (
+	sectionZKeyHeader        = 1
+	sectionZKeyGroth16Header = 2
+	sectionZKeyIC            = 3
+	sectionZKeyCoeffs        = 4
+	sectionZKeyA             = 5
+	sectionZKeyB1            = 6
+	sectionZKeyB2            = 7
+	sectionZKeyC             = 8
+	sectionZKeyH             = 9
+)
+
+const (
+	zkeyGroth16 = 1  // protocol of the header section
+	n8BN254     = 32 // size of the field elements of BN254 in bytes
+
+	sizeG1BN254 = 2 * n8BN254
+	sizeG2BN254 = 4 * n8BN254
+
+	// fft.Domain goes up to 2²⁸, which the H points take on twice the domain
+	zkeyMaxDomainSize = 1 << 27
+)
+
+// ErrInvalidZKey is wrapped by the errors of ReadZKey on malformed files, and on files
+// set up for another constraint system
+var ErrInvalidZKey = errors.New("invalid snarkjs zkey")
+
+// ReadZKey reads a Groth16 .zkey of snarkjs, the output of a phase-2 ceremony for r1cs,
+// and returns the constraint system, the proving key and the verifying key for the
+// Groth16 backend of gnark. Only BN254 is supported. The layout of the sections and the
+// points, which must be on the curve and in the subgroups, are checked, and so are the
+// coefficients of the zkey against the constraints of r1cs, so that the verifying key
+// records the fingerprint of the returned constraint system.
+//
+// snarkjs appends the constraints wᵢ·0 = 0 for the public wires and wire 0, which bind
+// the public inputs to the proofs, so that the constraint system has them after the
+// constraints of r1cs. Its witnesses are the ones of NewCircuit.
+//
+// The H points of the zkey are converted to the ones of gnark with n/2·log(n) scalar
+// multiplications, n being the size of the domain: the proving key is better written
+// with WriteTo once and read back than converted on each run.
+func ReadZKey(zkey io.Reader, r1cs *R1CS) (frontend.CompiledConstraintSystem, groth16.ProvingKey, groth16.VerifyingKey, error) {
+	if r1cs.Curve != ecc.BN254 {
+		return nil, nil, nil, fmt.Errorf("the zkeys of snarkjs are read for BN254 only, not %s", r1cs.Curve)
+	}
+	ccs, err := r1cs.zkeyCompiled()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, err := ioutil.ReadAll(zkey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sections, err := readSections(data, "zkey", 1)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidZKey, err)
+	}
+	for typ := uint32(sectionZKeyHeader); typ <= sectionZKeyH; typ++ {
+		if _, ok := sections[typ]; !ok {
+			return nil, nil, nil, fmt.Errorf("%w: missing section %d", ErrInvalidZKey, typ)
+		}
+	}
+
+	d := &decoder{buf: sections[sectionZKeyHeader]}
+	protocol := d.uint32()
+	if err := d.end(); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: header: %v", ErrInvalidZKey, err)
+	}
+	if protocol != zkeyGroth16 {
+		return nil, nil, nil, fmt.Errorf("%w: protocol %d, not Groth16", ErrInvalidZKey, protocol)
+	}
+
+	pk, vk := new(groth16_bn254.ProvingKey), new(groth16_bn254.VerifyingKey)
+	domainSize, err := readZKeyHeader(sections[sectionZKeyGroth16Header], r1cs, pk, vk)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: groth16 header: %v", ErrInvalidZKey, err)
+	}
+	if err := r1cs.checkZKeyCoeffs(sections[sectionZKeyCoeffs]); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: coefficients: %v", ErrInvalidZKey, err)
+	}
+	nbPublic := uint64(r1cs.NbPublic())
+	if vk.G1.K, err = g1Section(sections[sectionZKeyIC], nbPublic+1); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: IC: %v", ErrInvalidZKey, err)
+	}
+	if err := readZKeyPoints(sections, uint64(r1cs.NbWires), nbPublic, pk); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidZKey, err)
+	}
+	h, err := g1Section(sections[sectionZKeyH], domainSize)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: H: %v", ErrInvalidZKey, err)
+	}
+
+	pk.Domain = *fft.NewDomain(domainSize)
+	if pk.G1.Z, err = groth16_bn254.ZFromSnarkJS(h, &pk.Domain); err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: H: %v", ErrInvalidZKey, err)
+	}
+	vk.CircuitFingerprint = ccs.Fingerprint()
+	if err := vk.Precompute(); err != nil {
+		return nil, nil, nil, err
+	}
+	return ccs, pk, vk, nil
+}
+
+// readZKeyHeader reads the Groth16 header section into pk and vk, checks it against
+// r1cs, and returns the size of the domain
+func readZKeyHeader(s []byte, r1cs *R1CS, pk *groth16_bn254.ProvingKey, vk *groth16_bn254.VerifyingKey) (uint64, error) {
+	d := &decoder{buf: s}
+	n8q, q := d.field()
+	n8r, r := d.field()
+	nbVars, nbPublic, domainSize := d.uint32(), d.uint32(), d.uint32()
+	if d.err == nil && (n8q != n8BN254 || n8r != n8BN254 || q.Cmp(fp.Modulus()) != 0 || r.Cmp(fr.Modulus()) != 0) {
+		return 0, fmt.Errorf("primes %s and %s, not the ones of BN254", q, r)
+	}
+	d.g1(&pk.G1.Alpha)
+	d.g1(&pk.G1.Beta)
+	d.g2(&pk.G2.Beta)
+	d.g2(&vk.G2.Gamma)
+	d.g1(&pk.G1.Delta)
+	d.g2(&pk.G2.Delta)
+	if err := d.end(); err != nil {
+		return 0, err
+	}
+
+	if nbVars != r1cs.NbWires || int(nbPublic) != r1cs.NbPublic() {
+		return 0, fmt.Errorf("%d signals, %d public, but the constraint system has %d wires, %d public", nbVars, nbPublic, r1cs.NbWires, r1cs.NbPublic())
+	}
+	nbConstraints := uint64(len(r1cs.Constraints)) + uint64(nbPublic) + 1
+	if domainSize == 0 || domainSize&(domainSize-1) != 0 || uint64(domainSize) < nbConstraints || domainSize > zkeyMaxDomainSize {
+		return 0, fmt.Errorf("invalid domain size %d for %d constraints", domainSize, nbConstraints)
+	}
+
+	vk.G1.Alpha, vk.G1.Beta, vk.G1.Delta = pk.G1.Alpha, pk.G1.Beta, pk.G1.Delta
+	vk.G2.Beta, vk.G2.Delta = pk.G2.Beta, pk.G2.Delta
+	return uint64(domainSize), nil
+}
+
+// readZKeyPoints reads the points of the wires, A, B and C, into pk. The points at
+// infinity of A and B are filtered out as Setup does.
+func readZKeyPoints(sections map[uint32][]byte, nbVars, nbPublic uint64, pk *groth16_bn254.ProvingKey) error {
+	a, err := g1Section(sections[sectionZKeyA], nbVars)
+	if err != nil {
+		return fmt.Errorf("A: %v", err)
+	}
+	b1, err := g1Section(sections[sectionZKeyB1], nbVars)
+	if err != nil {
+		return fmt.Errorf("B1: %v", err)
+	}
+	b2, err := g2Section(sections[sectionZKeyB2], nbVars)
+	if err != nil {
+		return fmt.Errorf("B2: %v", err)
+	}
+	if pk.G1.K, err = g1Section(sections[sectionZKeyC], nbVars-nbPublic-1); err != nil {
+		return fmt.Errorf("C: %v", err)
+	}
+
+	pk.InfinityA = make([]bool, nbVars)
+	for i := range a {
+		if a[i].IsInfinity() {
+			pk.InfinityA[i] = true
+			pk.NbInfinityA++
+			continue
+		}
+		pk.G1.A = append(pk.G1.A, a[i])
+	}
+	pk.InfinityB = make([]bool, nbVars)
+	for i := range b1 {
+		if b1[i].IsInfinity() != b2[i].IsInfinity() {
+			return fmt.Errorf("only one of B1 and B2 is the point at infinity for signal %d", i)
+		}
+		if b1[i].IsInfinity() {
+			pk.InfinityB[i] = true
+			pk.NbInfinityB++
+			continue
+		}
+		pk.G1.B = append(pk.G1.B, b1[i])
+		pk.G2.B = append(pk.G2.B, b2[i])
+	}
+	return nil
+}
+
+// zkeyCoeff is a coefficient of the matrices A (0) and B (1) of the zkey
+type zkeyCoeff struct {
+	matrix, constraint, signal uint32
+	value                      fr.Element
+}
+
+// checkZKeyCoeffs checks that the coefficients of the zkey are the ones of the matrices
+// A and B of r, with the constraints snarkjs appends
+func (r *R1CS) checkZKeyCoeffs(s []byte) error {
+	d := &decoder{buf: s}
+	nbCoeffs := d.uint32()
+	if d.err == nil && uint64(len(s)) != 4+uint64(nbCoeffs)*(12+n8BN254) {
+		return fmt.Errorf("%d bytes for %d coefficients", len(s), nbCoeffs)
+	}
+	got := make([]zkeyCoeff, nbCoeffs)
+	for i := range got {
+		c := &got[i]
+		c.matrix, c.constraint, c.signal = d.uint32(), d.uint32(), d.uint32()
+		d.coeff(&c.value)
+		if d.err != nil {
+			return fmt.Errorf("coefficient %d: %w", i, d.err)
+		}
+	}
+	if err := d.end(); err != nil {
+		return err
+	}
+
+	var want []zkeyCoeff
+	for i, r1c := range r.Constraints {
+		for matrix, lc := range []LinearCombination{r1c.A, r1c.B} {
+			for _, t := range lc {
+				c := zkeyCoeff{matrix: uint32(matrix), constraint: uint32(i), signal: t.Wire}
+				c.value.SetBigInt(&t.Coeff)
+				want = append(want, c)
+			}
+		}
+	}
+	for i := 0; i <= r.NbPublic(); i++ {
+		c := zkeyCoeff{constraint: uint32(len(r.Constraints) + i), signal: uint32(i)}
+		c.value.SetOne()
+		want = append(want, c)
+	}
+
+	if len(got) != len(want) {
+		return fmt.Errorf("%d coefficients, but the constraint system has %d", len(got), len(want))
+	}
+	sortZKeyCoeffs(got)
+	sortZKeyCoeffs(want)
+	for i := range got {
+		if got[i] != want[i] {
+			return fmt.Errorf("signal %d in matrix %d of constraint %d doesn't match the constraint system", got[i].signal, got[i].matrix, got[i].constraint)
+		}
+	}
+	return nil
+}
+
+func sortZKeyCoeffs(c []zkeyCoeff) {
+	sort.Slice(c, func(i, j int) bool {
+		if c[i].matrix != c[j].matrix {
+			return c[i].matrix < c[j].matrix
+		}
+		if c[i].constraint != c[j].constraint {
+			return c[i].constraint < c[j].constraint
+		}
+		return c[i].signal < c[j].signal
+	})
+}
+
+// zkeyCompiled returns the constraint system of the zkeys of r: the constraints of r
+// followed by the constraints wᵢ·0 = 0 of snarkjs
+func (r *R1CS) zkeyCompiled() (*bn254r1cs.R1CS, error) {
+	res, coefficients, err := r.compiled()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i <= r.NbPublic(); i++ {
+		res.Constraints = append(res.Constraints, compiled.R1C{
+			L: compiled.LinearExpression{compiled.Pack(i, compiled.CoeffIdOne, schema.Public)},
+		})
+	}
+
+	// every wire is an input, so that the constraints don't depend on each other
+	level := make([]int, len(res.Constraints))
+	for i := range level {
+		level[i] = i
+	}
+	res.Levels = [][]int{level}
+	return bn254r1cs.NewR1CS(res, coefficients), nil
+}
+
+// g1Section reads the n points of G1 which make section s
+func g1Section(s []byte, n uint64) ([]curve.G1Affine, error) {
+	if uint64(len(s)) != n*sizeG1BN254 {
+		return nil, fmt.Errorf("%d bytes for %d points", len(s), n)
+	}
+	d := &decoder{buf: s}
+	res := make([]curve.G1Affine, n)
+	for i := range res {
+		if d.g1(&res[i]); d.err != nil {
+			return nil, fmt.Errorf("point %d: %w", i, d.err)
+		}
+	}
+	return res, nil
+}
+
+// g2Section reads the n points of G2 which make section s
+func g2Section(s []byte, n uint64) ([]curve.G2Affine, error) {
+	if uint64(len(s)) != n*sizeG2BN254 {
+		return nil, fmt.Errorf("%d bytes for %d points", len(s), n)
+	}
+	d := &decoder{buf: s}
+	res := make([]curve.G2Affine, n)
+	for i := range res {
+		if d.g2(&res[i]); d.err != nil {
+			return nil, fmt.Errorf("point %d: %w", i, d.err)
+		}
+	}
+	return res, nil
+}
+
+// the elements of the zkeys are written in Montgomery form, x·R, on n8
+// bytes, and the points as their affine coordinates, x then y, the point at infinity
+// being (0, 0)
+
+// montgomery reads an element of the field of modulus prime in Montgomery form into
+// limbs, which hold it in Montgomery form as well
+func (d *decoder) montgomery(prime *big.Int, limbs []uint64) {
+	var x big.Int
+	if d.element(n8BN254, prime, &x); d.err != nil {
+		return
+	}
+	var b [n8BN254]byte
+	x.FillBytes(b[:])
+	for i := range limbs {
+		limbs[i] = binary.BigEndian.Uint64(b[n8BN254-8*(i+1):])
+	}
+}
+
+// coeff reads a coefficient of the matrices, which snarkjs multiplies by R once more
+func (d *decoder) coeff(res *fr.Element) {
+	d.montgomery(fr.Modulus(), res[:])
+	res.FromMont()
+}
+
+func (d *decoder) g1(p *curve.G1Affine) {
+	d.montgomery(fp.Modulus(), p.X[:])
+	d.montgomery(fp.Modulus(), p.Y[:])
+	if d.err == nil && !p.IsInfinity() && !(p.IsOnCurve() && p.IsInSubGroup()) {
+		d.err = errors.New("G1 point not on the curve")
+	}
+}
+
+func (d *decoder) g2(p *curve.G2Affine) {
+	d.montgomery(fp.Modulus(), p.X.A0[:])
+	d.montgomery(fp.Modulus(), p.X.A1[:])
+	d.montgomery(fp.Modulus(), p.Y.A0[:])
+	d.montgomery(fp.Modulus(), p.Y.A1[:])
+	if d.err == nil && !p.IsInfinity() && !(p.IsOnCurve() && p.IsInSubGroup()) {
+		d.err = errors.New("G2 point not on the curve or not in the subgroup")
+	}
+}
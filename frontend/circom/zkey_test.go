@@ -0,0 +1,122 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package circom
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+)
+
+// offsets in testdata/cube.zkey, after the 12 bytes of the file header and the 12 of
+// each section header
+const (
+	offsetProtocol = 24      // in the header section
+	offsetAlpha1   = 40 + 84 // after the sizes and primes of the Groth16 header
+)
+
+// cubeZKey is a zkey of cube, laid out as snarkjs writes them after a contribution to
+// phase 2
+func cubeZKey(t *testing.T) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "cube.zkey"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReadZKey(t *testing.T) {
+	cs := cube(ecc.BN254).reduce()
+	ccs, pk, vk, err := ReadZKey(bytes.NewReader(cubeZKey(t)), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wᵢ·0 = 0 for wire 0, out and a
+	if nbConstraints := ccs.GetNbConstraints(); nbConstraints != 6 {
+		t.Fatalf("%d constraints, expected 3 + 3", nbConstraints)
+	}
+
+	// out = 3·2³ + 5
+	assignment := NewCircuit(cs)
+	if err := assignment.Assign(values(1, 29, 3, 2, 4, 8)); err != nil {
+		t.Fatal(err)
+	}
+	full, err := frontend.NewWitness(assignment, ecc.BN254)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := frontend.NewWitness(assignment, ecc.BN254, frontend.PublicOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := groth16.Prove(ccs, pk, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16.Verify(proof, vk, public); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := assignment.Assign(values(1, 30, 3, 2, 4, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if public, err = frontend.NewWitness(assignment, ecc.BN254, frontend.PublicOnly()); err != nil {
+		t.Fatal(err)
+	}
+	if err := groth16.Verify(proof, vk, public); err == nil {
+		t.Fatal("verifying with a wrong output should fail")
+	}
+}
+
+func TestReadZKeyMalformed(t *testing.T) {
+	data := cubeZKey(t)
+	flip := func(offset int, mask byte) []byte {
+		res := append([]byte(nil), data...)
+		res[offset] ^= mask
+		return res
+	}
+	cs := cube(ecc.BN254).reduce()
+	other := cube(ecc.BN254).reduce()
+	other.Constraints[0].A[0].Coeff.SetInt64(2)
+	withoutConstraint := cube(ecc.BN254).reduce()
+	withoutConstraint.Constraints = withoutConstraint.Constraints[1:]
+
+	for name, test := range map[string]struct {
+		data []byte
+		r1cs *R1CS
+	}{
+		"not a zkey":              {flip(0, 1), cs},
+		"truncated":               {data[:len(data)-1], cs},
+		"plonk":                   {flip(offsetProtocol, 3), cs},
+		"point off the curve":     {flip(offsetAlpha1+n8BN254, 1), cs},
+		"other coefficient":       {data, other},
+		"other constraint system": {data, withoutConstraint},
+	} {
+		if _, _, _, err := ReadZKey(bytes.NewReader(test.data), test.r1cs); !errors.Is(err, ErrInvalidZKey) {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	if _, _, _, err := ReadZKey(bytes.NewReader(data), cube(ecc.BLS12_381).reduce()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compiled
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend/schema"
+)
+
+// Fingerprint is the canonical hash of a constraint system, which the verifying keys
+// record to tie them to the constraint system they were set up for.
+//
+// It is the SHA-256 of the kind of the constraint system, its curve, its numbers of
+// variables, its schema, its coefficients and its constraints, with the lookups of the
+// sparse constraint systems. The hints, the logs, the debug info and the levels don't
+// change what the constraint system proves, so that they are left out. Compiling the
+// same circuit twice gives the same fingerprint, as the compilation is deterministic.
+//
+// The zero Fingerprint stands for an unknown constraint system, the verifying keys
+// serialized before they recorded it reading back with it.
+type Fingerprint [sha256.Size]byte
+
+var (
+	// ErrFingerprintMismatch is returned when a verifying key was set up for another
+	// constraint system than the one it is checked against
+	ErrFingerprintMismatch = errors.New("verifying key set up for another constraint system")
+
+	// ErrNoFingerprint is returned when checking a verifying key which doesn't record the
+	// fingerprint of its constraint system
+	ErrNoFingerprint = errors.New("verifying key without the fingerprint of its constraint system")
+)
+
+// fingerprintDomain prefixes the hashed data, and changes with its layout
+const fingerprintDomain = "gnark/fingerprint/v1"
+
+// String returns the fingerprint in hexadecimal
+func (f Fingerprint) String() string {
+	return hex.EncodeToString(f[:])
+}
+
+// IsZero returns true if f is the zero Fingerprint, of an unknown constraint system
+func (f Fingerprint) IsZero() bool {
+	return f == Fingerprint{}
+}
+
+// Check returns nil if f, the fingerprint a verifying key records, is the fingerprint
+// expected of the constraint system
+func (f Fingerprint) Check(expected Fingerprint) error {
+	if f.IsZero() {
+		return ErrNoFingerprint
+	}
+	if f != expected {
+		return fmt.Errorf("%w: fingerprint %s, expected %s", ErrFingerprintMismatch, f, expected)
+	}
+	return nil
+}
+
+// Fingerprint returns the fingerprint of the R1CS, of coefficients coeffs
+func (r1cs *R1CS) Fingerprint(coeffs []big.Int) Fingerprint {
+	h := r1cs.newFingerprintHash(jsonFormatR1CS, coeffs)
+	h.writeInt(len(r1cs.Constraints))
+	for i := range r1cs.Constraints {
+		for _, l := range []LinearExpression{r1cs.Constraints[i].L, r1cs.Constraints[i].R, r1cs.Constraints[i].O} {
+			h.writeInt(len(l))
+			for _, t := range l {
+				h.writeUint64(uint64(t))
+			}
+		}
+	}
+	return h.sum()
+}
+
+// Fingerprint returns the fingerprint of the SparseR1CS, of coefficients coeffs
+func (cs *SparseR1CS) Fingerprint(coeffs []big.Int) Fingerprint {
+	h := cs.newFingerprintHash(jsonFormatSparseR1CS, coeffs)
+	h.writeInt(len(cs.Constraints))
+	for i := range cs.Constraints {
+		c := &cs.Constraints[i]
+		for _, t := range []Term{c.L, c.R, c.O, c.M[0], c.M[1]} {
+			h.writeUint64(uint64(t))
+		}
+		h.writeInt(c.K)
+	}
+	h.writeInts(cs.LookupTable)
+	h.writeInts(cs.Lookups)
+	return h.sum()
+}
+
+// fingerprintHash writes the fields of a constraint system to a hash, the integers in
+// big endian and the lists after their length, so that the encoding is unambiguous
+type fingerprintHash struct {
+	hash.Hash
+	buf [8]byte
+}
+
+func (cs *ConstraintSystem) newFingerprintHash(format string, coeffs []big.Int) *fingerprintHash {
+	h := &fingerprintHash{Hash: sha256.New()}
+	h.writeString(fingerprintDomain)
+	h.writeString(format)
+	h.writeString(cs.CurveID.String())
+	h.writeInt(cs.NbPublicVariables)
+	h.writeInt(cs.NbSecretVariables)
+	h.writeInt(cs.NbInternalVariables)
+
+	if cs.Schema == nil {
+		h.writeUint64(0)
+	} else {
+		h.writeUint64(1)
+		h.writeInt(cs.Schema.NbPublic)
+		h.writeInt(cs.Schema.NbSecret)
+		h.writeFields(cs.Schema.Fields)
+	}
+
+	// the coefficients on as many bytes as the elements of the field
+	size := (cs.BitLen() + 7) / 8
+	b := make([]byte, size)
+	h.writeInt(len(coeffs))
+	for i := range coeffs {
+		h.Write(coeffs[i].FillBytes(b))
+	}
+	return h
+}
+
+func (h *fingerprintHash) writeFields(fields []schema.Field) {
+	h.writeInt(len(fields))
+	for i := range fields {
+		h.writeString(fields[i].Name)
+		h.writeString(fields[i].NameTag)
+		h.writeUint64(uint64(fields[i].Visibility))
+		h.writeUint64(uint64(fields[i].Type))
+		h.writeInt(fields[i].ArraySize)
+		h.writeFields(fields[i].SubFields)
+	}
+}
+
+func (h *fingerprintHash) writeUint64(v uint64) {
+	binary.BigEndian.PutUint64(h.buf[:], v)
+	h.Write(h.buf[:])
+}
+
+func (h *fingerprintHash) writeInt(v int) {
+	h.writeUint64(uint64(v))
+}
+
+func (h *fingerprintHash) writeInts(v []int) {
+	h.writeInt(len(v))
+	for _, x := range v {
+		h.writeInt(x)
+	}
+}
+
+func (h *fingerprintHash) writeString(s string) {
+	h.writeInt(len(s))
+	h.Write([]byte(s))
+}
+
+func (h *fingerprintHash) sum() Fingerprint {
+	var res Fingerprint
+	copy(res[:], h.Sum(nil))
+	return res
+}
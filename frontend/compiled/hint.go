@@ -14,10 +14,20 @@ import (
 // using pre-defined inputs
 type Hint struct {
 	ID     hint.ID       // hint function id
+	Name   string        // hint function name, from which the id derives
 	Inputs []interface{} // terms to inject in the hint function
 	Wires  []int         // IDs of wires the hint outputs map to
 }
 
+// checkName checks that the ID of h derives from its name. The hints serialized before
+// they were named have none, which is accepted.
+func (h *Hint) checkName() error {
+	if h.Name != "" && hint.NameID(h.Name) != h.ID {
+		return fmt.Errorf("hint function %d named %s, of ID %d", h.ID, h.Name, hint.NameID(h.Name))
+	}
+	return nil
+}
+
 func (h Hint) inputsCBORTags() (cbor.TagSet, error) {
 	defTagOpts := cbor.TagOptions{EncTag: cbor.EncTagRequired, DecTag: cbor.DecTagRequired}
 	tags := cbor.NewTagSet()
@@ -65,7 +75,7 @@ func (h Hint) MarshalCBOR() ([]byte, error) {
 			inputs[i] = h.Inputs[i]
 		}
 	}
-	v := vt{ID: h.ID, Inputs: inputs, Wires: h.Wires}
+	v := vt{ID: h.ID, Name: h.Name, Inputs: inputs, Wires: h.Wires}
 	return enc.Marshal(v)
 }
 
@@ -81,6 +91,7 @@ func (h *Hint) UnmarshalCBOR(b []byte) error {
 	// v of type vt is Hint but does not implement cbor.Marshaler
 	type vt struct {
 		ID     hint.ID
+		Name   string
 		Inputs []cbor.RawTag
 		Wires  []int
 	}
@@ -124,7 +135,8 @@ func (h *Hint) UnmarshalCBOR(b []byte) error {
 		}
 	}
 	h.ID = v.ID
+	h.Name = v.Name
 	h.Inputs = inputs
 	h.Wires = v.Wires
-	return nil
+	return h.checkName()
 }
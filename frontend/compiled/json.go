@@ -0,0 +1,696 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compiled
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/hint"
+	"github.com/consensys/gnark/frontend/schema"
+)
+
+// The JSON layout of the constraint systems, which WriteJSON and ReadJSON write and read
+// for the tools other than gnark, is, for version 1:
+//
+//	{
+//	  "format": "gnark-r1cs" or "gnark-sparse-r1cs",
+//	  "version": 1,
+//	  "curve": "bn254",
+//	  "nbPublic": 2, "nbSecret": 1, "nbInternal": 3,
+//	  "public": ["one", "Y"], "secret": ["X"],
+//	  "schema": {"nbPublic": 1, "nbSecret": 1, "fields": [field]},
+//	  "coefficients": ["0", "1", "2", "21888242871839275222246405745257275088548364400416034343698204186575808495616"],
+//	  "constraints": [constraint],
+//	  "lookupTable": [4, 5], "lookups": [0],
+//	  "hintFunctions": {"1925406384": "github.com/consensys/gnark/std/math/bits.NBits"},
+//	  "hints": [{"id": 1925406384, "name": "github.com/consensys/gnark/std/math/bits.NBits", "inputs": [input], "wires": [4, 5]}],
+//	  "levels": [[0, 1], [2]],
+//	  "logs": [log],
+//	  "debugInfo": [log],
+//	  "constraintDebugInfo": {"2": 0},
+//	  "counters": [{"from": "a", "to": "b", "nbVariables": 3, "nbConstraints": 2, "curve": "bn254", "backend": "plonk"}]
+//	}
+//
+// The wires are numbered from 0, the constant 1, with the public wires first, then the
+// secret and the internal ones, and their numbers of each kind are the counts nbPublic,
+// nbSecret and nbInternal. public and secret name the input wires. The coefficients are
+// the field elements in decimal, which the terms refer to by their index.
+//
+// A term is [wire, coefficient, visibility], the visibility being "public", "secret",
+// "internal", "virtual" or "unset", and stands for the product of the wire and of the
+// coefficient. A constraint of a R1CS is {"l": [term], "r": [term], "o": [term]}, for
+// L·R = O where L, R and O are the sums of the terms. A constraint of a SparseR1CS is
+// {"l": term, "r": term, "o": term, "m": [term, term], "k": coefficient}, for
+// l + r + m₀·m₁ + o + k = 0. lookupTable and lookups, only in the sparse constraint
+// systems, hold the coefficients of the entries of the lookup table and the constraints
+// whose l wire is looked up in it.
+//
+// A hint computes its wires from its inputs with the hint function of ID id and of name
+// name, id deriving from name when it isn't empty, hintFunctions listing the hint
+// functions the constraint system needs. An input is {"linearExpression": [term]},
+// {"term": term} or {"constant": "5"}. levels splits the constraints, each of them in
+// exactly one level, and the constraints of a level only depend on the wires of the
+// levels before, and of the hints.
+//
+// A log is {"caller": "main.go:12", "format": "x = %s", "toResolve": [term]}, null
+// delimiting the terms summed into one value. constraintDebugInfo maps the constraints
+// to the debug info they report when they aren't satisfied. A field of the schema is
+// {"name": "X", "tag": "x", "visibility": "secret", "type": "leaf", "arraySize": 0,
+// "subFields": [field]}, the type being "leaf", "array" or "struct".
+//
+// The layout mirrors the constraint systems exactly, so that reading what WriteJSON
+// writes gives back the same constraint system.
+
+const (
+	jsonVersion          = 1
+	jsonFormatR1CS       = "gnark-r1cs"
+	jsonFormatSparseR1CS = "gnark-sparse-r1cs"
+)
+
+type jsonSystem struct {
+	Format              string             `json:"format"`
+	Version             int                `json:"version"`
+	Curve               string             `json:"curve"`
+	NbPublic            int                `json:"nbPublic"`
+	NbSecret            int                `json:"nbSecret"`
+	NbInternal          int                `json:"nbInternal"`
+	Public              []string           `json:"public"`
+	Secret              []string           `json:"secret"`
+	Schema              *jsonSchema        `json:"schema"`
+	Coefficients        []string           `json:"coefficients"`
+	HintFunctions       map[hint.ID]string `json:"hintFunctions"`
+	Hints               []jsonHint         `json:"hints"`
+	Levels              [][]int            `json:"levels"`
+	Logs                []jsonLogEntry     `json:"logs"`
+	DebugInfo           []jsonLogEntry     `json:"debugInfo"`
+	ConstraintDebugInfo map[int]int        `json:"constraintDebugInfo"`
+	Counters            []jsonCounter      `json:"counters"`
+}
+
+type jsonR1CS struct {
+	jsonSystem
+	Constraints []jsonR1C `json:"constraints"`
+}
+
+type jsonSparseR1CS struct {
+	jsonSystem
+	Constraints []jsonSparseR1C `json:"constraints"`
+	LookupTable []int           `json:"lookupTable"`
+	Lookups     []int           `json:"lookups"`
+}
+
+type jsonR1C struct {
+	L LinearExpression `json:"l"`
+	R LinearExpression `json:"r"`
+	O LinearExpression `json:"o"`
+}
+
+type jsonSparseR1C struct {
+	L Term    `json:"l"`
+	R Term    `json:"r"`
+	O Term    `json:"o"`
+	M [2]Term `json:"m"`
+	K int     `json:"k"`
+}
+
+type jsonHint struct {
+	ID     hint.ID         `json:"id"`
+	Name   string          `json:"name"`
+	Inputs []jsonHintInput `json:"inputs"`
+	Wires  []int           `json:"wires"`
+}
+
+type jsonHintInput struct {
+	LinearExpression *LinearExpression `json:"linearExpression,omitempty"`
+	Term             *Term             `json:"term,omitempty"`
+	Constant         *string           `json:"constant,omitempty"`
+}
+
+type jsonLogEntry struct {
+	Caller    string `json:"caller"`
+	Format    string `json:"format"`
+	ToResolve []Term `json:"toResolve"`
+}
+
+type jsonCounter struct {
+	From          string `json:"from"`
+	To            string `json:"to"`
+	NbVariables   int    `json:"nbVariables"`
+	NbConstraints int    `json:"nbConstraints"`
+	Curve         string `json:"curve"`
+	Backend       string `json:"backend"`
+}
+
+type jsonSchema struct {
+	NbPublic int         `json:"nbPublic"`
+	NbSecret int         `json:"nbSecret"`
+	Fields   []jsonField `json:"fields"`
+}
+
+type jsonField struct {
+	Name       string      `json:"name"`
+	Tag        string      `json:"tag"`
+	Visibility string      `json:"visibility"`
+	Type       string      `json:"type"`
+	ArraySize  int         `json:"arraySize"`
+	SubFields  []jsonField `json:"subFields"`
+}
+
+// WriteJSON writes the R1CS, of coefficients coeffs, in the JSON layout of json.go
+func (r1cs *R1CS) WriteJSON(w io.Writer, coeffs []big.Int) error {
+	v := jsonR1CS{jsonSystem: r1cs.ConstraintSystem.toJSON(jsonFormatR1CS, coeffs)}
+	if r1cs.Constraints != nil {
+		v.Constraints = make([]jsonR1C, len(r1cs.Constraints))
+		for i := range r1cs.Constraints {
+			v.Constraints[i] = jsonR1C(r1cs.Constraints[i])
+		}
+	}
+	return json.NewEncoder(w).Encode(&v)
+}
+
+// ReadJSON reads the R1CS from the JSON layout of json.go, and returns its coefficients
+func (r1cs *R1CS) ReadJSON(r io.Reader) ([]big.Int, error) {
+	var v jsonR1CS
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, err
+	}
+	coeffs, err := r1cs.ConstraintSystem.fromJSON(&v.jsonSystem, jsonFormatR1CS)
+	if err != nil {
+		return nil, err
+	}
+	r1cs.Constraints = nil
+	if v.Constraints != nil {
+		r1cs.Constraints = make([]R1C, len(v.Constraints))
+		for i := range v.Constraints {
+			r1cs.Constraints[i] = R1C(v.Constraints[i])
+			for _, l := range []LinearExpression{v.Constraints[i].L, v.Constraints[i].R, v.Constraints[i].O} {
+				if err := r1cs.checkTerms(l, len(coeffs)); err != nil {
+					return nil, fmt.Errorf("constraint %d: %w", i, err)
+				}
+			}
+		}
+	}
+	if err := r1cs.checkLevels(len(r1cs.Constraints)); err != nil {
+		return nil, err
+	}
+	return coeffs, nil
+}
+
+// WriteJSON writes the SparseR1CS, of coefficients coeffs, in the JSON layout of json.go
+func (cs *SparseR1CS) WriteJSON(w io.Writer, coeffs []big.Int) error {
+	v := jsonSparseR1CS{
+		jsonSystem:  cs.ConstraintSystem.toJSON(jsonFormatSparseR1CS, coeffs),
+		LookupTable: cs.LookupTable,
+		Lookups:     cs.Lookups,
+	}
+	if cs.Constraints != nil {
+		v.Constraints = make([]jsonSparseR1C, len(cs.Constraints))
+		for i := range cs.Constraints {
+			v.Constraints[i] = jsonSparseR1C(cs.Constraints[i])
+		}
+	}
+	return json.NewEncoder(w).Encode(&v)
+}
+
+// ReadJSON reads the SparseR1CS from the JSON layout of json.go, and returns its coefficients
+func (cs *SparseR1CS) ReadJSON(r io.Reader) ([]big.Int, error) {
+	var v jsonSparseR1CS
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return nil, err
+	}
+	coeffs, err := cs.ConstraintSystem.fromJSON(&v.jsonSystem, jsonFormatSparseR1CS)
+	if err != nil {
+		return nil, err
+	}
+	cs.Constraints = nil
+	if v.Constraints != nil {
+		cs.Constraints = make([]SparseR1C, len(v.Constraints))
+		for i := range v.Constraints {
+			c := SparseR1C(v.Constraints[i])
+			if err := cs.checkTerms(LinearExpression{c.L, c.R, c.O, c.M[0], c.M[1]}, len(coeffs)); err != nil {
+				return nil, fmt.Errorf("constraint %d: %w", i, err)
+			}
+			if c.K < 0 || c.K >= len(coeffs) {
+				return nil, fmt.Errorf("constraint %d: coefficient %d out of range", i, c.K)
+			}
+			cs.Constraints[i] = c
+		}
+	}
+	for _, cID := range v.LookupTable {
+		if cID < 0 || cID >= len(coeffs) {
+			return nil, fmt.Errorf("lookup table: coefficient %d out of range", cID)
+		}
+	}
+	for _, cID := range v.Lookups {
+		if cID < 0 || cID >= len(cs.Constraints) {
+			return nil, fmt.Errorf("lookups: constraint %d out of range", cID)
+		}
+	}
+	if err := cs.checkLevels(len(cs.Constraints)); err != nil {
+		return nil, err
+	}
+	cs.LookupTable, cs.Lookups = v.LookupTable, v.Lookups
+	return coeffs, nil
+}
+
+// checkTerms checks that the terms of l refer to wires and coefficients of cs
+func (cs *ConstraintSystem) checkTerms(l LinearExpression, nbCoeffs int) error {
+	nbWires := cs.NbPublicVariables + cs.NbSecretVariables + cs.NbInternalVariables
+	for _, t := range l {
+		if t.WireID() >= nbWires {
+			return fmt.Errorf("wire %d out of range", t.WireID())
+		}
+		if t.CoeffID() >= nbCoeffs {
+			return fmt.Errorf("coefficient %d out of range", t.CoeffID())
+		}
+	}
+	return nil
+}
+
+// checkLogs checks that the terms of the logs refer to wires and coefficients of cs
+func (cs *ConstraintSystem) checkLogs(logs []LogEntry, nbCoeffs int) error {
+	for i := range logs {
+		for _, t := range logs[i].ToResolve {
+			if t == TermDelimitor {
+				continue
+			}
+			if err := cs.checkTerms(LinearExpression{t}, nbCoeffs); err != nil {
+				return fmt.Errorf("log %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkLevels checks that each of the nbConstraints constraints is in exactly one level
+func (cs *ConstraintSystem) checkLevels(nbConstraints int) error {
+	seen := make([]bool, nbConstraints)
+	for i, level := range cs.Levels {
+		for _, cID := range level {
+			if cID < 0 || cID >= nbConstraints {
+				return fmt.Errorf("level %d: constraint %d out of range", i, cID)
+			}
+			if seen[cID] {
+				return fmt.Errorf("level %d: constraint %d in two levels", i, cID)
+			}
+			seen[cID] = true
+		}
+	}
+	for cID := range seen {
+		if !seen[cID] {
+			return fmt.Errorf("levels: constraint %d in no level", cID)
+		}
+	}
+	return nil
+}
+
+func (cs *ConstraintSystem) toJSON(format string, coeffs []big.Int) jsonSystem {
+	v := jsonSystem{
+		Format:              format,
+		Version:             jsonVersion,
+		Curve:               cs.CurveID.String(),
+		NbPublic:            cs.NbPublicVariables,
+		NbSecret:            cs.NbSecretVariables,
+		NbInternal:          cs.NbInternalVariables,
+		Public:              cs.Public,
+		Secret:              cs.Secret,
+		HintFunctions:       cs.MHintsDependencies,
+		Levels:              cs.Levels,
+		Logs:                logsToJSON(cs.Logs),
+		DebugInfo:           logsToJSON(cs.DebugInfo),
+		ConstraintDebugInfo: cs.MDebug,
+	}
+	if cs.Schema != nil {
+		v.Schema = &jsonSchema{
+			NbPublic: cs.Schema.NbPublic,
+			NbSecret: cs.Schema.NbSecret,
+			Fields:   fieldsToJSON(cs.Schema.Fields),
+		}
+	}
+	if coeffs != nil {
+		v.Coefficients = make([]string, len(coeffs))
+		for i := range coeffs {
+			v.Coefficients[i] = coeffs[i].String()
+		}
+	}
+
+	// the hints shared by their wires, in the order of their first wire
+	if cs.MHints != nil {
+		wires := make([]int, 0, len(cs.MHints))
+		for w := range cs.MHints {
+			wires = append(wires, w)
+		}
+		sort.Ints(wires)
+		written := make(map[*Hint]bool)
+		v.Hints = make([]jsonHint, 0)
+		for _, w := range wires {
+			h := cs.MHints[w]
+			if written[h] {
+				continue
+			}
+			written[h] = true
+			v.Hints = append(v.Hints, hintToJSON(h))
+		}
+	}
+
+	if cs.Counters != nil {
+		v.Counters = make([]jsonCounter, len(cs.Counters))
+		for i, c := range cs.Counters {
+			v.Counters[i] = jsonCounter{
+				From:          c.From,
+				To:            c.To,
+				NbVariables:   c.NbVariables,
+				NbConstraints: c.NbConstraints,
+				Curve:         c.CurveID.String(),
+				Backend:       c.BackendID.String(),
+			}
+		}
+	}
+	return v
+}
+
+func (cs *ConstraintSystem) fromJSON(v *jsonSystem, format string) ([]big.Int, error) {
+	if v.Format != format {
+		return nil, fmt.Errorf("format %q, expected %q", v.Format, format)
+	}
+	if v.Version != jsonVersion {
+		return nil, fmt.Errorf("unsupported version %d", v.Version)
+	}
+	curve, err := curveFromString(v.Curve)
+	if err != nil {
+		return nil, err
+	}
+	if v.NbPublic < 1 || v.NbSecret < 0 || v.NbInternal < 0 {
+		return nil, fmt.Errorf("invalid numbers of wires %d, %d and %d", v.NbPublic, v.NbSecret, v.NbInternal)
+	}
+	*cs = ConstraintSystem{
+		NbInternalVariables: v.NbInternal,
+		NbPublicVariables:   v.NbPublic,
+		NbSecretVariables:   v.NbSecret,
+		Public:              v.Public,
+		Secret:              v.Secret,
+		MDebug:              v.ConstraintDebugInfo,
+		MHintsDependencies:  v.HintFunctions,
+		Levels:              v.Levels,
+		CurveID:             curve,
+	}
+
+	var coeffs []big.Int
+	if v.Coefficients != nil {
+		coeffs = make([]big.Int, len(v.Coefficients))
+		for i, c := range v.Coefficients {
+			if _, ok := coeffs[i].SetString(c, 10); !ok || coeffs[i].Sign() < 0 {
+				return nil, fmt.Errorf("coefficient %d: invalid %q", i, c)
+			}
+		}
+	}
+
+	if v.Schema != nil {
+		fields, err := fieldsFromJSON(v.Schema.Fields)
+		if err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+		cs.Schema = &schema.Schema{Fields: fields, NbPublic: v.Schema.NbPublic, NbSecret: v.Schema.NbSecret}
+	}
+
+	cs.Logs = logsFromJSON(v.Logs)
+	cs.DebugInfo = logsFromJSON(v.DebugInfo)
+	if err := cs.checkLogs(cs.Logs, len(coeffs)); err != nil {
+		return nil, fmt.Errorf("logs: %w", err)
+	}
+	if err := cs.checkLogs(cs.DebugInfo, len(coeffs)); err != nil {
+		return nil, fmt.Errorf("debug info: %w", err)
+	}
+	for cID, dID := range cs.MDebug {
+		if dID < 0 || dID >= len(cs.DebugInfo) {
+			return nil, fmt.Errorf("constraint %d: debug info %d out of range", cID, dID)
+		}
+	}
+
+	nbWires := cs.NbPublicVariables + cs.NbSecretVariables + cs.NbInternalVariables
+	if v.Hints != nil {
+		cs.MHints = make(map[int]*Hint)
+		for i := range v.Hints {
+			h, err := hintFromJSON(&v.Hints[i])
+			if err != nil {
+				return nil, fmt.Errorf("hint %d: %w", i, err)
+			}
+			if _, ok := cs.MHintsDependencies[h.ID]; !ok {
+				return nil, fmt.Errorf("hint %d: unknown hint function %d", i, h.ID)
+			}
+			for j, in := range h.Inputs {
+				var l LinearExpression
+				switch t := in.(type) {
+				case LinearExpression:
+					l = t
+				case Term:
+					l = LinearExpression{t}
+				}
+				if err := cs.checkTerms(l, len(coeffs)); err != nil {
+					return nil, fmt.Errorf("hint %d: input %d: %w", i, j, err)
+				}
+			}
+			for _, w := range h.Wires {
+				if w < 0 || w >= nbWires {
+					return nil, fmt.Errorf("hint %d: wire %d out of range", i, w)
+				}
+				if _, ok := cs.MHints[w]; ok {
+					return nil, fmt.Errorf("hint %d: wire %d computed by two hints", i, w)
+				}
+				cs.MHints[w] = h
+			}
+		}
+	}
+
+	if v.Counters != nil {
+		cs.Counters = make([]Counter, len(v.Counters))
+		for i, c := range v.Counters {
+			curve, err := curveFromString(c.Curve)
+			if err != nil {
+				return nil, fmt.Errorf("counter %d: %w", i, err)
+			}
+			b, err := backendFromString(c.Backend)
+			if err != nil {
+				return nil, fmt.Errorf("counter %d: %w", i, err)
+			}
+			cs.Counters[i] = Counter{
+				From:          c.From,
+				To:            c.To,
+				NbVariables:   c.NbVariables,
+				NbConstraints: c.NbConstraints,
+				CurveID:       curve,
+				BackendID:     b,
+			}
+		}
+	}
+	return coeffs, nil
+}
+
+func hintToJSON(h *Hint) jsonHint {
+	res := jsonHint{ID: h.ID, Name: h.Name, Wires: h.Wires}
+	if h.Inputs != nil {
+		res.Inputs = make([]jsonHintInput, len(h.Inputs))
+	}
+	for i, in := range h.Inputs {
+		switch t := in.(type) {
+		case LinearExpression:
+			res.Inputs[i].LinearExpression = &t
+		case Term:
+			res.Inputs[i].Term = &t
+		case big.Int:
+			s := t.String()
+			res.Inputs[i].Constant = &s
+		case *big.Int:
+			s := t.String()
+			res.Inputs[i].Constant = &s
+		default:
+			panic(fmt.Sprintf("unexpected hint input %T", in))
+		}
+	}
+	return res
+}
+
+func hintFromJSON(v *jsonHint) (*Hint, error) {
+	h := &Hint{ID: v.ID, Name: v.Name, Wires: v.Wires}
+	if err := h.checkName(); err != nil {
+		return nil, err
+	}
+	if v.Inputs != nil {
+		h.Inputs = make([]interface{}, len(v.Inputs))
+	}
+	for i, in := range v.Inputs {
+		switch {
+		case in.LinearExpression != nil && in.Term == nil && in.Constant == nil:
+			h.Inputs[i] = *in.LinearExpression
+		case in.LinearExpression == nil && in.Term != nil && in.Constant == nil:
+			h.Inputs[i] = *in.Term
+		case in.LinearExpression == nil && in.Term == nil && in.Constant != nil:
+			var c big.Int
+			if _, ok := c.SetString(*in.Constant, 10); !ok {
+				return nil, fmt.Errorf("input %d: invalid constant %q", i, *in.Constant)
+			}
+			h.Inputs[i] = c
+		default:
+			return nil, fmt.Errorf("input %d: expected one of linearExpression, term and constant", i)
+		}
+	}
+	return h, nil
+}
+
+func logsToJSON(logs []LogEntry) []jsonLogEntry {
+	if logs == nil {
+		return nil
+	}
+	res := make([]jsonLogEntry, len(logs))
+	for i := range logs {
+		res[i] = jsonLogEntry(logs[i])
+	}
+	return res
+}
+
+func logsFromJSON(logs []jsonLogEntry) []LogEntry {
+	if logs == nil {
+		return nil
+	}
+	res := make([]LogEntry, len(logs))
+	for i := range logs {
+		res[i] = LogEntry(logs[i])
+	}
+	return res
+}
+
+var fieldTypes = map[schema.FieldType]string{schema.Leaf: "leaf", schema.Array: "array", schema.Struct: "struct"}
+
+func fieldsToJSON(fields []schema.Field) []jsonField {
+	if fields == nil {
+		return nil
+	}
+	res := make([]jsonField, len(fields))
+	for i, f := range fields {
+		res[i] = jsonField{
+			Name:       f.Name,
+			Tag:        f.NameTag,
+			Visibility: f.Visibility.String(),
+			Type:       fieldTypes[f.Type],
+			ArraySize:  f.ArraySize,
+			SubFields:  fieldsToJSON(f.SubFields),
+		}
+	}
+	return res
+}
+
+func fieldsFromJSON(fields []jsonField) ([]schema.Field, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	res := make([]schema.Field, len(fields))
+	for i, f := range fields {
+		visibility, err := visibilityFromString(f.Visibility)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		typ, ok := schema.FieldType(0), false
+		for t, s := range fieldTypes {
+			if s == f.Type {
+				typ, ok = t, true
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("field %s: unknown type %q", f.Name, f.Type)
+		}
+		subFields, err := fieldsFromJSON(f.SubFields)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = schema.Field{
+			Name:       f.Name,
+			NameTag:    f.Tag,
+			Visibility: visibility,
+			Type:       typ,
+			SubFields:  subFields,
+			ArraySize:  f.ArraySize,
+		}
+	}
+	return res, nil
+}
+
+// MarshalJSON writes t as [wire, coefficient, visibility], and TermDelimitor as null
+func (t Term) MarshalJSON() ([]byte, error) {
+	if t == TermDelimitor {
+		return []byte("null"), nil
+	}
+	return json.Marshal([]interface{}{t.WireID(), t.CoeffID(), t.VariableVisibility().String()})
+}
+
+// UnmarshalJSON reads t from [wire, coefficient, visibility], or null for TermDelimitor
+func (t *Term) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = TermDelimitor
+		return nil
+	}
+	var (
+		wireID, coeffID int
+		visibility      string
+	)
+	v := []interface{}{&wireID, &coeffID, &visibility}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v) != 3 {
+		return fmt.Errorf("term with %d fields", len(v))
+	}
+	if wireID < 0 || uint64(wireID) > maskWireID || coeffID < 0 || uint64(coeffID) > maskCoeffID>>shiftCoeffID {
+		return fmt.Errorf("term [%d, %d] out of range", wireID, coeffID)
+	}
+	vis, err := visibilityFromString(visibility)
+	if err != nil {
+		return err
+	}
+	*t = Pack(wireID, coeffID, vis)
+	return nil
+}
+
+func visibilityFromString(s string) (schema.Visibility, error) {
+	for _, v := range []schema.Visibility{schema.Unset, schema.Internal, schema.Secret, schema.Public, schema.Virtual} {
+		if v.String() == s {
+			return v, nil
+		}
+	}
+	return schema.Unset, fmt.Errorf("unknown visibility %q", s)
+}
+
+func curveFromString(s string) (ecc.ID, error) {
+	for _, id := range append(ecc.Implemented(), ecc.UNKNOWN) {
+		if id.String() == s {
+			return id, nil
+		}
+	}
+	return ecc.UNKNOWN, fmt.Errorf("unknown curve %q", s)
+}
+
+func backendFromString(s string) (backend.ID, error) {
+	for _, id := range []backend.ID{backend.UNKNOWN, backend.GROTH16, backend.PLONK} {
+		if id.String() == s {
+			return id, nil
+		}
+	}
+	return backend.UNKNOWN, fmt.Errorf("unknown backend %q", s)
+}
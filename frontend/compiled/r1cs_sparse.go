@@ -23,6 +23,12 @@ import (
 type SparseR1CS struct {
 	ConstraintSystem
 	Constraints []SparseR1C
+
+	// LookupTable stores the coefficient IDs of the entries of the lookup table
+	LookupTable []int
+
+	// Lookups stores the IDs of the constraints whose L wire is looked up in the table
+	Lookups []int
 }
 
 // GetNbConstraints returns the number of constraints
@@ -30,6 +36,11 @@ func (cs *SparseR1CS) GetNbConstraints() int {
 	return len(cs.Constraints)
 }
 
+// HasLookups returns true if the constraint system looks up values in a table
+func (cs *SparseR1CS) HasLookups() bool {
+	return len(cs.Lookups) != 0
+}
+
 // SparseR1C used to compute the wires
 // L+R+M[0]M[1]+O+k=0
 // if a Term is zero, it means the field doesn't exist (ex M=[0,0] means there is no multiplicative term)
@@ -57,6 +57,10 @@ type scs struct {
 
 	// map for recording boolean constrained variables (to not constrain them twice)
 	mtBooleans map[int]struct{}
+
+	// lookup table (coefficient IDs) and constraints whose L wire is looked up
+	lookupTable []int
+	lookups     []int
 }
 
 // initialCapacity has quite some impact on frontend performance, especially on large circuits size
@@ -180,6 +184,58 @@ func (system *scs) MarkBoolean(v frontend.Variable) {
 	system.mtBooleans[int(v.(compiled.Term))] = struct{}{}
 }
 
+// SetLookupTable sets the entries of the table used by Lookup. The entries must be constants.
+func (system *scs) SetLookupTable(entries ...frontend.Variable) {
+	if system.lookupTable != nil {
+		panic("SetLookupTable called multiple times")
+	}
+	if len(entries) == 0 {
+		panic("lookup table is empty")
+	}
+	system.lookupTable = make([]int, len(entries))
+	mod := system.CurveID.ScalarField()
+	for i, e := range entries {
+		c, ok := system.ConstantValue(e)
+		if !ok {
+			panic("lookup table entries must be constants")
+		}
+		c.Mod(c, mod)
+		system.lookupTable[i] = system.st.CoeffID(c)
+	}
+}
+
+// Lookup constrains v to be one of the entries of the lookup table.
+//
+// It adds a constraint whose coefficients are all zero and whose L wire is v, the
+// membership of v in the table is enforced by the lookup argument of the backend.
+func (system *scs) Lookup(v frontend.Variable) {
+	if system.lookupTable == nil {
+		panic("Lookup called before SetLookupTable")
+	}
+	if c, ok := system.ConstantValue(v); ok {
+		c.Mod(c, system.CurveID.ScalarField())
+		for _, cID := range system.lookupTable {
+			if system.st.Coeffs[cID].Cmp(c) == 0 {
+				return
+			}
+		}
+		panic(fmt.Sprintf("lookup failed: constant(%s) is not in the table", c.String()))
+	}
+
+	t := v.(compiled.Term)
+	debug := system.AddDebugInfo("lookup", t, " ∈ table")
+
+	// the looked up wire must hold v itself, not a multiple of it
+	if cID, _, _ := t.Unpack(); cID != compiled.CoeffIdOne {
+		o := system.newInternalVariable()
+		system.addPlonkConstraint(t, system.zero(), o, cID, compiled.CoeffIdZero, compiled.CoeffIdZero, compiled.CoeffIdZero, compiled.CoeffIdMinusOne, compiled.CoeffIdZero, debug)
+		t = o
+	}
+
+	system.lookups = append(system.lookups, len(system.Constraints))
+	system.addPlonkConstraint(t, system.zero(), system.zero(), compiled.CoeffIdZero, compiled.CoeffIdZero, compiled.CoeffIdZero, compiled.CoeffIdZero, compiled.CoeffIdZero, compiled.CoeffIdZero, debug)
+}
+
 // checkVariables perform post compilation checks on the Variables
 //
 // 1. checks that all user inputs are referenced in at least one constraint
@@ -236,6 +292,12 @@ func (system *scs) checkVariables() error {
 		}
 
 	}
+	// a looked up wire is constrained even though its coefficient is zero
+	for _, cID := range system.lookups {
+		t := system.Constraints[cID].L
+		t.SetCoeffID(compiled.CoeffIdOne)
+		processTerm(t)
+	}
 	for _, c := range system.Constraints {
 		processTerm(c.L)
 		processTerm(c.R)
@@ -313,6 +375,8 @@ func (cs *scs) Compile() (frontend.CompiledConstraintSystem, error) {
 	res := compiled.SparseR1CS{
 		ConstraintSystem: cs.ConstraintSystem,
 		Constraints:      cs.Constraints,
+		LookupTable:      cs.lookupTable,
+		Lookups:          cs.lookups,
 	}
 	// sanity check
 	if res.NbPublicVariables != len(cs.Public) || res.NbPublicVariables != cs.Schema.NbPublic {
@@ -325,23 +389,27 @@ func (cs *scs) Compile() (frontend.CompiledConstraintSystem, error) {
 	// build levels
 	res.Levels = buildLevels(res)
 
-	switch cs.CurveID {
+	return newSparseR1CS(res, cs.st.Coeffs), nil
+}
+
+// newSparseR1CS returns the sparse constraint system of the curve of res
+func newSparseR1CS(res compiled.SparseR1CS, coefficients []big.Int) frontend.CompiledConstraintSystem {
+	switch res.CurveID {
 	case ecc.BLS12_377:
-		return bls12377r1cs.NewSparseR1CS(res, cs.st.Coeffs), nil
+		return bls12377r1cs.NewSparseR1CS(res, coefficients)
 	case ecc.BLS12_381:
-		return bls12381r1cs.NewSparseR1CS(res, cs.st.Coeffs), nil
+		return bls12381r1cs.NewSparseR1CS(res, coefficients)
 	case ecc.BN254:
-		return bn254r1cs.NewSparseR1CS(res, cs.st.Coeffs), nil
+		return bn254r1cs.NewSparseR1CS(res, coefficients)
 	case ecc.BW6_761:
-		return bw6761r1cs.NewSparseR1CS(res, cs.st.Coeffs), nil
+		return bw6761r1cs.NewSparseR1CS(res, coefficients)
 	case ecc.BLS24_315:
-		return bls24315r1cs.NewSparseR1CS(res, cs.st.Coeffs), nil
+		return bls24315r1cs.NewSparseR1CS(res, coefficients)
 	case ecc.BW6_633:
-		return bw6633r1cs.NewSparseR1CS(res, cs.st.Coeffs), nil
+		return bw6633r1cs.NewSparseR1CS(res, coefficients)
 	default:
 		panic("unknown curveID")
 	}
-
 }
 
 func (cs *scs) SetSchema(s *schema.Schema) {
@@ -541,7 +609,7 @@ func (system *scs) NewHint(f hint.Function, nbOutputs int, inputs ...frontend.Va
 		res[i] = r
 	}
 
-	ch := &compiled.Hint{ID: hintUUID, Inputs: hintInputs, Wires: varIDs}
+	ch := &compiled.Hint{ID: hintUUID, Name: hintID, Inputs: hintInputs, Wires: varIDs}
 	for _, vID := range varIDs {
 		system.MHints[vID] = ch
 	}
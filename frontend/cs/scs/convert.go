@@ -0,0 +1,617 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scs
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
+	"github.com/consensys/gnark/frontend/cs"
+	"github.com/consensys/gnark/frontend/schema"
+	bls12377r1cs "github.com/consensys/gnark/internal/backend/bls12-377/cs"
+	bls12381r1cs "github.com/consensys/gnark/internal/backend/bls12-381/cs"
+	bls24315r1cs "github.com/consensys/gnark/internal/backend/bls24-315/cs"
+	bn254r1cs "github.com/consensys/gnark/internal/backend/bn254/cs"
+	bw6633r1cs "github.com/consensys/gnark/internal/backend/bw6-633/cs"
+	bw6761r1cs "github.com/consensys/gnark/internal/backend/bw6-761/cs"
+	"github.com/consensys/gnark/logger"
+)
+
+// FromR1CS converts a rank-1 constraint system, coefficients being its coefficient
+// table, into a sparse one for plonk and the Pianist backends, without running the
+// circuit again.
+//
+// The linear expressions of each R1C are summed by balanced addition chains, whose
+// partial sums are shared across the constraints: x + c·y is computed once, and its
+// multiples reuse it. An R1C costs then a single constraint besides the sums, two when
+// it solves a wire next to other terms of a product.
+//
+// The R1C must be solvable in order, as the R1CS solver requires: each of them has at
+// most one wire which neither an input, a hint nor a previous R1C solves.
+func FromR1CS(r1cs compiled.R1CS, coefficients []big.Int) (frontend.CompiledConstraintSystem, error) {
+	res, coeffs, err := convert(r1cs, coefficients)
+	if err != nil {
+		return nil, err
+	}
+	logger.Logger().Info().
+		Str("curve", r1cs.CurveID.String()).
+		Int("nbR1C", len(r1cs.Constraints)).
+		Int("nbConstraints", len(res.Constraints)).
+		Msg("converted rank-1 constraint system")
+	return newSparseR1CS(res, coeffs), nil
+}
+
+// FromCompiledR1CS converts ccs, compiled with the r1cs builder, into a sparse
+// constraint system as FromR1CS does
+func FromCompiledR1CS(ccs frontend.CompiledConstraintSystem) (frontend.CompiledConstraintSystem, error) {
+	var coefficients []big.Int
+	switch r := ccs.(type) {
+	case *bls12377r1cs.R1CS:
+		coefficients = make([]big.Int, len(r.Coefficients))
+		for i := range r.Coefficients {
+			r.Coefficients[i].ToBigIntRegular(&coefficients[i])
+		}
+		return FromR1CS(r.R1CS, coefficients)
+	case *bls12381r1cs.R1CS:
+		coefficients = make([]big.Int, len(r.Coefficients))
+		for i := range r.Coefficients {
+			r.Coefficients[i].ToBigIntRegular(&coefficients[i])
+		}
+		return FromR1CS(r.R1CS, coefficients)
+	case *bn254r1cs.R1CS:
+		coefficients = make([]big.Int, len(r.Coefficients))
+		for i := range r.Coefficients {
+			r.Coefficients[i].ToBigIntRegular(&coefficients[i])
+		}
+		return FromR1CS(r.R1CS, coefficients)
+	case *bw6761r1cs.R1CS:
+		coefficients = make([]big.Int, len(r.Coefficients))
+		for i := range r.Coefficients {
+			r.Coefficients[i].ToBigIntRegular(&coefficients[i])
+		}
+		return FromR1CS(r.R1CS, coefficients)
+	case *bls24315r1cs.R1CS:
+		coefficients = make([]big.Int, len(r.Coefficients))
+		for i := range r.Coefficients {
+			r.Coefficients[i].ToBigIntRegular(&coefficients[i])
+		}
+		return FromR1CS(r.R1CS, coefficients)
+	case *bw6633r1cs.R1CS:
+		coefficients = make([]big.Int, len(r.Coefficients))
+		for i := range r.Coefficients {
+			r.Coefficients[i].ToBigIntRegular(&coefficients[i])
+		}
+		return FromR1CS(r.R1CS, coefficients)
+	default:
+		return nil, fmt.Errorf("%T isn't a rank-1 constraint system", ccs)
+	}
+}
+
+// sumKey identifies the partial sum x + d·y, x and y being terms of coefficient zero
+type sumKey struct {
+	x, y compiled.Term
+	d    int
+}
+
+type converter struct {
+	r1cs compiled.R1CS
+	q    *big.Int
+
+	st     cs.CoeffTable
+	coeffs []int // coefficient IDs of the R1CS to IDs of st
+
+	constraints []compiled.SparseR1C
+	mDebug      map[int]int
+	debugID     int // debug info of the R1C being converted, -1 if none
+
+	nbWires int    // wires of the sparse system so far
+	known   []bool // wires the solver knows once the constraints so far are solved
+
+	sums map[sumKey]compiled.Term // shared partial sums
+	one  compiled.Term            // wire holding 1 for the hint inputs, if needed
+}
+
+func convert(r1cs compiled.R1CS, coefficients []big.Int) (compiled.SparseR1CS, []big.Int, error) {
+	if r1cs.NbPublicVariables == 0 {
+		return compiled.SparseR1CS{}, nil, fmt.Errorf("the constraint system has no constant wire")
+	}
+	c := converter{
+		r1cs:    r1cs,
+		q:       r1cs.CurveID.ScalarField(),
+		st:      cs.NewCoeffTable(),
+		coeffs:  make([]int, len(coefficients)),
+		mDebug:  make(map[int]int),
+		nbWires: r1cs.NbPublicVariables - 1 + r1cs.NbSecretVariables + r1cs.NbInternalVariables,
+		sums:    make(map[sumKey]compiled.Term),
+	}
+	for i := range coefficients {
+		c.coeffs[i] = c.coeffID(&coefficients[i])
+	}
+
+	// the inputs and the outputs of the hints are known from the start
+	c.known = make([]bool, c.nbWires)
+	for i := 0; i < r1cs.NbPublicVariables-1+r1cs.NbSecretVariables; i++ {
+		c.known[i] = true
+	}
+	for wID := range r1cs.MHints {
+		c.known[wID-1] = true
+	}
+	mHints := c.hints()
+
+	for i, r1c := range r1cs.Constraints {
+		c.debugID = -1
+		if id, ok := r1cs.MDebug[i]; ok {
+			c.debugID = id
+		}
+		if err := c.convert(r1c); err != nil {
+			return compiled.SparseR1CS{}, nil, fmt.Errorf("R1C %d: %w", i, err)
+		}
+	}
+	c.debugID = -1
+	c.referenceHints()
+
+	res := compiled.SparseR1CS{
+		ConstraintSystem: r1cs.ConstraintSystem,
+		Constraints:      c.constraints,
+	}
+	res.NbPublicVariables--
+	res.NbInternalVariables = c.nbWires - res.NbPublicVariables - res.NbSecretVariables
+	res.Public = r1cs.Public[1:]
+	res.MHints = mHints
+	res.MDebug = c.mDebug
+	res.Logs = c.logs(r1cs.Logs)
+	res.DebugInfo = c.logs(r1cs.DebugInfo)
+	res.Counters = nil
+	res.Levels = buildLevels(res)
+	return res, c.st.Coeffs, nil
+}
+
+// referenceHints references the outputs of the hints which no constraint references,
+// the R1C referencing them having vanished, as the solver wouldn't solve them otherwise
+func (c *converter) referenceHints() {
+	referenced := make(map[int]bool)
+	for _, sc := range c.constraints {
+		for _, t := range []compiled.Term{sc.L, sc.R, sc.O, sc.M[0], sc.M[1]} {
+			if t.CoeffID() != compiled.CoeffIdZero {
+				referenced[t.WireID()] = true
+			}
+		}
+	}
+	wires := make([]int, 0, len(c.r1cs.MHints))
+	for wID := range c.r1cs.MHints {
+		wires = append(wires, wID-1)
+	}
+	sort.Ints(wires)
+	for _, wID := range wires {
+		h := c.r1cs.MHints[wID+1]
+		done := false
+		for _, w := range h.Wires {
+			done = done || referenced[w-1]
+		}
+		if done {
+			continue
+		}
+		// w - w = 0
+		t := compiled.Pack(wID, compiled.CoeffIdOne, schema.Internal)
+		c.addConstraint(t, compiled.Term(0), c.withCoeff(t, big.NewInt(-1)), new(big.Int), new(big.Int), new(big.Int))
+		referenced[wID] = true
+	}
+}
+
+// coeffID returns the ID of v mod q in the coefficient table
+func (c *converter) coeffID(v *big.Int) int {
+	var r big.Int
+	r.Mod(v, c.q)
+	if r.Sign() != 0 && new(big.Int).Add(&r, big.NewInt(1)).Cmp(c.q) == 0 {
+		return compiled.CoeffIdMinusOne
+	}
+	return c.st.CoeffID(&r)
+}
+
+// coeff returns the coefficient of t mod q
+func (c *converter) coeff(t compiled.Term) *big.Int {
+	return new(big.Int).Mod(&c.st.Coeffs[t.CoeffID()], c.q)
+}
+
+// value returns the coefficient of the term t of the R1CS mod q
+func (c *converter) value(t compiled.Term) *big.Int {
+	return new(big.Int).Mod(&c.st.Coeffs[c.coeffs[t.CoeffID()]], c.q)
+}
+
+// withCoeff returns t with the coefficient v
+func (c *converter) withCoeff(t compiled.Term, v *big.Int) compiled.Term {
+	t.SetCoeffID(c.coeffID(v))
+	return t
+}
+
+// term returns the term of the sparse system for the term t of the R1CS, whose wire
+// mustn't be the constant wire
+func (c *converter) term(t compiled.Term) compiled.Term {
+	cID, wID, visibility := t.Unpack()
+	return compiled.Pack(wID-1, c.coeffs[cID], visibility)
+}
+
+// isOne tells whether t is on the constant wire of the R1CS
+func isOne(t compiled.Term) bool {
+	return t.WireID() == 0 && t.VariableVisibility() == schema.Public
+}
+
+// split returns the terms of l in the sparse system, the null ones dropped, and the
+// constant of l
+func (c *converter) split(l compiled.LinearExpression) ([]compiled.Term, *big.Int) {
+	terms := make([]compiled.Term, 0, len(l))
+	k := new(big.Int)
+	for _, t := range l {
+		if isOne(t) {
+			k.Add(k, c.value(t))
+			continue
+		}
+		if c.value(t).Sign() == 0 {
+			continue
+		}
+		terms = append(terms, c.term(t))
+	}
+	return terms, k.Mod(k, c.q)
+}
+
+func (c *converter) newWire() compiled.Term {
+	t := compiled.Pack(c.nbWires, compiled.CoeffIdOne, schema.Internal)
+	c.nbWires++
+	c.known = append(c.known, true)
+	return t
+}
+
+// addConstraint adds the constraint qL·l + qR·r + qM·l·r + qO·o + k = 0, the
+// coefficients of l and r being qL and qR, the one of o qO, and qM = m0·m1
+func (c *converter) addConstraint(l, r, o compiled.Term, m0, m1 *big.Int, k *big.Int) {
+	if c.debugID >= 0 {
+		c.mDebug[len(c.constraints)] = c.debugID
+	}
+	c.constraints = append(c.constraints, compiled.SparseR1C{
+		L: l,
+		R: r,
+		O: o,
+		M: [2]compiled.Term{c.withCoeff(l, m0), c.withCoeff(r, m1)},
+		K: c.coeffID(k),
+	})
+}
+
+// combine returns a term equal to a + b
+func (c *converter) combine(a, b compiled.Term) compiled.Term {
+	if a.WireID() == b.WireID() {
+		return c.withCoeff(a, new(big.Int).Add(c.coeff(a), c.coeff(b)))
+	}
+	if a.WireID() > b.WireID() {
+		a, b = b, a
+	}
+
+	// a + b = ca·(x + d·y)
+	ca := c.coeff(a)
+	d := new(big.Int).ModInverse(ca, c.q)
+	d.Mul(d, c.coeff(b)).Mod(d, c.q)
+	key := sumKey{x: a, y: b, d: c.coeffID(d)}
+	key.x.SetCoeffID(compiled.CoeffIdZero)
+	key.y.SetCoeffID(compiled.CoeffIdZero)
+
+	s, ok := c.sums[key]
+	if !ok {
+		s = c.newWire()
+		c.addConstraint(c.withCoeff(a, big.NewInt(1)), c.withCoeff(b, d), c.withCoeff(s, big.NewInt(-1)), new(big.Int), new(big.Int), new(big.Int))
+		c.sums[key] = s
+	}
+	return c.withCoeff(s, ca)
+}
+
+// reduce sums terms into at most max terms, pairing the terms level by level so that
+// the chains are balanced and the partial sums of the same terms are shared
+func (c *converter) reduce(terms []compiled.Term, max int) []compiled.Term {
+	terms = c.merge(terms)
+	for len(terms) > max {
+		nbPairs := len(terms) - max
+		if nbPairs > len(terms)/2 {
+			nbPairs = len(terms) / 2
+		}
+		next := make([]compiled.Term, 0, len(terms)-nbPairs)
+		for i := 0; i < nbPairs; i++ {
+			next = append(next, c.combine(terms[2*i], terms[2*i+1]))
+		}
+		terms = c.merge(append(next, terms[2*nbPairs:]...))
+	}
+	return terms
+}
+
+// merge sorts terms by wire, sums the terms of the same wire, and drops the null ones
+func (c *converter) merge(terms []compiled.Term) []compiled.Term {
+	sort.Slice(terms, func(i, j int) bool { return terms[i].WireID() < terms[j].WireID() })
+	res := terms[:0]
+	for _, t := range terms {
+		if n := len(res); n != 0 && res[n-1].WireID() == t.WireID() {
+			res[n-1] = c.combine(res[n-1], t)
+		} else {
+			res = append(res, t)
+		}
+	}
+	nonZero := res[:0]
+	for _, t := range res {
+		if c.coeff(t).Sign() != 0 {
+			nonZero = append(nonZero, t)
+		}
+	}
+	return nonZero
+}
+
+// single returns a term equal to the sum of terms, the zero term if there is none
+func (c *converter) single(terms []compiled.Term) compiled.Term {
+	terms = c.reduce(terms, 1)
+	if len(terms) == 0 {
+		return compiled.Term(0)
+	}
+	return terms[0]
+}
+
+// scale returns the terms multiplied by k
+func (c *converter) scale(terms []compiled.Term, k *big.Int) []compiled.Term {
+	res := make([]compiled.Term, len(terms))
+	for i, t := range terms {
+		v := c.coeff(t)
+		res[i] = c.withCoeff(t, v.Mul(v, k))
+	}
+	return res
+}
+
+// linear adds the constraint Σ terms + k (+ u) = 0, solving the wire of u if u isn't
+// nil
+func (c *converter) linear(terms []compiled.Term, k *big.Int, u *compiled.Term) {
+	max := 3
+	if u != nil {
+		max = 2
+	}
+	terms = c.reduce(terms, max)
+	var slots [3]compiled.Term
+	copy(slots[:], terms)
+	if u != nil {
+		slots[2] = *u
+	}
+	c.addConstraint(slots[0], slots[1], slots[2], new(big.Int), new(big.Int), k)
+}
+
+// product adds the constraint (x + kx)·(y + ky) = o + ko
+func (c *converter) product(x compiled.Term, kx *big.Int, y compiled.Term, ky *big.Int, o compiled.Term, ko *big.Int) {
+	vx, vy := c.coeff(x), c.coeff(y)
+	k := new(big.Int).Mul(kx, ky)
+	k.Sub(k, ko)
+	c.addConstraint(
+		c.withCoeff(x, new(big.Int).Mul(vx, ky)),
+		c.withCoeff(y, new(big.Int).Mul(vy, kx)),
+		c.withCoeff(o, new(big.Int).Neg(c.coeff(o))),
+		vx, vy, k,
+	)
+}
+
+// neg returns the opposite of terms
+func (c *converter) neg(terms []compiled.Term) []compiled.Term {
+	return c.scale(terms, big.NewInt(-1))
+}
+
+// convert adds the constraints of L·R = O
+func (c *converter) convert(r1c compiled.R1C) error {
+	l, kl := c.split(r1c.L)
+	r, kr := c.split(r1c.R)
+	o, ko := c.split(r1c.O)
+
+	// find the wire the constraint solves, if any
+	var u *compiled.Term
+	where := -1
+	for i, terms := range [][]compiled.Term{l, r, o} {
+		for j := range terms {
+			if c.known[terms[j].WireID()] {
+				continue
+			}
+			if u != nil {
+				return fmt.Errorf("more than one wire to solve")
+			}
+			u, where = &terms[j], i
+		}
+	}
+	if u == nil {
+		c.convertProduct(l, kl, r, kr, o, ko, nil)
+		return nil
+	}
+	t := *u
+	remove := func(terms []compiled.Term) []compiled.Term {
+		res := make([]compiled.Term, 0, len(terms)-1)
+		for _, s := range terms {
+			if s != t {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	switch where {
+	case 0:
+		if err := c.convertFactor(t, remove(l), kl, r, kr, o, ko); err != nil {
+			return err
+		}
+	case 1:
+		if err := c.convertFactor(t, remove(r), kr, l, kl, o, ko); err != nil {
+			return err
+		}
+	default:
+		c.convertProduct(l, kl, r, kr, remove(o), ko, &t)
+	}
+	c.known[t.WireID()] = true
+	return nil
+}
+
+// convertProduct adds the constraints of (l + kl)·(r + kr) = o + ko + u, solving the wire
+// of u if u isn't nil
+func (c *converter) convertProduct(l []compiled.Term, kl *big.Int, r []compiled.Term, kr *big.Int, o []compiled.Term, ko *big.Int, u *compiled.Term) {
+	var nu *compiled.Term
+	if u != nil {
+		t := c.neg([]compiled.Term{*u})[0]
+		nu = &t
+	}
+
+	if len(l) == 0 || len(r) == 0 {
+		// linear: kl·r + kr·l - o + kl·kr - ko - u = 0, one of l and r being empty
+		terms := append(c.scale(r, kl), c.scale(l, kr)...)
+		terms = append(terms, c.neg(o)...)
+		k := new(big.Int).Mul(kl, kr)
+		c.linear(terms, k.Sub(k, ko), nu)
+		return
+	}
+
+	x, y := c.single(l), c.single(r)
+	switch {
+	case u == nil:
+		c.product(x, kl, y, kr, c.single(o), ko)
+	case len(o) == 0:
+		c.product(x, kl, y, kr, *u, ko)
+	default:
+		// the sparse constraint has no room for the wire to solve next to the product
+		// and o, the product gets its own wire
+		m := c.newWire()
+		c.product(x, kl, y, kr, m, new(big.Int))
+		c.linear(append([]compiled.Term{m}, c.neg(o)...), new(big.Int).Neg(ko), nu)
+	}
+}
+
+// convertFactor adds the constraints of (u + l + kl)·(r + kr) = o + ko, solving the wire
+// of u
+func (c *converter) convertFactor(u compiled.Term, l []compiled.Term, kl *big.Int, r []compiled.Term, kr *big.Int, o []compiled.Term, ko *big.Int) error {
+	if len(r) == 0 {
+		if kr.Sign() == 0 {
+			return fmt.Errorf("the wire to solve is multiplied by zero")
+		}
+		// linear: kr·u + kr·l - o + kl·kr - ko = 0
+		uk := c.scale([]compiled.Term{u}, kr)[0]
+		terms := append(c.scale(l, kr), c.neg(o)...)
+		k := new(big.Int).Mul(kl, kr)
+		c.linear(terms, k.Sub(k, ko), &uk)
+		return nil
+	}
+
+	// u·(y + kr) + p - o - ko = 0 with p = (l + kl)·(y + kr)
+	y := c.single(r)
+	var rest []compiled.Term
+	k := new(big.Int).Neg(ko)
+	if len(l) != 0 {
+		p := c.newWire()
+		c.product(c.single(l), kl, y, kr, p, new(big.Int))
+		rest = append(rest, p)
+	} else {
+		rest = append(rest, c.scale([]compiled.Term{y}, kl)...)
+		k.Add(k, new(big.Int).Mul(kl, kr))
+	}
+	o2 := c.single(append(rest, c.neg(o)...))
+
+	// the solver solves L from R and O
+	vu := c.coeff(u)
+	c.addConstraint(
+		c.withCoeff(u, new(big.Int).Mul(vu, kr)),
+		c.withCoeff(y, new(big.Int)),
+		o2,
+		vu, c.coeff(y), k,
+	)
+	return nil
+}
+
+// hints returns the hints of the sparse system. The inputs on the constant wire become
+// constants, or terms of a wire holding 1 when they're mixed with other terms.
+func (c *converter) hints() map[int]*compiled.Hint {
+	res := make(map[int]*compiled.Hint, len(c.r1cs.MHints))
+	converted := make(map[*compiled.Hint]*compiled.Hint)
+	for wID, h := range c.r1cs.MHints {
+		nh, ok := converted[h]
+		if !ok {
+			nh = &compiled.Hint{ID: h.ID, Name: h.Name, Inputs: make([]interface{}, len(h.Inputs)), Wires: make([]int, len(h.Wires))}
+			for i, in := range h.Inputs {
+				nh.Inputs[i] = c.hintInput(in)
+			}
+			for i, w := range h.Wires {
+				nh.Wires[i] = w - 1
+			}
+			converted[h] = nh
+		}
+		res[wID-1] = nh
+	}
+	return res
+}
+
+func (c *converter) hintInput(in interface{}) interface{} {
+	switch t := in.(type) {
+	case compiled.Term:
+		if isOne(t) {
+			return *c.value(t)
+		}
+		return c.term(t)
+	case compiled.LinearExpression:
+		res := make(compiled.LinearExpression, 0, len(t))
+		k := new(big.Int)
+		for _, s := range t {
+			if isOne(s) {
+				k.Add(k, c.value(s))
+			} else {
+				res = append(res, c.term(s))
+			}
+		}
+		if len(res) == 0 {
+			return *k.Mod(k, c.q)
+		}
+		if k.Mod(k, c.q).Sign() != 0 {
+			res = append(res, c.withCoeff(c.oneWire(), k))
+		}
+		return res
+	default:
+		return in
+	}
+}
+
+// oneWire returns a wire holding 1, constrained first
+func (c *converter) oneWire() compiled.Term {
+	if c.one == 0 {
+		c.one = c.newWire()
+		c.debugID = -1
+		c.addConstraint(compiled.Term(0), compiled.Term(0), c.withCoeff(c.one, big.NewInt(-1)), new(big.Int), new(big.Int), big.NewInt(1))
+	}
+	return c.one
+}
+
+// logs returns the log entries of the sparse system
+func (c *converter) logs(entries []compiled.LogEntry) []compiled.LogEntry {
+	res := make([]compiled.LogEntry, len(entries))
+	for i, e := range entries {
+		res[i] = e
+		res[i].ToResolve = make([]compiled.Term, len(e.ToResolve))
+		for j, t := range e.ToResolve {
+			switch {
+			case t == compiled.TermDelimitor:
+				res[i].ToResolve[j] = t
+			case t.VariableVisibility() == schema.Virtual:
+				res[i].ToResolve[j] = t
+				res[i].ToResolve[j].SetCoeffID(c.coeffs[t.CoeffID()])
+			case isOne(t):
+				// the sparse system has no constant wire, its constants are virtual
+				res[i].ToResolve[j] = compiled.Pack(0, c.coeffs[t.CoeffID()], schema.Virtual)
+			default:
+				res[i].ToResolve[j] = c.term(t)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scs
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+type convertCircuit struct {
+	X, Y frontend.Variable `gnark:",public"`
+	Z    [4]frontend.Variable
+}
+
+func (c *convertCircuit) Define(api frontend.API) error {
+	// the same sums in several constraints
+	s := api.Add(c.Z[0], c.Z[1], c.Z[2], c.Z[3])
+	p := api.Mul(s, c.X)
+	api.AssertIsEqual(api.Mul(api.Add(c.Z[0], c.Z[1], c.Z[2], c.Z[3], 1), c.Y), api.Add(api.Mul(s, c.Y), c.Y))
+
+	// hints and solved factors
+	q := api.Div(p, api.Add(c.Y, 3))
+	bits := api.ToBinary(c.Z[0], 8)
+	api.AssertIsEqual(api.FromBinary(bits...), c.Z[0])
+	api.Println("q", q, api.Add(q, 2))
+	api.AssertIsDifferent(q, 0)
+	return nil
+}
+
+func TestFromCompiledR1CS(t *testing.T) {
+	for _, curve := range gnark.Curves() {
+		ccs, err := frontend.Compile(curve, r1cs.NewBuilder, &convertCircuit{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sparse, err := FromCompiledR1CS(ccs)
+		if err != nil {
+			t.Fatalf("%s: %v", curve, err)
+		}
+		if sparse.GetSchema().NbPublic != 2 {
+			t.Fatalf("%s: %d public variables, expected 2", curve, sparse.GetSchema().NbPublic)
+		}
+
+		// Z sums to 5
+		assignment := &convertCircuit{X: 7, Y: 11, Z: [4]frontend.Variable{200, 2, -196, -1}}
+		witness, err := frontend.NewWitness(assignment, curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sparse.IsSolved(witness); err != nil {
+			t.Fatalf("%s: %v", curve, err)
+		}
+
+		// Z[0] doesn't fit on 8 bits
+		assignment.Z[0], assignment.Z[3] = 256, -57
+		witness, err = frontend.NewWitness(assignment, curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sparse.IsSolved(witness); err == nil {
+			t.Fatalf("%s: expected an error", curve)
+		}
+	}
+}
+
+func TestFromR1CSNotR1CS(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, NewBuilder, &convertCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FromCompiledR1CS(ccs); err == nil {
+		t.Fatal("expected an error")
+	}
+}
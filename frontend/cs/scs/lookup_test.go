@@ -0,0 +1,118 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	bn254r1cs "github.com/consensys/gnark/internal/backend/bn254/cs"
+)
+
+// lookupCircuit checks that its inputs are below 16
+type lookupCircuit struct {
+	X   [3]frontend.Variable
+	Sum frontend.Variable `gnark:",public"`
+}
+
+func (c *lookupCircuit) Define(api frontend.API) error {
+	l := api.Compiler().(frontend.Lookuper)
+	entries := make([]frontend.Variable, 16)
+	for i := range entries {
+		entries[i] = i
+	}
+	l.SetLookupTable(entries...)
+
+	l.Lookup(c.X[0])
+	l.Lookup(c.X[1])
+	// a multiple of a variable is copied to a wire of its own first
+	l.Lookup(api.Mul(c.X[2], 3))
+	// a constant of the table adds no constraint
+	l.Lookup(7)
+
+	api.AssertIsEqual(api.Add(c.X[0], c.X[1], c.X[2]), c.Sum)
+	return nil
+}
+
+func TestLookup(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BN254, NewBuilder, &lookupCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spr := ccs.(*bn254r1cs.SparseR1CS)
+	if len(spr.LookupTable) != 16 {
+		t.Fatalf("table of %d entries, expected 16", len(spr.LookupTable))
+	}
+	if len(spr.Lookups) != 3 {
+		t.Fatalf("%d lookups, expected 3", len(spr.Lookups))
+	}
+	for _, cID := range spr.Lookups {
+		if cID < 0 || cID >= len(spr.Constraints) {
+			t.Fatalf("lookup of constraint %d out of range", cID)
+		}
+	}
+}
+
+// misuseCircuit calls the lookup API as define does
+type misuseCircuit struct {
+	X      frontend.Variable
+	define func(l frontend.Lookuper, x frontend.Variable)
+}
+
+func (c *misuseCircuit) Define(api frontend.API) error {
+	c.define(api.Compiler().(frontend.Lookuper), c.X)
+	return nil
+}
+
+func TestLookupMisuse(t *testing.T) {
+	for name, tc := range map[string]struct {
+		define func(l frontend.Lookuper, x frontend.Variable)
+		err    string
+	}{
+		"lookup before the table": {
+			func(l frontend.Lookuper, x frontend.Variable) { l.Lookup(x) },
+			"Lookup called before SetLookupTable",
+		},
+		"table set twice": {
+			func(l frontend.Lookuper, x frontend.Variable) {
+				l.SetLookupTable(1)
+				l.SetLookupTable(2)
+			},
+			"SetLookupTable called multiple times",
+		},
+		"empty table": {
+			func(l frontend.Lookuper, x frontend.Variable) { l.SetLookupTable() },
+			"lookup table is empty",
+		},
+		"variable entry": {
+			func(l frontend.Lookuper, x frontend.Variable) { l.SetLookupTable(1, x) },
+			"lookup table entries must be constants",
+		},
+		"constant out of the table": {
+			func(l frontend.Lookuper, x frontend.Variable) {
+				l.SetLookupTable(1, 2)
+				l.Lookup(3)
+			},
+			"lookup failed",
+		},
+	} {
+		_, err := frontend.Compile(ecc.BN254, NewBuilder, &misuseCircuit{define: tc.define})
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got %v, expected %q", name, err, tc.err)
+		}
+	}
+}
@@ -534,3 +534,26 @@ func (cs *SparseR1CS) ReadFrom(r io.Reader) (int64, error) {
 	err = decoder.Decode(cs)
 	return int64(decoder.NumBytesRead()), err
 }
+
+// WriteJSON writes the SparseR1CS in the portable JSON layout of compiled.SparseR1CS.WriteJSON
+func (cs *SparseR1CS) WriteJSON(w io.Writer) error {
+	return cs.SparseR1CS.WriteJSON(w, coefficientsToBigInt(cs.Coefficients))
+}
+
+// Fingerprint returns the canonical hash of the SparseR1CS, see compiled.Fingerprint
+func (cs *SparseR1CS) Fingerprint() compiled.Fingerprint {
+	return cs.SparseR1CS.Fingerprint(coefficientsToBigInt(cs.Coefficients))
+}
+
+// ReadJSON reads the SparseR1CS from the portable JSON layout of compiled.SparseR1CS.ReadJSON
+func (cs *SparseR1CS) ReadJSON(r io.Reader) error {
+	coefficients, err := cs.SparseR1CS.ReadJSON(r)
+	if err != nil {
+		return err
+	}
+	if cs.SparseR1CS.CurveID != ecc.BLS12_377 {
+		return fmt.Errorf("constraint system of %s, expected %s", cs.SparseR1CS.CurveID, ecc.BLS12_377)
+	}
+	cs.Coefficients, err = coefficientsFromBigInt(coefficients)
+	return err
+}
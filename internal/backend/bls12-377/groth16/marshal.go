@@ -17,7 +17,11 @@
 package groth16
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
 	curve "github.com/consensys/gnark-crypto/ecc/bls12-377"
+	"github.com/consensys/gnark/frontend/compiled"
 	"io"
 )
 
@@ -129,6 +133,7 @@ func (vk *VerifyingKey) writeTo(w io.Writer, raw bool) (int64, error) {
 
 // ReadFrom attempts to decode a VerifyingKey from reader
 // VerifyingKey must be encoded through WriteTo (compressed) or WriteRawTo (uncompressed)
+// the key read has no fingerprint, see ReadVersionedFrom
 // serialization format:
 // https://github.com/zkcrypto/bellman/blob/fa9be45588227a8c6ec34957de3f68705f07bd92/src/groth16/mod.rs#L143
 // [α]1,[β]1,[β]2,[γ]2,[δ]1,[δ]2,uint32(len(Kvk)),[Kvk]1
@@ -170,6 +175,9 @@ func (vk *VerifyingKey) readFrom(r io.Reader, decOptions ...func(*curve.Decoder)
 		return dec.BytesRead(), err
 	}
 
+	// the bellman format has no fingerprint
+	vk.CircuitFingerprint = compiled.Fingerprint{}
+
 	// recompute vk.e (e(α, β)) and  -[δ]2, -[γ]2
 	var err error
 	vk.e, err = curve.Pair([]curve.G1Affine{vk.G1.Alpha}, []curve.G2Affine{vk.G2.Beta})
@@ -182,6 +190,57 @@ func (vk *VerifyingKey) readFrom(r io.Reader, decOptions ...func(*curve.Decoder)
 	return dec.BytesRead(), nil
 }
 
+// vkMagic prefixes the versioned encoding of VerifyingKey, the version being its last
+// byte. The version 1 is the bellman format of WriteTo followed by the fingerprint of
+// the constraint system.
+const (
+	vkMagic   uint64 = 0x673136766b000000 // "g16vk\x00\x00\x00"
+	vkVersion uint64 = 1
+)
+
+// WriteVersionedTo writes the versioned encoding of the key, which records the
+// fingerprint of the constraint system the bellman format has no room for:
+// uint64(vkMagic|vkVersion), the encoding of WriteTo, then the fingerprint (32 bytes)
+// use ReadVersionedFrom(...) to decode it
+func (vk *VerifyingKey) WriteVersionedTo(w io.Writer) (int64, error) {
+	var header [8]byte
+	binary.BigEndian.PutUint64(header[:], vkMagic|vkVersion)
+	n, err := w.Write(header[:])
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := vk.writeTo(w, false)
+	written := int64(n) + m
+	if err != nil {
+		return written, err
+	}
+	n, err = w.Write(vk.CircuitFingerprint[:])
+	return written + int64(n), err
+}
+
+// ReadVersionedFrom attempts to decode a VerifyingKey encoded through WriteVersionedTo,
+// with the fingerprint of its constraint system
+func (vk *VerifyingKey) ReadVersionedFrom(r io.Reader) (int64, error) {
+	var header [8]byte
+	if n, err := io.ReadFull(r, header[:]); err != nil {
+		return int64(n), err
+	}
+	h := binary.BigEndian.Uint64(header[:])
+	if h&^0xff != vkMagic {
+		return 8, errors.New("not a versioned verifying key")
+	}
+	if version := h & 0xff; version == 0 || version > vkVersion {
+		return 8, fmt.Errorf("unknown verifying key format version %d", version)
+	}
+	m, err := vk.readFrom(r)
+	read := 8 + m
+	if err != nil {
+		return read, err
+	}
+	n, err := io.ReadFull(r, vk.CircuitFingerprint[:])
+	return read + int64(n), err
+}
+
 // WriteTo writes binary encoding of the key elements to writer
 // points are compressed
 // use WriteRawTo(...) to encode the key without point compression
@@ -17,7 +17,6 @@
 package plonk
 
 import (
-	"crypto/sha256"
 	"math/big"
 	"math/bits"
 	"runtime"
@@ -36,6 +35,7 @@ import (
 
 	"github.com/consensys/gnark/internal/backend/bls12-377/cs"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/fiat-shamir"
 	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/internal/utils"
@@ -66,7 +66,10 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls12_377witness.Witn
 	log := logger.Logger().With().Str("curve", spr.CurveID().String()).Int("nbConstraints", len(spr.Constraints)).Str("backend", "plonk").Logger()
 	start := time.Now()
 	// pick a hash function that will be used to derive the challenges
-	hFunc := sha256.New()
+	hFunc, err := pk.Vk.TranscriptHash.New(ecc.BLS12_377)
+	if err != nil {
+		return nil, err
+	}
 
 	// create a transcript manager to apply Fiat Shamir
 	fs := fiatshamir.NewTranscript(hFunc, "gamma", "beta", "alpha", "zeta")
@@ -76,7 +79,6 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bls12_377witness.Witn
 
 	// compute the constraint system solution
 	var solution []fr.Element
-	var err error
 	if solution, err = spr.Solve(fullWitness, opt); err != nil {
 		if !opt.Force {
 			return nil, err
@@ -17,7 +17,6 @@
 package plonk
 
 import (
-	"crypto/sha256"
 	"errors"
 	"math/big"
 	"time"
@@ -44,7 +43,10 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bls12_377witness.Witne
 	start := time.Now()
 
 	// pick a hash function to derive the challenge (the same as in the prover)
-	hFunc := sha256.New()
+	hFunc, err := vk.TranscriptHash.New(ecc.BLS12_377)
+	if err != nil {
+		return err
+	}
 
 	// transcript to derive the challenge
 	fs := fiatshamir.NewTranscript(hFunc, "gamma", "beta", "alpha", "zeta")
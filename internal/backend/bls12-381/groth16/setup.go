@@ -74,6 +74,10 @@ type VerifyingKey struct {
 
 	// e(α, β)
 	e curve.GT // not serialized
+
+	// CircuitFingerprint is the fingerprint of the constraint system the key was set up
+	// for, zero if unknown
+	CircuitFingerprint compiled.Fingerprint
 }
 
 // Setup constructs the SRS
@@ -272,6 +276,8 @@ func Setup(r1cs *cs.R1CS, pk *ProvingKey, vk *VerifyingKey) error {
 	// set domain
 	pk.Domain = *domain
 
+	vk.CircuitFingerprint = r1cs.Fingerprint()
+
 	return nil
 }
 
@@ -571,6 +577,12 @@ func (vk *VerifyingKey) NbG2() int {
 	return 3
 }
 
+// Fingerprint returns the fingerprint of the constraint system the VerifyingKey was set
+// up for, zero if unknown
+func (vk *VerifyingKey) Fingerprint() compiled.Fingerprint {
+	return vk.CircuitFingerprint
+}
+
 // NbG1 returns the number of G1 elements in the ProvingKey
 func (pk *ProvingKey) NbG1() int {
 	return 3 + len(pk.G1.A) + len(pk.G1.B) + len(pk.G1.Z) + len(pk.G1.K)
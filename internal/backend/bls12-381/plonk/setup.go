@@ -21,6 +21,8 @@ import (
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/fft"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend/compiled"
 	"github.com/consensys/gnark/internal/backend/bls12-381/cs"
 
 	kzgg "github.com/consensys/gnark-crypto/kzg"
@@ -83,6 +85,13 @@ type VerifyingKey struct {
 	// Commitments to ql, qr, qm, qo prepended with as many zeroes (ones for l) as there are public inputs.
 	// In particular Qk is not complete.
 	Ql, Qr, Qm, Qo, Qk kzg.Digest
+
+	// TranscriptHash is the hash function of the Fiat-Shamir transcript
+	TranscriptHash backend.TranscriptHash
+
+	// CircuitFingerprint is the fingerprint of the constraint system the key was set up
+	// for
+	CircuitFingerprint compiled.Fingerprint
 }
 
 // Setup sets proving and verifying keys
@@ -190,6 +199,8 @@ func Setup(spr *cs.SparseR1CS, srs *kzg.SRS) (*ProvingKey, *VerifyingKey, error)
 		return nil, nil, err
 	}
 
+	vk.CircuitFingerprint = spr.Fingerprint()
+
 	return &pk, &vk, nil
 
 }
@@ -347,6 +358,12 @@ func (vk *VerifyingKey) NbPublicWitness() int {
 	return int(vk.NbPublicVariables)
 }
 
+// Fingerprint returns the fingerprint of the constraint system the VerifyingKey was set
+// up for
+func (vk *VerifyingKey) Fingerprint() compiled.Fingerprint {
+	return vk.CircuitFingerprint
+}
+
 // VerifyingKey returns pk.Vk
 func (pk *ProvingKey) VerifyingKey() interface{} {
 	return pk.Vk
@@ -18,9 +18,13 @@ package cs_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/compiled"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
 	"github.com/consensys/gnark/internal/backend/circuits"
 	"reflect"
 	"testing"
@@ -116,6 +120,186 @@ func TestSerialization(t *testing.T) {
 	}
 }
 
+func TestJSONSerialization(t *testing.T) {
+
+	for name := range circuits.Circuits {
+		t.Run(name, func(t *testing.T) {
+			tc := circuits.Circuits[name]
+			for _, newBuilder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+				ccs, err := frontend.Compile(ecc.BLS24_315, newBuilder, tc.Circuit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if testing.Short() && ccs.GetNbConstraints() > 50 {
+					return
+				}
+
+				var buffer bytes.Buffer
+				if err := ccs.WriteJSON(&buffer); err != nil {
+					t.Fatal(err)
+				}
+				var reconstructed, other frontend.CompiledConstraintSystem
+				if _, ok := ccs.(*cs.R1CS); ok {
+					reconstructed, other = new(cs.R1CS), new(cs.SparseR1CS)
+				} else {
+					reconstructed, other = new(cs.SparseR1CS), new(cs.R1CS)
+				}
+				if err := other.ReadJSON(bytes.NewReader(buffer.Bytes())); err == nil {
+					t.Fatal("reading a constraint system of another kind should fail")
+				}
+				if err := reconstructed.ReadJSON(&buffer); err != nil {
+					t.Fatal(err)
+				}
+
+				// compare the hashes of the binary serializations
+				if csHash(t, ccs) != csHash(t, reconstructed) {
+					t.Fatal("round trip JSON serialization failed")
+				}
+			}
+		})
+	}
+}
+
+func TestJSONMalformed(t *testing.T) {
+
+	for _, newBuilder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+		ccs, err := frontend.Compile(ecc.BLS24_315, newBuilder, circuits.Circuits["hint"].Circuit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buffer bytes.Buffer
+		if err := ccs.WriteJSON(&buffer); err != nil {
+			t.Fatal(err)
+		}
+		internal, secret, public := ccs.GetNbVariables()
+		outOfRange := []interface{}{internal + secret + public, 0, "internal"}
+		nbConstraints := ccs.GetNbConstraints()
+
+		corruptions := map[string]func(v map[string]interface{}){
+			"none": func(v map[string]interface{}) {},
+			"level out of range": func(v map[string]interface{}) {
+				v["levels"] = append(v["levels"].([]interface{}), []interface{}{nbConstraints})
+			},
+			"constraint in two levels": func(v map[string]interface{}) {
+				v["levels"] = append(v["levels"].([]interface{}), []interface{}{0})
+			},
+			"constraint in no level": func(v map[string]interface{}) {
+				levels := v["levels"].([]interface{})
+				v["levels"] = levels[:len(levels)-1]
+			},
+			"hint input out of range": func(v map[string]interface{}) {
+				hint := v["hints"].([]interface{})[0].(map[string]interface{})
+				hint["inputs"] = []interface{}{map[string]interface{}{"term": outOfRange}}
+			},
+			"hint name of another ID": func(v map[string]interface{}) {
+				hint := v["hints"].([]interface{})[0].(map[string]interface{})
+				hint["name"] = hint["name"].(string) + "/v2"
+			},
+			"log out of range": func(v map[string]interface{}) {
+				v["logs"] = []interface{}{map[string]interface{}{"caller": "", "format": "%s", "toResolve": []interface{}{outOfRange}}}
+			},
+		}
+		for name, corrupt := range corruptions {
+			var v map[string]interface{}
+			decoder := json.NewDecoder(bytes.NewReader(buffer.Bytes()))
+			decoder.UseNumber()
+			if err := decoder.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			corrupt(v)
+			data, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var reconstructed frontend.CompiledConstraintSystem
+			if _, ok := ccs.(*cs.R1CS); ok {
+				reconstructed = new(cs.R1CS)
+			} else {
+				reconstructed = new(cs.SparseR1CS)
+			}
+			err = reconstructed.ReadJSON(bytes.NewReader(data))
+			if name == "none" && err != nil {
+				t.Fatal(err)
+			}
+			if name != "none" && err == nil {
+				t.Fatalf("%s: reading should fail", name)
+			}
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+
+	for name := range circuits.Circuits {
+		t.Run(name, func(t *testing.T) {
+			tc := circuits.Circuits[name]
+			fingerprints := make(map[compiled.Fingerprint]bool)
+			for _, newBuilder := range []frontend.NewBuilder{r1cs.NewBuilder, scs.NewBuilder} {
+				ccs, err := frontend.Compile(ecc.BLS24_315, newBuilder, tc.Circuit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if testing.Short() && ccs.GetNbConstraints() > 50 {
+					return
+				}
+
+				// compiling again and serializing keep the fingerprint
+				ccs2, err := frontend.Compile(ecc.BLS24_315, newBuilder, tc.Circuit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ccs.Fingerprint() != ccs2.Fingerprint() {
+					t.Fatal("compiling twice gave two fingerprints")
+				}
+				var buffer bytes.Buffer
+				if _, err := ccs.WriteTo(&buffer); err != nil {
+					t.Fatal(err)
+				}
+				var reconstructed frontend.CompiledConstraintSystem
+				if _, ok := ccs.(*cs.R1CS); ok {
+					reconstructed = new(cs.R1CS)
+				} else {
+					reconstructed = new(cs.SparseR1CS)
+				}
+				if _, err := reconstructed.ReadFrom(&buffer); err != nil {
+					t.Fatal(err)
+				}
+				if reconstructed.Fingerprint() != ccs.Fingerprint() {
+					t.Fatal("the serialization changed the fingerprint")
+				}
+				fingerprints[ccs.Fingerprint()] = true
+
+				// changing a coefficient changes the fingerprint
+				switch c := reconstructed.(type) {
+				case *cs.R1CS:
+					c.Coefficients[len(c.Coefficients)-1].Neg(&c.Coefficients[len(c.Coefficients)-1])
+				case *cs.SparseR1CS:
+					c.Coefficients[len(c.Coefficients)-1].Neg(&c.Coefficients[len(c.Coefficients)-1])
+				}
+				if reconstructed.Fingerprint() == ccs.Fingerprint() {
+					t.Fatal("changing a coefficient kept the fingerprint")
+				}
+			}
+			if len(fingerprints) != 2 {
+				t.Fatal("the R1CS and the SparseR1CS have the same fingerprint")
+			}
+		})
+	}
+}
+
+// csHash returns the SHA-256 of the binary serialization of ccs
+func csHash(t *testing.T, ccs frontend.CompiledConstraintSystem) [sha256.Size]byte {
+	t.Helper()
+	h := sha256.New()
+	if _, err := ccs.WriteTo(h); err != nil {
+		t.Fatal(err)
+	}
+	var res [sha256.Size]byte
+	copy(res[:], h.Sum(nil))
+	return res
+}
+
 const n = 10000
 
 type circuit struct {
@@ -20,8 +20,21 @@ import (
 	curve "github.com/consensys/gnark-crypto/ecc/bls24-315"
 
 	"errors"
+	"fmt"
 	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr"
 	"io"
+
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend/compiled"
+)
+
+// vkMagic prefixes the versioned encodings of VerifyingKey, the version being its last
+// byte. The unversioned encoding, written before the transcript hash was recorded,
+// starts with Size, a power of two, so that the two can't be confused. The version 1
+// adds the transcript hash, and the version 2 the fingerprint of the constraint system.
+const (
+	vkMagic   uint64 = 0x706c6f6e6b766b00 // "plonkvk\x00"
+	vkVersion uint64 = 2
 )
 
 // WriteTo writes binary encoding of Proof to w
@@ -183,6 +196,7 @@ func (vk *VerifyingKey) WriteTo(w io.Writer) (n int64, err error) {
 	enc := curve.NewEncoder(w)
 
 	toEncode := []interface{}{
+		vkMagic | vkVersion,
 		vk.Size,
 		&vk.SizeInv,
 		&vk.Generator,
@@ -195,6 +209,7 @@ func (vk *VerifyingKey) WriteTo(w io.Writer) (n int64, err error) {
 		&vk.Qm,
 		&vk.Qo,
 		&vk.Qk,
+		uint64(vk.TranscriptHash),
 	}
 
 	for _, v := range toEncode {
@@ -203,14 +218,34 @@ func (vk *VerifyingKey) WriteTo(w io.Writer) (n int64, err error) {
 		}
 	}
 
-	return enc.BytesWritten(), nil
+	// fingerprint of the constraint system
+	n2, err := w.Write(vk.CircuitFingerprint[:])
+	return enc.BytesWritten() + int64(n2), err
 }
 
-// ReadFrom reads from binary representation in r into VerifyingKey
+// ReadFrom reads from binary representation in r into VerifyingKey, which may be
+// unversioned, the transcript hash being then SHA256, or of the version 1, the
+// fingerprint being then zero
 func (vk *VerifyingKey) ReadFrom(r io.Reader) (int64, error) {
 	dec := curve.NewDecoder(r)
+
+	var header uint64
+	if err := dec.Decode(&header); err != nil {
+		return dec.BytesRead(), err
+	}
+	version := uint64(0)
+	if header&^0xff == vkMagic {
+		if version = header & 0xff; version > vkVersion {
+			return dec.BytesRead(), fmt.Errorf("unknown verifying key format version %d", version)
+		}
+		if err := dec.Decode(&vk.Size); err != nil {
+			return dec.BytesRead(), err
+		}
+	} else {
+		vk.Size = header
+	}
+
 	toDecode := []interface{}{
-		&vk.Size,
 		&vk.SizeInv,
 		&vk.Generator,
 		&vk.NbPublicVariables,
@@ -230,5 +265,20 @@ func (vk *VerifyingKey) ReadFrom(r io.Reader) (int64, error) {
 		}
 	}
 
-	return dec.BytesRead(), nil
+	vk.TranscriptHash = backend.SHA256
+	if version >= 1 {
+		var transcriptHash uint64
+		if err := dec.Decode(&transcriptHash); err != nil {
+			return dec.BytesRead(), err
+		}
+		vk.TranscriptHash = backend.TranscriptHash(transcriptHash)
+	}
+
+	// fingerprint of the constraint system
+	vk.CircuitFingerprint = compiled.Fingerprint{}
+	if version < 2 {
+		return dec.BytesRead(), nil
+	}
+	n, err := io.ReadFull(r, vk.CircuitFingerprint[:])
+	return dec.BytesRead() + int64(n), err
 }
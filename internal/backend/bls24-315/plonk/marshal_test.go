@@ -23,6 +23,7 @@ import (
 
 	"bytes"
 	"github.com/consensys/gnark-crypto/ecc/bls24-315/fr/fft"
+	"github.com/consensys/gnark/backend"
 	"reflect"
 	"testing"
 )
@@ -103,6 +104,8 @@ func TestVerifyingKeySerialization(t *testing.T) {
 	vk.Qm = g1gen
 	vk.Qo = g1gen
 	vk.Qk = g1gen
+	vk.TranscriptHash = backend.KECCAK256
+	vk.CircuitFingerprint[0] = 42
 
 	var buf bytes.Buffer
 	written, err := vk.WriteTo(&buf)
@@ -125,3 +128,53 @@ func TestVerifyingKeySerialization(t *testing.T) {
 		t.Fatal("bytes written / read don't match")
 	}
 }
+
+func TestVerifyingKeyUnversioned(t *testing.T) {
+	var vk VerifyingKey
+	vk.Size = 42
+	vk.SizeInv = fr.One()
+	vk.NbPublicVariables = 8
+
+	_, _, g1gen, _ := curve.Generators()
+	vk.S[0] = g1gen
+	vk.S[1] = g1gen
+	vk.S[2] = g1gen
+	vk.Ql = g1gen
+	vk.Qr = g1gen
+	vk.Qm = g1gen
+	vk.Qo = g1gen
+	vk.Qk = g1gen
+
+	// a key serialized before the encoding was versioned, without the transcript hash
+	var buf bytes.Buffer
+	enc := curve.NewEncoder(&buf)
+	toEncode := []interface{}{
+		vk.Size,
+		&vk.SizeInv,
+		&vk.Generator,
+		vk.NbPublicVariables,
+		&vk.S[0],
+		&vk.S[1],
+		&vk.S[2],
+		&vk.Ql,
+		&vk.Qr,
+		&vk.Qm,
+		&vk.Qo,
+		&vk.Qk,
+	}
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// it reads back with the default transcript hash, and without fingerprint
+	reconstructed := VerifyingKey{TranscriptHash: backend.KECCAK256}
+	reconstructed.CircuitFingerprint[0] = 42
+	if _, err := reconstructed.ReadFrom(&buf); err != nil {
+		t.Fatal("coudln't deserialize", err)
+	}
+	if !reflect.DeepEqual(&vk, &reconstructed) {
+		t.Fatal("reconstructed object don't match original")
+	}
+}
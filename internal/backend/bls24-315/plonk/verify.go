@@ -17,7 +17,6 @@
 package plonk
 
 import (
-	"crypto/sha256"
 	"errors"
 	"math/big"
 	"time"
@@ -44,7 +43,10 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bls24_315witness.Witne
 	start := time.Now()
 
 	// pick a hash function to derive the challenge (the same as in the prover)
-	hFunc := sha256.New()
+	hFunc, err := vk.TranscriptHash.New(ecc.BLS24_315)
+	if err != nil {
+		return err
+	}
 
 	// transcript to derive the challenge
 	fs := fiatshamir.NewTranscript(hFunc, "gamma", "beta", "alpha", "zeta")
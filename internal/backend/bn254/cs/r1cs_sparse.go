@@ -118,6 +118,11 @@ func (cs *SparseR1CS) Solve(witness []fr.Element, opt backend.ProverConfig) ([]f
 		return solution.values, err
 	}
 
+	if err := cs.checkLookups(&solution); err != nil {
+		log.Err(errors.New("unsatisfied lookup")).Int("id", err.CID).Send()
+		return solution.values, err
+	}
+
 	// sanity check; ensure all wires are marked as "instantiated"
 	if !solution.isValid() {
 		log.Err(errors.New("solver didn't instantiate all wires")).Send()
@@ -492,6 +497,28 @@ func (cs *SparseR1CS) checkConstraint(c compiled.SparseR1C, solution *solution)
 
 }
 
+// checkLookups verifies that the looked up wires hold entries of the lookup table
+func (cs *SparseR1CS) checkLookups(solution *solution) *UnsatisfiedConstraintError {
+	if len(cs.Lookups) == 0 {
+		return nil
+	}
+	table := make(map[fr.Element]struct{}, len(cs.LookupTable))
+	for _, cID := range cs.LookupTable {
+		table[cs.Coefficients[cID]] = struct{}{}
+	}
+	for _, cID := range cs.Lookups {
+		v := solution.values[cs.Constraints[cID].L.WireID()]
+		if _, ok := table[v]; !ok {
+			if dID, ok := cs.MDebug[cID]; ok {
+				errMsg := solution.logValue(cs.DebugInfo[dID])
+				return &UnsatisfiedConstraintError{CID: cID, DebugInfo: &errMsg}
+			}
+			return &UnsatisfiedConstraintError{CID: cID, Err: fmt.Errorf("%s is not in the lookup table", v.String())}
+		}
+	}
+	return nil
+}
+
 // FrSize return fr.Limbs * 8, size in byte of a fr element
 func (cs *SparseR1CS) FrSize() int {
 	return fr.Limbs * 8
@@ -534,3 +561,26 @@ func (cs *SparseR1CS) ReadFrom(r io.Reader) (int64, error) {
 	err = decoder.Decode(cs)
 	return int64(decoder.NumBytesRead()), err
 }
+
+// WriteJSON writes the SparseR1CS in the portable JSON layout of compiled.SparseR1CS.WriteJSON
+func (cs *SparseR1CS) WriteJSON(w io.Writer) error {
+	return cs.SparseR1CS.WriteJSON(w, coefficientsToBigInt(cs.Coefficients))
+}
+
+// Fingerprint returns the canonical hash of the SparseR1CS, see compiled.Fingerprint
+func (cs *SparseR1CS) Fingerprint() compiled.Fingerprint {
+	return cs.SparseR1CS.Fingerprint(coefficientsToBigInt(cs.Coefficients))
+}
+
+// ReadJSON reads the SparseR1CS from the portable JSON layout of compiled.SparseR1CS.ReadJSON
+func (cs *SparseR1CS) ReadJSON(r io.Reader) error {
+	coefficients, err := cs.SparseR1CS.ReadJSON(r)
+	if err != nil {
+		return err
+	}
+	if cs.SparseR1CS.CurveID != ecc.BN254 {
+		return fmt.Errorf("constraint system of %s, expected %s", cs.SparseR1CS.CurveID, ecc.BN254)
+	}
+	cs.Coefficients, err = coefficientsFromBigInt(coefficients)
+	return err
+}
@@ -17,7 +17,6 @@
 package cs
 
 import (
-	"errors"
 	"fmt"
 	"math/big"
 	"sync/atomic"
@@ -54,15 +53,8 @@ func newSolution(nbWires int, hintFunctions map[hint.ID]hint.Function, hintsDepe
 	}
 
 	// hintsDependencies is from compile time; it contains the list of hints the solver **needs**
-	var missing []string
-	for hintUUID, hintID := range hintsDependencies {
-		if _, ok := s.mHintsFunctions[hintUUID]; !ok {
-			missing = append(missing, hintID)
-		}
-	}
-
-	if len(missing) > 0 {
-		return s, fmt.Errorf("solver missing hint(s): %v", missing)
+	if err := hint.CheckMissing(hintsDependencies, s.mHintsFunctions); err != nil {
+		return s, err
 	}
 
 	return s, nil
@@ -140,7 +132,7 @@ func (s *solution) solveWithHint(vID int, h *compiled.Hint) error {
 	// ensure hint function was provided
 	f, ok := s.mHintsFunctions[h.ID]
 	if !ok {
-		return errors.New("missing hint function")
+		return fmt.Errorf("%w: %s", hint.ErrMissing, h.Name)
 	}
 
 	// tmp IO big int memory
@@ -0,0 +1,100 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+// sizes of the random circuits of the tests
+const (
+	randomNbConstraints = 1 << 6
+	randomNbPublic      = 4
+)
+
+// proveRandom sets up a random circuit in a single party world and proves it nbProofs times
+func proveRandom(t *testing.T, nbProofs int) ([]*Proof, *VerifyingKey, []bn254witness.Witness) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	pk, vk, witnesses, err := SetupRandom(ecc.BN254, randomNbConstraints, randomNbPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proofs := make([]*Proof, nbProofs)
+	publicWitnesses := make([]bn254witness.Witness, nbProofs)
+	for i := range proofs {
+		publicWitnesses[i] = bn254witness.New(witnesses[0][:randomNbPublic])
+		if proofs[i], err = ProveDirect(pk, witnesses, publicWitnesses[i], opt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return proofs, vk, publicWitnesses
+}
+
+func TestBatchVerify(t *testing.T) {
+	const nbProofs = 4
+	proofs, vk, publicWitnesses := proveRandom(t, nbProofs)
+
+	if err := BatchVerify(proofs, vk, publicWitnesses); err != nil {
+		t.Fatal(err)
+	}
+
+	// the opening of Z on omegaX * alpha of another proof
+	const bad = 2
+	tampered := *proofs[bad]
+	tampered.PartialZShiftedProofs = append([]dkzg.OpeningProof(nil), proofs[bad].PartialZShiftedProofs...)
+	tampered.PartialZShiftedProofs[0].H = proofs[0].PartialZShiftedProofs[0].H
+	if err := Verify(&tampered, vk, publicWitnesses[bad]); err == nil {
+		t.Fatal("tampered proof should not verify")
+	}
+
+	batch := append([]*Proof(nil), proofs...)
+	batch[bad] = &tampered
+	err := BatchVerify(batch, vk, publicWitnesses)
+	var batchErr *BatchVerifyError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a BatchVerifyError, got %v", err)
+	}
+	if batchErr.Index != bad {
+		t.Fatalf("BatchVerifyError reports proof %d, expected %d", batchErr.Index, bad)
+	}
+
+	// a commitment of another proof fails while replaying the transcript
+	tampered = *proofs[bad]
+	tampered.Z = proofs[0].Z
+	batch[bad] = &tampered
+	err = BatchVerify(batch, vk, publicWitnesses)
+	if !errors.As(err, &batchErr) || batchErr.Index != bad {
+		t.Fatalf("expected a BatchVerifyError on proof %d, got %v", bad, err)
+	}
+
+	if err := BatchVerify(proofs, vk, publicWitnesses[1:]); err == nil {
+		t.Fatal("batch verification should fail on a missing public witness")
+	}
+}
@@ -0,0 +1,341 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/comm"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// Rounds of the prover after which its state is checkpointed, each one ending with
+// the challenges derived from its commitments.
+const (
+	// commitments to the witnesses, then gamma, etaY, etaX
+	roundWitnesses = iota + 1
+	// Z, W and the lookup accumulators, then delta and lambda
+	roundAccumulators
+	// Hx, then alpha
+	roundQuotientX
+	nbRounds = roundQuotientX
+)
+
+// checkpointVersion is written in the header of the checkpoint files
+const checkpointVersion = 2
+
+// errCheckpointMismatch is returned when a checkpoint doesn't belong to the proof
+// being resumed
+var errCheckpointMismatch = errors.New("checkpoint doesn't match the proof being resumed")
+
+// checkpointed is called after the state of each round is written, it lets the
+// tests interrupt the prover.
+var checkpointed = func(round int) error { return nil }
+
+// checkpoint persists the state of the prover of this party after each round, so that
+// a prover restarted on the same directory resumes after the last round completed by
+// all the parties instead of starting from scratch. The prover is deterministic, so a
+// resumed proof is the same as an uninterrupted one.
+//
+// The transcript isn't written as such: the challenges are derived again from the
+// commitments of the rounds loaded, and checked against the ones in the checkpoint.
+type checkpoint struct {
+	// directory of the checkpoint files, empty if checkpoints are disabled
+	dir string
+
+	// last round loaded from the checkpoint, 0 if the prover starts from scratch
+	resumed int
+
+	// challenges written after the rounds loaded
+	challenges map[int][]fr.Element
+
+	// header of the checkpoint files of this party
+	header []uint64
+}
+
+// openCheckpoint opens the checkpoint of this party in dir and agrees with the other
+// parties on the round to resume from. It is collective, and a no-op without dir.
+//
+// The header of the files binds the witness columns of this party and the public
+// inputs, so that the parties refuse to resume, all together, if any of them was
+// restarted with another witness than the one of its checkpoint.
+func openCheckpoint(dir string, pk *ProvingKey, witnesses [][]fr.Element, publicInput []fr.Element) (*checkpoint, error) {
+	c := &checkpoint{
+		dir:        dir,
+		challenges: make(map[int][]fr.Element),
+	}
+	if dir == "" {
+		return c, nil
+	}
+	c.header = append([]uint64{
+		checkpointVersion,
+		mpi.SelfRank,
+		mpi.WorldSize,
+		pk.Domain[0].Cardinality,
+		uint64(pk.RowStart),
+	}, witnessDigest(witnesses, publicInput)...)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	// last round such that all the rounds up to it are written
+	var last, mismatch uint64
+	for round := 1; round <= nbRounds; round++ {
+		ok, err := c.exists(round)
+		if errors.Is(err, errCheckpointMismatch) {
+			mismatch = 1
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		last = uint64(round)
+	}
+
+	// resume from the last round written by all the parties, unless one of them has a
+	// checkpoint of another proof
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], last)
+	binary.BigEndian.PutUint64(buf[8:], mismatch)
+	res, err := comm.Default.Reduce(buf[:], func(a, b []byte) ([]byte, error) {
+		if binary.BigEndian.Uint64(b[:8]) < binary.BigEndian.Uint64(a[:8]) {
+			copy(a[:8], b[:8])
+		}
+		if binary.BigEndian.Uint64(b[8:]) != 0 {
+			copy(a[8:], b[8:])
+		}
+		return a, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res, err = comm.Default.Broadcast(res, len(buf)); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint64(res[8:]) != 0 {
+		return nil, errCheckpointMismatch
+	}
+	c.resumed = int(binary.BigEndian.Uint64(res[:8]))
+	return c, nil
+}
+
+// witnessDigest returns the SHA-256 of the witness columns and of the public inputs, as
+// words of the header
+func witnessDigest(witnesses [][]fr.Element, publicInput []fr.Element) []uint64 {
+	h := sha256.New()
+	var buf [8]byte
+	for _, v := range append(witnesses, publicInput) {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(v)))
+		h.Write(buf[:])
+		for i := range v {
+			b := v[i].Bytes()
+			h.Write(b[:])
+		}
+	}
+	sum := h.Sum(nil)
+	res := make([]uint64, len(sum)/8)
+	for i := range res {
+		res[i] = binary.BigEndian.Uint64(sum[8*i:])
+	}
+	return res
+}
+
+// resumes returns true if the state of the round is loaded from the checkpoint
+func (c *checkpoint) resumes(round int) bool {
+	return round <= c.resumed
+}
+
+// path returns the file of the round for this party
+func (c *checkpoint) path(round int) string {
+	return filepath.Join(c.dir, fmt.Sprintf("gpiano-%d-round-%d.ckpt", mpi.SelfRank, round))
+}
+
+// exists returns true if the file of the round is written, and an error if it was
+// written for another proving key or another party
+func (c *checkpoint) exists(round int) (bool, error) {
+	f, err := os.Open(c.path(round))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if err := c.readHeader(curve.NewDecoder(bufio.NewReader(f))); err != nil {
+		return false, fmt.Errorf("%s: %w", c.path(round), err)
+	}
+	return true, nil
+}
+
+func (c *checkpoint) readHeader(dec *curve.Decoder) error {
+	for _, want := range c.header {
+		var got uint64
+		if err := dec.Decode(&got); err != nil {
+			return err
+		}
+		if got != want {
+			return errCheckpointMismatch
+		}
+	}
+	return nil
+}
+
+// load reads the state of the round into the pointers of state
+func (c *checkpoint) load(round int, state ...interface{}) error {
+	f, err := os.Open(c.path(round))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := curve.NewDecoder(bufio.NewReader(f))
+	if err := c.readHeader(dec); err != nil {
+		return err
+	}
+	var challenges []fr.Element
+	if err := dec.Decode(&challenges); err != nil {
+		return err
+	}
+	c.challenges[round] = challenges
+	for _, v := range state {
+		if m, ok := v.(*[][]fr.Element); ok {
+			var n uint64
+			if err := dec.Decode(&n); err != nil {
+				return err
+			}
+			*m = make([][]fr.Element, n)
+			for i := range *m {
+				if err := dec.Decode(&(*m)[i]); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := dec.Decode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// complete ends the round once its challenges are derived. The state of a round
+// computed by the prover is written, the challenges of a round loaded from the
+// checkpoint are checked against the ones written.
+func (c *checkpoint) complete(round int, challenges []fr.Element, state ...interface{}) error {
+	if c.dir == "" {
+		return nil
+	}
+	if c.resumes(round) {
+		want := c.challenges[round]
+		if len(want) != len(challenges) {
+			return errCheckpointMismatch
+		}
+		for i := range want {
+			if !want[i].Equal(&challenges[i]) {
+				return errCheckpointMismatch
+			}
+		}
+		return nil
+	}
+
+	// write to a temporary file first, so that an interrupted write doesn't leave
+	// a truncated round behind
+	f, err := os.CreateTemp(c.dir, filepath.Base(c.path(round))+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	w := bufio.NewWriter(f)
+	if err := c.write(curve.NewEncoder(w), challenges, state); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), c.path(round)); err != nil {
+		return err
+	}
+	return checkpointed(round)
+}
+
+func (c *checkpoint) write(enc *curve.Encoder, challenges []fr.Element, state []interface{}) error {
+	for _, v := range c.header {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+	if err := enc.Encode(challenges); err != nil {
+		return err
+	}
+	for _, v := range state {
+		var err error
+		switch t := v.(type) {
+		case *[][]fr.Element:
+			if err = enc.Encode(uint64(len(*t))); err != nil {
+				return err
+			}
+			for i := range *t {
+				if err = enc.Encode((*t)[i]); err != nil {
+					return err
+				}
+			}
+		case *[]fr.Element:
+			err = enc.Encode(*t)
+		case *[]curve.G1Affine:
+			err = enc.Encode(*t)
+		default:
+			err = enc.Encode(v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clear removes the checkpoint of this party once the proof is done, so that the
+// next proof doesn't resume from it
+func (c *checkpoint) clear() error {
+	if c.dir == "" {
+		return nil
+	}
+	for round := 1; round <= nbRounds; round++ {
+		if err := os.Remove(c.path(round)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
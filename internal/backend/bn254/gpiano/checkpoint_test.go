@@ -0,0 +1,254 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+func TestCheckpoint(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	table := make([]fr.Element, 1<<4)
+	for i := range table {
+		table[i].SetUint64(uint64(i))
+	}
+	pk, vk, witnesses, err := SetupRandomLookup(ecc.BN254, randomNbConstraints, randomNbPublic, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := bn254witness.New(witnesses[0][:randomNbPublic])
+
+	prove := func(opts ...backend.ProverOption) ([]byte, error) {
+		opt, err := backend.NewProverConfig(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := ProveDirect(pk, witnesses, publicWitness, opt)
+		if err != nil {
+			return nil, err
+		}
+		if err := Verify(proof, vk, publicWitness); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := proof.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	want, err := prove()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(f func(int) error) { checkpointed = f }(checkpointed)
+	errInterrupted := errors.New("interrupted")
+	for interrupted := 1; interrupted <= nbRounds; interrupted++ {
+		dir := t.TempDir()
+
+		// stop the prover once the round is written
+		checkpointed = func(round int) error {
+			if round == interrupted {
+				return errInterrupted
+			}
+			return nil
+		}
+		if _, err := prove(backend.WithCheckpoint(dir)); !errors.Is(err, errInterrupted) {
+			t.Fatalf("round %d: got %v, want the prover to be interrupted", interrupted, err)
+		}
+
+		// the restarted prover only computes the rounds after it
+		var written []int
+		checkpointed = func(round int) error {
+			written = append(written, round)
+			return nil
+		}
+		got, err := prove(backend.WithCheckpoint(dir))
+		if err != nil {
+			t.Fatalf("round %d: %v", interrupted, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("round %d: resumed proof differs from the uninterrupted one", interrupted)
+		}
+		if len(written) != nbRounds-interrupted || (len(written) > 0 && written[0] != interrupted+1) {
+			t.Fatalf("round %d: the resumed prover wrote rounds %v", interrupted, written)
+		}
+
+		// the checkpoint is removed once the proof is done
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Fatalf("round %d: %d files left in the checkpoint", interrupted, len(entries))
+		}
+	}
+}
+
+func TestCheckpointOtherWitness(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	pk, _, witnesses, err := SetupRandom(ecc.BN254, randomNbConstraints, randomNbPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := bn254witness.New(witnesses[0][:randomNbPublic])
+	dir := t.TempDir()
+
+	defer func(f func(int) error) { checkpointed = f }(checkpointed)
+	errInterrupted := errors.New("interrupted")
+	checkpointed = func(round int) error {
+		return errInterrupted
+	}
+	opt, err := backend.NewProverConfig(backend.WithCheckpoint(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProveDirect(pk, witnesses, publicWitness, opt); !errors.Is(err, errInterrupted) {
+		t.Fatalf("got %v, want the prover to be interrupted", err)
+	}
+
+	// a secret entry of another witness doesn't resume the rounds of the first one
+	other := make([][]fr.Element, len(witnesses))
+	for i := range witnesses {
+		other[i] = append([]fr.Element(nil), witnesses[i]...)
+	}
+	last := other[len(other)-1]
+	last[len(last)-1].SetUint64(42)
+	checkpointed = func(round int) error {
+		t.Fatalf("round %d written over the checkpoint of another witness", round)
+		return nil
+	}
+	if _, err := ProveDirect(pk, other, publicWitness, opt); !errors.Is(err, errCheckpointMismatch) {
+		t.Fatalf("got %v, want %v", err, errCheckpointMismatch)
+	}
+}
+
+func TestCheckpointTwoParties(t *testing.T) {
+	if !inWorld(t, 2) {
+		return
+	}
+
+	pk, vk, witnesses, err := SetupRandom(ecc.BN254, randomNbConstraints, randomNbPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := bn254witness.New(witnesses[0][:randomNbPublic])
+
+	prove := func(witnesses [][]fr.Element, opts ...backend.ProverOption) ([]byte, error) {
+		opt, err := backend.NewProverConfig(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := ProveDirect(pk, witnesses, publicWitness, opt)
+		if err != nil {
+			return nil, err
+		}
+		if mpi.SelfRank != 0 {
+			return nil, nil
+		}
+		if err := Verify(proof, vk, publicWitness); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := proof.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	want, err := prove(witnesses)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both parties write all the rounds, then party 1 loses the ones after the first,
+	// as if it had been interrupted earlier than party 0
+	defer func(f func(int) error) { checkpointed = f }(checkpointed)
+	errInterrupted := errors.New("interrupted")
+	checkpointed = func(round int) error {
+		if round == nbRounds {
+			return errInterrupted
+		}
+		return nil
+	}
+	dir := t.TempDir()
+	if _, err := prove(witnesses, backend.WithCheckpoint(dir)); !errors.Is(err, errInterrupted) {
+		t.Fatalf("got %v, want the prover to be interrupted", err)
+	}
+	if mpi.SelfRank == 1 {
+		c := checkpoint{dir: dir}
+		for round := roundWitnesses + 1; round <= nbRounds; round++ {
+			if err := os.Remove(c.path(round)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// both parties resume after the first round, the last one written by all of them
+	var written []int
+	checkpointed = func(round int) error {
+		written = append(written, round)
+		return nil
+	}
+	got, err := prove(witnesses, backend.WithCheckpoint(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != nbRounds-roundWitnesses || written[0] != roundWitnesses+1 {
+		t.Fatalf("party %d: the resumed prover wrote rounds %v", mpi.SelfRank, written)
+	}
+	if mpi.SelfRank == 0 && !bytes.Equal(got, want) {
+		t.Fatal("resumed proof differs from the uninterrupted one")
+	}
+
+	// party 1 restarts with another witness: both parties refuse to resume
+	checkpointed = func(round int) error {
+		return errInterrupted
+	}
+	if _, err := prove(witnesses, backend.WithCheckpoint(dir)); !errors.Is(err, errInterrupted) {
+		t.Fatalf("got %v, want the prover to be interrupted", err)
+	}
+	if mpi.SelfRank == 1 {
+		other := make([][]fr.Element, len(witnesses))
+		for i := range witnesses {
+			other[i] = append([]fr.Element(nil), witnesses[i]...)
+		}
+		last := other[len(other)-1]
+		last[len(last)-1].SetUint64(42)
+		witnesses = other
+	}
+	checkpointed = func(round int) error {
+		t.Fatalf("party %d: round %d written over the checkpoint of another witness", mpi.SelfRank, round)
+		return nil
+	}
+	if _, err := prove(witnesses, backend.WithCheckpoint(dir)); !errors.Is(err, errCheckpointMismatch) {
+		t.Fatalf("party %d: got %v, want %v", mpi.SelfRank, err, errCheckpointMismatch)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/comm"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+)
+
+// Wire identifies the wire Column of the row Row held by the party Party, Row being
+// counted from the first row of the party.
+type Wire struct {
+	Party  uint64
+	Column int
+	Row    int
+}
+
+// CircuitPart describes the rows of a circuit held by this party, for SetupDirect.
+//
+// Each row is an instance of the custom gate of gateFunc on NUM_WITNESSES wires, with
+// NUM_SELECTORS selectors.
+type CircuitPart struct {
+	// Selectors[i][j] is the selector i of row j
+	Selectors [][]fr.Element
+
+	// Permutation[k][j] is the wire to which the wire k of row j is copied. The copies
+	// of all the parties must form a permutation of their wires, a wire copied to
+	// itself being free.
+	Permutation [][]Wire
+
+	NbPublicInputs int
+}
+
+// RandomCircuit describes a circuit with random gates generated by SetupRandomCircuit.
+type RandomCircuit struct {
+	NbConstraints  int
+	NbPublicInputs int
+
+	// GateDensity is the fraction of the rows holding a random gate, the other ones
+	// have all their selectors set to zero
+	GateDensity float64
+
+	// CrossPartyWiring is the fraction of the wires copied to the same wire of the
+	// next party, for the rows held by all the parties
+	CrossPartyWiring float64
+}
+
+// SetupRandomCircuit is SetupRandom for a circuit with empty rows and copy constraints
+// across the parties, as described by circuit, committing with srs.
+func SetupRandomCircuit(curveID ecc.ID, circuit RandomCircuit, srs SRS, weights ...uint64) (*ProvingKey, *VerifyingKey, [][]fr.Element, error) {
+	return setupRandom(curveID, circuit, nil, srs, weights)
+}
+
+// SetupDirect sets proving and verifying keys for the circuit described by the parts
+// of all the parties, committing with srs. It is collective, each party passing its own
+// part.
+func SetupDirect(curveID ecc.ID, circuit *CircuitPart, srs SRS) (*ProvingKey, *VerifyingKey, error) {
+	if len(circuit.Selectors) != NUM_SELECTORS {
+		return nil, nil, fmt.Errorf("got %d selectors, want %d", len(circuit.Selectors), NUM_SELECTORS)
+	}
+	if len(circuit.Permutation) != NUM_WITNESSES {
+		return nil, nil, fmt.Errorf("got the permutation of %d wires, want %d", len(circuit.Permutation), NUM_WITNESSES)
+	}
+	nbRows := len(circuit.Selectors[0])
+	if nbRows == 0 {
+		return nil, nil, errors.New("party holds no rows")
+	}
+	for i := range circuit.Selectors {
+		if len(circuit.Selectors[i]) != nbRows {
+			return nil, nil, fmt.Errorf("selector %d has %d rows, want %d", i, len(circuit.Selectors[i]), nbRows)
+		}
+	}
+	for k := range circuit.Permutation {
+		if len(circuit.Permutation[k]) != nbRows {
+			return nil, nil, fmt.Errorf("permutation of wire %d has %d rows, want %d", k, len(circuit.Permutation[k]), nbRows)
+		}
+	}
+
+	part, err := exchangeRows(nbRows)
+	if err != nil {
+		return nil, nil, err
+	}
+	if part.rows[0] < circuit.NbPublicInputs {
+		return nil, nil, fmt.Errorf("public inputs not in a single sub-circuit")
+	}
+	if err := checkPermutation(circuit.Permutation, part); err != nil {
+		return nil, nil, err
+	}
+	pk, err := newKeys(curveID, part, srs)
+	if err != nil {
+		return nil, nil, err
+	}
+	vk := pk.Vk
+	vk.NbPublicVariables = uint64(circuit.NbPublicInputs)
+
+	for i := range pk.Q {
+		copy(pk.Q[i], circuit.Selectors[i])
+	}
+	sizeSystem := int(pk.Domain[0].Cardinality)
+	for k := range circuit.Permutation {
+		for j, w := range circuit.Permutation[k] {
+			pk.PermutationX[j+k*sizeSystem] = int64(w.Row + w.Column*int(part.sizes[w.Party]))
+			pk.PermutationY[j+k*sizeSystem] = int64(w.Party)
+		}
+	}
+
+	if err := commitKeys(pk); err != nil {
+		return nil, nil, err
+	}
+	return pk, vk, nil
+}
+
+// exchangeRows sends the number of rows of this party to the other ones, and returns
+// the partition where each party holds the rows it declared
+func exchangeRows(nbRows int) (*partition, error) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(nbRows))
+	parts, err := comm.Default.Gather(buf[:])
+	if err != nil {
+		return nil, err
+	}
+	var all []byte
+	if mpi.SelfRank == 0 {
+		for i := range parts {
+			all = append(all, parts[i]...)
+		}
+	}
+	if all, err = comm.Default.Broadcast(all, 8*int(mpi.WorldSize)); err != nil {
+		return nil, err
+	}
+
+	rows := make([]int, mpi.WorldSize)
+	for k := range rows {
+		rows[k] = int(binary.BigEndian.Uint64(all[8*k:]))
+	}
+	return partitionOf(rows), nil
+}
+
+// checkPermutation returns an error, on all the parties, unless the copies of their
+// wires form a permutation of the wires of the circuit: every wire must be copied to a
+// wire of the circuit, and no two wires to the same one. It is collective, party 0
+// checking the copies gathered from all the parties.
+func checkPermutation(permutation [][]Wire, part *partition) error {
+	// the wires are numbered party by party, then column by column
+	offsets := make([]uint64, len(part.rows)+1)
+	for p := range part.rows {
+		offsets[p+1] = offsets[p] + uint64(NUM_WITNESSES*part.rows[p])
+	}
+	const dangling = ^uint64(0)
+
+	// the parts are padded to the same number of rows for the gather
+	maxRows := 0
+	for _, n := range part.rows {
+		if n > maxRows {
+			maxRows = n
+		}
+	}
+	buf := make([]byte, 8*NUM_WITNESSES*maxRows)
+	for k := range permutation {
+		for j, w := range permutation[k] {
+			target := dangling
+			if w.Party < uint64(len(part.rows)) && w.Column >= 0 && w.Column < NUM_WITNESSES && w.Row >= 0 && w.Row < part.rows[w.Party] {
+				target = offsets[w.Party] + uint64(w.Column*part.rows[w.Party]+w.Row)
+			}
+			binary.BigEndian.PutUint64(buf[8*(k*maxRows+j):], target)
+		}
+	}
+	parts, err := comm.Default.Gather(buf)
+	if err != nil {
+		return err
+	}
+
+	// party 0 finds the first wire which is copied out of the circuit or to a wire
+	// already copied to, and sends [kind, party, column, row] of it
+	const (
+		valid uint64 = iota
+		outOfCircuit
+		duplicate
+	)
+	var res []byte
+	if mpi.SelfRank == 0 {
+		res = make([]byte, 32)
+		seen := make([]bool, offsets[len(part.rows)])
+	check:
+		for p := range part.rows {
+			for k := 0; k < NUM_WITNESSES; k++ {
+				for j := 0; j < part.rows[p]; j++ {
+					var kind uint64
+					target := binary.BigEndian.Uint64(parts[p][8*(k*maxRows+j):])
+					switch {
+					case target == dangling:
+						kind = outOfCircuit
+					case seen[target]:
+						kind = duplicate
+					default:
+						seen[target] = true
+						continue
+					}
+					for i, v := range []uint64{kind, uint64(p), uint64(k), uint64(j)} {
+						binary.BigEndian.PutUint64(res[8*i:], v)
+					}
+					break check
+				}
+			}
+		}
+	}
+	if res, err = comm.Default.Broadcast(res, 32); err != nil {
+		return err
+	}
+
+	p, k, j := binary.BigEndian.Uint64(res[8:]), binary.BigEndian.Uint64(res[16:]), binary.BigEndian.Uint64(res[24:])
+	switch binary.BigEndian.Uint64(res) {
+	case outOfCircuit:
+		return fmt.Errorf("wire %d of row %d of party %d is copied out of the circuit", k, j, p)
+	case duplicate:
+		return fmt.Errorf("wire %d of row %d of party %d is copied to a wire already copied to", k, j, p)
+	}
+	return nil
+}
+
+// randomSources returns a source of randomness shared by all the parties, and one of
+// this party. They are meant for the synthetic circuits, not for secrets.
+func randomSources() (shared, local *mrand.Rand, err error) {
+	var buf []byte
+	if mpi.SelfRank == 0 {
+		buf = make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+	}
+	if buf, err = comm.Default.Broadcast(buf, 8); err != nil {
+		return nil, nil, err
+	}
+	seed := int64(binary.BigEndian.Uint64(buf))
+	shared = mrand.New(mrand.NewSource(seed))
+	local = mrand.New(mrand.NewSource(seed ^ int64(mpi.SelfRank+1)))
+	return shared, local, nil
+}
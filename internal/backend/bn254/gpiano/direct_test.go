@@ -0,0 +1,164 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/backend"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+func TestSetupDirect(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	// fewer rows than the X-domain, the columns are padded by the prover
+	nbRows := randomNbConstraints - 3
+	circuit := CircuitPart{
+		Selectors:      make([][]fr.Element, NUM_SELECTORS),
+		Permutation:    make([][]Wire, NUM_WITNESSES),
+		NbPublicInputs: randomNbPublic,
+	}
+	witnesses := make([][]fr.Element, NUM_WITNESSES)
+	for i := range circuit.Selectors {
+		circuit.Selectors[i] = make([]fr.Element, nbRows)
+	}
+	for k := range witnesses {
+		witnesses[k] = make([]fr.Element, nbRows)
+		circuit.Permutation[k] = make([]Wire, nbRows)
+		for j := range witnesses[k] {
+			witnesses[k][j].SetRandom()
+			circuit.Permutation[k][j] = Wire{Column: k, Row: j}
+		}
+	}
+
+	// the wire 1 of each odd row is copied to the wire 2 of the row before
+	for j := 1; j < nbRows; j += 2 {
+		witnesses[1][j] = witnesses[2][j-1]
+		circuit.Permutation[1][j] = Wire{Column: 2, Row: j - 1}
+		circuit.Permutation[2][j-1] = Wire{Column: 1, Row: j}
+	}
+
+	var out, tmp fr.Element
+	for j := 0; j < nbRows; j++ {
+		for i := 0; i < NUM_SELECTORS-1; i++ {
+			circuit.Selectors[i][j].SetRandom()
+		}
+		gateFunc(witnesses, circuit.Selectors, uint64(j), &out, &tmp)
+		circuit.Selectors[NUM_SELECTORS-1][j].Neg(&out)
+	}
+
+	pk, vk, err := SetupDirect(ecc.BN254, &circuit, SRS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := bn254witness.New(witnesses[0][:randomNbPublic])
+	proof, err := ProveDirect(pk, witnesses, publicWitness, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	// a copy constraint doesn't hold
+	witnesses[1][1].SetRandom()
+	gateFunc(witnesses, circuit.Selectors, 1, &out, &tmp)
+	circuit.Selectors[NUM_SELECTORS-1][1].Neg(&out)
+	if pk, _, err = SetupDirect(ecc.BN254, &circuit, SRS{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProveDirect(pk, witnesses, publicWitness, opt); err == nil {
+		t.Fatal("broken copy constraint should fail")
+	}
+
+	circuit.Permutation[0][0] = Wire{Column: 0, Row: nbRows}
+	if _, _, err := SetupDirect(ecc.BN254, &circuit, SRS{}); err == nil {
+		t.Fatal("copy out of the circuit should fail")
+	}
+	circuit.Permutation[0][0] = Wire{Party: 1, Column: 0, Row: 0}
+	if _, _, err := SetupDirect(ecc.BN254, &circuit, SRS{}); err == nil {
+		t.Fatal("copy to another party out of the world should fail")
+	}
+
+	// the wire 2 of row 0 is copied to by two wires, and the wire 0 of row 0 by none
+	circuit.Permutation[0][0] = Wire{Column: 2, Row: 0}
+	if _, _, err := SetupDirect(ecc.BN254, &circuit, SRS{}); err == nil {
+		t.Fatal("copies which are not a permutation should fail")
+	}
+}
+
+func TestSetupRandomCircuit(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	pk, vk, witnesses, err := SetupRandomCircuit(ecc.BN254, RandomCircuit{
+		NbConstraints:    randomNbConstraints,
+		NbPublicInputs:   randomNbPublic,
+		GateDensity:      0.5,
+		CrossPartyWiring: 0.5,
+	}, SRS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rows without a gate have all their selectors set to zero
+	selectors := make([][]fr.Element, len(pk.Q))
+	for i := range selectors {
+		selectors[i] = append([]fr.Element(nil), pk.Q[i]...)
+		pk.Domain[0].FFT(selectors[i], fft.DIF)
+		fft.BitReverse(selectors[i])
+	}
+	empty := 0
+	for j := 0; j < pk.NbRows; j++ {
+		isEmpty := true
+		for i := range selectors {
+			isEmpty = isEmpty && selectors[i][j].IsZero()
+		}
+		if isEmpty {
+			empty++
+		}
+	}
+	if empty == 0 || empty == pk.NbRows {
+		t.Fatalf("got %d empty rows out of %d", empty, pk.NbRows)
+	}
+
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := bn254witness.New(witnesses[0][:randomNbPublic])
+	proof, err := ProveDirect(pk, witnesses, publicWitness, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, err := SetupRandomCircuit(ecc.BN254, RandomCircuit{NbConstraints: randomNbConstraints, GateDensity: 2}, SRS{}); err == nil {
+		t.Fatal("gate density out of [0, 1] should fail")
+	}
+}
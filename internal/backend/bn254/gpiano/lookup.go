@@ -0,0 +1,404 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark-crypto/fiat-shamir"
+	"github.com/consensys/gnark/backend/comm"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+)
+
+// The lookup argument is a logUp argument: the rows selected by Q look up their first
+// wire f in the table t, and m(Y, X) counts how many times every entry of the table is
+// looked up, such that
+//
+//	Σ q/(δ+f) = Σ m/(δ+t)
+//
+// over the rows of all the parties. Each party accumulates its own rows in Phi(X), with
+// Phi(1) = 0 and Phi(ωX) = Phi(X) + m/(δ+t) - q/(δ+f), and the sums of the parties are
+// accumulated in S(Y), with S(ωY) = S(Y) + Σ_party, the same way W accumulates the
+// products of the permutation argument. S is cyclic, hence the sums of all the parties
+// add up to zero.
+
+// LookupKey stores the data of the lookup argument needed by the prover
+type LookupKey struct {
+	// Table in Lagrange form, padded with its first entry to as many entries as the
+	// rows of the X-domains of all the parties. Party k holds the entries
+	// Table[offset:offset+N_k], offset being the sum of the sizes of the parties before k.
+	Table []fr.Element
+
+	// T, the entries of the table of this party, and Q, the lookup selector, in canonical basis
+	T, Q []fr.Element
+
+	// Rows of this party looking up their first wire in the table
+	Rows []int
+
+	// index maps an entry to its first position in Table
+	index map[fr.Element]int
+}
+
+// LookupVerifyingKey stores the commitments to the table and to the lookup selector
+type LookupVerifyingKey struct {
+	T, Q dkzg.Digest
+}
+
+// LookupProof stores the part of a Proof related to the lookup argument
+type LookupProof struct {
+	// Commitment to the multiplicities of the entries of the table
+	M dkzg.Digest
+
+	// Commitments to Phi, the accumulator of each party, and S, the accumulator
+	// across the parties
+	Phi dkzg.Digest
+	S   kzg.Digest
+
+	// Opening partially proofs of Phi(Y, X) on X = omegaX * alpha, one per size
+	// of X-domain, see Proof.PartialZShiftedProofs
+	PartialPhiShiftedProofs []dkzg.OpeningProof
+
+	// Opening proof of S(Y) on Y = omegaY * beta
+	SShiftedProof kzg.OpeningProof
+}
+
+// lookupWitness stores the polynomials of the lookup argument computed by the prover
+type lookupWitness struct {
+	delta fr.Element
+
+	// m, phi in canonical basis
+	m, phi []fr.Element
+
+	// pS, cS = S on this party and on the next one
+	pS, cS fr.Element
+
+	// S in Lagrange and canonical basis, only on rank 0
+	sSmallY, sCanonicalY []fr.Element
+}
+
+// challengeNames returns the challenges of the transcript, delta is only derived
+// when the circuit has table lookups.
+func challengeNames(vk *VerifyingKey) []string {
+	if vk.Lookup != nil {
+		return []string{"gamma", "etaY", "etaX", "delta", "lambda", "alpha", "beta", "nu"}
+	}
+	return []string{"gamma", "etaY", "etaX", "lambda", "alpha", "beta", "nu"}
+}
+
+// setupLookup sets the table and the rows of this party looking up in it,
+// and commits to them.
+func setupLookup(pk *ProvingKey, table []fr.Element, rows []int) error {
+	n := int(pk.Domain[0].Cardinality)
+	size := int(pk.Vk.offsetX(mpi.WorldSize))
+	if len(table) == 0 {
+		return errors.New("lookup table is empty")
+	}
+	if len(table) > size {
+		return fmt.Errorf("lookup table has %d entries but the circuit only has %d rows", len(table), size)
+	}
+
+	lk := &LookupKey{
+		Table: make([]fr.Element, size),
+		Rows:  rows,
+		index: make(map[fr.Element]int, len(table)),
+	}
+	copy(lk.Table, table)
+	for i := len(table); i < size; i++ {
+		lk.Table[i] = table[0]
+	}
+	for i := len(table) - 1; i >= 0; i-- {
+		lk.index[table[i]] = i
+	}
+
+	lk.T = make([]fr.Element, n)
+	copy(lk.T, lk.Table[pk.Vk.offsetX(mpi.SelfRank):])
+	lk.Q = make([]fr.Element, n)
+	for _, i := range rows {
+		if i < 0 || i >= n {
+			return fmt.Errorf("lookup row %d out of range", i)
+		}
+		lk.Q[i].SetOne()
+	}
+	pk.Domain[0].FFTInverse(lk.T, fft.DIF)
+	fft.BitReverse(lk.T)
+	pk.Domain[0].FFTInverse(lk.Q, fft.DIF)
+	fft.BitReverse(lk.Q)
+
+	pk.Lookup = lk
+	pk.Vk.Lookup = &LookupVerifyingKey{}
+	var err error
+	if pk.Vk.Lookup.T, err = dkzg.Commit(lk.T, pk.Vk.DKZGSRS); err != nil {
+		return err
+	}
+	if pk.Vk.Lookup.Q, err = dkzg.Commit(lk.Q, pk.Vk.DKZGSRS); err != nil {
+		return err
+	}
+	return nil
+}
+
+// proveLookup commits to the multiplicities, derives delta and commits to Phi and S.
+//
+// f is the first wire of this party in Lagrange form.
+func proveLookup(fs *fiatshamir.Transcript, pk *ProvingKey, proof *Proof, f []fr.Element) (*lookupWitness, error) {
+	lw := &lookupWitness{}
+	proof.Lookup = &LookupProof{}
+
+	mSmallX, err := computeMultiplicities(pk.Vk, pk.Lookup, f)
+	if err != nil {
+		return nil, err
+	}
+	lw.m = make([]fr.Element, len(mSmallX))
+	copy(lw.m, mSmallX)
+	pk.Domain[0].FFTInverse(lw.m, fft.DIF)
+	fft.BitReverse(lw.m)
+	if proof.Lookup.M, err = dkzg.Commit(lw.m, pk.Vk.DKZGSRS, runtime.NumCPU()/2); err != nil {
+		return nil, err
+	}
+
+	// derive delta from Comm(M)
+	if lw.delta, err = deriveRandomness(fs, "delta", false, &proof.Lookup.M); err != nil {
+		return nil, err
+	}
+
+	var selfSum fr.Element
+	lw.phi, selfSum = computePhiCanonicalX(pk, f, mSmallX, lw.delta)
+
+	var pS, cS *fr.Element
+	if lw.sSmallY, lw.sCanonicalY, pS, cS, err = computeSCanonicalY(selfSum); err != nil {
+		return nil, err
+	}
+	lw.pS, lw.cS = *pS, *cS
+
+	if proof.Lookup.Phi, err = dkzg.Commit(lw.phi, pk.Vk.DKZGSRS, runtime.NumCPU()*2); err != nil {
+		return nil, err
+	}
+	if mpi.SelfRank == 0 {
+		if proof.Lookup.S, err = kzg.Commit(lw.sCanonicalY, globalSRS); err != nil {
+			return nil, err
+		}
+	}
+
+	return lw, nil
+}
+
+// computeMultiplicities returns, in Lagrange form, how many times each entry of the
+// table held by this party is looked up by all the parties.
+//
+// The parties send their counts to rank 0, which adds them and sends back to every
+// party the counts of its entries.
+func computeMultiplicities(vk *VerifyingKey, lk *LookupKey, f []fr.Element) ([]fr.Element, error) {
+	n := len(lk.T)
+
+	counts := make(map[int]uint64)
+	for _, i := range lk.Rows {
+		j, ok := lk.index[f[i]]
+		if !ok {
+			return nil, fmt.Errorf("row %d of party %d: %s is not in the lookup table", i, mpi.SelfRank, f[i].String())
+		}
+		counts[j]++
+	}
+
+	local := make([]uint64, n)
+	if mpi.SelfRank == 0 {
+		global := make([]uint64, len(lk.Table))
+		for j, c := range counts {
+			global[j] += c
+		}
+		for i := uint64(1); i < mpi.WorldSize; i++ {
+			recvBuf, err := comm.Default.Transport.Receive(8, i)
+			if err != nil {
+				return nil, err
+			}
+			nbCounts := binary.BigEndian.Uint64(recvBuf)
+			if nbCounts == 0 {
+				continue
+			}
+			if recvBuf, err = comm.Default.Transport.Receive(16*nbCounts, i); err != nil {
+				return nil, err
+			}
+			for k := uint64(0); k < nbCounts; k++ {
+				j := binary.BigEndian.Uint64(recvBuf[16*k:])
+				if j >= uint64(len(global)) {
+					return nil, fmt.Errorf("party %d looked up entry %d out of the table", i, j)
+				}
+				global[j] += binary.BigEndian.Uint64(recvBuf[16*k+8:])
+			}
+		}
+		for i := uint64(1); i < mpi.WorldSize; i++ {
+			offset, size := vk.offsetX(i), vk.sizeX(i)
+			sendBuf := make([]byte, 8*size)
+			for k, c := range global[offset : offset+size] {
+				binary.BigEndian.PutUint64(sendBuf[8*k:], c)
+			}
+			if err := comm.Default.Transport.Send(sendBuf, i); err != nil {
+				return nil, err
+			}
+		}
+		copy(local, global[:n])
+	} else {
+		header := make([]byte, 8)
+		binary.BigEndian.PutUint64(header, uint64(len(counts)))
+		if err := comm.Default.Transport.Send(header, 0); err != nil {
+			return nil, err
+		}
+		if len(counts) != 0 {
+			sendBuf := make([]byte, 16*len(counts))
+			k := 0
+			for j, c := range counts {
+				binary.BigEndian.PutUint64(sendBuf[16*k:], uint64(j))
+				binary.BigEndian.PutUint64(sendBuf[16*k+8:], c)
+				k++
+			}
+			if err := comm.Default.Transport.Send(sendBuf, 0); err != nil {
+				return nil, err
+			}
+		}
+		recvBuf, err := comm.Default.Transport.Receive(uint64(8*n), 0)
+		if err != nil {
+			return nil, err
+		}
+		for k := range local {
+			local[k] = binary.BigEndian.Uint64(recvBuf[8*k:])
+		}
+	}
+
+	m := make([]fr.Element, n)
+	for i := range m {
+		m[i].SetUint64(local[i])
+	}
+	return m, nil
+}
+
+// computePhiCanonicalX computes Phi in canonical basis, where
+//
+// * Phi(1) = 0
+// * for i>0: Phi(g**i) = Σ_{k<i} m(g**k)/(δ+t(g**k)) - q(g**k)/(δ+f(g**k))
+//
+// and returns the sum over all the rows of this party.
+func computePhiCanonicalX(pk *ProvingKey, f, m []fr.Element, delta fr.Element) ([]fr.Element, fr.Element) {
+	lk := pk.Lookup
+	n := len(lk.T)
+	offset := int(pk.Vk.offsetX(mpi.SelfRank))
+	t := lk.Table[offset : offset+n]
+
+	den := make([]fr.Element, 2*n)
+	for i := 0; i < n; i++ {
+		den[i].Add(&delta, &t[i])
+		den[n+i].Add(&delta, &f[i])
+	}
+	den = fr.BatchInvert(den)
+
+	queried := make([]bool, n)
+	for _, i := range lk.Rows {
+		queried[i] = true
+	}
+
+	phi := make([]fr.Element, n)
+	var sum, term fr.Element
+	for i := 0; i < n; i++ {
+		phi[i] = sum
+		term.Mul(&m[i], &den[i])
+		if queried[i] {
+			term.Sub(&term, &den[n+i])
+		}
+		sum.Add(&sum, &term)
+	}
+
+	pk.Domain[0].FFTInverse(phi, fft.DIF)
+	fft.BitReverse(phi)
+
+	return phi, sum
+}
+
+// computeSCanonicalY accumulates the sums of the parties into S, S(1) = 0 and
+// S(omegaY**(k+1)) = S(omegaY**k) + the sum of party k, and returns S(omegaY**k)
+// and S(omegaY**(k+1)) to party k. S in Lagrange and canonical basis are only
+// returned on rank 0.
+func computeSCanonicalY(selfSum fr.Element) ([]fr.Element, []fr.Element, *fr.Element, *fr.Element, error) {
+	sendBuf := selfSum.Bytes()
+	sums, err := comm.Default.Gather(sendBuf[:])
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	var S []fr.Element
+	var parts [][]byte
+	if mpi.SelfRank == 0 {
+		S = make([]fr.Element, mpi.WorldSize+1)
+		for i := range sums {
+			S[i+1].SetBytes(sums[i])
+		}
+		for i := uint64(1); i < mpi.WorldSize; i++ {
+			S[i+1].Add(&S[i+1], &S[i])
+		}
+		if !S[mpi.WorldSize].IsZero() {
+			return nil, nil, nil, nil, fmt.Errorf("the lookups don't match the table, got a sum of %v", S[mpi.WorldSize])
+		}
+		// party i gets S[i] and S[i+1]
+		parts = make([][]byte, mpi.WorldSize)
+		for i := range parts {
+			parts[i] = elementsToBytes(S[i : i+2])
+		}
+	}
+
+	recvBuf, err := comm.Default.Scatter(parts, 2*fr.Bytes)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	var p, c fr.Element
+	p.SetBytes(recvBuf[:fr.Bytes])
+	c.SetBytes(recvBuf[fr.Bytes:])
+	if mpi.SelfRank != 0 {
+		return nil, nil, &p, &c, nil
+	}
+
+	sCanonicalY := make([]fr.Element, mpi.WorldSize)
+	copy(sCanonicalY, S[:len(S)-1])
+	globalDomain[0].FFTInverse(sCanonicalY, fft.DIF)
+	fft.BitReverse(sCanonicalY)
+	return S[:len(S)-1], sCanonicalY, &p, &c, nil
+}
+
+// lookupConstraint computes the constraint of the lookup argument
+//
+// (δ+t)(δ+f)((1 - L_{n-1})(Phi(ωX) - Phi) + L_{n-1}(cS - pS - Phi)) - m(δ+f) + q(δ+t)
+// + lambda * L_0 * Phi
+//
+// where pS, cS are S(Y) and S(omegaY*Y).
+func lookupConstraint(t, q, m, f, phi, phiShifted, pS, cS, l0, ll, delta, lambda fr.Element) fr.Element {
+	var dt, df, a, b, res fr.Element
+	dt.Add(&delta, &t)
+	df.Add(&delta, &f)
+
+	// (1 - L_{n-1})a + L_{n-1}b = a + L_{n-1}(b - a)
+	a.Sub(&phiShifted, &phi)
+	b.Sub(&cS, &pS).Sub(&b, &phi).Sub(&b, &a).Mul(&b, &ll)
+	a.Add(&a, &b).Mul(&a, &dt).Mul(&a, &df)
+
+	b.Mul(&m, &df)
+	a.Sub(&a, &b)
+	b.Mul(&q, &dt)
+	a.Add(&a, &b)
+
+	res.Mul(&l0, &phi).Mul(&res, &lambda).Add(&res, &a)
+	return res
+}
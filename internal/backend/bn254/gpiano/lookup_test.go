@@ -0,0 +1,120 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+func TestLookup(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	table := make([]fr.Element, 1<<4)
+	for i := range table {
+		table[i].SetUint64(uint64(i))
+	}
+
+	pk, vk, witnesses, err := SetupRandomLookup(ecc.BN254, randomNbConstraints, randomNbPublic, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicWitness := bn254witness.New(witnesses[0][:randomNbPublic])
+	proof, err := ProveDirect(pk, witnesses, publicWitness, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lookup argument can't be dropped from the proof
+	stripped := *proof
+	stripped.Lookup = nil
+	if err := Verify(&stripped, vk, publicWitness); err == nil {
+		t.Fatal("proof without lookup argument should not verify")
+	}
+
+	// the multiplicities are bound to the proof
+	tampered := *proof
+	lookup := *proof.Lookup
+	lookup.M = lookup.Phi
+	tampered.Lookup = &lookup
+	if err := Verify(&tampered, vk, publicWitness); err == nil {
+		t.Fatal("proof with tampered multiplicities should not verify")
+	}
+
+	// a value out of the table can't be looked up
+	witnesses[0][pk.Lookup.Rows[0]].SetUint64(uint64(len(table)))
+	if _, err := ProveDirect(pk, witnesses, publicWitness, opt); err == nil {
+		t.Fatal("looking up a value out of the table should fail")
+	}
+}
+
+func TestLookupTwoParties(t *testing.T) {
+	if !inWorld(t, 2) {
+		return
+	}
+
+	// both parties look up some of their rows, so that the multiplicities of the table
+	// add up over the parties
+	table := make([]fr.Element, 1<<4)
+	for i := range table {
+		table[i].SetUint64(uint64(i))
+	}
+	pk, vk, witnesses, err := SetupRandomLookup(ecc.BN254, randomNbConstraints, randomNbPublic, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pk.Lookup.Rows) == 0 {
+		t.Fatalf("party %d doesn't look up any row", mpi.SelfRank)
+	}
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicWitness := bn254witness.New(witnesses[0][:randomNbPublic])
+	proof, err := ProveDirect(pk, witnesses, publicWitness, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mpi.SelfRank != 0 {
+		return
+	}
+	if err := Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := *proof
+	lookup := *proof.Lookup
+	lookup.M = lookup.Phi
+	tampered.Lookup = &lookup
+	if err := Verify(&tampered, vk, publicWitness); err == nil {
+		t.Fatal("proof with tampered multiplicities should not verify")
+	}
+}
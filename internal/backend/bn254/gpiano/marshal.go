@@ -18,9 +18,20 @@
 package gpiano
 
 import (
+	"errors"
 	"fmt"
 	"io"
+
 	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend/compiled"
+)
+
+// vkMagic prefixes the encodings of VerifyingKey, the version being its last byte
+const (
+	vkMagic   uint64 = 0x677069616e766b00 // "gpianvk\x00"
+	vkVersion uint64 = 1
 )
 
 func (proof *Proof) WriteTo(w io.Writer) (int64, error) {
@@ -56,12 +67,59 @@ func (proof *Proof) writeTo(w io.Writer, raw bool) (int64, error) {
 
 	n := enc.BytesWritten()
 
-	toWrite := []io.WriterTo{
-		&proof.PartialBatchedProof,
-		&proof.PartialZShiftedProof,
-		&proof.BatchedProof,
-		&proof.WShiftedProof,
+	toWrite := []io.WriterTo{&proof.PartialBatchedProof}
+	for i := range proof.PartialZShiftedProofs {
+		toWrite = append(toWrite, &proof.PartialZShiftedProofs[i])
+	}
+	toWrite = append(toWrite, &proof.BatchedProof, &proof.WShiftedProof)
+
+	for _, v := range toWrite {
+		siz, err := v.WriteTo(w)
+		n += siz
+		if err != nil {
+			return n, err
+		}
+	}
+
+	if proof.Lookup != nil {
+		siz, err := proof.Lookup.writeTo(w, raw)
+		n += siz
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// writeTo writes binary encoding of LookupProof to w
+func (proof *LookupProof) writeTo(w io.Writer, raw bool) (int64, error) {
+	var enc *curve.Encoder
+	if raw {
+		enc = curve.NewEncoder(w, curve.RawEncoding())
+	} else {
+		enc = curve.NewEncoder(w)
+	}
+
+	toEncode := []interface{}{
+		&proof.M,
+		&proof.Phi,
+		&proof.S,
+	}
+
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			return enc.BytesWritten(), err
+		}
+	}
+
+	n := enc.BytesWritten()
+
+	var toWrite []io.WriterTo
+	for i := range proof.PartialPhiShiftedProofs {
+		toWrite = append(toWrite, &proof.PartialPhiShiftedProofs[i])
 	}
+	toWrite = append(toWrite, &proof.SShiftedProof)
 
 	for _, v := range toWrite {
 		siz, err := v.WriteTo(w)
@@ -90,12 +148,156 @@ func (pk *ProvingKey) ReadFrom(r io.Reader) (int64, error) {
 
 }
 
-// WriteTo writes binary encoding of VerifyingKey to w
+// WriteTo writes binary encoding of VerifyingKey to w. Only the points of the SRS on Y
+// the verifier needs are written, and the SRS on X isn't, see InitKZG.
 func (vk *VerifyingKey) WriteTo(w io.Writer) (n int64, err error) {
-	return 0, fmt.Errorf("not implemented")
+	enc := curve.NewEncoder(w)
+
+	// the verifier needs [1]1, [1]2 and [τ]2 of the SRS on Y, which only the first
+	// party has
+	var srsG1 []curve.G1Affine
+	if vk.KZGSRS != nil {
+		srsG1 = vk.KZGSRS.G1[:1]
+	}
+	var lookup []curve.G1Affine
+	if vk.Lookup != nil {
+		lookup = []curve.G1Affine{vk.Lookup.T, vk.Lookup.Q}
+	}
+
+	toEncode := []interface{}{
+		vkMagic | vkVersion,
+		vk.SizeY,
+		vk.SizeX,
+		uint64(len(vk.SizesX)),
+	}
+	for _, size := range vk.SizesX {
+		toEncode = append(toEncode, size)
+	}
+	toEncode = append(toEncode,
+		&vk.SizeYInv,
+		&vk.SizeXInv,
+		&vk.GeneratorY,
+		&vk.GeneratorX,
+		&vk.GeneratorXInv,
+		vk.NbPublicVariables,
+		&vk.CosetShift,
+		vk.Sy,
+		vk.Sx,
+		vk.Q,
+		lookup,
+		uint64(vk.TranscriptHash),
+		srsG1,
+	)
+	if vk.KZGSRS != nil {
+		toEncode = append(toEncode, &vk.KZGSRS.G2[0], &vk.KZGSRS.G2[1])
+	}
+
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			return enc.BytesWritten(), err
+		}
+	}
+
+	// fingerprint of the constraint system
+	n2, err := w.Write(vk.CircuitFingerprint[:])
+	return enc.BytesWritten() + int64(n2), err
 }
 
-// ReadFrom reads from binary representation in r into VerifyingKey
+// ReadFrom reads from binary representation in r into VerifyingKey. The SRS on X is left
+// nil, InitKZG setting it.
 func (vk *VerifyingKey) ReadFrom(r io.Reader) (int64, error) {
-	return 0, fmt.Errorf("not implemented")
+	dec := curve.NewDecoder(r)
+
+	var header uint64
+	if err := dec.Decode(&header); err != nil {
+		return dec.BytesRead(), err
+	}
+	if header&^0xff != vkMagic {
+		return dec.BytesRead(), errors.New("not a gpiano verifying key")
+	}
+	if version := header & 0xff; version == 0 || version > vkVersion {
+		return dec.BytesRead(), fmt.Errorf("unknown verifying key format version %d", version)
+	}
+
+	var nbSizesX uint64
+	toDecode := []interface{}{
+		&vk.SizeY,
+		&vk.SizeX,
+		&nbSizesX,
+	}
+	for _, v := range toDecode {
+		if err := dec.Decode(v); err != nil {
+			return dec.BytesRead(), err
+		}
+	}
+	if nbSizesX != 0 && nbSizesX != vk.SizeY {
+		return dec.BytesRead(), fmt.Errorf("%d sizes of X-domains for %d parties", nbSizesX, vk.SizeY)
+	}
+	vk.SizesX = nil
+	if nbSizesX > 0 {
+		vk.SizesX = make([]uint64, nbSizesX)
+	}
+	for i := range vk.SizesX {
+		if err := dec.Decode(&vk.SizesX[i]); err != nil {
+			return dec.BytesRead(), err
+		}
+	}
+
+	var (
+		lookup         []curve.G1Affine
+		transcriptHash uint64
+		srsG1          []curve.G1Affine
+	)
+	toDecode = []interface{}{
+		&vk.SizeYInv,
+		&vk.SizeXInv,
+		&vk.GeneratorY,
+		&vk.GeneratorX,
+		&vk.GeneratorXInv,
+		&vk.NbPublicVariables,
+		&vk.CosetShift,
+		&vk.Sy,
+		&vk.Sx,
+		&vk.Q,
+		&lookup,
+		&transcriptHash,
+		&srsG1,
+	}
+	for _, v := range toDecode {
+		if err := dec.Decode(v); err != nil {
+			return dec.BytesRead(), err
+		}
+	}
+	vk.TranscriptHash = backend.TranscriptHash(transcriptHash)
+
+	switch len(lookup) {
+	case 0:
+		vk.Lookup = nil
+	case 2:
+		vk.Lookup = &LookupVerifyingKey{T: lookup[0], Q: lookup[1]}
+	default:
+		return dec.BytesRead(), fmt.Errorf("%d lookup digests, expected 2", len(lookup))
+	}
+
+	switch len(srsG1) {
+	case 0:
+		vk.KZGSRS = nil
+	case 1:
+		srs := &kzg.SRS{G1: srsG1}
+		if err := dec.Decode(&srs.G2[0]); err != nil {
+			return dec.BytesRead(), err
+		}
+		if err := dec.Decode(&srs.G2[1]); err != nil {
+			return dec.BytesRead(), err
+		}
+		vk.KZGSRS = srs
+	default:
+		return dec.BytesRead(), fmt.Errorf("%d points of the SRS in G1, expected 1", len(srsG1))
+	}
+	vk.DKZGSRS = nil
+
+	// fingerprint of the constraint system
+	vk.CircuitFingerprint = compiled.Fingerprint{}
+	n, err := io.ReadFull(r, vk.CircuitFingerprint[:])
+	return dec.BytesRead() + int64(n), err
 }
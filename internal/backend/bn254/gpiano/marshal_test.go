@@ -26,6 +26,8 @@ import (
 	"testing"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend"
 )
 
 func TestProvingKeySerialization(t *testing.T) {
@@ -125,6 +127,14 @@ func TestVerifyingKeySerialization(t *testing.T) {
 	vk.Q[2] = g1gen
 	vk.Q[3] = g1gen
 	vk.Q[4] = g1gen
+	vk.SizesX = []uint64{32, 16, 16, 8, 8, 8, 8, 8, 8, 8}
+	vk.Lookup = &LookupVerifyingKey{T: g1gen, Q: g1gen}
+	vk.TranscriptHash = backend.KECCAK256
+	vk.CircuitFingerprint[0] = 42
+
+	// only the points of the SRS on Y the verifier needs are serialized
+	_, _, _, g2gen := curve.Generators()
+	vk.KZGSRS = &kzg.SRS{G1: []curve.G1Affine{g1gen}, G2: [2]curve.G2Affine{g2gen, g2gen}}
 
 	var buf bytes.Buffer
 	written, err := vk.WriteTo(&buf)
@@ -147,3 +157,55 @@ func TestVerifyingKeySerialization(t *testing.T) {
 		t.Fatal("bytes written / read don't match")
 	}
 }
+
+func TestVerifyingKeyMalformed(t *testing.T) {
+	var vk VerifyingKey
+	vk.SizeY = 2
+	vk.SizesX = []uint64{8, 4}
+
+	var buf bytes.Buffer
+	if _, err := vk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	var reconstructed VerifyingKey
+	if _, err := reconstructed.ReadFrom(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Fatal("reading a truncated fingerprint should fail")
+	}
+	other := append([]byte{}, data...)
+	other[0] ^= 1
+	if _, err := reconstructed.ReadFrom(bytes.NewReader(other)); err == nil {
+		t.Fatal("reading another header should fail")
+	}
+
+	// a size of X-domain per party
+	vk.SizesX = []uint64{8, 4, 4}
+	buf.Reset()
+	if _, err := vk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reconstructed.ReadFrom(&buf); err == nil {
+		t.Fatal("reading more sizes of X-domains than parties should fail")
+	}
+}
+
+func TestVerifyingKeyRoundTrip(t *testing.T) {
+	proofs, vk, publicWitnesses := proveRandom(t, 1)
+
+	// the key read back verifies once given the SRS on X again
+	var buf bytes.Buffer
+	if _, err := vk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var reconstructed VerifyingKey
+	if _, err := reconstructed.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := reconstructed.InitKZG(vk.DKZGSRS); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(proofs[0], &reconstructed, publicWitnesses[0]); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/internal/utils"
+)
+
+// Layout of the file written by WritePolynomialsTo: a header of polynomialsHeaderWords
+// little-endian words (version, cardinality of the X-domain, number of polynomials, 0)
+// and the element 1, followed by the polynomials. The elements are in the in-memory
+// representation of fr.Element, so that the file can be mapped as is; the element 1
+// tells apart a file written on a machine with another representation.
+const (
+	polynomialsVersion     = 1
+	polynomialsHeaderWords = 4
+	polynomialsHeaderSize  = polynomialsHeaderWords*8 + fr.Bytes
+)
+
+var (
+	errMmapUnsupported = errors.New("memory-mapping is not supported on this platform")
+	errMemoryBudget    = errors.New("quotient over the memory budget")
+)
+
+// quotientMemoryX estimates the memory held by computeQuotientCanonicalX when it evaluates
+// all the polynomials of the constraint on a coset together, with the quotient on the
+// big domain.
+func quotientMemoryX(pk *ProvingKey) uint64 {
+	// L0, L_last, Z and the witnesses on top of the polynomials of the key
+	nbPolys := 3 + NUM_WITNESSES + len(pk.Q) + len(pk.Sy) + len(pk.Sx)
+	if pk.Lookup != nil {
+		nbPolys += 4
+	}
+	return (uint64(nbPolys)*pk.Domain[0].Cardinality + pk.Domain[1].Cardinality) * fr.Bytes
+}
+
+// quotientMemoryStreamedX is quotientMemoryX when the polynomials of the key are
+// evaluated on a coset one at a time, which is the least computeQuotientCanonicalX holds.
+func quotientMemoryStreamedX(pk *ProvingKey) uint64 {
+	// L0, L_last, Z and the witnesses, the permutation and gate terms, and a selector or
+	// a pair of permutation polynomials
+	nbPolys := 3 + NUM_WITNESSES + 2 + 2
+	if pk.Lookup != nil {
+		nbPolys += 4
+	}
+	return (uint64(nbPolys)*pk.Domain[0].Cardinality + pk.Domain[1].Cardinality) * fr.Bytes
+}
+
+// quotientStreamed returns whether computeQuotientCanonicalX evaluates the polynomials
+// of the key on the cosets one at a time to stay within budget, or errMemoryBudget if
+// it can't
+func quotientStreamed(pk *ProvingKey, budget uint64) (bool, error) {
+	if budget == 0 || quotientMemoryX(pk) <= budget {
+		return false, nil
+	}
+	if need := quotientMemoryStreamedX(pk); need > budget {
+		return false, fmt.Errorf("%w: %d bytes at least, budget of %d", errMemoryBudget, need, budget)
+	}
+	return true, nil
+}
+
+// permutationOnCoset evaluates the product over the wires k of
+// Sx_k(X)*etaX + Sy_k(X)*etaY + w_k(X) + gamma on the coset of the X-domain shifted by
+// shift, one permutation polynomial at a time, witnesses being already on the coset.
+func permutationOnCoset(pk *ProvingKey, witnesses [][]fr.Element, shift, etaY, etaX, gamma fr.Element) []fr.Element {
+	g := make([]fr.Element, pk.Domain[0].Cardinality)
+	for i := range g {
+		g[i].SetOne()
+	}
+	for k := range witnesses {
+		sy := pk.Domain[0].FFTPart(pk.Sy[k], fft.DIF, shift, true)
+		sx := pk.Domain[0].FFTPart(pk.Sx[k], fft.DIF, shift, true)
+		utils.Parallelize(len(g), func(start, end int) {
+			var t, u fr.Element
+			for i := start; i < end; i++ {
+				t.Mul(&sx[i], &etaX)
+				u.Mul(&sy[i], &etaY)
+				t.Add(&t, &u).Add(&t, &witnesses[k][i]).Add(&t, &gamma)
+				g[i].Mul(&g[i], &t)
+			}
+		})
+	}
+	return g
+}
+
+// gateOnCoset evaluates the gate constraint of gateFunc on the coset of the X-domain
+// shifted by shift, one selector at a time, witnesses being already on the coset.
+func gateOnCoset(pk *ProvingKey, witnesses [][]fr.Element, shift fr.Element) []fr.Element {
+	gate := make([]fr.Element, pk.Domain[0].Cardinality)
+	for s := range pk.Q {
+		q := pk.Domain[0].FFTPart(pk.Q[s], fft.DIF, shift, true)
+		utils.Parallelize(len(gate), func(start, end int) {
+			var m fr.Element
+			for i := start; i < end; i++ {
+				selectorMonomial(witnesses, s, uint64(i), &m)
+				m.Mul(&m, &q[i])
+				gate[i].Add(&gate[i], &m)
+			}
+		})
+	}
+	return gate
+}
+
+// polynomials returns the polynomials of the proving key in canonical basis, in the
+// order of the file written by WritePolynomialsTo
+func (pk *ProvingKey) polynomials() []*[]fr.Element {
+	var res []*[]fr.Element
+	for _, polys := range [][][]fr.Element{pk.Q, pk.Sy, pk.Sx} {
+		for i := range polys {
+			res = append(res, &polys[i])
+		}
+	}
+	if pk.Lookup != nil {
+		res = append(res, &pk.Lookup.T, &pk.Lookup.Q)
+	}
+	return res
+}
+
+// WritePolynomialsTo writes the selectors, the permutation polynomials and the lookup
+// polynomials of the proving key to w, in a raw encoding which MapPolynomials maps back
+// in memory. The encoding depends on the architecture.
+func (pk *ProvingKey) WritePolynomialsTo(w io.Writer) (int64, error) {
+	polys := pk.polynomials()
+	n := pk.Domain[0].Cardinality
+
+	bw := bufio.NewWriter(w)
+	var header [polynomialsHeaderSize]byte
+	binary.LittleEndian.PutUint64(header[0:], polynomialsVersion)
+	binary.LittleEndian.PutUint64(header[8:], n)
+	binary.LittleEndian.PutUint64(header[16:], uint64(len(polys)))
+	var one fr.Element
+	one.SetOne()
+	copy(header[polynomialsHeaderWords*8:], elementsAsBytes([]fr.Element{one}))
+
+	written, err := bw.Write(header[:])
+	total := int64(written)
+	if err != nil {
+		return total, err
+	}
+	for i, p := range polys {
+		if uint64(len(*p)) != n {
+			return total, fmt.Errorf("polynomial %d has %d coefficients, want %d", i, len(*p), n)
+		}
+		written, err := bw.Write(elementsAsBytes(*p))
+		total += int64(written)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, bw.Flush()
+}
+
+// MapPolynomials replaces the polynomials of the proving key by the ones of the file at
+// path written by WritePolynomialsTo, memory-mapped so that the operating system pages
+// them in as the prover reads them. The mapping is read-only, as the prover never
+// writes the polynomials of the key: writing them faults instead of diverging from the
+// file, until UnmapPolynomials copies them back in memory.
+func (pk *ProvingKey) MapPolynomials(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	polys := pk.polynomials()
+	n := pk.Domain[0].Cardinality
+	size := polynomialsHeaderSize + int64(len(polys))*int64(n)*fr.Bytes
+	if info.Size() != size {
+		return fmt.Errorf("%s: got %d bytes, want %d", path, info.Size(), size)
+	}
+	data, err := mapFile(f, int(size))
+	if err != nil {
+		return err
+	}
+
+	var one fr.Element
+	one.SetOne()
+	switch {
+	case binary.LittleEndian.Uint64(data[0:]) != polynomialsVersion:
+		err = fmt.Errorf("%s: unsupported version %d", path, binary.LittleEndian.Uint64(data[0:]))
+	case binary.LittleEndian.Uint64(data[8:]) != n || binary.LittleEndian.Uint64(data[16:]) != uint64(len(polys)):
+		err = fmt.Errorf("%s: written for another proving key", path)
+	case bytesAsElements(data[polynomialsHeaderWords*8 : polynomialsHeaderSize])[0] != one:
+		err = fmt.Errorf("%s: written on another architecture", path)
+	}
+	if err != nil {
+		unmapFile(data)
+		return err
+	}
+
+	offset := polynomialsHeaderSize
+	for _, p := range polys {
+		*p = bytesAsElements(data[offset : offset+int(n)*fr.Bytes])
+		offset += int(n) * fr.Bytes
+	}
+	if pk.mapped != nil {
+		unmapFile(pk.mapped)
+	}
+	pk.mapped = data
+	return nil
+}
+
+// UnmapPolynomials copies the polynomials mapped by MapPolynomials back in memory, and
+// releases the mapping.
+func (pk *ProvingKey) UnmapPolynomials() error {
+	if pk.mapped == nil {
+		return nil
+	}
+	for _, p := range pk.polynomials() {
+		*p = append([]fr.Element(nil), *p...)
+	}
+	data := pk.mapped
+	pk.mapped = nil
+	return unmapFile(data)
+}
+
+// elementsAsBytes returns the memory of v, without copy
+func elementsAsBytes(v []fr.Element) []byte {
+	if len(v) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&v[0])), len(v)*fr.Bytes)
+}
+
+// bytesAsElements returns the elements in the memory of buf, without copy. buf must
+// be aligned on 8 bytes.
+func bytesAsElements(buf []byte) []fr.Element {
+	if len(buf) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*fr.Element)(unsafe.Pointer(&buf[0])), len(buf)/fr.Bytes)
+}
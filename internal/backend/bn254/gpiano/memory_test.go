@@ -0,0 +1,115 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	table := make([]fr.Element, 1<<4)
+	for i := range table {
+		table[i].SetUint64(uint64(i))
+	}
+	pk, vk, witnesses, err := SetupRandomLookup(ecc.BN254, randomNbConstraints, randomNbPublic, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := bn254witness.New(witnesses[0][:randomNbPublic])
+
+	prove := func(opts ...backend.ProverOption) []byte {
+		opt, err := backend.NewProverConfig(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := ProveDirect(pk, witnesses, publicWitness, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Verify(proof, vk, publicWitness); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := proof.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	want := prove()
+
+	// the quotient evaluated one polynomial at a time is the same
+	if got := prove(backend.WithMemoryBudget(quotientMemoryStreamedX(pk))); !bytes.Equal(got, want) {
+		t.Fatal("proof under a memory budget differs")
+	}
+
+	// and the prover fails under the budget of the streamed quotient
+	opt, err := backend.NewProverConfig(backend.WithMemoryBudget(quotientMemoryStreamedX(pk) - 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProveDirect(pk, witnesses, publicWitness, opt); !errors.Is(err, errMemoryBudget) {
+		t.Fatalf("got %v, want %v", err, errMemoryBudget)
+	}
+
+	// so are the polynomials of the key mapped from a file
+	path := filepath.Join(t.TempDir(), "polynomials")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pk.WritePolynomialsTo(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	q := append([]fr.Element(nil), pk.Q[0]...)
+	if err := pk.MapPolynomials(path); errors.Is(err, errMmapUnsupported) {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	var stats backend.ProverStats
+	if got := prove(backend.WithStats(&stats)); !bytes.Equal(got, want) {
+		t.Fatal("proof from the mapped polynomials differs")
+	}
+	if err := pk.UnmapPolynomials(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range q {
+		if !pk.Q[0][i].Equal(&q[i]) {
+			t.Fatal("polynomials changed by the mapping")
+		}
+	}
+
+	if stats.Duration == 0 || (runtime.GOOS == "linux" && stats.PeakRSS == 0) {
+		t.Fatalf("statistics not reported: %+v", stats)
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+)
+
+// The parties may hold X-domains of different sizes N_k, all powers of 2, hence
+// subgroups of the largest one, of size N = vk.SizeX. The polynomials of party k are
+// interpolated on its own X-domain, and the pieces of Hx are split by N so that they
+// are folded with alpha**N by every party.
+//
+// L_0(alpha), L_{N_k-1}(alpha) and alpha**N_k - 1 now depend on the party, so the
+// constraint on Y uses the polynomials in Y taking these values on the parties. Z and
+// Phi are opened on X = omega_N_k * alpha once per size N_k, and the shifted openings
+// of the parties are selected with the polynomials in Y which are one on the parties of
+// each size and zero elsewhere. These polynomials are public and of degree M-1, which
+// raises the degree of the numerator of Hy by M-1.
+
+// partition splits the rows of the circuit between the parties: party k holds the rows
+// [start[k], start[k]+rows[k]) in an X-domain of size sizes[k].
+type partition struct {
+	start, rows []int
+	sizes       []uint64
+}
+
+// newPartition splits nbRows rows between nbParties parties. Without weights, every
+// party gets an X-domain large enough for an equal share of the rows, and the last
+// parties may get fewer rows. Otherwise party k gets a share of the rows proportional
+// to weights[k], in an X-domain of the next power of 2.
+func newPartition(nbRows int, nbParties uint64, weights []uint64) (*partition, error) {
+	m := int(nbParties)
+	p := &partition{
+		start: make([]int, m),
+		rows:  make([]int, m),
+		sizes: make([]uint64, m),
+	}
+
+	if weights == nil {
+		n := int(ecc.NextPowerOfTwo(uint64((nbRows + m - 1) / m)))
+		for k := 0; k < m; k++ {
+			p.start[k] = k * n
+			if p.start[k] > nbRows {
+				p.start[k] = nbRows
+			}
+			p.rows[k] = nbRows - p.start[k]
+			if p.rows[k] > n {
+				p.rows[k] = n
+			}
+			p.sizes[k] = uint64(n)
+		}
+		return p, nil
+	}
+
+	if len(weights) != m {
+		return nil, fmt.Errorf("got %d weights for %d parties", len(weights), m)
+	}
+	var total uint64
+	for k, w := range weights {
+		if w == 0 {
+			return nil, fmt.Errorf("party %d has a weight of 0", k)
+		}
+		var carry uint64
+		if total, carry = bits.Add64(total, w, 0); carry != 0 {
+			return nil, errors.New("the sum of the weights overflows")
+		}
+	}
+
+	// largest remainder: the rows left after the rounded down shares go to the
+	// parties with the largest remainders, the first parties first
+	remainders := make([]uint64, m)
+	assigned := 0
+	for k, w := range weights {
+		hi, lo := bits.Mul64(uint64(nbRows), w)
+		q, r := bits.Div64(hi, lo, total)
+		p.rows[k] = int(q)
+		remainders[k] = r
+		assigned += int(q)
+	}
+	order := make([]int, m)
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+	for i := 0; i < nbRows-assigned; i++ {
+		p.rows[order[i]]++
+	}
+
+	for k := 0; k < m; k++ {
+		if k > 0 {
+			p.start[k] = p.start[k-1] + p.rows[k-1]
+		}
+		rows := uint64(p.rows[k])
+		if rows == 0 {
+			rows = 1
+		}
+		p.sizes[k] = ecc.NextPowerOfTwo(rows)
+	}
+	return p, nil
+}
+
+// partitionOf returns the partition where party k holds rows[k] rows
+func partitionOf(rows []int) *partition {
+	p := &partition{
+		start: make([]int, len(rows)),
+		rows:  append([]int(nil), rows...),
+		sizes: make([]uint64, len(rows)),
+	}
+	for k := range rows {
+		if k > 0 {
+			p.start[k] = p.start[k-1] + rows[k-1]
+		}
+		p.sizes[k] = ecc.NextPowerOfTwo(uint64(rows[k]))
+	}
+	return p
+}
+
+// maxSize returns the size of the largest X-domain
+func (p *partition) maxSize() uint64 {
+	var res uint64
+	for _, s := range p.sizes {
+		if s > res {
+			res = s
+		}
+	}
+	return res
+}
+
+// sizesX returns the sizes of the X-domains for the verifying key, nil if they are
+// all the same
+func (p *partition) sizesX() []uint64 {
+	for _, s := range p.sizes {
+		if s != p.sizes[0] {
+			return append([]uint64(nil), p.sizes...)
+		}
+	}
+	return nil
+}
+
+// partyOf returns the party holding the row of the circuit
+func (p *partition) partyOf(row int) int {
+	return sort.Search(len(p.start), func(k int) bool {
+		return p.start[k]+p.rows[k] > row
+	})
+}
+
+// sizeX returns the size of the X-domain of party k
+func (vk *VerifyingKey) sizeX(k uint64) uint64 {
+	if vk.SizesX == nil {
+		return vk.SizeX
+	}
+	return vk.SizesX[k]
+}
+
+// offsetX returns the sum of the sizes of the X-domains of the parties before party k
+func (vk *VerifyingKey) offsetX(k uint64) uint64 {
+	if vk.SizesX == nil {
+		return k * vk.SizeX
+	}
+	var res uint64
+	for _, s := range vk.SizesX[:k] {
+		res += s
+	}
+	return res
+}
+
+// sizeClasses returns the distinct sizes of the X-domains, from the largest
+func (vk *VerifyingKey) sizeClasses() []uint64 {
+	if vk.SizesX == nil {
+		return []uint64{vk.SizeX}
+	}
+	var res []uint64
+	seen := make(map[uint64]bool)
+	for _, s := range vk.SizesX {
+		if !seen[s] {
+			seen[s] = true
+			res = append(res, s)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] > res[j] })
+	return res
+}
+
+// generatorX returns the generator of the X-domain of the given size and its inverse
+func (vk *VerifyingKey) generatorX(size uint64) (g, gInv fr.Element) {
+	e := new(big.Int).SetUint64(vk.SizeX / size)
+	g.Exp(vk.GeneratorX, e)
+	gInv.Exp(vk.GeneratorXInv, e)
+	return
+}
+
+// classIndicators returns, for every size of sizeClasses, the values on the parties of
+// the polynomial in Y which is one on the parties of this size and zero elsewhere
+func (vk *VerifyingKey) classIndicators() [][]fr.Element {
+	classes := vk.sizeClasses()
+	res := make([][]fr.Element, len(classes))
+	for c, size := range classes {
+		res[c] = make([]fr.Element, vk.SizeY)
+		for k := uint64(0); k < vk.SizeY; k++ {
+			if vk.sizeX(k) == size {
+				res[c][k].SetOne()
+			}
+		}
+	}
+	return res
+}
+
+// lagrangeX returns, for every party k, L_0(alpha), L_{N_k-1}(alpha) and alpha**N_k - 1
+// on the X-domain of party k
+func (vk *VerifyingKey) lagrangeX(alpha fr.Element) (l0, ll, vanishing []fr.Element) {
+	l0 = make([]fr.Element, vk.SizeY)
+	ll = make([]fr.Element, vk.SizeY)
+	vanishing = make([]fr.Element, vk.SizeY)
+
+	var one fr.Element
+	one.SetOne()
+	done := make(map[uint64]uint64)
+	for k := uint64(0); k < vk.SizeY; k++ {
+		size := vk.sizeX(k)
+		if j, ok := done[size]; ok {
+			l0[k], ll[k], vanishing[k] = l0[j], ll[j], vanishing[j]
+			continue
+		}
+		done[size] = k
+
+		_, gInv := vk.generatorX(size)
+		var sizeInv, den fr.Element
+		sizeInv.SetUint64(size).Inverse(&sizeInv)
+		vanishing[k].Exp(alpha, new(big.Int).SetUint64(size)).Sub(&vanishing[k], &one)
+		den.Sub(&alpha, &one).Inverse(&den)
+		l0[k].Mul(&vanishing[k], &den).Mul(&l0[k], &sizeInv)
+		den.Sub(&alpha, &gInv).Inverse(&den)
+		ll[k].Mul(&vanishing[k], &den).Mul(&ll[k], &sizeInv).Mul(&ll[k], &gInv)
+	}
+	return
+}
+
+// lagrangeY returns the Lagrange polynomials of the Y-domain evaluated on beta, such that
+// the polynomial taking the values v on the parties evaluates to Σ v_k L_k(beta)
+func (vk *VerifyingKey) lagrangeY(beta fr.Element) []fr.Element {
+	res := make([]fr.Element, vk.SizeY)
+	omegas := make([]fr.Element, vk.SizeY)
+	omegas[0].SetOne()
+	for k := 1; k < len(omegas); k++ {
+		omegas[k].Mul(&omegas[k-1], &vk.GeneratorY)
+	}
+	for k := range omegas {
+		if beta.Equal(&omegas[k]) {
+			res[k].SetOne()
+			return res
+		}
+		res[k].Sub(&beta, &omegas[k])
+	}
+	res = fr.BatchInvert(res)
+
+	var one, num fr.Element
+	one.SetOne()
+	num.Exp(beta, new(big.Int).SetUint64(vk.SizeY)).Sub(&num, &one).Mul(&num, &vk.SizeYInv)
+	for k := range res {
+		res[k].Mul(&res[k], &omegas[k]).Mul(&res[k], &num)
+	}
+	return res
+}
+
+// evalY evaluates on beta the polynomial taking the values v on the parties, lagrange
+// being returned by lagrangeY
+func evalY(lagrange, v []fr.Element) fr.Element {
+	var res, t fr.Element
+	for k := range v {
+		t.Mul(&lagrange[k], &v[k])
+		res.Add(&res, &t)
+	}
+	return res
+}
+
+// canonicalY interpolates in place the polynomials taking the values v on the parties
+func canonicalY(v ...[]fr.Element) {
+	for i := range v {
+		globalDomain[0].FFTInverse(v[i], fft.DIF)
+		fft.BitReverse(v[i])
+	}
+}
+
+// nbQuotientPiecesY returns the number of pieces of Hy, the per-party polynomials
+// raising the degree of the numerator of Hy by M-1, see partition.
+func nbQuotientPiecesY(vk *VerifyingKey) int {
+	if vk.SizesX == nil {
+		return MAX_DEGREE
+	}
+	return MAX_DEGREE + 2
+}
+
+// splitQuotientX splits h in nbPieces pieces of size n, the last ones being shorter,
+// or a single zero if h is too short to reach them
+func splitQuotientX(h []fr.Element, n uint64, nbPieces int) [][]fr.Element {
+	res := make([][]fr.Element, nbPieces)
+	for i := range res {
+		start := uint64(i) * n
+		end := start + n
+		switch {
+		case start >= uint64(len(h)):
+			res[i] = make([]fr.Element, 1)
+		case end > uint64(len(h)):
+			res[i] = h[start:]
+		default:
+			res[i] = h[start:end]
+		}
+	}
+	return res
+}
+
+// setDomainX sets the size and the generators of the largest X-domain and the sizes
+// of the X-domains of the parties, domain being the X-domain of this party
+func (vk *VerifyingKey) setDomainX(domain *fft.Domain, part *partition) {
+	largest := domain
+	if domain.Cardinality != part.maxSize() {
+		largest = fft.NewDomain(part.maxSize())
+	}
+	vk.SizeX = largest.Cardinality
+	vk.SizeXInv = largest.CardinalityInv
+	vk.GeneratorX.Set(&largest.Generator)
+	vk.GeneratorXInv.Set(&largest.GeneratorInv)
+	vk.SizesX = part.sizesX()
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+)
+
+func TestPartition(t *testing.T) {
+	// without weights, every party gets the same X-domain
+	p, err := newPartition(100, 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows := []int{32, 32, 32, 4}
+	for k := range wantRows {
+		if p.sizes[k] != 32 || p.rows[k] != wantRows[k] || p.start[k] != 32*k {
+			t.Fatalf("party %d: got rows [%d, +%d) on %d, want [%d, +%d) on 32", k, p.start[k], p.rows[k], p.sizes[k], 32*k, wantRows[k])
+		}
+	}
+	if p.sizesX() != nil {
+		t.Fatal("equal X-domains should not be recorded in the verifying key")
+	}
+
+	// with weights, the rows are split proportionally
+	p, err = newPartition(1000, 3, []uint64{1, 2, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows = []int{125, 250, 625}
+	wantSizes := []uint64{128, 256, 1024}
+	start := 0
+	for k := range wantRows {
+		if p.start[k] != start || p.rows[k] != wantRows[k] || p.sizes[k] != wantSizes[k] {
+			t.Fatalf("party %d: got rows [%d, +%d) on %d, want [%d, +%d) on %d", k, p.start[k], p.rows[k], p.sizes[k], start, wantRows[k], wantSizes[k])
+		}
+		start += wantRows[k]
+	}
+	if p.maxSize() != 1024 || len(p.sizesX()) != 3 {
+		t.Fatal("unexpected X-domains")
+	}
+	if p.partyOf(0) != 0 || p.partyOf(374) != 1 || p.partyOf(375) != 2 || p.partyOf(999) != 2 {
+		t.Fatal("unexpected owner of the rows")
+	}
+
+	// the rows left by rounding down go to the largest remainders
+	p, err = newPartition(10, 3, []uint64{1, 1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.rows[0] != 4 || p.rows[1] != 3 || p.rows[2] != 3 {
+		t.Fatalf("got rows %v, want [4 3 3]", p.rows)
+	}
+
+	if _, err := newPartition(10, 3, []uint64{1, 2}); err == nil {
+		t.Fatal("missing weight should fail")
+	}
+	if _, err := newPartition(10, 2, []uint64{1, 0}); err == nil {
+		t.Fatal("zero weight should fail")
+	}
+}
+
+func TestLagrangeY(t *testing.T) {
+	domain := fft.NewDomain(8)
+	vk := VerifyingKey{
+		SizeY:      domain.Cardinality,
+		SizeYInv:   domain.CardinalityInv,
+		GeneratorY: domain.Generator,
+	}
+
+	v := make([]fr.Element, vk.SizeY)
+	for k := range v {
+		v[k].SetUint64(uint64(k*k + 1))
+	}
+
+	// on the Y-domain, the polynomial takes the values v
+	var omega fr.Element
+	omega.SetOne()
+	for k := range v {
+		got := evalY(vk.lagrangeY(omega), v)
+		if !got.Equal(&v[k]) {
+			t.Fatalf("got %s on omega**%d, want %s", got.String(), k, v[k].String())
+		}
+		omega.Mul(&omega, &vk.GeneratorY)
+	}
+
+	// elsewhere, it agrees with the polynomial in canonical form
+	var beta fr.Element
+	beta.SetUint64(42)
+	coeffs := append([]fr.Element(nil), v...)
+	domain.FFTInverse(coeffs, fft.DIF)
+	fft.BitReverse(coeffs)
+	var want fr.Element
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		want.Mul(&want, &beta).Add(&want, &coeffs[i])
+	}
+	got := evalY(vk.lagrangeY(beta), v)
+	if !got.Equal(&want) {
+		t.Fatalf("got %s on beta, want %s", got.String(), want.String())
+	}
+}
@@ -19,19 +19,22 @@
 package gpiano
 
 import (
-	"crypto/sha256"
+	"errors"
 	"fmt"
 	"math/big"
 	"math/bits"
 	"runtime"
+	"runtime/debug"
 	"time"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
 	"github.com/consensys/gnark-crypto/fiat-shamir"
 	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/comm"
 	"github.com/consensys/gnark/internal/backend/bn254/cs"
 	"github.com/consensys/gnark/internal/utils"
 	"github.com/consensys/gnark/logger"
@@ -69,17 +72,22 @@ type Proof struct {
 	// Z(Y, X) on X = alpha
 	PartialBatchedProof dkzg.BatchOpeningProof
 
-	// Opening partially proof of Z(Y, X) on X = omegaX * alpha
-	PartialZShiftedProof dkzg.OpeningProof
+	// Opening partially proofs of Z(Y, X) on X = omegaX * alpha, one per size of the
+	// X-domains of the parties, from the largest, omegaX generating the X-domain of
+	// this size
+	PartialZShiftedProofs []dkzg.OpeningProof
 
 	// Batch opening proof of FoldedHx(Y, alpha), L(Y, alpha), R(Y, alpha), O(Y, alpha),
 	// Ql(Y, alpha), Qr(Y, alpha), Qm(Y, alpha), Qo(Y, alpha), Qk(Y, alpha),
 	// Sy1(Y, alpha), Sy2(Y, alpha), Sy3(Y, alpha), Sx1(Y, alpha), Sx2(Y, alpha), Sx3(Y, alpha),
-	// Z(Y, alpha), z(Y, omegaX * alpha), W(Y), FoldedHy(Y) on Y = beta
+	// Z(Y, alpha), z(Y, omegaX * alpha), W(Y), FoldedHy(Y) on Y = beta, see batchOpenY
 	BatchedProof kzg.BatchOpeningProof
 
 	// Opening partially proof of W(Y) on Y = omegaY * beta
 	WShiftedProof kzg.OpeningProof
+
+	// Lookup argument, nil if the circuit doesn't look up values in a table
+	Lookup *LookupProof
 }
 
 // Prove from the public data
@@ -104,10 +112,13 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 	fmt.Println("Solution computed")
 
 	// pick a hash function that will be used to derive the challenges
-	hFunc := sha256.New()
+	hFunc, err := pk.Vk.TranscriptHash.New(ecc.BN254)
+	if err != nil {
+		return nil, err
+	}
 
 	// create a transcript manager to apply Fiat Shamir
-	fs := fiatshamir.NewTranscript(hFunc, "gamma", "etaY", "etaX", "lambda", "alpha", "beta")
+	fs := fiatshamir.NewTranscript(hFunc, challengeNames(pk.Vk)...)
 	
 	// query L, R, O in Lagrange basis, not blinded
 	lSmallX, rSmallX, oSmallX := evaluateLROSmallDomainX(spr, pk, solution)
@@ -115,15 +126,36 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 	return ProveCommon(&fs, pk, [][]fr.Element{lSmallX, rSmallX, oSmallX}, fullWitness[:spr.NbPublicVariables], opt)
 }
 
+// ProveDirect proves from the witness columns of the rows held by this party, which
+// may stop at the last row instead of filling the X-domain.
 func ProveDirect(pk *ProvingKey,
 	witnesses [][]fr.Element,
 	publicInput []fr.Element,
 	opt backend.ProverConfig) (*Proof, error) {
+	if len(witnesses) != NUM_WITNESSES {
+		return nil, fmt.Errorf("got %d witness columns, want %d", len(witnesses), NUM_WITNESSES)
+	}
+	n := int(pk.Domain[0].Cardinality)
+	witnesses = append([][]fr.Element(nil), witnesses...)
+	for i := range witnesses {
+		if len(witnesses[i]) > n {
+			return nil, fmt.Errorf("witness column %d has %d rows, more than %d", i, len(witnesses[i]), n)
+		}
+		if len(witnesses[i]) < n {
+			padded := make([]fr.Element, n)
+			copy(padded, witnesses[i])
+			witnesses[i] = padded
+		}
+	}
+
 	// pick a hash function that will be used to derive the challenges
-	hFunc := sha256.New()
+	hFunc, err := pk.Vk.TranscriptHash.New(ecc.BN254)
+	if err != nil {
+		return nil, err
+	}
 
 	// create a transcript manager to apply Fiat Shamir
-	fs := fiatshamir.NewTranscript(hFunc, "gamma", "etaY", "etaX", "lambda", "alpha", "beta")
+	fs := fiatshamir.NewTranscript(hFunc, challengeNames(pk.Vk)...)
 
 	return ProveCommon(&fs, pk, witnesses, publicInput, opt)
 }
@@ -138,26 +170,48 @@ func ProveCommon(fs *fiatshamir.Transcript,
 	log := logger.Logger().With().Str("backend", "gpiano").Logger()
 	start := time.Now()
 	// pick a hash function that will be used to derive the challenges
-	hFunc := sha256.New()
+	hFunc, err := pk.Vk.TranscriptHash.New(ecc.BN254)
+	if err != nil {
+		return nil, err
+	}
 
 	// result
 	proof := &Proof{}
 
-	// save lL, lR, lO, and make a copy of them in
-	// canonical basis note that we allocate more capacity to reuse for blinded
-	// polynomials
-	witCanonicalX := computeWitnessCanonicalX(
-		witnesses,
-		&pk.Domain[0],
-	)
+	// over the memory budget, the polynomials are evaluated on the cosets one at a time
+	streamed, err := quotientStreamed(pk, opt.MemoryBudget)
+	if err != nil {
+		return nil, err
+	}
 
-	// compute kzg commitments of bcL, bcR and bcO
+	// resume from the rounds checkpointed by all the parties
+	ckpt, err := openCheckpoint(opt.CheckpointDir, pk, witnesses, publicInput)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
 
-	step := time.Now()
-	if err := commitWitnesses(witCanonicalX, proof, pk.Vk.DKZGSRS); err != nil {
-		return nil, err
+	var witCanonicalX [][]fr.Element
+	if ckpt.resumes(roundWitnesses) {
+		if err := ckpt.load(roundWitnesses, &witCanonicalX, &proof.witnesses); err != nil {
+			return nil, fmt.Errorf("checkpoint: %w", err)
+		}
+	} else {
+		// save lL, lR, lO, and make a copy of them in
+		// canonical basis note that we allocate more capacity to reuse for blinded
+		// polynomials
+		witCanonicalX = computeWitnessCanonicalX(
+			witnesses,
+			&pk.Domain[0],
+		)
+
+		// compute kzg commitments of bcL, bcR and bcO
+
+		step := time.Now()
+		if err := commitWitnesses(witCanonicalX, proof, pk.Vk.DKZGSRS); err != nil {
+			return nil, err
+		}
+		log.Debug().Dur("took", time.Since(step)).Msg("commitWitnesses")
 	}
-	log.Debug().Dur("took", time.Since(step)).Msg("commitWitnesses")
 
 	// The first challenge is derived using the public data: the commitments to the permutation,
 	// the coefficients of the circuit, and the public inputs.
@@ -185,51 +239,104 @@ func ProveCommon(fs *fiatshamir.Transcript,
 	if err != nil {
 		return nil, err
 	}
-
-	// compute Z, the permutation accumulator polynomial, in canonical basis
-	// lL, lR, lO are NOT blinded
-	
-	zCanonicalX, selfProd, err := computeZCanonicalX(
-		witnesses,
-		pk, etaY, etaX, gamma,
-	)
-	if err != nil {
-		return nil, err
+	if err := ckpt.complete(roundWitnesses, []fr.Element{gamma, etaY, etaX}, &witCanonicalX, &proof.witnesses); err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
 	}
 
-	wSmallY, wCanonicalY, pW, cW, err := computeWCanonicalY(selfProd)
-	if err != nil {
-		return nil, err
+	var lw *lookupWitness
+	var zCanonicalX, wSmallY, wCanonicalY []fr.Element
+	pW, cW := new(fr.Element), new(fr.Element)
+	accumulators := func() []interface{} {
+		state := []interface{}{&zCanonicalX, &wSmallY, &wCanonicalY, pW, cW, &proof.Z, &proof.W}
+		if lw != nil {
+			state = append(state, &lw.m, &lw.phi, &lw.pS, &lw.cS, &lw.sSmallY, &lw.sCanonicalY,
+				&proof.Lookup.M, &proof.Lookup.Phi, &proof.Lookup.S)
+		}
+		return state
 	}
+	if ckpt.resumes(roundAccumulators) {
+		if pk.Lookup != nil {
+			lw, proof.Lookup = &lookupWitness{}, &LookupProof{}
+		}
+		if err := ckpt.load(roundAccumulators, accumulators()...); err != nil {
+			return nil, fmt.Errorf("checkpoint: %w", err)
+		}
+		// derive delta from Comm(M)
+		if lw != nil {
+			if lw.delta, err = deriveRandomness(fs, "delta", false, &proof.Lookup.M); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// compute Phi and S, the accumulators of the lookup argument
+		if pk.Lookup != nil {
+			if lw, err = proveLookup(fs, pk, proof, witnesses[0]); err != nil {
+				return nil, err
+			}
+		}
 
-	// commit to z
-	// note that we explicitly double the number of tasks for the multi exp
-	// in dkzg.Commit
-	// this may add additional arithmetic operations, but with smaller tasks
-	// we ensure that this commitment is well parallelized, without having a
-	// "unbalanced task" making the rest of the code wait too long
-	if proof.Z, err = dkzg.Commit(zCanonicalX, pk.Vk.DKZGSRS, runtime.NumCPU()*2); err != nil {
-		return nil, err
-	}
-	if mpi.SelfRank == 0 {
-		if proof.W, err = kzg.Commit(wCanonicalY, globalSRS); err != nil {
+		// compute Z, the permutation accumulator polynomial, in canonical basis
+		// lL, lR, lO are NOT blinded
+
+		var selfProd fr.Element
+		zCanonicalX, selfProd, err = computeZCanonicalX(
+			witnesses,
+			pk, etaY, etaX, gamma,
+		)
+		if err != nil {
 			return nil, err
 		}
+
+		wSmallY, wCanonicalY, pW, cW, err = computeWCanonicalY(selfProd)
+		if err != nil {
+			return nil, err
+		}
+
+		// commit to z
+		// note that we explicitly double the number of tasks for the multi exp
+		// in dkzg.Commit
+		// this may add additional arithmetic operations, but with smaller tasks
+		// we ensure that this commitment is well parallelized, without having a
+		// "unbalanced task" making the rest of the code wait too long
+		if proof.Z, err = dkzg.Commit(zCanonicalX, pk.Vk.DKZGSRS, runtime.NumCPU()*2); err != nil {
+			return nil, err
+		}
+		if mpi.SelfRank == 0 {
+			if proof.W, err = kzg.Commit(wCanonicalY, globalSRS); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// derive lambda from the Comm(L), Comm(R), Comm(O), Com(Z)
-	lambda, err := deriveRandomness(fs, "lambda", false, &proof.Z, &proof.W)
+	lambda, err := deriveRandomness(fs, "lambda", false, proof.accumulators()...)
 	if err != nil {
 		return nil, err
 	}
+	challenges := []fr.Element{lambda}
+	if lw != nil {
+		challenges = []fr.Element{lw.delta, lambda}
+	}
+	if err := ckpt.complete(roundAccumulators, challenges, accumulators()...); err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
 
-	hx := computeQuotientCanonicalX(pk, witCanonicalX, zCanonicalX, *pW, *cW, etaY, etaX, gamma, lambda)
+	var hx [][]fr.Element
+	if ckpt.resumes(roundQuotientX) {
+		if err := ckpt.load(roundQuotientX, &hx, &proof.Hx); err != nil {
+			return nil, fmt.Errorf("checkpoint: %w", err)
+		}
+	} else {
+		step := time.Now()
+		hx = computeQuotientCanonicalX(pk, witCanonicalX, zCanonicalX, *pW, *cW, etaY, etaX, gamma, lambda, lw, streamed)
+		log.Debug().Dur("took", time.Since(step)).Bool("streamed", streamed).Msg("computeQuotientCanonicalX")
 
-	// print vector of hx1, hx2, hx3, hx4
+		// print vector of hx1, hx2, hx3, hx4
 
-	// compute kzg commitments of Hx1, Hx2, Hx3, Hx4
-	if err := commitToQuotientX(hx, proof, pk.Vk.DKZGSRS); err != nil {
-		return nil, err
+		// compute kzg commitments of Hx1, Hx2, Hx3, Hx4
+		if err := commitToQuotientX(hx, proof, pk.Vk.DKZGSRS); err != nil {
+			return nil, err
+		}
 	}
 
 	// derive alpha
@@ -237,23 +344,47 @@ func ProveCommon(fs *fiatshamir.Transcript,
 	if err != nil {
 		return nil, err
 	}
+	if err := ckpt.complete(roundQuotientX, []fr.Element{alpha}, &hx, &proof.Hx); err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
 
-	// open Z at u*alpha
-	var alphaShifted fr.Element
-	alphaShifted.Mul(&alpha, &pk.Domain[0].Generator)
-	var zShiftedAlpha []fr.Element
-	proof.PartialZShiftedProof, zShiftedAlpha, err = dkzg.Open(
-		zCanonicalX,
-		alphaShifted,
-		pk.Vk.DKZGSRS,
-	)
-	if err != nil {
-		return nil, err
+	// open Z and Phi at u*alpha, u generating the X-domains of each size
+	classes := pk.Vk.sizeClasses()
+	proof.PartialZShiftedProofs = make([]dkzg.OpeningProof, len(classes))
+	zShiftedAlpha := make([][]fr.Element, len(classes))
+	var phiShiftedAlpha [][]fr.Element
+	if lw != nil {
+		proof.Lookup.PartialPhiShiftedProofs = make([]dkzg.OpeningProof, len(classes))
+		phiShiftedAlpha = make([][]fr.Element, len(classes))
+	}
+	for c, size := range classes {
+		var alphaShifted fr.Element
+		generator, _ := pk.Vk.generatorX(size)
+		alphaShifted.Mul(&alpha, &generator)
+		proof.PartialZShiftedProofs[c], zShiftedAlpha[c], err = dkzg.Open(
+			zCanonicalX,
+			alphaShifted,
+			pk.Vk.DKZGSRS,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if lw != nil {
+			proof.Lookup.PartialPhiShiftedProofs[c], phiShiftedAlpha[c], err = dkzg.Open(
+				lw.phi,
+				alphaShifted,
+				pk.Vk.DKZGSRS,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// foldedHDigest = Comm(Hx1) + (alpha**(N))*Comm(Hx2) + (alpha**(2(N)))*Comm(Hx3) + (alpha**(3(N)))*Comm(Hx4)
 	var bAlphaPowerN, bSize big.Int
-	bSize.SetUint64(pk.Domain[0].Cardinality)
+	bSize.SetUint64(pk.Vk.SizeX)
 	var alphaPowerN fr.Element
 	alphaPowerN.Exp(alpha, &bSize)
 	alphaPowerN.ToBigIntRegular(&bAlphaPowerN)
@@ -263,13 +394,16 @@ func ProveCommon(fs *fiatshamir.Transcript,
 		foldedHxDigest.Add(&foldedHxDigest, &proof.Hx[i])
 	}
 
-	// foldedHx = Hx1 + (alpha**(N))*Hx2 + (alpha**(2(N)))*Hx3
-	foldedHx := hx[len(hx) - 1]
+	// foldedHx = Hx1 + (alpha**(N))*Hx2 + (alpha**(2(N)))*Hx3, the last pieces
+	// being shorter on the parties with a smaller X-domain
+	foldedHx := make([]fr.Element, len(hx[0]))
 	utils.Parallelize(len(foldedHx), func(start, end int) {
 		for i := start; i < end; i++ {
-			for j := len(hx) - 2; j >= 0; j-- {
+			for j := len(hx) - 1; j >= 0; j-- {
 				foldedHx[i].Mul(&foldedHx[i], &alphaPowerN)
-				foldedHx[i].Add(&foldedHx[i], &hx[j][i])
+				if i < len(hx[j]) {
+					foldedHx[i].Add(&foldedHx[i], &hx[j][i])
+				}
 			}
 		}
 	})
@@ -290,6 +424,10 @@ func ProveCommon(fs *fiatshamir.Transcript,
 	dkzgDigests = append(dkzgDigests, pk.Vk.Q...)
 	dkzgDigests = append(dkzgDigests, pk.Vk.Sy...)
 	dkzgDigests = append(dkzgDigests, pk.Vk.Sx...)
+	if lw != nil {
+		dkzgOpeningPolys = append(dkzgOpeningPolys, pk.Lookup.T, pk.Lookup.Q, lw.m, lw.phi)
+		dkzgDigests = append(dkzgDigests, pk.Vk.Lookup.T, pk.Vk.Lookup.Q, proof.Lookup.M, proof.Lookup.Phi)
+	}
 
 	// Batch open the first list of polynomials
 	var evalsXOnAlpha [][]fr.Element
@@ -305,11 +443,34 @@ func ProveCommon(fs *fiatshamir.Transcript,
 		return nil, err
 	}
 
+	// the work on Y is shared between the parties: the polynomials on Y are the ones
+	// opened on X followed by Z(Y, omegaX*alpha) for each size of X-domain, W and, with
+	// lookups, Phi(Y, omegaX*alpha) for each size and S, W and S being computed by rank 0
+	// in canonical form, see quotientY and batchOpenY
+	nbPolysY := len(dkzgOpeningPolys) + len(classes) + 1
+	canonical := []int{nbPolysY - 1}
+	if lw != nil {
+		nbPolysY += len(classes) + 1
+		canonical = append(canonical, nbPolysY-1)
+	}
+	owners := ownersY(nbPolysY, canonical, comm.Default.Size())
+
 	if mpi.SelfRank != 0 {
-		log.Debug().Dur("took", time.Since(start)).Msg("prover done")
+		_, ownedY, err := quotientY(comm.Default, pk, nil, owners, canonical, etaY, etaX, gamma, lambda, alpha, lw)
 		if err != nil {
 			return nil, err
 		}
+		// rank 0 opens FoldedHy
+		if _, err := batchOpenY(comm.Default, append(ownedY, nil), append(owners, 0), fr.Element{}, globalSRS, nil); err != nil {
+			return nil, err
+		}
+		if err := ckpt.clear(); err != nil {
+			return nil, fmt.Errorf("checkpoint: %w", err)
+		}
+		if opt.Stats != nil {
+			*opt.Stats = backend.ProverStats{Duration: time.Since(start), PeakRSS: peakRSS()}
+		}
+		log.Debug().Dur("took", time.Since(start)).Uint64("peakRSS", peakRSS()).Msg("prover done")
 
 		return proof, nil
 	}
@@ -320,6 +481,8 @@ func ProveCommon(fs *fiatshamir.Transcript,
 		evalsXOnAlpha,
 		zShiftedAlpha,
 		wSmallY,
+		phiShiftedAlpha,
+		lw,
 		etaY,
 		etaX,
 		gamma,
@@ -329,22 +492,22 @@ func ProveCommon(fs *fiatshamir.Transcript,
 		return nil, err
 	}
 
-	polysCanonicalY := append(evalsXOnAlpha, zShiftedAlpha)
-	for i := 0; i < len(polysCanonicalY); i++ {
-		globalDomain[0].FFTInverse(polysCanonicalY[i], fft.DIF)
-		fft.BitReverse(polysCanonicalY[i])
+	polysY := append(evalsXOnAlpha, zShiftedAlpha...)
+	polysY = append(polysY, wCanonicalY)
+	if lw != nil {
+		polysY = append(polysY, phiShiftedAlpha...)
+		polysY = append(polysY, lw.sCanonicalY)
 	}
-	polysCanonicalY = append(polysCanonicalY, wCanonicalY)
 
 	// compute Hy in canonical form
-	hy := computeQuotientCanonicalY(pk,
-		polysCanonicalY,
-		etaY,
-		etaX,
-		gamma,
-		lambda,
-		alpha,
-	)
+	h, ownedY, err := quotientY(comm.Default, pk, polysY, owners, canonical, etaY, etaX, gamma, lambda, alpha, lw)
+	if err != nil {
+		return nil, err
+	}
+	hy, err := splitQuotientY(h, pk.Vk)
+	if err != nil {
+		return nil, err
+	}
 
 	// compute kzg commitments of Hy1, Hy2 and Hy3
 	if err := commitToQuotientOnY(hy, proof, globalSRS); err != nil {
@@ -354,11 +517,11 @@ func ProveCommon(fs *fiatshamir.Transcript,
 	ts := []*curve.G1Affine{
 		&proof.PartialBatchedProof.H,
 	}
-	for _, digest := range proof.PartialBatchedProof.ClaimedDigests {
-		ts = append(ts, &digest)
+	for i := range proof.PartialBatchedProof.ClaimedDigests {
+		ts = append(ts, &proof.PartialBatchedProof.ClaimedDigests[i])
 	}
-	for _, digest := range proof.Hy {
-		ts = append(ts, &digest)
+	for i := range proof.Hy {
+		ts = append(ts, &proof.Hy[i])
 	}
 	beta, err := deriveRandomness(fs, "beta", true, ts...)
 	if err != nil {
@@ -366,17 +529,9 @@ func ProveCommon(fs *fiatshamir.Transcript,
 	}
 
 	// foldedHy = Hy1 + (beta**M)*Hy2 + (beta**(2M))*Hy3 + (beta**(3M))*Hy4
-	var bBetaPowerM big.Int
 	bSize.SetUint64(globalDomain[0].Cardinality)
 	var betaPowerM fr.Element
 	betaPowerM.Exp(beta, &bSize)
-	betaPowerM.ToBigIntRegular(&bBetaPowerM)
-
-	foldedHyDigest := proof.Hy[len(proof.Hy) - 1]
-	for i := len(proof.Hy) - 2; i >= 0; i-- {
-		foldedHyDigest.ScalarMultiplication(&foldedHyDigest, &bBetaPowerM)
-		foldedHyDigest.Add(&foldedHyDigest, &proof.Hy[i])
-	}
 
 	// foldedHx = Hx1 + (alpha**(N))*Hx2 + (alpha**(2(N)))*Hx3
 	foldedHy := hy[len(hy) - 1]
@@ -389,48 +544,76 @@ func ProveCommon(fs *fiatshamir.Transcript,
 		}
 	})
 
-	polysCanonicalY = append(polysCanonicalY, foldedHy)
-
-	evalsOnBeta := evalPolynomialsAtPoint(polysCanonicalY, beta)
 	var betaShifted fr.Element
 	betaShifted.Mul(&beta, &globalDomain[0].Generator)
-	// DBG check whether constraints are satisfied
-	if err := checkConstraintY(pk.Vk,
-		evalsOnBeta,
-		eval(wCanonicalY, betaShifted),
-		etaY,
-		etaX,
-		gamma,
-		lambda,
-		alpha,
-		beta,
-	); err != nil {
+	var sShiftedBeta, delta fr.Element
+	if lw != nil {
+		sShiftedBeta = eval(lw.sCanonicalY, betaShifted)
+		delta = lw.delta
+	}
+
+	// the parties open the polynomials they own on beta, rank 0 opening FoldedHy,
+	// and nu folding them is derived from their digests and evaluations
+	proof.BatchedProof, err = batchOpenY(comm.Default, append(ownedY, foldedHy), append(owners, 0), beta, globalSRS, func(evalsOnBeta []fr.Element) (fr.Element, error) {
+		// DBG check whether constraints are satisfied
+		if err := checkConstraintY(pk.Vk,
+			evalsOnBeta,
+			eval(wCanonicalY, betaShifted),
+			sShiftedBeta,
+			etaY,
+			etaX,
+			gamma,
+			delta,
+			lambda,
+			alpha,
+			beta,
+		); err != nil {
+			return fr.Element{}, err
+		}
+		return deriveNu(fs, digestsOnBeta(proof, foldHyDigests(proof.Hy, beta, pk.Vk.SizeY)), evalsOnBeta)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	var digestsY []curve.G1Affine
-	digestsY = append(digestsY, proof.PartialBatchedProof.ClaimedDigests...)
-	digestsY = append(digestsY, proof.PartialZShiftedProof.ClaimedDigest, proof.W, foldedHyDigest)
-	proof.BatchedProof, err = kzg.BatchOpenSinglePoint(
-		polysCanonicalY,
-		digestsY,
-		beta,
-		hFunc,
-		globalSRS,
-	)
-	
 	proof.WShiftedProof, err = kzg.Open(
 		wCanonicalY,
 		betaShifted,
 		globalSRS,
 	)
-	log.Debug().Dur("took", time.Since(start)).Msg("prover done")
 	if err != nil {
 		return nil, err
 	}
+	if lw != nil {
+		proof.Lookup.SShiftedProof, err = kzg.Open(
+			lw.sCanonicalY,
+			betaShifted,
+			globalSRS,
+		)
+	}
+	if opt.Stats != nil {
+		*opt.Stats = backend.ProverStats{Duration: time.Since(start), PeakRSS: peakRSS()}
+	}
+	log.Debug().Dur("took", time.Since(start)).Uint64("peakRSS", peakRSS()).Msg("prover done")
+	if err != nil {
+		return nil, err
+	}
+	if err := ckpt.clear(); err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
 	return proof, nil
 }
 
+// accumulators returns the commitments to the accumulators of the permutation
+// and lookup arguments, from which lambda is derived
+func (proof *Proof) accumulators() []*curve.G1Affine {
+	res := []*curve.G1Affine{&proof.Z, &proof.W}
+	if proof.Lookup != nil {
+		res = append(res, &proof.Lookup.Phi, &proof.Lookup.S)
+	}
+	return res
+}
+
 // eval evaluates c at p
 func eval(c []fr.Element, p fr.Element) fr.Element {
 	var r fr.Element
@@ -440,14 +623,6 @@ func eval(c []fr.Element, p fr.Element) fr.Element {
 	return r
 }
 
-func evalPolynomialsAtPoint(polys [][]fr.Element, point fr.Element) []fr.Element {
-	res := make([]fr.Element, len(polys))
-	for i := range polys {
-		res[i] = eval(polys[i], point)
-	}
-	return res
-}
-
 func commitWitnesses(witnesses [][]fr.Element, proof *Proof, srs *dkzg.SRS) error {
 	n := runtime.NumCPU() / 2
 	var err error
@@ -549,35 +724,25 @@ func evaluateLROSmallDomainX(spr *cs.SparseR1CS, pk *ProvingKey, solution []fr.E
 		panic("The current circuit partition requires the public input be fitted in the first circuit.")
 	}
 
-	var offset int
+	start := pk.RowStart
 	if mpi.SelfRank == 0 {
 		for i := 0; i < spr.NbPublicVariables; i++ { // placeholders
 			l[i].Set(&solution[i])
 			r[i] = s0
 			o[i] = s0
 		}
-		offset = spr.NbPublicVariables
-	} else {
-		offset = 0
+		start += spr.NbPublicVariables
 	}
 
-	start := int(mpi.SelfRank) * n + offset
-	end := start - offset + n
-	if end > len(spr.Constraints) + spr.NbPublicVariables {
-		end = len(spr.Constraints) + spr.NbPublicVariables
-	}
+	end := pk.RowStart + pk.NbRows
 	for i := start; i < end; i++ { // constraints
-		j := i % n
+		j := i - pk.RowStart
 		ii := i - spr.NbPublicVariables
 		l[j].Set(&solution[spr.Constraints[ii].L.WireID()])
 		r[j].Set(&solution[spr.Constraints[ii].R.WireID()])
 		o[j].Set(&solution[spr.Constraints[ii].O.WireID()])
 	}
-	offset += end - start
-	if offset < 0 {
-		offset = 0
-	}
-	for i := offset; i < n; i++ { // offset to reach 2**n constraints (where the id of l,r,o is 0, so we assign solution[0])
+	for i := pk.NbRows; i < n; i++ { // offset to reach 2**n constraints (where the id of l,r,o is 0, so we assign solution[0])
 		l[i] = s0
 		r[i] = s0
 		o[i] = s0
@@ -605,6 +770,7 @@ func computeZCanonicalX(witnesses [][]fr.Element, pk *ProvingKey, etaY, etaX, ga
 
 	IDys := getIDySmallDomain(globalDomain[0])
 	IDxs := getIDxSmallDomain(&pk.Domain[0], len(witnesses))
+	permutedIDxs := getPermutedIDx(pk)
 
 	var IDEtaY fr.Element
 	IDEtaY.Mul(&IDys[mpi.SelfRank], &etaY)
@@ -622,7 +788,7 @@ func computeZCanonicalX(witnesses [][]fr.Element, pk *ProvingKey, etaY, etaX, ga
 			for j := 0; j < len(witnesses); j++ {
 				f[j].Mul(&IDxs[i+j*n], &etaX).Add(&f[j], &IDEtaY).Add(&f[j], &witnesses[j][i]).Add(&f[j], &gamma)
 				t[j].Mul(&IDys[pk.PermutationY[i+j*n]], &etaY)
-				g[j].Mul(&IDxs[pk.PermutationX[i+j*n]], &etaX).Add(&g[j], &t[j]).Add(&g[j], &witnesses[j][i]).Add(&g[j], &gamma)
+				g[j].Mul(&permutedIDxs[i+j*n], &etaX).Add(&g[j], &t[j]).Add(&g[j], &witnesses[j][i]).Add(&g[j], &gamma)
 			}
 			for j := 1; j < len(witnesses); j++ {
 				f[0].Mul(&f[0], &f[j])
@@ -646,55 +812,50 @@ func computeZCanonicalX(witnesses [][]fr.Element, pk *ProvingKey, etaY, etaX, ga
 }
 
 func computeWCanonicalY(selfProd fr.Element) ([]fr.Element, []fr.Element, *fr.Element, *fr.Element, error) {
+	sendBuf := selfProd.Bytes()
+	prods, err := comm.Default.Gather(sendBuf[:])
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	var W []fr.Element
+	var parts [][]byte
 	if mpi.SelfRank == 0 {
-		W := make([]fr.Element, mpi.WorldSize + 1)
+		W = make([]fr.Element, mpi.WorldSize + 1)
 		W[0].SetOne()
-		W[1] = selfProd
-		for i := uint64(1); i < mpi.WorldSize; i++ {
-			recvBuf, err := mpi.ReceiveBytes(fr.Bytes, i)
-			if err != nil {
-				return nil, nil, nil, nil, err
-			}
-			W[i + 1].SetBytes(recvBuf)
+		for i := range prods {
+			W[i + 1].SetBytes(prods[i])
 		}
 		for i := uint64(1); i < mpi.WorldSize; i++ {
 			W[i + 1].Mul(&W[i + 1], &W[i])
 		}
 		// DBG: Check whether the product is one.
 		if !W[mpi.WorldSize].IsOne() {
-			// wait for 10 seconds to see the output
 			return nil, nil, nil, nil, fmt.Errorf("the product of Z is not one, got %v", W[mpi.WorldSize])
 		}
-		for i := uint64(1); i < mpi.WorldSize; i++ {
-			// concatenate W[i].Bytes() and W[i+1].Bytes()
-			a := W[i].Bytes()
-			b:= W[i + 1].Bytes()
-			sendBuf := make([]byte, len(a)+len(b))
-			copy(sendBuf, a[:])
-			copy(sendBuf[len(a):], b[:])
-			if err := mpi.SendBytes(sendBuf, i); err != nil {
-				return nil, nil, nil, nil, err
-			}
-		}
-		wCanonicalY := make([]fr.Element, mpi.WorldSize)
-		copy(wCanonicalY, W[:len(W) - 1])
-		globalDomain[0].FFTInverse(wCanonicalY, fft.DIF)
-		fft.BitReverse(wCanonicalY)
-		return W[:len(W) - 1], wCanonicalY, &W[0], &W[1], nil
-	} else {
-		sendBuf := selfProd.Bytes()
-		if err := mpi.SendBytes(sendBuf[:], 0); err != nil {
-			return nil, nil, nil, nil, err
-		}
-		recvBuf, err := mpi.ReceiveBytes(2 * fr.Bytes, 0)
-		if err != nil {
-			return nil, nil, nil, nil, err
+		// party i gets W[i] and W[i+1]
+		parts = make([][]byte, mpi.WorldSize)
+		for i := range parts {
+			parts[i] = elementsToBytes(W[i:i + 2])
 		}
-		var l, r fr.Element
-		l.SetBytes(recvBuf[:fr.Bytes])
-		r.SetBytes(recvBuf[fr.Bytes:])
+	}
+
+	recvBuf, err := comm.Default.Scatter(parts, 2 * fr.Bytes)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	var l, r fr.Element
+	l.SetBytes(recvBuf[:fr.Bytes])
+	r.SetBytes(recvBuf[fr.Bytes:])
+	if mpi.SelfRank != 0 {
 		return nil, nil, &l, &r, nil
 	}
+
+	wCanonicalY := make([]fr.Element, mpi.WorldSize)
+	copy(wCanonicalY, W[:len(W) - 1])
+	globalDomain[0].FFTInverse(wCanonicalY, fft.DIF)
+	fft.BitReverse(wCanonicalY)
+	return W[:len(W) - 1], wCanonicalY, &l, &r, nil
 }
 
 // evaluateXnMinusOneBig evalutes X^N-1 on DomainBig coset
@@ -731,8 +892,12 @@ const MAX_DEGREE = 5
 //      L_{n-1}(X)*(cW*g1(X)*g2(X)*g3(X) - pW*z(X)*f1(X)*f2(X)*f3(X))
 // )
 // + (lambda**2) * L0(X)*(z(X)-1)
+// + (lambda**4) * lookup(X) if the circuit has table lookups, see lookupConstraint
 // = hx(X)Zn(X)
-func computeQuotientCanonicalX(pk *ProvingKey, witCanonicalX [][]fr.Element, zCanonicalX []fr.Element, pW, cW, etaY, etaX, gamma, lambda fr.Element) [][]fr.Element {
+//
+// If streamed is set, the selectors and the permutation polynomials are evaluated on
+// each coset one at a time, see gateOnCoset and permutationOnCoset.
+func computeQuotientCanonicalX(pk *ProvingKey, witCanonicalX [][]fr.Element, zCanonicalX []fr.Element, pW, cW, etaY, etaX, gamma, lambda fr.Element, lw *lookupWitness, streamed bool) [][]fr.Element {
 	ratio := pk.Domain[1].Cardinality / pk.Domain[0].Cardinality
 
 	// Compute the power of domain[1].Generator with bit-reversed order.
@@ -766,6 +931,9 @@ func computeQuotientCanonicalX(pk *ProvingKey, witCanonicalX [][]fr.Element, zCa
 		LagLst[i].Mul(&LagLst[i-1], &pk.Domain[0].Generator)
 	}
 
+	var lambda4 fr.Element
+	lambda4.Square(&lambda).Square(&lambda4)
+
 	n := pk.Domain[0].Cardinality
 	nn := uint64(64 - bits.TrailingZeros64(uint64(pk.Domain[0].Cardinality)))
 	h := make([]fr.Element, pk.Domain[1].Cardinality)
@@ -774,29 +942,45 @@ func computeQuotientCanonicalX(pk *ProvingKey, witCanonicalX [][]fr.Element, zCa
 		l0 := pk.Domain[0].FFTPart(Lag0, fft.DIF, factorsBR[_j], true)
 		ll := pk.Domain[0].FFTPart(LagLst, fft.DIF, factorsBR[_j], true)
 
-		sy := make([][]fr.Element, len(pk.Sy))
-		for i := 0; i < len(pk.Sy); i++ {
-			sy[i] = pk.Domain[0].FFTPart(pk.Sy[i], fft.DIF, factorsBR[_j], true)
-		}
-		sx := make([][]fr.Element, len(pk.Sx))
-		for i := 0; i < len(pk.Sy); i++ {
-			sx[i] = pk.Domain[0].FFTPart(pk.Sx[i], fft.DIF, factorsBR[_j], true)
-		}
 		z := pk.Domain[0].FFTPart(zCanonicalX, fft.DIF, factorsBR[_j], true)
 
-		q := make([][]fr.Element, len(pk.Q))
-		for i := 0; i < len(pk.Q); i++ {
-			q[i] = pk.Domain[0].FFTPart(pk.Q[i], fft.DIF, factorsBR[_j], true)
-		}
-
 		witnesses := make([][]fr.Element, len(witCanonicalX))
 		for i := 0; i < len(witnesses); i++ {
 			witnesses[i] = pk.Domain[0].FFTPart(witCanonicalX[i], fft.DIF, factorsBR[_j], true)
 		}
 
+		// either the polynomials on the coset, or the terms they contribute to
+		var sy, sx, q [][]fr.Element
+		var gs, gates []fr.Element
+		if streamed {
+			gs = permutationOnCoset(pk, witnesses, factorsBR[_j], etaY, etaX, gamma)
+			gates = gateOnCoset(pk, witnesses, factorsBR[_j])
+		} else {
+			sy = make([][]fr.Element, len(pk.Sy))
+			for i := 0; i < len(pk.Sy); i++ {
+				sy[i] = pk.Domain[0].FFTPart(pk.Sy[i], fft.DIF, factorsBR[_j], true)
+			}
+			sx = make([][]fr.Element, len(pk.Sx))
+			for i := 0; i < len(pk.Sx); i++ {
+				sx[i] = pk.Domain[0].FFTPart(pk.Sx[i], fft.DIF, factorsBR[_j], true)
+			}
+			q = make([][]fr.Element, len(pk.Q))
+			for i := 0; i < len(pk.Q); i++ {
+				q[i] = pk.Domain[0].FFTPart(pk.Q[i], fft.DIF, factorsBR[_j], true)
+			}
+		}
+
+		var lt, lq, lm, lphi []fr.Element
+		if lw != nil {
+			lt = pk.Domain[0].FFTPart(pk.Lookup.T, fft.DIF, factorsBR[_j], true)
+			lq = pk.Domain[0].FFTPart(pk.Lookup.Q, fft.DIF, factorsBR[_j], true)
+			lm = pk.Domain[0].FFTPart(lw.m, fft.DIF, factorsBR[_j], true)
+			lphi = pk.Domain[0].FFTPart(lw.phi, fft.DIF, factorsBR[_j], true)
+		}
+
 		hStart := uint64(_j) * n
 		utils.Parallelize(int(n), func(start, end int) {
-			var f, g, t []fr.Element = make([]fr.Element, len(sy)), make([]fr.Element, len(sy)), make([]fr.Element, len(sy))
+			var f, g, t []fr.Element = make([]fr.Element, len(witnesses)), make([]fr.Element, len(witnesses)), make([]fr.Element, len(witnesses))
 			var oneMinusLL fr.Element
 			var t0, t1 fr.Element
 			var IDEtaX fr.Element
@@ -827,14 +1011,18 @@ func computeQuotientCanonicalX(pk *ProvingKey, witCanonicalX [][]fr.Element, zCa
 					f[0].Mul(&f[0], &f[j])
 				}
 
-				for j := 0; j < len(sy); j++ {
-					t[j].Mul(&sy[j][_i], &etaY)
-				}
-				for j := 0; j < len(witnesses); j++ {
-					g[j].Mul(&sx[j][_i], &etaX).Add(&g[j], &t[j]).Add(&g[j], &witnesses[j][_i]).Add(&g[j], &gamma)
-				}
-				for j := 1; j < len(witnesses); j++ {
-					g[0].Mul(&g[0], &g[j])
+				if streamed {
+					g[0].Set(&gs[_i])
+				} else {
+					for j := 0; j < len(sy); j++ {
+						t[j].Mul(&sy[j][_i], &etaY)
+					}
+					for j := 0; j < len(witnesses); j++ {
+						g[j].Mul(&sx[j][_i], &etaX).Add(&g[j], &t[j]).Add(&g[j], &witnesses[j][_i]).Add(&g[j], &gamma)
+					}
+					for j := 1; j < len(witnesses); j++ {
+						g[0].Mul(&g[0], &g[j])
+					}
 				}
 				
 				oneMinusLL.Sub(&one, &ll[_i])
@@ -850,10 +1038,26 @@ func computeQuotientCanonicalX(pk *ProvingKey, witCanonicalX [][]fr.Element, zCa
 				IDEtaX.Mul(&IDEtaX, &pk.Domain[0].Generator)
 
 				// Compute gate constraint
-				gateFunc(witnesses, q, _i, &t0, &t1)
+				if streamed {
+					t0.Set(&gates[_i])
+				} else {
+					gateFunc(witnesses, q, _i, &t0, &t1)
+				}
 				h[hStart + _i].Mul(&h[hStart + _i], &lambda).Add(&h[hStart + _i], &t0)
+
+				// Compute lookup constraint
+				if lw != nil {
+					t0 = lookupConstraint(lt[_i], lq[_i], lm[_i], witnesses[0][_i], lphi[_i], lphi[_is], lw.pS, lw.cS, l0[_i], ll[_i], lw.delta, lambda)
+					t0.Mul(&t0, &lambda4)
+					h[hStart + _i].Add(&h[hStart + _i], &t0)
+				}
 			}
 		})
+
+		if streamed {
+			// hand the evaluations on this coset back before the next one
+			debug.FreeOSMemory()
+		}
 	}
 
 	XnMinusOneInv := evaluateXnMinusOneBig(&pk.Domain[1], &pk.Domain[0])
@@ -867,11 +1071,6 @@ func computeQuotientCanonicalX(pk *ProvingKey, witCanonicalX [][]fr.Element, zCa
 	})
 	pk.Domain[1].FFTInverse(h, fft.DIT, true)
 
-	outH := make([][]fr.Element, MAX_DEGREE)
-	for i := uint64(0); i < uint64(len(outH)); i++ {
-		outH[i] = h[i*n:(i+1)*n]
-	}
-
 	for i := int(MAX_DEGREE * n); i < len(h); i++ {
 		// fmt.Println(h[i].String())
 		if !h[i].IsZero() {
@@ -879,10 +1078,53 @@ func computeQuotientCanonicalX(pk *ProvingKey, witCanonicalX [][]fr.Element, zCa
 		}
 	}
 
-	return outH
+	// split by the size of the largest X-domain, see partition
+	return splitQuotientX(h[:MAX_DEGREE*n], pk.Vk.SizeX, MAX_DEGREE)
 }
 
-// computeQuotientCanonicalY computes Hy in canonical form, split as
+// ownersY returns the party owning each of the nbPolys polynomials on Y: it interpolates
+// the polynomial, sends it to the parties evaluating the numerator of Hy and opens it on
+// beta. Rank 0 owns the polynomials of index in canonical, which it computed in
+// canonical form, and the others are dealt to the parties from the last one down, away
+// from the parties of quotientCosetsY.
+func ownersY(nbPolys int, canonical []int, size uint64) []uint64 {
+	owners := make([]uint64, nbPolys)
+	isCanonical := make(map[int]bool, len(canonical))
+	for _, i := range canonical {
+		isCanonical[i] = true
+	}
+	k := uint64(0)
+	for i := range owners {
+		if size == 1 || isCanonical[i] {
+			continue
+		}
+		owners[i] = size - 1 - k%(size-1)
+		k++
+	}
+	return owners
+}
+
+// quotientCosetsY returns the cosets of the big domain on which party rank, out of size
+// parties, evaluates the numerator of Hy. With more parties than cosets, the parties
+// 1, ..., ratio get one coset each and rank 0 none, otherwise the cosets are dealt to
+// all the parties in turn.
+func quotientCosetsY(rank, size uint64) []int {
+	ratio := globalDomain[1].Cardinality / globalDomain[0].Cardinality
+	first, nbParties := uint64(0), size
+	if size > ratio {
+		first, nbParties = 1, ratio
+	}
+	if rank < first || rank >= first+nbParties {
+		return nil
+	}
+	var cosets []int
+	for _j := rank - first; _j < ratio; _j += nbParties {
+		cosets = append(cosets, int(_j))
+	}
+	return cosets
+}
+
+// quotientY computes Hy in canonical form, to be split by splitQuotientY as
 // Hy1 + (Y**M)Hy2 + (Y**(2M))Hy3 + (Y**(3M))Hy4 such that
 //
 // Ql(Y, alpha)L(Y, alpha)+Qr(Y, alpha)R(Y, alpha)+Qm(Y, alpha)L(Y, alpha)R(Y, alpha)+Qo(Y, alpha)O(Y, alpha)+Qk(Y, alpha)
@@ -892,18 +1134,447 @@ func computeQuotientCanonicalX(pk *ProvingKey, witCanonicalX [][]fr.Element, zCa
 // )
 // + lambda**2 * Lx0(alpha)*(Z(Y, alpha) - 1)
 // + lambda**3 * Ly0(Y)(W(Y) - 1)
+// + lambda**4 * lookup(Y, alpha) if the circuit has table lookups, see lookupConstraint
 // - Hx(Y, alpha)Zn(X) = Hy(Y)Zm(Y)
-func computeQuotientCanonicalY(pk *ProvingKey, polys [][]fr.Element, etaY, etaX, gamma, lambda, alpha fr.Element) [][]fr.Element {
-	h := make([]fr.Element, globalDomain[1].Cardinality)
+//
+// All the parties of c call quotientY, and Hy is returned on rank 0. On rank 0, polys holds
+// the polynomials laid out as in computeQuotientCosetsY, those of index in canonical in
+// canonical form and the others as their values on the parties; polys is ignored on the
+// other parties. Every party returns the polynomials it owns in canonical form, see
+// ownersY, and nil in place of the others.
+//
+// Rank 0 hands the values of each polynomial to its owner, which interpolates it and
+// sends it to the parties of quotientCosetsY, see sharePolysY. These evaluate the numerator on their
+// cosets of the big domain, divide it by Zm and interpolate it on each coset, so that
+// rank 0 is left with ratio values to combine per coefficient of Hy, see combineCosetsY.
+func quotientY(c *comm.Communicator, pk *ProvingKey, polys [][]fr.Element, owners []uint64, canonical []int, etaY, etaX, gamma, lambda, alpha fr.Element, lw *lookupWitness) ([][]fr.Element, [][]fr.Element, error) {
+	rank, size := c.Rank(), c.Size()
+	n := globalDomain[0].Cardinality
+	isCanonical := make(map[int]bool, len(canonical))
+	for _, i := range canonical {
+		isCanonical[i] = true
+	}
+
+	// the owners get the values of their polynomials and interpolate them
+	owned := make([][]fr.Element, len(owners))
+	for i := range owned {
+		switch {
+		case rank == 0 && owners[i] == 0:
+			owned[i] = polys[i]
+		case rank == 0:
+			if err := c.Transport.Send(elementsToBytes(polys[i]), owners[i]); err != nil {
+				return nil, nil, err
+			}
+		case owners[i] == rank:
+			recvBuf, err := c.Transport.Receive(n*fr.Bytes, 0)
+			if err != nil {
+				return nil, nil, err
+			}
+			owned[i] = make([]fr.Element, n)
+			bytesToElements(owned[i], recvBuf)
+		}
+		if owned[i] != nil && !isCanonical[i] {
+			canonicalY(owned[i])
+		}
+	}
+
+	// the parties evaluating the numerator get all the polynomials
+	cosets := quotientCosetsY(rank, size)
+	all, err := sharePolysY(c, owned, owners)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var hCosets [][]fr.Element
+	if len(cosets) != 0 {
+		hCosets = computeQuotientCosetsY(pk, all, cosets, etaY, etaX, gamma, lambda, alpha, lw)
+		interpolateCosetsY(hCosets, cosets)
+		if rank != 0 {
+			sendBuf := make([]byte, 0, len(cosets)*int(n)*fr.Bytes)
+			for _, hj := range hCosets {
+				sendBuf = append(sendBuf, elementsToBytes(hj)...)
+			}
+			if err := c.Transport.Send(sendBuf, 0); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if rank != 0 {
+		return nil, owned, nil
+	}
+
+	// gather the cosets, indexed as in computeQuotientCosetsY
 	ratio := globalDomain[1].Cardinality / globalDomain[0].Cardinality
+	interpolated := make([][]fr.Element, ratio)
+	for p := uint64(0); p < size; p++ {
+		cosets := quotientCosetsY(p, size)
+		if len(cosets) == 0 {
+			continue
+		}
+		if p == 0 {
+			for k, _j := range cosets {
+				interpolated[_j] = hCosets[k]
+			}
+			continue
+		}
+		recvBuf, err := c.Transport.Receive(uint64(len(cosets))*n*fr.Bytes, p)
+		if err != nil {
+			return nil, nil, err
+		}
+		for k, _j := range cosets {
+			interpolated[_j] = make([]fr.Element, n)
+			bytesToElements(interpolated[_j], recvBuf[uint64(k)*n*fr.Bytes:])
+		}
+	}
 
-	// Compute the power of globalDomain[1].Generator with bit-reversed order.
+	return combineCosetsY(interpolated), owned, nil
+}
+
+// splitQuotientY splits Hy, returned by quotientY, in nbQuotientPiecesY pieces of size M
+func splitQuotientY(h []fr.Element, vk *VerifyingKey) ([][]fr.Element, error) {
+	n := globalDomain[0].Cardinality
+	outH := make([][]fr.Element, nbQuotientPiecesY(vk))
+	for i := uint64(0); i < uint64(len(outH)); i++ {
+		if (i+1)*n <= uint64(len(h)) {
+			outH[i] = h[i*n : (i+1)*n]
+		} else {
+			outH[i] = make([]fr.Element, n)
+		}
+	}
+	for i := len(outH) * int(n); i < len(h); i++ {
+		if !h[i].IsZero() {
+			return nil, errors.New("invalid proof: wrong h degree")
+		}
+	}
+	return outH, nil
+}
+
+// sharePolysY returns all the polynomials on Y on the parties of quotientCosetsY, and nil
+// on the others, owned holding the polynomials of this party and nil in place of the
+// others. The owners send their polynomials to each other if the topology of c links all
+// the parties, otherwise through rank 0, simpleMPI only linking rank 0 to the others.
+func sharePolysY(c *comm.Communicator, owned [][]fr.Element, owners []uint64) ([][]fr.Element, error) {
+	rank, size := c.Rank(), c.Size()
+	n := globalDomain[0].Cardinality
+	all := make([][]fr.Element, len(owned))
+	copy(all, owned)
+	receive := func(i int, from uint64) error {
+		recvBuf, err := c.Transport.Receive(n*fr.Bytes, from)
+		if err != nil {
+			return err
+		}
+		all[i] = make([]fr.Element, n)
+		bytesToElements(all[i], recvBuf)
+		return nil
+	}
+
+	if c.Topology == comm.Star {
+		// the owners send their polynomials to rank 0, which forwards them
+		if rank != 0 {
+			for i := range owned {
+				if owned[i] == nil {
+					continue
+				}
+				if err := c.Transport.Send(elementsToBytes(owned[i]), 0); err != nil {
+					return nil, err
+				}
+			}
+			if len(quotientCosetsY(rank, size)) == 0 {
+				return nil, nil
+			}
+			for i := range all {
+				if owners[i] != rank {
+					if err := receive(i, 0); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return all, nil
+		}
+
+		for i := range all {
+			if owners[i] != 0 {
+				if err := receive(i, owners[i]); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for p := uint64(1); p < size; p++ {
+			if len(quotientCosetsY(p, size)) == 0 {
+				continue
+			}
+			for i := range all {
+				if owners[i] == p {
+					continue
+				}
+				if err := c.Transport.Send(elementsToBytes(all[i]), p); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(quotientCosetsY(0, size)) == 0 {
+			return nil, nil
+		}
+		return all, nil
+	}
+
+	// the owners send their polynomials to the parties of quotientCosetsY while these
+	// receive the others
+	sent := make(chan error, 1)
+	go func() {
+		for p := uint64(0); p < size; p++ {
+			if p == rank || len(quotientCosetsY(p, size)) == 0 {
+				continue
+			}
+			for i := range owned {
+				if owned[i] == nil {
+					continue
+				}
+				if err := c.Transport.Send(elementsToBytes(owned[i]), p); err != nil {
+					sent <- err
+					return
+				}
+			}
+		}
+		sent <- nil
+	}()
+	var err error
+	if len(quotientCosetsY(rank, size)) != 0 {
+		for i := 0; i < len(all) && err == nil; i++ {
+			if owners[i] != rank {
+				err = receive(i, owners[i])
+			}
+		}
+	} else {
+		all = nil
+	}
+	if errSend := <-sent; err == nil {
+		err = errSend
+	}
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// cosetFactorsY returns the powers of the generator of the big domain on Y in
+// bit-reversed order, the coset _j of the big domain being the shift by factorsBR[_j]
+// of the coset of the small domain by the multiplicative generator
+func cosetFactorsY() []fr.Element {
+	ratio := globalDomain[1].Cardinality / globalDomain[0].Cardinality
 	factorsBR := make([]fr.Element, ratio)
 	factorsBR[0].SetOne()
 	for i := 1; i < int(ratio); i++ {
 		factorsBR[i].Mul(&factorsBR[i-1], &globalDomain[1].Generator)
 	}
 	fft.BitReverse(factorsBR)
+	return factorsBR
+}
+
+// interpolateCosetsY divides in place the numerator of Hy, evaluated on the cosets by
+// computeQuotientCosetsY, by Zm(Y) = Y**M - 1, constant on each coset, and interpolates
+// it: for the coset shifted by c, hCosets[k] becomes the sum over l of
+// Hy_{i+lM} (c**M)**l at i, Hy_j being the coefficients of Hy.
+func interpolateCosetsY(hCosets [][]fr.Element, cosets []int) {
+	n := globalDomain[0].Cardinality
+	factorsBR := cosetFactorsY()
+	var one fr.Element
+	one.SetOne()
+	for k, _j := range cosets {
+		var shift, shiftInv, zmInv fr.Element
+		shift.Mul(&globalDomain[1].FrMultiplicativeGen, &factorsBR[_j])
+		shiftInv.Inverse(&shift)
+		zmInv.Exp(shift, new(big.Int).SetUint64(n)).Sub(&zmInv, &one).Inverse(&zmInv)
+
+		h := hCosets[k]
+		for i := range h {
+			h[i].Mul(&h[i], &zmInv)
+		}
+		// the coefficients of Hy(c*Y) mod Y**M - 1, divided by c**i
+		globalDomain[0].FFTInverse(h, fft.DIT)
+		var acc fr.Element
+		acc.SetOne()
+		for i := range h {
+			h[i].Mul(&h[i], &acc)
+			acc.Mul(&acc, &shiftInv)
+		}
+	}
+}
+
+// combineCosetsY returns the coefficients of Hy from its interpolations on all the cosets
+// of the big domain by interpolateCosetsY. With c = g*w_j the shift of coset j, g the
+// multiplicative generator and w_j**M = rho_j a ratio-th root of unity, interpolated[j]
+// at i is the sum over l of (Hy_{i+lM} g**(lM)) rho_j**l, an FFT of size ratio which is
+// inverted for each i.
+func combineCosetsY(interpolated [][]fr.Element) []fr.Element {
+	ratio := len(interpolated)
+	n := len(interpolated[0])
+	factorsBR := cosetFactorsY()
+	bn := new(big.Int).SetUint64(uint64(n))
+
+	// inv[j][l] = rho_j**(-l) / ratio
+	var ratioInv fr.Element
+	ratioInv.SetUint64(uint64(ratio)).Inverse(&ratioInv)
+	inv := make([][]fr.Element, ratio)
+	for j := range inv {
+		var rhoInv fr.Element
+		rhoInv.Exp(factorsBR[j], bn).Inverse(&rhoInv)
+		inv[j] = make([]fr.Element, ratio)
+		inv[j][0] = ratioInv
+		for l := 1; l < ratio; l++ {
+			inv[j][l].Mul(&inv[j][l-1], &rhoInv)
+		}
+	}
+
+	// g**(-lM)
+	var gInv fr.Element
+	gInv.Exp(globalDomain[1].FrMultiplicativeGen, bn).Inverse(&gInv)
+	shifts := make([]fr.Element, ratio)
+	shifts[0].SetOne()
+	for l := 1; l < ratio; l++ {
+		shifts[l].Mul(&shifts[l-1], &gInv)
+	}
+
+	h := make([]fr.Element, ratio*n)
+	utils.Parallelize(n, func(start, end int) {
+		var b, t fr.Element
+		for i := start; i < end; i++ {
+			for l := 0; l < ratio; l++ {
+				b.SetZero()
+				for j := 0; j < ratio; j++ {
+					t.Mul(&interpolated[j][i], &inv[j][l])
+					b.Add(&b, &t)
+				}
+				h[l*n+i].Mul(&b, &shifts[l])
+			}
+		}
+	})
+	return h
+}
+
+// batchOpenY opens on Y = beta the polynomials of owners, each party folding the ones it
+// owns: with nu derived from their digests and evaluations on beta, the opening proof is
+// the one of Σ nu**i * p_i, whose quotient by Y - beta the parties commit to share by
+// share.
+//
+// All the parties of c call batchOpenY with the polynomials they own in canonical form,
+// nil in place of the others. beta is ignored on the other parties than rank 0, which
+// derives nu with derive and gets the opening proof.
+func batchOpenY(c *comm.Communicator, owned [][]fr.Element, owners []uint64, beta fr.Element, srs *kzg.SRS, derive func(evalsOnBeta []fr.Element) (fr.Element, error)) (kzg.BatchOpeningProof, error) {
+	var proof kzg.BatchOpeningProof
+	rank, size := c.Rank(), c.Size()
+
+	buf := beta.Bytes()
+	recvBuf, err := c.Broadcast(buf[:], fr.Bytes)
+	if err != nil {
+		return proof, err
+	}
+	c.NextRound()
+	beta.SetBytes(recvBuf)
+
+	// evaluate the polynomials of this party on beta, rank 0 gathers them
+	var evals []fr.Element
+	for i := range owned {
+		if owned[i] != nil {
+			evals = append(evals, eval(owned[i], beta))
+		}
+	}
+	if rank != 0 {
+		if len(evals) != 0 {
+			if err := c.Transport.Send(elementsToBytes(evals), 0); err != nil {
+				return proof, err
+			}
+		}
+	} else {
+		byParty := make([][]fr.Element, size)
+		byParty[0] = evals
+		nbOwned := make([]int, size)
+		for _, o := range owners {
+			nbOwned[o]++
+		}
+		for p := uint64(1); p < size; p++ {
+			if nbOwned[p] == 0 {
+				continue
+			}
+			recvBuf, err := c.Transport.Receive(uint64(nbOwned[p])*fr.Bytes, p)
+			if err != nil {
+				return proof, err
+			}
+			byParty[p] = make([]fr.Element, nbOwned[p])
+			bytesToElements(byParty[p], recvBuf)
+		}
+		proof.ClaimedValues = make([]fr.Element, len(owners))
+		for i, o := range owners {
+			proof.ClaimedValues[i], byParty[o] = byParty[o][0], byParty[o][1:]
+		}
+	}
+
+	var nu fr.Element
+	if rank == 0 {
+		if nu, err = derive(proof.ClaimedValues); err != nil {
+			return proof, err
+		}
+	}
+	buf = nu.Bytes()
+	if recvBuf, err = c.Broadcast(buf[:], fr.Bytes); err != nil {
+		return proof, err
+	}
+	c.NextRound()
+	nu.SetBytes(recvBuf)
+
+	// this party's share of Σ nu**i * p_i and of its quotient by Y - beta
+	nuPowers := make([]fr.Element, len(owners))
+	nuPowers[0].SetOne()
+	for i := 1; i < len(nuPowers); i++ {
+		nuPowers[i].Mul(&nuPowers[i-1], &nu)
+	}
+	var folded []fr.Element
+	for i := range owned {
+		if owned[i] == nil {
+			continue
+		}
+		if len(owned[i]) > len(folded) {
+			folded = append(folded, make([]fr.Element, len(owned[i])-len(folded))...)
+		}
+		p := owned[i]
+		utils.Parallelize(len(p), func(start, end int) {
+			var t fr.Element
+			for j := start; j < end; j++ {
+				t.Mul(&p[j], &nuPowers[i])
+				folded[j].Add(&folded[j], &t)
+			}
+		})
+	}
+	var share kzg.Digest
+	if len(folded) > 1 {
+		if share, err = kzg.Commit(divideByYMinus(folded, beta), srs); err != nil {
+			return proof, err
+		}
+	}
+	if proof.H, err = c.ReduceG1(share); err != nil {
+		return proof, err
+	}
+	return proof, nil
+}
+
+// divideByYMinus returns the quotient of p by Y - a, dropping the remainder p(a)
+func divideByYMinus(p []fr.Element, a fr.Element) []fr.Element {
+	q := make([]fr.Element, len(p)-1)
+	var acc fr.Element
+	for i := len(p) - 1; i > 0; i-- {
+		acc.Mul(&acc, &a).Add(&acc, &p[i])
+		q[i-1] = acc
+	}
+	return q
+}
+
+// computeQuotientCosetsY evaluates the numerator of Hy on the given cosets of the big
+// domain, in bit-reversed order. polys are, in canonical form, the polynomials opened on X,
+// Z(Y, omegaX*alpha) for each size of X-domain, W and, with lookups, Phi(Y, omegaX*alpha)
+// for each size and S.
+func computeQuotientCosetsY(pk *ProvingKey, polys [][]fr.Element, cosets []int, etaY, etaX, gamma, lambda, alpha fr.Element, lw *lookupWitness) [][]fr.Element {
+	// Compute the power of globalDomain[1].Generator with bit-reversed order.
+	factorsBR := cosetFactorsY()
 
 	// Variables needed in permutation constraint.
 	n := globalDomain[0].Cardinality
@@ -917,28 +1588,27 @@ func computeQuotientCanonicalY(pk *ProvingKey, polys [][]fr.Element, etaY, etaX,
 	var one fr.Element
 	one.SetOne()
 
-	var lx0, lxl, oneMinusLxL, den fr.Element
-	lx0.Exp(alpha, big.NewInt(int64(pk.Domain[0].Cardinality))).
-		Sub(&lx0, &one)
-	lxl.Set(&lx0)
-	den.Sub(&alpha, &one).Inverse(&den)
-	lx0.Mul(&lx0, &den).Mul(&lx0, &pk.Domain[0].CardinalityInv)
-	den.Sub(&alpha, &pk.Domain[0].GeneratorInv).
-		Inverse(&den)
-	lxl.Mul(&lxl, &den).Mul(&lxl, &pk.Domain[0].GeneratorInv).Mul(&lxl, &pk.Domain[0].CardinalityInv)
-	oneMinusLxL.Sub(&one, &lxl)
+	// Lx0(alpha), Lx_{n-1}(alpha) and alpha**n - 1 on the X-domain of each party, and
+	// the selectors of the parties of each size of X-domain, as polynomials in Y
+	LagX0, LagXLst, VanishingX := pk.Vk.lagrangeX(alpha)
+	indicators := pk.Vk.classIndicators()
+	canonicalY(LagX0, LagXLst, VanishingX)
+	canonicalY(indicators...)
+	nbClasses := len(indicators)
 
 	LagY0 := make([]fr.Element, globalDomain[0].Cardinality)
 	for i := 0; i < int(globalDomain[0].Cardinality); i++ {
 		LagY0[i].Set(&globalDomain[0].CardinalityInv)
 	}
 
-	var vanishingX fr.Element
-	vanishingX.Exp(alpha, big.NewInt(int64(pk.Domain[0].Cardinality)))
-	vanishingX.Sub(&vanishingX, &one)
+	var lambda4 fr.Element
+	lambda4.Square(&lambda).Square(&lambda4)
 
 	nn := uint64(64 - bits.TrailingZeros64(uint64(globalDomain[0].Cardinality)))
-	for _j := 0; _j < int(ratio); _j++ {
+	hCosets := make([][]fr.Element, len(cosets))
+	for k, _j := range cosets {
+		h := make([]fr.Element, n)
+		hCosets[k] = h
 		// Compute FFT part for each polynomial.
 		foldedHx := globalDomain[0].FFTPart(polys[0], fft.DIF, factorsBR[_j], true)
 		z := globalDomain[0].FFTPart(polys[1], fft.DIF, factorsBR[_j], true)
@@ -959,14 +1629,44 @@ func computeQuotientCanonicalY(pk *ProvingKey, polys [][]fr.Element, etaY, etaX,
 			sx[i] = globalDomain[0].FFTPart(polys[2 + len(witnesses) + len(q) + len(sy) + i], fft.DIF, factorsBR[_j], true)
 		}
 		offset := 2 + len(witnesses) + len(q) + len(sy) + len(sx)
-		zs := globalDomain[0].FFTPart(polys[offset], fft.DIF, factorsBR[_j], true)
-		w := globalDomain[0].FFTPart(polys[offset + 1], fft.DIF, factorsBR[_j], true)
+		var lt, lq, lm, lphi, lphis, ls []fr.Element
+		if lw != nil {
+			lt = globalDomain[0].FFTPart(polys[offset], fft.DIF, factorsBR[_j], true)
+			lq = globalDomain[0].FFTPart(polys[offset + 1], fft.DIF, factorsBR[_j], true)
+			lm = globalDomain[0].FFTPart(polys[offset + 2], fft.DIF, factorsBR[_j], true)
+			lphi = globalDomain[0].FFTPart(polys[offset + 3], fft.DIF, factorsBR[_j], true)
+			offset += 4
+			ls = globalDomain[0].FFTPart(polys[offset + 2 * nbClasses + 1], fft.DIF, factorsBR[_j], true)
+		}
+		ind := make([][]fr.Element, nbClasses)
+		for c := range ind {
+			ind[c] = globalDomain[0].FFTPart(indicators[c], fft.DIF, factorsBR[_j], true)
+		}
+		// select the shifted openings of the parties of each size
+		shifted := func(first int) []fr.Element {
+			res := make([]fr.Element, n)
+			for c := 0; c < nbClasses; c++ {
+				p := globalDomain[0].FFTPart(polys[first + c], fft.DIF, factorsBR[_j], true)
+				for i := range res {
+					p[i].Mul(&p[i], &ind[c][i])
+					res[i].Add(&res[i], &p[i])
+				}
+			}
+			return res
+		}
+		zs := shifted(offset)
+		w := globalDomain[0].FFTPart(polys[offset + nbClasses], fft.DIF, factorsBR[_j], true)
+		if lw != nil {
+			lphis = shifted(offset + nbClasses + 1)
+		}
 		ly0 := globalDomain[0].FFTPart(LagY0, fft.DIF, factorsBR[_j], true)
+		lx0 := globalDomain[0].FFTPart(LagX0, fft.DIF, factorsBR[_j], true)
+		lxl := globalDomain[0].FFTPart(LagXLst, fft.DIF, factorsBR[_j], true)
+		vanishingX := globalDomain[0].FFTPart(VanishingX, fft.DIF, factorsBR[_j], true)
 
-		hStart := uint64(_j) * n
 		utils.Parallelize(int(n), func(start, end int) {
-			var f, g, t []fr.Element = make([]fr.Element, len(sy)), make([]fr.Element, len(sy)), make([]fr.Element, len(sy))
-			var t0, t1 fr.Element
+			var f, g, t []fr.Element = make([]fr.Element, len(witnesses)), make([]fr.Element, len(witnesses)), make([]fr.Element, len(witnesses))
+			var t0, t1, oneMinusLxL fr.Element
 			var IDEtaY fr.Element
 			IDEtaY.Exp(globalDomain[0].Generator, big.NewInt(int64(start))).
 				Mul(&IDEtaY, &factorsBR[_j]).
@@ -975,11 +1675,11 @@ func computeQuotientCanonicalY(pk *ProvingKey, polys [][]fr.Element, etaY, etaX,
 				_i := bits.Reverse64(uint64(i)) >> nn
 				_is := bits.Reverse64(uint64((i + 1)) & (n - 1)) >> nn
 				// Compute the permutation constraint Ly0(Y)(W(Y) - 1)
-				h[hStart + _i].Sub(&w[_i], &one).Mul(&h[hStart + _i], &ly0[_i])
+				h[_i].Sub(&w[_i], &one).Mul(&h[_i], &ly0[_i])
 
 				// Compute the permutation constraint Lx0(alpha)(Z(Y, alpha) - 1)
-				t0.Sub(&z[_i], &one).Mul(&t0, &lx0)
-				h[hStart + _i].Mul(&h[hStart + _i], &lambda).Add(&h[hStart + _i], &t0)
+				t0.Sub(&z[_i], &one).Mul(&t0, &lx0[_i])
+				h[_i].Mul(&h[_i], &lambda).Add(&h[_i], &t0)
 
 				// Compute the permutation constraint
 				// (1 - Lx_{n - 1}(X))(Z(Y, omegaX*alpha)()()() - Z(Y, alpha)()()())
@@ -1001,68 +1701,49 @@ func computeQuotientCanonicalY(pk *ProvingKey, polys [][]fr.Element, etaY, etaX,
 					g[0].Mul(&g[0], &g[j])
 				}
 
+				oneMinusLxL.Sub(&one, &lxl[_i])
 				t0.Mul(&f[0], &z[_i])
 				t1.Mul(&g[0], &zs[_i])
 				t1.Sub(&t1, &t0).Mul(&t1, &oneMinusLxL)
-				h[hStart + _i].Mul(&h[hStart + _i], &lambda).Add(&h[hStart + _i], &t1)
+				h[_i].Mul(&h[_i], &lambda).Add(&h[_i], &t1)
 
 				t0.Mul(&t0, &w[_i])
 				t1.Mul(&g[0], &w[_is])
-				t1.Sub(&t1, &t0).Mul(&t1, &lxl)
-				h[hStart + _i].Add(&h[hStart + _i], &t1)
+				t1.Sub(&t1, &t0).Mul(&t1, &lxl[_i])
+				h[_i].Add(&h[_i], &t1)
 				IDEtaY.Mul(&IDEtaY, &globalDomain[0].Generator)
 
 				// Compute the gate constraint.
 				gateFunc(witnesses, q, _i, &t0, &t1)
-				h[hStart + _i].Mul(&h[hStart + _i], &lambda).Add(&h[hStart + _i], &t0)
+				h[_i].Mul(&h[_i], &lambda).Add(&h[_i], &t0)
+
+				// Compute the lookup constraint.
+				if lw != nil {
+					t0 = lookupConstraint(lt[_i], lq[_i], lm[_i], witnesses[0][_i], lphi[_i], lphis[_i], ls[_i], ls[_is], lx0[_i], lxl[_i], lw.delta, lambda)
+					t0.Mul(&t0, &lambda4)
+					h[_i].Add(&h[_i], &t0)
+				}
 
 				// Remove Hx(Y, alpha) * (alpha^N - 1)
-				t0.Mul(&foldedHx[_i], &vanishingX)
-				h[hStart + _i].Sub(&h[hStart + _i], &t0)
+				t0.Mul(&foldedHx[_i], &vanishingX[_i])
+				h[_i].Sub(&h[_i], &t0)
 			}
 		})
 	}
 
-	evaluationYmMinusOneInverse := evaluateXnMinusOneBig(globalDomain[1], globalDomain[0])
-	evaluationYmMinusOneInverse = fr.BatchInvert(evaluationYmMinusOneInverse)
-	nn2 := uint64(64 - bits.TrailingZeros64(uint64(globalDomain[1].Cardinality)))
-	utils.Parallelize(int(globalDomain[1].Cardinality), func(start, end int) {
-		for _i := uint64(start); _i < uint64(end); _i++ {
-			i := bits.Reverse64(_i) >> nn2
-			h[_i].Mul(&h[_i], &evaluationYmMinusOneInverse[i % ratio])
-		}
-	})
-
-	globalDomain[1].FFTInverse(h, fft.DIT, true)
-
-	outH := make([][]fr.Element, MAX_DEGREE)
-	for i := uint64(0); i < uint64(len(outH)); i++ {
-		outH[i] = h[i*n:(i+1)*n]
-	}
-
-	for i := int(MAX_DEGREE * n); i < len(h); i++ {
-		// fmt.Println(h[i].String())
-		if !h[i].IsZero() {
-			panic("invalid proof: wrong h degree")
-		}
-	}
-	return outH
+	return hCosets
 }
 
 // checkConstraintX checks that the constraint is satisfied
-func checkConstraintX(pk *ProvingKey, evalsXOnAlpha [][]fr.Element, zShiftedAlpha, wSmallY []fr.Element, etaY, etaX, gamma, lambda, alpha fr.Element) error {
-	n := int64(pk.Domain[0].Cardinality)
-	var l0, ll, oneMinusLL, one, den fr.Element
+//
+// zShiftedAlpha and phiShiftedAlpha hold the shifted openings for every size of X-domain,
+// see sizeClasses.
+func checkConstraintX(pk *ProvingKey, evalsXOnAlpha [][]fr.Element, zShiftedAlpha [][]fr.Element, wSmallY []fr.Element, phiShiftedAlpha [][]fr.Element, lw *lookupWitness, etaY, etaX, gamma, lambda, alpha fr.Element) error {
+	var one fr.Element
 	one.SetOne()
 
-	l0.Exp(alpha, big.NewInt(n)).Sub(&l0, &one)
-	ll.Set(&l0)
-	den.Sub(&alpha, &one).Inverse(&den)
-	l0.Mul(&l0, &den).Mul(&l0, &pk.Domain[0].CardinalityInv)
-
-	den.Sub(&alpha, &pk.Domain[0].GeneratorInv).Inverse(&den)
-	ll.Mul(&ll, &den).Mul(&ll, &pk.Domain[0].CardinalityInv).Mul(&ll, &pk.Domain[0].GeneratorInv)
-	oneMinusLL.Sub(&one, &ll)
+	lagX0, lagXLst, vanishingX := pk.Vk.lagrangeX(alpha)
+	classes := pk.Vk.sizeClasses()
 
 	IDEtaXShifted := make([]fr.Element, len(pk.Sy))
 	IDEtaXShifted[0].Mul(&alpha, &etaX)
@@ -1092,7 +1773,16 @@ func checkConstraintX(pk *ProvingKey, evalsXOnAlpha [][]fr.Element, zShiftedAlph
 		for i := 0; i < len(sy); i++ {
 			sx[i] = evalsXOnAlpha[2 + len(witnesses)  + len(q) + len(sy) + i][k]
 		}
-		zs := zShiftedAlpha[k]
+		// shifted openings on the X-domain of party k
+		c := 0
+		for classes[c] != pk.Vk.sizeX(uint64(k)) {
+			c++
+		}
+		l0, ll := lagX0[k], lagXLst[k]
+		var oneMinusLL fr.Element
+		oneMinusLL.Sub(&one, &ll)
+
+		zs := zShiftedAlpha[c][k]
 		pw := wSmallY[k]
 		cw := wSmallY[(k + 1)%int(mpi.WorldSize)]
 		var IDEtaY fr.Element
@@ -1139,12 +1829,27 @@ func checkConstraintX(pk *ProvingKey, evalsXOnAlpha [][]fr.Element, zShiftedAlph
 		var result fr.Element
 		result.Mul(&thirdPart, &lambda).Add(&result, &secondPart).Mul(&result, &lambda).Add(&result, &firstPart)
 
-		var vanishingX fr.Element
-		vanishingX.Exp(alpha, big.NewInt(int64(pk.Domain[0].Cardinality)))
-		vanishingX.Sub(&vanishingX, &one)
+		// lookup part
+		if lw != nil {
+			offset := 2 + len(witnesses) + len(q) + len(sy) + len(sx)
+			lookupPart := lookupConstraint(
+				evalsXOnAlpha[offset][k],
+				evalsXOnAlpha[offset + 1][k],
+				evalsXOnAlpha[offset + 2][k],
+				witnesses[0],
+				evalsXOnAlpha[offset + 3][k],
+				phiShiftedAlpha[c][k],
+				lw.sSmallY[k],
+				lw.sSmallY[(k + 1)%int(mpi.WorldSize)],
+				l0, ll, lw.delta, lambda,
+			)
+			tmp.Square(&lambda).Square(&tmp)
+			lookupPart.Mul(&lookupPart, &tmp)
+			result.Add(&result, &lookupPart)
+		}
 
 		var vH fr.Element
-		vH.Mul(&hx, &vanishingX)
+		vH.Mul(&hx, &vanishingX[k])
 		result.Sub(&result, &vH)
 
 		// if result != 0 return error
@@ -1154,3 +1859,20 @@ func checkConstraintX(pk *ProvingKey, evalsXOnAlpha [][]fr.Element, zShiftedAlph
 	}
 	return nil
 }
+
+// elementsToBytes returns the concatenated big-endian encodings of v
+func elementsToBytes(v []fr.Element) []byte {
+	res := make([]byte, len(v)*fr.Bytes)
+	for i := range v {
+		b := v[i].Bytes()
+		copy(res[i*fr.Bytes:], b[:])
+	}
+	return res
+}
+
+// bytesToElements decodes len(v) elements encoded by elementsToBytes from buf into v
+func bytesToElements(v []fr.Element, buf []byte) {
+	for i := range v {
+		v[i].SetBytes(buf[i*fr.Bytes : (i+1)*fr.Bytes])
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark-crypto/fiat-shamir"
+	"github.com/consensys/gnark/backend/comm"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+// runParties runs f on size parties linked in memory
+func runParties(size int, topology comm.Topology, f func(c *comm.Communicator) error) error {
+	transports := comm.NewLocal(size)
+	errs := make(chan error, size)
+	for _, t := range transports {
+		go func(t *comm.Local) {
+			errs <- f(&comm.Communicator{Transport: t, Topology: topology})
+		}(t)
+	}
+	for range transports {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quotientSingleMasterY evaluates the numerator of Hy on all the cosets of the big
+// domain and interpolates it on the big domain at once, polys being in canonical form
+func quotientSingleMasterY(pk *ProvingKey, polys [][]fr.Element, etaY, etaX, gamma, lambda, alpha fr.Element) []fr.Element {
+	n := globalDomain[0].Cardinality
+	ratio := globalDomain[1].Cardinality / n
+	cosets := make([]int, ratio)
+	for j := range cosets {
+		cosets[j] = j
+	}
+	hCosets := computeQuotientCosetsY(pk, polys, cosets, etaY, etaX, gamma, lambda, alpha, nil)
+	h := make([]fr.Element, globalDomain[1].Cardinality)
+	for j := range hCosets {
+		copy(h[uint64(j)*n:], hCosets[j])
+	}
+
+	zmInv := fr.BatchInvert(evaluateXnMinusOneBig(globalDomain[1], globalDomain[0]))
+	nn := uint64(64 - bits.TrailingZeros64(globalDomain[1].Cardinality))
+	for _i := range h {
+		i := bits.Reverse64(uint64(_i)) >> nn
+		h[_i].Mul(&h[_i], &zmInv[i%ratio])
+	}
+	globalDomain[1].FFTInverse(h, fft.DIT, true)
+	return h
+}
+
+func clonePolys(polys [][]fr.Element) [][]fr.Element {
+	res := make([][]fr.Element, len(polys))
+	for i := range polys {
+		res[i] = append([]fr.Element(nil), polys[i]...)
+	}
+	return res
+}
+
+func TestQuotientY(t *testing.T) {
+	const m = 4
+	globalDomain[0] = fft.NewDomain(m)
+	globalDomain[1] = fft.NewDomain(8 * m)
+
+	domainX := fft.NewDomain(16)
+	vk := &VerifyingKey{
+		SizeY:         m,
+		SizeX:         domainX.Cardinality,
+		GeneratorY:    globalDomain[0].Generator,
+		GeneratorX:    domainX.Generator,
+		GeneratorXInv: domainX.GeneratorInv,
+		CosetShift:    domainX.FrMultiplicativeGen,
+	}
+	pk := &ProvingKey{
+		Vk: vk,
+		Q:  make([][]fr.Element, NUM_SELECTORS),
+		Sy: make([][]fr.Element, NUM_WITNESSES),
+		Sx: make([][]fr.Element, NUM_WITNESSES),
+	}
+
+	// the polynomials opened on X, Z(Y, omegaX*alpha) and W, in canonical form
+	nbPolys := 2 + 3*NUM_WITNESSES + NUM_SELECTORS + 2
+	canonical := []int{nbPolys - 1}
+	values := make([][]fr.Element, nbPolys)
+	for i := range values {
+		values[i] = make([]fr.Element, m)
+		for j := range values[i] {
+			values[i][j].SetRandom()
+		}
+	}
+	polys := clonePolys(values)
+	canonicalY(polys[:nbPolys-1]...)
+
+	var etaY, etaX, gamma, lambda, alpha, beta, nu fr.Element
+	for _, e := range []*fr.Element{&etaY, &etaX, &gamma, &lambda, &alpha, &beta, &nu} {
+		e.SetRandom()
+	}
+	expected := quotientSingleMasterY(pk, polys, etaY, etaX, gamma, lambda, alpha)
+
+	// the opening of the polynomials and of a last one standing for FoldedHy
+	srs, err := kzg.NewSRS(m, big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	foldedHy := make([]fr.Element, m)
+	for j := range foldedHy {
+		foldedHy[j].SetRandom()
+	}
+	digests := make([]kzg.Digest, nbPolys+1)
+	for i, p := range append(polys, foldedHy) {
+		if digests[i], err = kzg.Commit(p, srs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, topology := range []comm.Topology{comm.Star, comm.BinomialTree} {
+		// up to more parties than cosets, rank 0 leaving them to the others
+		for _, size := range []int{1, 2, 3, 8, 11} {
+			name := fmt.Sprintf("%s/%d parties", topology, size)
+			owners := ownersY(nbPolys, canonical, uint64(size))
+
+			var h []fr.Element
+			var proof kzg.BatchOpeningProof
+			err := runParties(size, topology, func(c *comm.Communicator) error {
+				var input [][]fr.Element
+				if c.Rank() == 0 {
+					input = clonePolys(values)
+				}
+				res, owned, err := quotientY(c, pk, input, owners, canonical, etaY, etaX, gamma, lambda, alpha, nil)
+				if err != nil {
+					return err
+				}
+				var last []fr.Element
+				if c.Rank() == 0 {
+					h, last = res, foldedHy
+				}
+				opening, err := batchOpenY(c, append(owned, last), append(owners, 0), beta, srs, func(evalsOnBeta []fr.Element) (fr.Element, error) {
+					return nu, nil
+				})
+				if c.Rank() == 0 {
+					proof = opening
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			if len(h) != len(expected) {
+				t.Fatalf("%s: got %d coefficients, expected %d", name, len(h), len(expected))
+			}
+			for i := range h {
+				if !h[i].Equal(&expected[i]) {
+					t.Fatalf("%s: Hy differs from the single master one at %d", name, i)
+				}
+			}
+
+			for i, p := range append(polys, foldedHy) {
+				if v := eval(p, beta); !proof.ClaimedValues[i].Equal(&v) {
+					t.Fatalf("%s: wrong evaluation of polynomial %d", name, i)
+				}
+			}
+			folded, digest, err := foldOpeningY(digests, &proof, nu)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := kzg.BatchVerifyMultiPoints([]kzg.Digest{digest}, []kzg.OpeningProof{folded}, []fr.Element{beta}, srs); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+	}
+}
+
+func TestDeriveNu(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	_, _, g1, _ := curve.Generators()
+	var g2 curve.G1Affine
+	g2.Double(&g1)
+	values := make([]fr.Element, 2)
+	values[0].SetUint64(3)
+	values[1].SetUint64(5)
+	derive := func(digests []kzg.Digest, values []fr.Element) fr.Element {
+		fs := fiatshamir.NewTranscript(sha256.New(), "nu")
+		nu, err := deriveNu(fs, digests, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return nu
+	}
+
+	// nu depends on the digests opened, not only on their evaluations
+	nu := derive([]kzg.Digest{g1, g2}, values)
+	if other := derive([]kzg.Digest{g1, g1}, values); other.Equal(&nu) {
+		t.Fatal("nu doesn't bind the digests")
+	}
+	if other := derive([]kzg.Digest{g1, g2}, values[:1]); other.Equal(&nu) {
+		t.Fatal("nu doesn't bind the evaluations")
+	}
+}
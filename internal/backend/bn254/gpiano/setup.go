@@ -23,12 +23,16 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/comm"
+	"github.com/consensys/gnark/frontend/compiled"
 	"github.com/consensys/gnark/internal/backend/bn254/cs"
 	"github.com/sunblaze-ucb/simpleMPI/mpi"
 
@@ -70,6 +74,15 @@ type ProvingKey struct {
 	// position -> permuted position (position in [0,3*sizeSystem-1])
 	PermutationY []int64
 	PermutationX []int64
+
+	// Rows of the circuit held by this party: [RowStart, RowStart+NbRows)
+	RowStart, NbRows int
+
+	// Lookup argument, nil if the circuit doesn't look up values in a table
+	Lookup *LookupKey
+
+	// file backing Q, Sy, Sx and the lookup polynomials, see MapPolynomials
+	mapped []byte
 }
 
 // VerifyingKey stores the data needed to verify a proof:
@@ -78,9 +91,11 @@ type ProvingKey struct {
 // * Commitments of qr, qm, qo, qk prepended with as many zeroes as there are public inputs
 // * Commitments to S1, S2, S3
 type VerifyingKey struct {
-	// Size circuit
+	// Size circuit, SizeX being the size of the largest X-domain
 	SizeY              uint64
 	SizeX              uint64
+	// Sizes of the X-domains of the parties, nil if they all have an X-domain of size SizeX
+	SizesX             []uint64
 	SizeYInv	       fr.Element
 	SizeXInv		   fr.Element
 	GeneratorY         fr.Element
@@ -100,15 +115,37 @@ type VerifyingKey struct {
 	// Commitments to ql, qr, qm, qo prepended with as many zeroes (ones for l) as there are public inputs.
 	// In particular Qk is not complete.
 	Q []kzg.Digest
+
+	// Commitments to the lookup table and selector, nil if the circuit doesn't look up values in a table
+	Lookup *LookupVerifyingKey
+
+	// TranscriptHash is the hash function of the Fiat-Shamir transcript
+	TranscriptHash backend.TranscriptHash
+
+	// CircuitFingerprint is the fingerprint of the constraint system the parties share, zero if
+	// the key wasn't set up from a constraint system
+	CircuitFingerprint compiled.Fingerprint
+}
+
+// SRS is the pair of structured reference strings the keys commit with: X the distributed
+// SRS of the party on X, and Y the SRS on Y, which only the first party needs. They must
+// share the trapdoor of Y, which X is computed from: Y can come from a Powers of Tau
+// ceremony, but X can't. With the zero SRS, the setup draws the trapdoors.
+type SRS struct {
+	X *dkzg.SRS
+	Y *kzg.SRS
 }
 
-// Setup sets proving and verifying keys
-func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey, *VerifyingKey, error) {
+// Setup sets proving and verifying keys committing with srs
+//
+// If weights are given, party k gets a share of the constraints proportional to
+// weights[k] and an X-domain of its own size, otherwise all the parties get X-domains
+// of the same size.
+func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness, srs SRS, weights ...uint64) (*ProvingKey, *VerifyingKey, error) {
 	globalDomain[0] = fft.NewDomain(mpi.WorldSize)
 	if globalDomain[0].Cardinality != mpi.WorldSize {
 		return nil, nil, fmt.Errorf("mpi.WorldSize is not a power of 2")
 	}
-	globalDomain[1] = fft.NewDomain(4 * mpi.WorldSize)
 
 	var pk ProvingKey
 	var vk VerifyingKey
@@ -119,85 +156,26 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 	nbConstraints := len(spr.Constraints)
 
 	// fft domains
-	sizeSystem := int(nbConstraints + spr.NbPublicVariables) // spr.NbPublicVariables is for the placeholder constraints
-	sizeSystem = (sizeSystem + int(mpi.WorldSize) - 1) / int(mpi.WorldSize)
+	part, err := newPartition(nbConstraints+spr.NbPublicVariables, mpi.WorldSize, weights) // spr.NbPublicVariables is for the placeholder constraints
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if sizeSystem < spr.NbPublicVariables {
+	if part.rows[0] < spr.NbPublicVariables {
 		return nil, nil, fmt.Errorf("public variables not in a single sub-circuit")
 	}
 
-	pk.Domain[0] = *fft.NewDomain(uint64(sizeSystem))
-	pk.Vk.CosetShift.Set(&pk.Domain[0].FrMultiplicativeGen)
-
-	var t, s *big.Int
-	var err error
-	if mpi.SelfRank == 0 {
-		var one fr.Element
-		one.SetOne()
-		for {
-			t, err = rand.Int(rand.Reader, spr.CurveID().ScalarField())
-			if err != nil {
-				return nil, nil, err
-			}
-			var ele fr.Element
-			ele.SetBigInt(t)
-			if !ele.Exp(ele, big.NewInt(int64(globalDomain[0].Cardinality))).Equal(&one) {
-				break
-			}
-		}
-		for {
-			s, err = rand.Int(rand.Reader, spr.CurveID().ScalarField())
-			if err != nil {
-				return nil, nil, err
-			}
-			var ele fr.Element
-			ele.SetBigInt(s)
-			if !ele.Exp(ele, big.NewInt(int64(pk.Domain[0].Cardinality))).Equal(&one) {
-				break
-			}
-		}
-		// send t and s to all other processes
-		tByteLen := (t.BitLen() + 7) / 8
-		sByteLen := (s.BitLen() + 7) / 8
-		for i := uint64(1); i < mpi.WorldSize; i++ {
-			if err := mpi.SendBytes([]byte{byte(tByteLen)}, i); err != nil {
-				return nil, nil, err
-			}
-			if err := mpi.SendBytes(t.Bytes(), i); err != nil {
-				return nil, nil, err
-			}
-			if err := mpi.SendBytes([]byte{byte(sByteLen)}, i); err != nil {
-				return nil, nil, err
-			}
-			if err := mpi.SendBytes(s.Bytes(), i); err != nil {
-				return nil, nil, err
-			}
-		}
-		globalSRS, err = kzg.NewSRS(globalDomain[0].Cardinality, t)
-		if err != nil {
-			return nil, nil, err
-		}
+	// the per-party polynomials on X raise the degree on Y, see partition
+	if part.sizesX() == nil {
+		globalDomain[1] = fft.NewDomain(4 * mpi.WorldSize)
 	} else {
-		tByteLen, err := mpi.ReceiveBytes(1, 0)
-		if err != nil {
-			return nil, nil, err
-		}
-		tbytes, err := mpi.ReceiveBytes(uint64(tByteLen[0]), 0)
-		if err != nil {
-			return nil, nil, err
-		}
-		t = new(big.Int).SetBytes(tbytes)
-		sByteLen, err := mpi.ReceiveBytes(1, 0)
-		if err != nil {
-			return nil, nil, err
-		}
-		sbytes, err := mpi.ReceiveBytes(uint64(sByteLen[0]), 0)
-		if err != nil {
-			return nil, nil, err
-		}
-		s = new(big.Int).SetBytes(sbytes)
+		globalDomain[1] = fft.NewDomain(8 * mpi.WorldSize)
 	}
-	vk.KZGSRS = globalSRS
+
+	pk.Domain[0] = *fft.NewDomain(part.sizes[mpi.SelfRank])
+	pk.RowStart, pk.NbRows = part.start[mpi.SelfRank], part.rows[mpi.SelfRank]
+	pk.Vk.CosetShift.Set(&pk.Domain[0].FrMultiplicativeGen)
+	sizeSystem := int(pk.Domain[0].Cardinality)
 
 	// h, the quotient polynomial is of degree 3(n+1)+2, so it's in a 3(n+2) dim vector space,
 	// the domain is the next power of 2 superior to 3(n+2). 4*domainNum is enough in all cases
@@ -206,21 +184,14 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 
 	vk.SizeY = globalDomain[0].Cardinality
 	vk.SizeYInv = globalDomain[0].CardinalityInv
-	vk.SizeX = pk.Domain[0].Cardinality
-	vk.SizeXInv = pk.Domain[0].CardinalityInv
+	vk.setDomainX(&pk.Domain[0], part)
 	vk.GeneratorY.Set(&globalDomain[0].Generator)
-	vk.GeneratorX.Set(&pk.Domain[0].Generator)
-	vk.GeneratorXInv.Set(&pk.Domain[0].GeneratorInv)
 	vk.NbPublicVariables = uint64(spr.NbPublicVariables)
 	vk.Q = make([]kzg.Digest, 5)
 	vk.Sy = make([]kzg.Digest, 3)
 	vk.Sx = make([]kzg.Digest, 3)
 
-	dkzgSRS, err := dkzg.NewSRS(vk.SizeX+3, []*big.Int{t, s}, &globalDomain[0].Generator)
-	if err != nil {
-		return nil, nil, err
-	}
-	if err := pk.InitKZG(dkzgSRS); err != nil {
+	if err := initSRS(&pk, spr.CurveID(), part, srs); err != nil {
 		return nil, nil, err
 	}
 
@@ -230,7 +201,7 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 		pk.Q[i] = make([]fr.Element, pk.Domain[0].Cardinality)
 	}
 
-	var offset int
+	start := pk.RowStart
 	if mpi.SelfRank == 0 {
 		for i := 0; i < spr.NbPublicVariables; i++ { // placeholders (-PUB_INPUT_i + qk_i = 0) TODO should return error is size is inconsistant
 			pk.Q[0][i].SetOne().Neg(&pk.Q[0][i])
@@ -239,19 +210,12 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 			pk.Q[3][i].SetZero()
 			pk.Q[4][i].Set(&publicWitness[i])
 		}
-		offset = spr.NbPublicVariables
-	} else {
-		offset = 0
+		start += spr.NbPublicVariables
 	}
 	
-	sizeSystem = int(pk.Domain[0].Cardinality)
-	start := int(mpi.SelfRank) * sizeSystem + offset
-	end := start - offset + sizeSystem
-	if end > len(spr.Constraints) + spr.NbPublicVariables {
-		end = len(spr.Constraints) + spr.NbPublicVariables
-	}
+	end := pk.RowStart + pk.NbRows
 	for i := start; i < end; i++ { // constraints
-		j := i % sizeSystem
+		j := i - pk.RowStart
 		ii := i - spr.NbPublicVariables
 		pk.Q[0][j].Set(&spr.Coefficients[spr.Constraints[ii].L.CoeffID()])
 		pk.Q[1][j].Set(&spr.Coefficients[spr.Constraints[ii].R.CoeffID()])
@@ -269,7 +233,7 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 	}
 
 	// build permutation. Note: at this stage, the permutation takes in account the placeholders
-	buildPermutation(spr, &pk)
+	buildPermutation(spr, &pk, part)
 
 	// set s1, s2, s3
 	ccomputePermutationPolynomials(&pk)
@@ -291,98 +255,164 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 		}
 	}
 
+	// lookup table and rows of this sub-circuit looking up their L wire
+	if spr.HasLookups() {
+		table := make([]fr.Element, len(spr.LookupTable))
+		for i, cID := range spr.LookupTable {
+			table[i].Set(&spr.Coefficients[cID])
+		}
+		var rows []int
+		for _, cID := range spr.Lookups {
+			i := cID + spr.NbPublicVariables
+			if i >= pk.RowStart && i < pk.RowStart+pk.NbRows {
+				rows = append(rows, i-pk.RowStart)
+			}
+		}
+		if err := setupLookup(&pk, table, rows); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	vk.CircuitFingerprint = spr.Fingerprint()
+
 	return &pk, &vk, nil
 }
 
-func SetupRandom(curveID ecc.ID, nbConstraints int, nbPublicInputs int) (*ProvingKey, *VerifyingKey, [][]fr.Element, error) {
-	globalDomain[0] = fft.NewDomain(mpi.WorldSize)
-	if globalDomain[0].Cardinality != mpi.WorldSize {
-		return nil, nil, nil, fmt.Errorf("mpi.WorldSize is not a power of 2")
-	}
-	globalDomain[1] = fft.NewDomain(8 * mpi.WorldSize)
+// SetupRandom sets proving and verifying keys for a circuit of nbConstraints random gates,
+// and returns the witnesses of this party. Weights split the gates as in Setup.
+func SetupRandom(curveID ecc.ID, nbConstraints int, nbPublicInputs int, weights ...uint64) (*ProvingKey, *VerifyingKey, [][]fr.Element, error) {
+	return setupRandom(curveID, RandomCircuit{NbConstraints: nbConstraints, NbPublicInputs: nbPublicInputs, GateDensity: 1}, nil, SRS{}, weights)
+}
 
-	var pk ProvingKey
-	var vk VerifyingKey
+// randomLookupPeriod is the distance between two rows looking up in the table in SetupRandomLookup
+const randomLookupPeriod = 4
 
-	// The verifying key shares data with the proving key
-	pk.Vk = &vk
+// SetupRandomLookup is SetupRandom where one row out of randomLookupPeriod looks up
+// its first wire, picked at random, in table.
+func SetupRandomLookup(curveID ecc.ID, nbConstraints int, nbPublicInputs int, table []fr.Element, weights ...uint64) (*ProvingKey, *VerifyingKey, [][]fr.Element, error) {
+	if len(table) == 0 {
+		return nil, nil, nil, errors.New("lookup table is empty")
+	}
+	return setupRandom(curveID, RandomCircuit{NbConstraints: nbConstraints, NbPublicInputs: nbPublicInputs, GateDensity: 1}, table, SRS{}, weights)
+}
+
+func setupRandom(curveID ecc.ID, circuit RandomCircuit, table []fr.Element, srs SRS, weights []uint64) (*ProvingKey, *VerifyingKey, [][]fr.Element, error) {
+	if circuit.GateDensity < 0 || circuit.GateDensity > 1 {
+		return nil, nil, nil, fmt.Errorf("gate density %v is not in [0, 1]", circuit.GateDensity)
+	}
+	if circuit.CrossPartyWiring < 0 || circuit.CrossPartyWiring > 1 {
+		return nil, nil, nil, fmt.Errorf("cross-party wiring ratio %v is not in [0, 1]", circuit.CrossPartyWiring)
+	}
 
 	// fft domains
-	sizeSystem := int(nbConstraints) // spr.NbPublicVariables is for the placeholder constraints
-	sizeSystem = (sizeSystem + int(mpi.WorldSize) - 1) / int(mpi.WorldSize)
+	part, err := newPartition(circuit.NbConstraints, mpi.WorldSize, weights)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pk, err := newKeys(curveID, part, srs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	vk := pk.Vk
+	vk.NbPublicVariables = uint64(circuit.NbPublicInputs)
 
-	pk.Domain[0] = *fft.NewDomain(uint64(sizeSystem))
-	pk.Vk.CosetShift.Set(&pk.Domain[0].FrMultiplicativeGen)
+	witnesses := make([][]fr.Element, NUM_WITNESSES)
+	for i := 0; i < len(witnesses); i++ {
+		witnesses[i] = make([]fr.Element, pk.Domain[0].Cardinality)
+	}
 
-	var t, s *big.Int
-	var err error
-	if mpi.SelfRank == 0 {
-		var one fr.Element
-		one.SetOne()
-		for {
-			t, err = rand.Int(rand.Reader, curveID.ScalarField())
-			if err != nil {
-				return nil, nil, nil, err
-			}
-			var ele fr.Element
-			ele.SetBigInt(t)
-			if !ele.Exp(ele, big.NewInt(int64(globalDomain[0].Cardinality))).Equal(&one) {
-				break
-			}
+	// the parties draw the wires copied across them from a shared seed
+	shared, local, err := randomSources()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the wire k of row j is copied to the wire k of row j of the next party, for the
+	// rows held by all the parties, so that the copies form cycles through all of them
+	sizeSystem := int(pk.Domain[0].Cardinality)
+	next := (mpi.SelfRank + 1) % mpi.WorldSize
+	minRows := part.rows[0]
+	for _, rows := range part.rows {
+		if rows < minRows {
+			minRows = rows
 		}
-		for {
-			s, err = rand.Int(rand.Reader, curveID.ScalarField())
-			if err != nil {
-				return nil, nil, nil, err
+	}
+	var buf [fr.Bytes]byte
+	for j := 0; j < pk.NbRows; j++ {
+		for k := 0; k < len(witnesses); k++ {
+			pk.PermutationX[j + k * sizeSystem] = int64(j + k * sizeSystem)
+			pk.PermutationY[j + k * sizeSystem] = int64(mpi.SelfRank)
+			if circuit.CrossPartyWiring == 0 || j >= minRows || shared.Float64() >= circuit.CrossPartyWiring {
+				witnesses[k][j].SetRandom()
+				continue
 			}
-			var ele fr.Element
-			ele.SetBigInt(s)
-			if !ele.Exp(ele, big.NewInt(int64(pk.Domain[0].Cardinality))).Equal(&one) {
-				break
+			shared.Read(buf[:])
+			witnesses[k][j].SetBytes(buf[:])
+			if table != nil && k == 0 && j%randomLookupPeriod == 0 {
+				// looked up in the table below
+				continue
 			}
+			pk.PermutationX[j + k * sizeSystem] = int64(j + k * int(part.sizes[next]))
+			pk.PermutationY[j + k * sizeSystem] = int64(next)
 		}
-		// send t and s to all other processes
-		tByteLen := (t.BitLen() + 7) / 8
-		sByteLen := (s.BitLen() + 7) / 8
-		for i := uint64(1); i < mpi.WorldSize; i++ {
-			if err := mpi.SendBytes([]byte{byte(tByteLen)}, i); err != nil {
-				return nil, nil, nil, err
-			}
-			if err := mpi.SendBytes(t.Bytes(), i); err != nil {
-				return nil, nil, nil, err
-			}
-			if err := mpi.SendBytes([]byte{byte(sByteLen)}, i); err != nil {
-				return nil, nil, nil, err
-			}
-			if err := mpi.SendBytes(s.Bytes(), i); err != nil {
+	}
+
+	var out, tmp fr.Element
+	var rows []int
+	for j := 0; j < pk.NbRows; j++ { // constraints
+		if table != nil && j%randomLookupPeriod == 0 {
+			e, err := rand.Int(rand.Reader, big.NewInt(int64(len(table))))
+			if err != nil {
 				return nil, nil, nil, err
 			}
+			witnesses[0][j].Set(&table[e.Int64()])
+			rows = append(rows, j)
 		}
-		globalSRS, err = kzg.NewSRS(globalDomain[0].Cardinality, t)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-	} else {
-		tByteLen, err := mpi.ReceiveBytes(1, 0)
-		if err != nil {
-			return nil, nil, nil, err
+		if circuit.GateDensity < 1 && local.Float64() >= circuit.GateDensity {
+			// empty row, all the selectors are zero
+			continue
 		}
-		tbytes, err := mpi.ReceiveBytes(uint64(tByteLen[0]), 0)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		t = new(big.Int).SetBytes(tbytes)
-		sByteLen, err := mpi.ReceiveBytes(1, 0)
-		if err != nil {
-			return nil, nil, nil, err
+		for k := 0; k < len(pk.Q) - 1; k++ {
+			pk.Q[k][j].SetRandom()
 		}
-		sbytes, err := mpi.ReceiveBytes(uint64(sByteLen[0]), 0)
-		if err != nil {
+		pk.Q[len(pk.Q) - 1][j].SetZero()
+		gateFunc(witnesses, pk.Q, uint64(j), &out, &tmp)
+		pk.Q[len(pk.Q) - 1][j].Neg(&out)
+	}
+
+	if err := commitKeys(pk); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if table != nil {
+		if err := setupLookup(pk, table, rows); err != nil {
 			return nil, nil, nil, err
 		}
-		s = new(big.Int).SetBytes(sbytes)
 	}
-	vk.KZGSRS = globalSRS
+
+	return pk, vk, witnesses, nil
+}
+
+// newKeys sets the domains and the SRS of the proving and verifying keys of a circuit
+// with the custom gate, split between the parties as in part. The selectors and the
+// permutation are allocated for the caller to fill in, then committed to with commitKeys.
+func newKeys(curveID ecc.ID, part *partition, srs SRS) (*ProvingKey, error) {
+	globalDomain[0] = fft.NewDomain(mpi.WorldSize)
+	if globalDomain[0].Cardinality != mpi.WorldSize {
+		return nil, fmt.Errorf("mpi.WorldSize is not a power of 2")
+	}
+	globalDomain[1] = fft.NewDomain(8 * mpi.WorldSize)
+
+	var pk ProvingKey
+	var vk VerifyingKey
+
+	// The verifying key shares data with the proving key
+	pk.Vk = &vk
+
+	pk.Domain[0] = *fft.NewDomain(part.sizes[mpi.SelfRank])
+	pk.RowStart, pk.NbRows = part.start[mpi.SelfRank], part.rows[mpi.SelfRank]
+	pk.Vk.CosetShift.Set(&pk.Domain[0].FrMultiplicativeGen)
+	sizeSystem := int(pk.Domain[0].Cardinality)
 
 	// h, the quotient polynomial is of degree 3(n+1)+2, so it's in a 3(n+2) dim vector space,
 	// the domain is the next power of 2 superior to 3(n+2). 4*domainNum is enough in all cases
@@ -391,22 +421,14 @@ func SetupRandom(curveID ecc.ID, nbConstraints int, nbPublicInputs int) (*Provin
 
 	vk.SizeY = globalDomain[0].Cardinality
 	vk.SizeYInv = globalDomain[0].CardinalityInv
-	vk.SizeX = pk.Domain[0].Cardinality
-	vk.SizeXInv = pk.Domain[0].CardinalityInv
+	vk.setDomainX(&pk.Domain[0], part)
 	vk.GeneratorY.Set(&globalDomain[0].Generator)
-	vk.GeneratorX.Set(&pk.Domain[0].Generator)
-	vk.GeneratorXInv.Set(&pk.Domain[0].GeneratorInv)
-	vk.NbPublicVariables = uint64(nbPublicInputs)
 	vk.Q = make([]kzg.Digest, NUM_SELECTORS)
 	vk.Sy = make([]kzg.Digest, NUM_WITNESSES)
 	vk.Sx = make([]kzg.Digest, NUM_WITNESSES)
 
-	dkzgSRS, err := dkzg.NewSRS(vk.SizeX+3, []*big.Int{t, s}, &globalDomain[0].Generator)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	if err := pk.InitKZG(dkzgSRS); err != nil {
-		return nil, nil, nil, err
+	if err := initSRS(&pk, curveID, part, srs); err != nil {
+		return nil, err
 	}
 
 	// public polynomials corresponding to constraints: [ placholders | constraints | assertions ]
@@ -416,39 +438,21 @@ func SetupRandom(curveID ecc.ID, nbConstraints int, nbPublicInputs int) (*Provin
 	}
 	pk.PermutationX = make([]int64, NUM_WITNESSES * pk.Domain[0].Cardinality)
 	pk.PermutationY = make([]int64, NUM_WITNESSES * pk.Domain[0].Cardinality)
-	witnesses := make([][]fr.Element, NUM_WITNESSES)
-	for i := 0; i < len(witnesses); i++ {
-		witnesses[i] = make([]fr.Element, pk.Domain[0].Cardinality)
-	}
-
-	sizeSystem = int(pk.Domain[0].Cardinality)
-	start := int(mpi.SelfRank) * sizeSystem
-	end := start + sizeSystem
-	if end > nbConstraints {
-		end = nbConstraints
-	}
-	var out, tmp fr.Element
-	for i := start; i < end; i++ { // constraints
-		j := i % sizeSystem
-		for k := 0; k < len(witnesses); k++ {
-			witnesses[k][j].SetRandom()
-		}
-		for k := 0; k < len(pk.Q) - 1; k++ {
-			pk.Q[k][j].SetRandom()
-		}
-		pk.Q[len(pk.Q) - 1][j].SetZero()
-		gateFunc(witnesses, pk.Q, uint64(j), &out, &tmp)
-		pk.Q[len(pk.Q) - 1][j].Neg(&out)
-	}
 
-	for i := start; i < end; i++ {
-		j := i % sizeSystem
-		for k := 0; k < len(witnesses); k++ {
+	// the padding rows are sent to themselves
+	for j := pk.NbRows; j < sizeSystem; j++ {
+		for k := 0; k < NUM_WITNESSES; k++ {
 			pk.PermutationX[j + k * sizeSystem] = int64(j + k * sizeSystem)
 			pk.PermutationY[j + k * sizeSystem] = int64(mpi.SelfRank)
 		}
 	}
 
+	return &pk, nil
+}
+
+// commitKeys interpolates the selectors and the permutation filled in the keys returned
+// by newKeys, and commits to them in the verifying key
+func commitKeys(pk *ProvingKey) error {
 	for i := 0; i < len(pk.Q); i++ {
 		pk.Domain[0].FFTInverse(pk.Q[i], fft.DIF)
 	}
@@ -457,26 +461,27 @@ func SetupRandom(curveID ecc.ID, nbConstraints int, nbPublicInputs int) (*Provin
 	}
 
 	// set s1, s2, s3
-	ccomputePermutationPolynomials(&pk)
+	ccomputePermutationPolynomials(pk)
 
 	// Commit to the polynomials to set up the verifying key
+	var err error
+	vk := pk.Vk
 	for i := 0; i < len(pk.Q); i++ {
 		if vk.Q[i], err = dkzg.Commit(pk.Q[i], vk.DKZGSRS); err != nil {
-			return nil, nil, nil, err
+			return err
 		}
 	}
 	for i := 0; i < len(pk.Sy); i++ {
 		if vk.Sy[i], err = dkzg.Commit(pk.Sy[i], vk.DKZGSRS); err != nil {
-			return nil, nil, nil, err
+			return err
 		}
 	}
 	for i := 0; i < len(pk.Sx); i++ {
 		if vk.Sx[i], err = dkzg.Commit(pk.Sx[i], vk.DKZGSRS); err != nil {
-			return nil, nil, nil, err
+			return err
 		}
 	}
-
-	return &pk, &vk, witnesses, nil
+	return nil
 }
 
 // buildPermutation builds the Permutation associated with a circuit.
@@ -491,10 +496,16 @@ func SetupRandom(curveID ecc.ID, nbConstraints int, nbPublicInputs int) (*Provin
 // The permutation is encoded as a slice s of size 3*size(l), where the
 // i-th entry of l∥r∥o is sent to the s[i]-th entry, so it acts on a tab
 // like this: for i in tab: tab[i] = tab[permutation[i]]
-func buildPermutation(spr *cs.SparseR1CS, pk *ProvingKey) {
+func buildPermutation(spr *cs.SparseR1CS, pk *ProvingKey, part *partition) {
 	nbVariables := spr.NbInternalVariables + spr.NbPublicVariables + spr.NbSecretVariables
 	size := pk.Domain[0].Cardinality
-	totalSize := int(pk.Domain[0].Cardinality * mpi.WorldSize)
+
+	// the positions of party y are [offsets[y], offsets[y+1]) in each of l, r, o
+	offsets := make([]int64, len(part.sizes)+1)
+	for y, s := range part.sizes {
+		offsets[y+1] = offsets[y] + int64(s)
+	}
+	totalSize := int(offsets[len(part.sizes)])
 
 	// init permutation
 	pk.PermutationY = make([]int64, 3*size)
@@ -504,17 +515,24 @@ func buildPermutation(spr *cs.SparseR1CS, pk *ProvingKey) {
 		pk.PermutationX[i] = -1
 	}
 
+	// position of a row of the circuit
+	position := func(row int) int {
+		y := part.partyOf(row)
+		return int(offsets[y]) + row - part.start[y]
+	}
+
 	// init LRO position -> variable_ID
 	lro := make([]int, 3*totalSize) // position -> variable_ID
 	for i := 0; i < spr.NbPublicVariables; i++ {
-		lro[i] = i // IDs of LRO associated to placeholders (only L needs to be taken care of)
+		lro[position(i)] = i // IDs of LRO associated to placeholders (only L needs to be taken care of)
 	}
 
 	offset := spr.NbPublicVariables
 	for i := 0; i < len(spr.Constraints); i++ { // IDs of LRO associated to constraints
-		lro[offset+i] = spr.Constraints[i].L.WireID()
-		lro[totalSize+offset+i] = spr.Constraints[i].R.WireID()
-		lro[2*totalSize+offset+i] = spr.Constraints[i].O.WireID()
+		j := position(offset + i)
+		lro[j] = spr.Constraints[i].L.WireID()
+		lro[totalSize+j] = spr.Constraints[i].R.WireID()
+		lro[2*totalSize+j] = spr.Constraints[i].O.WireID()
 	}
 
 	// init cycle:
@@ -528,14 +546,14 @@ func buildPermutation(spr *cs.SparseR1CS, pk *ProvingKey) {
 	parseID := func(id int64) (int64, int64) {
 		v := id / int64(totalSize)
 		r := id % int64(totalSize)
-		y := r / int64(size)
-		x := r % int64(size)
-		return y, v * int64(size) + x
+		y := int64(sort.Search(len(part.sizes), func(k int) bool { return offsets[k+1] > r }))
+		x := r - offsets[y]
+		return y, v * int64(part.sizes[y]) + x
 	}
 	computeID := func(y, x int64) int64 {
 		v := x / int64(size)
 		r := x % int64(size)
-		return v*int64(totalSize) + y*int64(size) + r
+		return v*int64(totalSize) + offsets[y] + r
 	}
 
 	for i := 0; i < len(lro); i++ {
@@ -576,7 +594,7 @@ func ccomputePermutationPolynomials(pk *ProvingKey) {
 
 	// Lagrange form of ID
 	IDys := getIDySmallDomain(globalDomain[0])
-	IDxs := getIDxSmallDomain(&pk.Domain[0], NUM_WITNESSES)
+	IDxs := getPermutedIDx(pk)
 
 	// Lagrange form of S1, S2, S3
 	pk.Sy = make([][]fr.Element, NUM_WITNESSES)
@@ -592,7 +610,7 @@ func ccomputePermutationPolynomials(pk *ProvingKey) {
 			pk.Sy[k][i].Set(&IDys[pk.PermutationY[k*n+i]])
 		}
 		for k := 0; k < len(pk.Sx); k++ {
-			pk.Sx[k][i].Set(&IDxs[pk.PermutationX[k*n+i]])
+			pk.Sx[k][i].Set(&IDxs[k*n+i])
 		}
 	}
 
@@ -629,6 +647,33 @@ func getIDxSmallDomain(domain *fft.Domain, numWitnesses int) []fr.Element {
 	return res
 }
 
+// getPermutedIDx returns, for every position of this party, the ID on X of the position
+// the permutation sends it to, on the X-domain of the party holding that position
+func getPermutedIDx(pk *ProvingKey) []fr.Element {
+	n := int(pk.Domain[0].Cardinality)
+	numWitnesses := len(pk.PermutationX) / n
+
+	IDxs := make(map[uint64][]fr.Element)
+	IDxs[pk.Domain[0].Cardinality] = getIDxSmallDomain(&pk.Domain[0], numWitnesses)
+
+	res := make([]fr.Element, len(pk.PermutationX))
+	for i := range res {
+		size := pk.Vk.sizeX(uint64(pk.PermutationY[i]))
+		ids, ok := IDxs[size]
+		if !ok {
+			// the generators of the X-domains are powers of the one of the largest
+			var domain fft.Domain
+			domain.Cardinality = size
+			domain.Generator, _ = pk.Vk.generatorX(size)
+			domain.FrMultiplicativeGen.Set(&pk.Vk.CosetShift)
+			ids = getIDxSmallDomain(&domain, numWitnesses)
+			IDxs[size] = ids
+		}
+		res[i].Set(&ids[pk.PermutationX[i]])
+	}
+	return res
+}
+
 // getIDySmallDomain returns the Lagrange form of ID on the small domain
 func getIDySmallDomain(domain *fft.Domain) []fr.Element {
 
@@ -642,6 +687,96 @@ func getIDySmallDomain(domain *fft.Domain) []fr.Element {
 	return res
 }
 
+// initSRS sets the SRS of pk and globalSRS to srs or, if srs.X is nil, to the ones of the
+// trapdoors the first party draws and sends to the others. The domains of pk must be set.
+func initSRS(pk *ProvingKey, curveID ecc.ID, part *partition, srs SRS) error {
+	vk := pk.Vk
+	if srs.X != nil {
+		if mpi.SelfRank == 0 && (srs.Y == nil || uint64(len(srs.Y.G1)) < globalDomain[0].Cardinality) {
+			return errors.New("kzg srs is too small")
+		}
+		globalSRS = srs.Y
+		vk.KZGSRS = globalSRS
+		return pk.InitKZG(srs.X)
+	}
+
+	var t, s *big.Int
+	var err error
+	if mpi.SelfRank == 0 {
+		var one fr.Element
+		one.SetOne()
+		for {
+			t, err = rand.Int(rand.Reader, curveID.ScalarField())
+			if err != nil {
+				return err
+			}
+			var ele fr.Element
+			ele.SetBigInt(t)
+			if !ele.Exp(ele, big.NewInt(int64(globalDomain[0].Cardinality))).Equal(&one) {
+				break
+			}
+		}
+		for {
+			s, err = rand.Int(rand.Reader, curveID.ScalarField())
+			if err != nil {
+				return err
+			}
+			var ele fr.Element
+			ele.SetBigInt(s)
+			if !ele.Exp(ele, big.NewInt(int64(part.maxSize()))).Equal(&one) {
+				break
+			}
+		}
+		// send t and s to all other processes
+		tByteLen := (t.BitLen() + 7) / 8
+		sByteLen := (s.BitLen() + 7) / 8
+		for i := uint64(1); i < mpi.WorldSize; i++ {
+			if err := comm.Default.Transport.Send([]byte{byte(tByteLen)}, i); err != nil {
+				return err
+			}
+			if err := comm.Default.Transport.Send(t.Bytes(), i); err != nil {
+				return err
+			}
+			if err := comm.Default.Transport.Send([]byte{byte(sByteLen)}, i); err != nil {
+				return err
+			}
+			if err := comm.Default.Transport.Send(s.Bytes(), i); err != nil {
+				return err
+			}
+		}
+		globalSRS, err = kzg.NewSRS(globalDomain[0].Cardinality, t)
+		if err != nil {
+			return err
+		}
+	} else {
+		tByteLen, err := comm.Default.Transport.Receive(1, 0)
+		if err != nil {
+			return err
+		}
+		tbytes, err := comm.Default.Transport.Receive(uint64(tByteLen[0]), 0)
+		if err != nil {
+			return err
+		}
+		t = new(big.Int).SetBytes(tbytes)
+		sByteLen, err := comm.Default.Transport.Receive(1, 0)
+		if err != nil {
+			return err
+		}
+		sbytes, err := comm.Default.Transport.Receive(uint64(sByteLen[0]), 0)
+		if err != nil {
+			return err
+		}
+		s = new(big.Int).SetBytes(sbytes)
+	}
+	vk.KZGSRS = globalSRS
+
+	dkzgSRS, err := dkzg.NewSRS(vk.SizeX+3, []*big.Int{t, s}, &globalDomain[0].Generator)
+	if err != nil {
+		return err
+	}
+	return pk.InitKZG(dkzgSRS)
+}
+
 // InitKZG inits pk.Vk.KZG using pk.Domain[0] cardinality and provided SRS
 //
 // This should be used after deserializing a ProvingKey
@@ -672,6 +807,11 @@ func (vk *VerifyingKey) NbPublicWitness() int {
 	return int(vk.NbPublicVariables)
 }
 
+// Fingerprint returns the fingerprint of the constraint system the parties share
+func (vk *VerifyingKey) Fingerprint() compiled.Fingerprint {
+	return vk.CircuitFingerprint
+}
+
 // VerifyingKey returns pk.Vk
 func (pk *ProvingKey) VerifyingKey() interface{} {
 	return pk.Vk
@@ -19,12 +19,13 @@
 package gpiano
 
 import (
-	"crypto/sha256"
+	"errors"
 	"fmt"
 	"math/big"
 	"runtime/debug"
 	"time"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/sunblaze-ucb/simpleMPI/mpi"
 
@@ -32,6 +33,7 @@ import (
 	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
 
 	"github.com/consensys/gnark-crypto/fiat-shamir"
+	"github.com/consensys/gnark/backend/comm"
 	"github.com/consensys/gnark/logger"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
@@ -42,17 +44,138 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bn254witness.Witness)
 	log := logger.Logger().With().Str("curve", "bn254").Str("backend", "gpiano").Logger()
 	start := time.Now()
 
+	claims, err := foldClaims(proof, vk, publicWitness)
+	if err != nil {
+		return err
+	}
+	if err := claims.verify(vk); err != nil {
+		return err
+	}
+
+	log.Debug().Dur("took", time.Since(start)).Msg("verifier done")
+
+	return nil
+}
+
+// BatchVerifyError is returned by BatchVerify when the batch doesn't pass.
+// Index is the position in the batch of the first proof that fails on its own.
+type BatchVerifyError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchVerifyError) Error() string {
+	return fmt.Sprintf("proof %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchVerifyError) Unwrap() error {
+	return e.Err
+}
+
+// BatchVerify verifies several proofs generated for the same verifying key.
+//
+// The transcripts and the constraints on Y = beta are checked proof by proof,
+// while the openings of all the proofs are folded with verifier randomness into
+// a single dkzg and a single kzg pairing check. When the batch doesn't pass,
+// the openings are checked proof by proof to find out which one is wrong.
+func BatchVerify(proofs []*Proof, vk *VerifyingKey, publicWitnesses []bn254witness.Witness) error {
+	log := logger.Logger().With().Str("curve", "bn254").Str("backend", "gpiano").Int("nbProofs", len(proofs)).Logger()
+	start := time.Now()
+
+	if len(proofs) != len(publicWitnesses) {
+		return fmt.Errorf("got %d proofs but %d public witnesses", len(proofs), len(publicWitnesses))
+	}
+	if len(proofs) == 0 {
+		return nil
+	}
+
+	claims := make([]*openingClaims, len(proofs))
+	var batch openingClaims
+	for i := range proofs {
+		var err error
+		if claims[i], err = foldClaims(proofs[i], vk, publicWitnesses[i]); err != nil {
+			return &BatchVerifyError{Index: i, Err: err}
+		}
+		batch.append(claims[i])
+	}
+
+	if err := batch.verify(vk); err != nil {
+		for i := range claims {
+			if err := claims[i].verify(vk); err != nil {
+				return &BatchVerifyError{Index: i, Err: err}
+			}
+		}
+		return err
+	}
+
+	log.Debug().Dur("took", time.Since(start)).Msg("batch verifier done")
+
+	return nil
+}
+
+// openingClaims stores the opening claims of a proof which remain to be checked
+// with pairings once the transcript and the constraints on Y = beta are checked.
+type openingClaims struct {
+	// claims on X = alpha, X = omegaX * alpha
+	digestsX []dkzg.Digest
+	proofsX  []dkzg.OpeningProof
+	pointsX  []fr.Element
+
+	// claims on Y = beta, Y = omegaY * beta
+	digestsY []kzg.Digest
+	proofsY  []kzg.OpeningProof
+	pointsY  []fr.Element
+}
+
+// append adds the claims of o to c
+func (c *openingClaims) append(o *openingClaims) {
+	c.digestsX = append(c.digestsX, o.digestsX...)
+	c.proofsX = append(c.proofsX, o.proofsX...)
+	c.pointsX = append(c.pointsX, o.pointsX...)
+	c.digestsY = append(c.digestsY, o.digestsY...)
+	c.proofsY = append(c.proofsY, o.proofsY...)
+	c.pointsY = append(c.pointsY, o.pointsY...)
+}
+
+// verify checks the claims with one dkzg and one kzg batch verification
+func (c *openingClaims) verify(vk *VerifyingKey) error {
+	if err := dkzg.BatchVerifyMultiPoints(c.digestsX, c.proofsX, c.pointsX, vk.DKZGSRS); err != nil {
+		return fmt.Errorf("failed to batch verify on X = alpha: %v", err)
+	}
+	if err := kzg.BatchVerifyMultiPoints(c.digestsY, c.proofsY, c.pointsY, vk.KZGSRS); err != nil {
+		return fmt.Errorf("failed to batch verify on Y = beta: %v", err)
+	}
+	return nil
+}
+
+// foldClaims replays the transcript of the proof, checks the constraints on
+// Y = beta and folds the batch opening proofs into single opening claims.
+func foldClaims(proof *Proof, vk *VerifyingKey, publicWitness bn254witness.Witness) (*openingClaims, error) {
 	// pick a hash function to derive the challenge (the same as in the prover)
-	hFunc := sha256.New()
+	hFunc, err := vk.TranscriptHash.New(ecc.BN254)
+	if err != nil {
+		return nil, err
+	}
 
 	// transcript to derive the challenge
-	fs := fiatshamir.NewTranscript(hFunc, "gamma", "etaY", "etaX", "lambda", "alpha", "beta")
+	fs := fiatshamir.NewTranscript(hFunc, challengeNames(vk)...)
+
+	if (vk.Lookup == nil) != (proof.Lookup == nil) {
+		return nil, errors.New("the lookup argument of the proof doesn't match the verifying key")
+	}
+	classes := vk.sizeClasses()
+	if len(proof.PartialZShiftedProofs) != len(classes) {
+		return nil, fmt.Errorf("got %d openings of Z on omegaX * alpha, want %d", len(proof.PartialZShiftedProofs), len(classes))
+	}
+	if proof.Lookup != nil && len(proof.Lookup.PartialPhiShiftedProofs) != len(classes) {
+		return nil, fmt.Errorf("got %d openings of Phi on omegaX * alpha, want %d", len(proof.Lookup.PartialPhiShiftedProofs), len(classes))
+	}
 
 	// The first challenge is derived using the public data: the commitments to the permutation,
 	// the coefficients of the circuit, and the public inputs.
 	// derive gamma from the Comm(blinded cl), Comm(blinded cr), Comm(blinded co)
 	if err := bindPublicData(&fs, "gamma", *vk, publicWitness); err != nil {
-		return err
+		return nil, err
 	}
 	witnessPtrs := make([]*curve.G1Affine, len(proof.witnesses))
 	for i := 0; i < len(proof.witnesses); i++ {
@@ -60,37 +183,43 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bn254witness.Witness)
 	}
 	gamma, err := deriveRandomness(&fs, "gamma", true, witnessPtrs...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// derive eta from Comm(l), Comm(r), Comm(o)
 	etaY, err := deriveRandomness(&fs, "etaY", true)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	etaX, err := deriveRandomness(&fs, "etaX", true)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// derive delta from Comm(M)
+	var delta fr.Element
+	if proof.Lookup != nil {
+		if delta, err = deriveRandomness(&fs, "delta", true, &proof.Lookup.M); err != nil {
+			return nil, err
+		}
 	}
 
 	// derive lambda from Comm(l), Comm(r), Comm(o), Com(Z)
-	lambda, err := deriveRandomness(&fs, "lambda", true, &proof.Z, &proof.W)
+	lambda, err := deriveRandomness(&fs, "lambda", true, proof.accumulators()...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// derive alpha, the point of evaluation
 	alpha, err := deriveRandomness(&fs, "alpha", true, &proof.Hx[0], &proof.Hx[1], &proof.Hx[2], &proof.Hx[3])
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// evaluation of Z=Xⁿ⁻¹ at α
-	var alphaPowerN, zalpha fr.Element
+	var alphaPowerN fr.Element
 	var bExpo big.Int
-	one := fr.One()
 	bExpo.SetUint64(vk.SizeX)
 	alphaPowerN.Exp(alpha, &bExpo)
-	zalpha.Sub(&alphaPowerN, &one)
 
 	// compute the folded commitment to H: Comm(h₁) + αᵐ*Comm(h₂) + α²⁽ᵐ⁾*Comm(h₃)
 	var alphaNBigInt big.Int
@@ -109,6 +238,9 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bn254witness.Witness)
 	digests = append(digests, vk.Q...)
 	digests = append(digests, vk.Sy...)
 	digests = append(digests, vk.Sx...)
+	if proof.Lookup != nil {
+		digests = append(digests, vk.Lookup.T, vk.Lookup.Q, proof.Lookup.M, proof.Lookup.Phi)
+	}
 
 	foldedPartialProof, foldedPartialDigest, err := dkzg.FoldProof(
 		digests,
@@ -117,96 +249,162 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bn254witness.Witness)
 		hFunc)
 
 	if err != nil {
-		return fmt.Errorf("failed to fold proof on X = alpha: %v", err)
-	}
-	var shiftedAlpha fr.Element
-	shiftedAlpha.Mul(&alpha, &vk.GeneratorX)
-	err = dkzg.BatchVerifyMultiPoints(
-		[]dkzg.Digest{
-			foldedPartialDigest,
-			proof.Z,
-		},
-		[]dkzg.OpeningProof{
-			foldedPartialProof,
-			proof.PartialZShiftedProof,
-		},
-		[]fr.Element{
-			alpha,
-			shiftedAlpha,
-		},
-		vk.DKZGSRS,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to batch verify on X = alpha: %v", err)
+		return nil, fmt.Errorf("failed to fold proof on X = alpha: %v", err)
+	}
+	// omegaX * alpha for each size of X-domain
+	shiftedAlphas := make([]fr.Element, len(classes))
+	for c, size := range classes {
+		generator, _ := vk.generatorX(size)
+		shiftedAlphas[c].Mul(&alpha, &generator)
 	}
 
 	// derive beta
 	ts := []*curve.G1Affine{
 		&proof.PartialBatchedProof.H,
 	}
-	for _, digest := range proof.PartialBatchedProof.ClaimedDigests {
-		ts = append(ts, &digest)
+	for i := range proof.PartialBatchedProof.ClaimedDigests {
+		ts = append(ts, &proof.PartialBatchedProof.ClaimedDigests[i])
 	}
-	for _, digest := range proof.Hy {
-		ts = append(ts, &digest)
+	for i := range proof.Hy {
+		ts = append(ts, &proof.Hy[i])
 	}
 	beta, err := deriveRandomness(&fs, "beta", true, ts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := checkConstraintY(vk, proof.BatchedProof.ClaimedValues, proof.WShiftedProof.ClaimedValue, etaY, etaX, gamma, lambda, alpha, beta); err != nil {
-		return err
+	var sShiftedBeta fr.Element
+	if proof.Lookup != nil {
+		sShiftedBeta = proof.Lookup.SShiftedProof.ClaimedValue
+	}
+	if err := checkConstraintY(vk, proof.BatchedProof.ClaimedValues, proof.WShiftedProof.ClaimedValue, sShiftedBeta, etaY, etaX, gamma, delta, lambda, alpha, beta); err != nil {
+		return nil, err
+	}
+	// derive nu from the digests opened on beta and their evaluations, see batchOpenY
+	digestsY := digestsOnBeta(proof, foldHyDigests(proof.Hy, beta, vk.SizeY))
+	nu, err := deriveNu(&fs, digestsY, proof.BatchedProof.ClaimedValues)
+	if err != nil {
+		return nil, err
+	}
+	foldedProof, foldedDigest, err := foldOpeningY(digestsY, &proof.BatchedProof, nu)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fold proof on Y = beta: %v", err)
+	}
+	var shiftedBeta fr.Element
+	shiftedBeta.Mul(&beta, &vk.GeneratorY)
+
+	claims := &openingClaims{
+		digestsX: []dkzg.Digest{foldedPartialDigest},
+		proofsX:  []dkzg.OpeningProof{foldedPartialProof},
+		pointsX:  []fr.Element{alpha},
+		digestsY: []kzg.Digest{foldedDigest, proof.W},
+		proofsY:  []kzg.OpeningProof{foldedProof, proof.WShiftedProof},
+		pointsY:  []fr.Element{beta, shiftedBeta},
+	}
+	for c := range classes {
+		claims.digestsX = append(claims.digestsX, proof.Z)
+		claims.proofsX = append(claims.proofsX, proof.PartialZShiftedProofs[c])
+		claims.pointsX = append(claims.pointsX, shiftedAlphas[c])
 	}
-	// foldedHy = Hy1 + (beta**M)*Hy2 + (beta**(2M))*Hy3
+	if proof.Lookup != nil {
+		for c := range classes {
+			claims.digestsX = append(claims.digestsX, proof.Lookup.Phi)
+			claims.proofsX = append(claims.proofsX, proof.Lookup.PartialPhiShiftedProofs[c])
+			claims.pointsX = append(claims.pointsX, shiftedAlphas[c])
+		}
+		claims.append(&openingClaims{
+			digestsY: []kzg.Digest{proof.Lookup.S},
+			proofsY:  []kzg.OpeningProof{proof.Lookup.SShiftedProof},
+			pointsY:  []fr.Element{shiftedBeta},
+		})
+	}
+
+	return claims, nil
+}
+
+// foldHyDigests returns the digest of foldedHy = Hy1 + (beta**M)*Hy2 + (beta**(2M))*Hy3 + ...
+// given the digests of the Hyi
+func foldHyDigests(hy []kzg.Digest, beta fr.Element, sizeY uint64) kzg.Digest {
 	var bBetaPowerM, bSize big.Int
-	bSize.SetUint64(globalDomain[0].Cardinality)
+	bSize.SetUint64(sizeY)
 	var betaPowerM fr.Element
 	betaPowerM.Exp(beta, &bSize)
 	betaPowerM.ToBigIntRegular(&bBetaPowerM)
-	foldedHyDigest := proof.Hy[len(proof.Hy) - 1]
-	for i := len(proof.Hy) - 2; i >= 0; i-- {
-		foldedHyDigest.ScalarMultiplication(&foldedHyDigest, &bBetaPowerM)
-		foldedHyDigest.Add(&foldedHyDigest, &proof.Hy[i])
+	folded := hy[len(hy)-1]
+	for i := len(hy) - 2; i >= 0; i-- {
+		folded.ScalarMultiplication(&folded, &bBetaPowerM)
+		folded.Add(&folded, &hy[i])
 	}
-	
-	foldedProof, foldedDigest, err := kzg.FoldProof(
-		append(proof.PartialBatchedProof.ClaimedDigests,
-			proof.PartialZShiftedProof.ClaimedDigest,
-			proof.W,
-			foldedHyDigest,
-		),
-		&proof.BatchedProof,
-		beta,
-		hFunc)
+	return folded
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to fold proof on Y = beta: %v", err)
+// digestsOnBeta returns the digests of the polynomials opened on Y = beta, in the order of
+// the claimed values of proof.BatchedProof, the digest of foldedHy last
+func digestsOnBeta(proof *Proof, foldedHy kzg.Digest) []kzg.Digest {
+	digests := append([]kzg.Digest{}, proof.PartialBatchedProof.ClaimedDigests...)
+	for _, p := range proof.PartialZShiftedProofs {
+		digests = append(digests, p.ClaimedDigest)
 	}
-	var shiftedBeta fr.Element
-	shiftedBeta.Mul(&beta, &vk.GeneratorY)
-	err = kzg.BatchVerifyMultiPoints(
-		[]kzg.Digest{
-			foldedDigest,
-			proof.W,
-		},
-		[]kzg.OpeningProof{
-			foldedProof,
-			proof.WShiftedProof,
-		},
-		[]fr.Element{
-			beta,
-			shiftedBeta,
-		},
-		vk.KZGSRS,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to batch verify on Y = beta: %v", err)
+	digests = append(digests, proof.W)
+	if proof.Lookup != nil {
+		for _, p := range proof.Lookup.PartialPhiShiftedProofs {
+			digests = append(digests, p.ClaimedDigest)
+		}
+		digests = append(digests, proof.Lookup.S)
 	}
+	return append(digests, foldedHy)
+}
 
-	log.Debug().Dur("took", time.Since(start)).Msg("verifier done")
+// deriveNu derives the challenge folding the openings on Y = beta from the digests
+// opened and their evaluations, so that neither can be picked after nu
+func deriveNu(fs *fiatshamir.Transcript, digests []kzg.Digest, evalsOnBeta []fr.Element) (fr.Element, error) {
+	for i := range digests {
+		buf := digests[i].RawBytes()
+		if err := fs.Bind("nu", buf[:]); err != nil {
+			return fr.Element{}, err
+		}
+	}
+	if err := bindValues(fs, "nu", evalsOnBeta); err != nil {
+		return fr.Element{}, err
+	}
+	return deriveRandomness(fs, "nu", true)
+}
 
-	return err
+// foldOpeningY folds the batch opening proof of digests on Y = beta into the opening
+// proof of Σ nu**i * p_i, see batchOpenY
+func foldOpeningY(digests []kzg.Digest, proof *kzg.BatchOpeningProof, nu fr.Element) (kzg.OpeningProof, kzg.Digest, error) {
+	var folded kzg.OpeningProof
+	var digest kzg.Digest
+	if len(digests) != len(proof.ClaimedValues) {
+		return folded, digest, fmt.Errorf("got %d claimed values for %d digests", len(proof.ClaimedValues), len(digests))
+	}
+
+	nuPowers := make([]fr.Element, len(digests))
+	nuPowers[0].SetOne()
+	for i := 1; i < len(nuPowers); i++ {
+		nuPowers[i].Mul(&nuPowers[i-1], &nu)
+	}
+	var t fr.Element
+	for i := range nuPowers {
+		t.Mul(&nuPowers[i], &proof.ClaimedValues[i])
+		folded.ClaimedValue.Add(&folded.ClaimedValue, &t)
+	}
+	folded.H = proof.H
+	if _, err := digest.MultiExp(digests, nuPowers, ecc.MultiExpConfig{ScalarsMont: true}); err != nil {
+		return folded, digest, err
+	}
+	return folded, digest, nil
+}
+
+// bindValues binds the field elements values to the challenge
+func bindValues(fs *fiatshamir.Transcript, challenge string, values []fr.Element) error {
+	for i := range values {
+		b := values[i].Bytes()
+		if err := fs.Bind(challenge, b[:]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // unpack unpacks evaluations from an array
@@ -236,6 +434,16 @@ func bindPublicData(fs *fiatshamir.Transcript, challenge string, vk VerifyingKey
 		}
 	}
 
+	// lookup table and selector
+	if vk.Lookup != nil {
+		if err := fs.Bind(challenge, vk.Lookup.T.Marshal()); err != nil {
+			return err
+		}
+		if err := fs.Bind(challenge, vk.Lookup.Q.Marshal()); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -264,18 +472,18 @@ func deriveRandomness(fs *fiatshamir.Transcript, challenge string, notSend bool,
 			return r, nil
 		}
 		sendBuf := r.Bytes()
-		for i := 1; i < int(mpi.WorldSize); i++ {
-			if err := mpi.SendBytes(sendBuf[:], uint64(i)); err != nil {
-				return r, err
-			}
+		if _, err := comm.Default.Broadcast(sendBuf[:], fr.Bytes); err != nil {
+			return r, err
 		}
+		comm.Default.NextRound()
 		return r, nil
 	} else {
 		var r fr.Element
-		recvBuf, err := mpi.ReceiveBytes(fr.Bytes, 0)
+		recvBuf, err := comm.Default.Broadcast(nil, fr.Bytes)
 		if err != nil {
 			return r, err
 		}
+		comm.Default.NextRound()
 		r.SetBytes(recvBuf)
 		return r, nil
 	}
@@ -356,8 +564,36 @@ func gateFunc(witnesses [][]fr.Element, q [][]fr.Element, i uint64, t0, t1 *fr.E
 	t0.Add(t0, &q[12][i])
 }
 
+// selectorMonomial sets m to the product of wires multiplied by the selector s in
+// gateFunc, on row i, so that the gate is the sum over s of q[s][i] * m
+func selectorMonomial(witnesses [][]fr.Element, s int, i uint64, m *fr.Element) {
+	switch {
+	case s < 4:
+		m.Set(&witnesses[s][i])
+	case s == 4:
+		m.Mul(&witnesses[0][i], &witnesses[1][i])
+	case s == 5:
+		m.Mul(&witnesses[2][i], &witnesses[3][i])
+	case s < 10:
+		w := &witnesses[s-6][i]
+		m.Square(w).Square(m).Mul(m, w)
+	case s == 10:
+		m.Mul(&witnesses[0][i], &witnesses[1][i]).
+			Mul(m, &witnesses[2][i]).
+			Mul(m, &witnesses[3][i])
+	case s == 11:
+		m.Set(&witnesses[4][i])
+	default:
+		m.SetOne()
+	}
+}
+
 // checkConstraintY checks that the constraint is satisfied
-func checkConstraintY(vk *VerifyingKey, evalsYOnBeta []fr.Element, ws, etaY, etaX, gamma, lambda, alpha, beta fr.Element) error {
+//
+// ws and ss are W and S evaluated on omegaY * beta, ss and delta are ignored when
+// the circuit has no table lookups. Z and Phi on omegaX * alpha come once for each
+// size of X-domain, see sizeClasses.
+func checkConstraintY(vk *VerifyingKey, evalsYOnBeta []fr.Element, ws, ss, etaY, etaX, gamma, delta, lambda, alpha, beta fr.Element) error {
 	// unpack vector evalsXOnAlpha on l, r, o, ql, qr, qm, qo, qk, s1, s2, s3, z, zmu
 	hx := evalsYOnBeta[0]
 	z := evalsYOnBeta[1]
@@ -366,9 +602,37 @@ func checkConstraintY(vk *VerifyingKey, evalsYOnBeta []fr.Element, ws, etaY, eta
 	sy := append([]fr.Element(nil), evalsYOnBeta[2+len(witnesses)+len(vk.Q):2+len(witnesses)+len(vk.Q)+len(vk.Sy)]...)
 	sx := append([]fr.Element(nil), evalsYOnBeta[2+len(witnesses)+len(vk.Q)+len(vk.Sy):2+len(witnesses)+len(vk.Q)+len(vk.Sy)+len(vk.Sx)]...)
 	offset := 2+len(witnesses) + len(vk.Q) + len(vk.Sy) + len(vk.Sx)
-	zs := evalsYOnBeta[offset]
-	w := evalsYOnBeta[offset + 1]
-	hy := evalsYOnBeta[offset + 2]
+
+	// Lx0(alpha), Lx_{n-1}(alpha), alpha**n - 1 and the shifted openings depend on
+	// the size n of the X-domain of each party, evaluate them on Y = beta
+	lagrange := vk.lagrangeY(beta)
+	lagX0, lagXLst, lagVanishingX := vk.lagrangeX(alpha)
+	indicators := vk.classIndicators()
+	nbClasses := len(indicators)
+	selectors := make([]fr.Element, nbClasses)
+	for c := range indicators {
+		selectors[c] = evalY(lagrange, indicators[c])
+	}
+	selectShifted := func(evals []fr.Element) fr.Element {
+		var res, t fr.Element
+		for c := range evals {
+			t.Mul(&evals[c], &selectors[c])
+			res.Add(&res, &t)
+		}
+		return res
+	}
+
+	var lookupEvals []fr.Element
+	if vk.Lookup != nil {
+		// t, q, m, phi, then phi(omegaX * alpha), s after zs and w
+		lookupEvals = evalsYOnBeta[offset:offset + 4]
+		offset += 4
+		phis := evalsYOnBeta[offset + nbClasses + 1:offset + 2 * nbClasses + 1]
+		lookupEvals = append(lookupEvals[:4:4], selectShifted(phis), evalsYOnBeta[offset + 2 * nbClasses + 1])
+	}
+	zs := selectShifted(evalsYOnBeta[offset:offset + nbClasses])
+	w := evalsYOnBeta[offset + nbClasses]
+	hy := evalsYOnBeta[len(evalsYOnBeta) - 1]
 	// first part: individual constraints
 	var firstPart fr.Element	
 	var tmp fr.Element
@@ -405,10 +669,11 @@ func checkConstraintY(vk *VerifyingKey, evalsYOnBeta []fr.Element, ws, etaY, eta
 	var one, den fr.Element
 	one.SetOne()
 
-	var secondPart, case1, case2, Lxl, oneMinusLxL fr.Element
-	Lxl.Exp(alpha, big.NewInt(int64(vk.SizeX))).Sub(&Lxl, &one)
-	den.Sub(&alpha, &vk.GeneratorXInv).Inverse(&den)
-	Lxl.Mul(&Lxl, &den).Mul(&Lxl, &vk.SizeXInv).Mul(&Lxl, &vk.GeneratorXInv)
+	Lx0 := evalY(lagrange, lagX0)
+	Lxl := evalY(lagrange, lagXLst)
+	vanishingX := evalY(lagrange, lagVanishingX)
+
+	var secondPart, case1, case2, oneMinusLxL fr.Element
 	oneMinusLxL.Sub(&one, &Lxl)
 	case1.Mul(&prodg, &zs).Sub(&case1, &prodfz).Mul(&case1, &oneMinusLxL)
 	prodfz.Mul(&prodfz, &w)
@@ -418,9 +683,7 @@ func checkConstraintY(vk *VerifyingKey, evalsYOnBeta []fr.Element, ws, etaY, eta
 	// third part Lx0(alpha)*(Z(beta, alpha) - 1)
 	var thirdPart fr.Element
 	z.Sub(&z, &one)
-	thirdPart.Exp(alpha, big.NewInt(int64(vk.SizeX))).Sub(&thirdPart, &one)
-	den.Sub(&alpha, &one).Inverse(&den)
-	thirdPart.Mul(&thirdPart, &den).Mul(&thirdPart, &vk.SizeXInv).Mul(&thirdPart, &z)
+	thirdPart.Mul(&Lx0, &z)
 
 	// forth part Ly0(beta)*(W(beta) - 1)
 	var forthPart fr.Element
@@ -433,9 +696,17 @@ func checkConstraintY(vk *VerifyingKey, evalsYOnBeta []fr.Element, ws, etaY, eta
 	var result fr.Element
 	result.Mul(&forthPart, &lambda).Add(&result, &thirdPart).Mul(&result, &lambda).Add(&result, &secondPart).Mul(&result, &lambda).Add(&result, &firstPart)
 
-	var vanishingX fr.Element
-	vanishingX.Exp(alpha, big.NewInt(int64(vk.SizeX)))
-	vanishingX.Sub(&vanishingX, &one)
+	// lookup part
+	if lookupEvals != nil {
+		lookupPart := lookupConstraint(
+			lookupEvals[0], lookupEvals[1], lookupEvals[2], witnesses[0],
+			lookupEvals[3], lookupEvals[4], lookupEvals[5], ss,
+			Lx0, Lxl, delta, lambda,
+		)
+		tmp.Square(&lambda).Square(&tmp)
+		lookupPart.Mul(&lookupPart, &tmp)
+		result.Add(&result, &lookupPart)
+	}
 
 	var vHx fr.Element
 	vHx.Mul(&hx, &vanishingX)
@@ -0,0 +1,199 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gpiano
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/comm"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+// Environment of the parties started by inWorld
+const (
+	envWorldRank = "GPIANO_TEST_RANK"
+	envWorldSize = "GPIANO_TEST_WORLD_SIZE"
+	envWorldRoot = "GPIANO_TEST_ROOT"
+)
+
+// inWorld runs the calling test in size processes linked by simpleMPI over loopback
+// TCP, as the parties of a cluster. In the test process it starts the parties, waits
+// for them and returns false; in a party it connects simpleMPI and returns true, and
+// the test goes on as this party.
+//
+// The parties set the simpleMPI connections themselves, so that the init() of the dkzg
+// package must leave simpleMPI with a single party, as for the other tests.
+func inWorld(t *testing.T, size int) bool {
+	if s := os.Getenv(envWorldRank); s != "" {
+		rank, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := connectWorld(t, rank, uint64(size), os.Getenv(envWorldRoot)); err != nil {
+			t.Fatalf("rank %d: %v", rank, err)
+		}
+		return true
+	}
+	if testing.Short() {
+		t.Skip("skipping the multi-process test in short mode")
+	}
+
+	// the root listens for the other parties on a port picked here
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := l.Addr().String()
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := make([]*exec.Cmd, size)
+	outputs := make([][]byte, size)
+	errs := make(chan error, size)
+	for rank := range cmds {
+		cmd := exec.Command(os.Args[0], "-test.run=^"+t.Name()+"$", "-test.v")
+		cmd.Env = append(os.Environ(),
+			envWorldRank+"="+strconv.Itoa(rank),
+			envWorldSize+"="+strconv.Itoa(size),
+			envWorldRoot+"="+root,
+		)
+		cmds[rank] = cmd
+		go func(rank int) {
+			var err error
+			outputs[rank], err = cmds[rank].CombinedOutput()
+			if err != nil {
+				err = fmt.Errorf("rank %d: %w", rank, err)
+			}
+			errs <- err
+		}(rank)
+	}
+	failed := false
+	for range cmds {
+		if err := <-errs; err != nil {
+			t.Error(err)
+			failed = true
+		}
+	}
+	if failed {
+		for rank := range outputs {
+			t.Logf("rank %d:\n%s", rank, outputs[rank])
+		}
+	}
+	return false
+}
+
+// connectWorld links this party to the root, which accepts the other parties in any
+// order, each one sending its rank first
+func connectWorld(t *testing.T, rank, size uint64, root string) error {
+	mpi.SelfRank, mpi.WorldSize = rank, size
+	comm.Default = &comm.Communicator{Transport: comm.SimpleMPI{}, Topology: comm.Star}
+
+	if rank != 0 {
+		var conn net.Conn
+		var err error
+		for i := 0; i < 100; i++ {
+			if conn, err = net.Dial("tcp", root); err == nil {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err != nil {
+			return err
+		}
+		t.Cleanup(func() { conn.Close() })
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], rank)
+		if _, err := conn.Write(buf[:]); err != nil {
+			return err
+		}
+		mpi.SlaveToMasterTCPConn = &conn
+		return nil
+	}
+
+	l, err := net.Listen("tcp", root)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	mpi.MasterToSlaveTCPConn = make([]*net.Conn, size)
+	for i := uint64(1); i < size; i++ {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		t.Cleanup(func() { conn.Close() })
+		var buf [8]byte
+		if _, err := io.ReadFull(conn, buf[:]); err != nil {
+			return err
+		}
+		from := binary.LittleEndian.Uint64(buf[:])
+		if from == 0 || from >= size || mpi.MasterToSlaveTCPConn[from] != nil {
+			return fmt.Errorf("unexpected party %d", from)
+		}
+		mpi.MasterToSlaveTCPConn[from] = &conn
+	}
+	return nil
+}
+
+func TestProveUnevenWeights(t *testing.T) {
+	if !inWorld(t, 2) {
+		return
+	}
+
+	// party 1 holds three times as many gates as party 0, in an X-domain of its own size
+	const nbConstraints, nbPublic = 1 << 8, 4
+	pk, vk, witnesses, err := SetupRandom(ecc.BN254, nbConstraints, nbPublic, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vk.SizesX) != 2 || vk.SizesX[0] >= vk.SizesX[1] {
+		t.Fatalf("unexpected X-domains %v", vk.SizesX)
+	}
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicWitness := bn254witness.New(witnesses[0][:nbPublic])
+	proof, err := ProveDirect(pk, witnesses, publicWitness, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mpi.SelfRank != 0 {
+		return
+	}
+	if err := Verify(proof, vk, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+
+	// the proof doesn't verify with other public inputs
+	publicWitness[0].SetUint64(42)
+	if err := Verify(proof, vk, publicWitness); err == nil {
+		t.Fatal("proof verified with other public inputs")
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groth16
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+// the JSON layouts of snarkjs: points are affine, G1 ones as [x, y, "1"] and G2 ones as
+// [[x.A0, x.A1], [y.A0, y.A1], ["1", "0"]], the point at infinity having "0" as last
+// coordinate; every number is written in decimal.
+
+const (
+	snarkJSProtocol = "groth16"
+	snarkJSCurve    = "bn128"
+)
+
+// ErrSnarkJS is wrapped by the errors of the snarkjs importers on malformed files
+var ErrSnarkJS = errors.New("invalid snarkjs file")
+
+type snarkJSProof struct {
+	A        []string   `json:"pi_a"`
+	B        [][]string `json:"pi_b"`
+	C        []string   `json:"pi_c"`
+	Protocol string     `json:"protocol"`
+	Curve    string     `json:"curve"`
+}
+
+type snarkJSVerifyingKey struct {
+	Protocol  string       `json:"protocol"`
+	Curve     string       `json:"curve"`
+	NbPublic  int          `json:"nPublic"`
+	Alpha     []string     `json:"vk_alpha_1"`
+	Beta      [][]string   `json:"vk_beta_2"`
+	Gamma     [][]string   `json:"vk_gamma_2"`
+	Delta     [][]string   `json:"vk_delta_2"`
+	AlphaBeta [][][]string `json:"vk_alphabeta_12"`
+	IC        [][]string   `json:"IC"`
+}
+
+// ExportSnarkJS writes the proof as the proof.json of snarkjs
+func (proof *Proof) ExportSnarkJS(w io.Writer) error {
+	return writeSnarkJS(w, snarkJSProof{
+		A:        g1ToSnarkJS(&proof.Ar),
+		B:        g2ToSnarkJS(&proof.Bs),
+		C:        g1ToSnarkJS(&proof.Krs),
+		Protocol: snarkJSProtocol,
+		Curve:    snarkJSCurve,
+	})
+}
+
+// ImportSnarkJS reads the proof from the proof.json of snarkjs
+func (proof *Proof) ImportSnarkJS(r io.Reader) error {
+	var p snarkJSProof
+	if err := readSnarkJS(r, &p); err != nil {
+		return err
+	}
+	if err := checkSnarkJSHeader(p.Protocol, p.Curve); err != nil {
+		return err
+	}
+	if err := g1FromSnarkJS(&proof.Ar, p.A); err != nil {
+		return fmt.Errorf("pi_a: %w", err)
+	}
+	if err := g2FromSnarkJS(&proof.Bs, p.B); err != nil {
+		return fmt.Errorf("pi_b: %w", err)
+	}
+	if err := g1FromSnarkJS(&proof.Krs, p.C); err != nil {
+		return fmt.Errorf("pi_c: %w", err)
+	}
+	return nil
+}
+
+// ExportSnarkJS writes the verifying key as the verification_key.json of snarkjs.
+// vk_alphabeta_12, which snarkjs doesn't read back, is e(α, β) as gnark computes it.
+func (vk *VerifyingKey) ExportSnarkJS(w io.Writer) error {
+	e, err := curve.Pair([]curve.G1Affine{vk.G1.Alpha}, []curve.G2Affine{vk.G2.Beta})
+	if err != nil {
+		return err
+	}
+	res := snarkJSVerifyingKey{
+		Protocol: snarkJSProtocol,
+		Curve:    snarkJSCurve,
+		NbPublic: len(vk.G1.K) - 1,
+		Alpha:    g1ToSnarkJS(&vk.G1.Alpha),
+		Beta:     g2ToSnarkJS(&vk.G2.Beta),
+		Gamma:    g2ToSnarkJS(&vk.G2.Gamma),
+		Delta:    g2ToSnarkJS(&vk.G2.Delta),
+		AlphaBeta: [][][]string{
+			{e2ToSnarkJS(&e.C0.B0), e2ToSnarkJS(&e.C0.B1), e2ToSnarkJS(&e.C0.B2)},
+			{e2ToSnarkJS(&e.C1.B0), e2ToSnarkJS(&e.C1.B1), e2ToSnarkJS(&e.C1.B2)},
+		},
+		IC: make([][]string, len(vk.G1.K)),
+	}
+	for i := range vk.G1.K {
+		res.IC[i] = g1ToSnarkJS(&vk.G1.K[i])
+	}
+	return writeSnarkJS(w, res)
+}
+
+// ImportSnarkJS reads the verifying key from the verification_key.json of snarkjs.
+// [β]1 and [δ]1, which snarkjs doesn't export and the verifier doesn't use, are left
+// unset.
+func (vk *VerifyingKey) ImportSnarkJS(r io.Reader) error {
+	var v snarkJSVerifyingKey
+	if err := readSnarkJS(r, &v); err != nil {
+		return err
+	}
+	if err := checkSnarkJSHeader(v.Protocol, v.Curve); err != nil {
+		return err
+	}
+	if v.NbPublic+1 != len(v.IC) {
+		return fmt.Errorf("%w: %d points in IC for %d public signals", ErrSnarkJS, len(v.IC), v.NbPublic)
+	}
+
+	*vk = VerifyingKey{}
+	if err := g1FromSnarkJS(&vk.G1.Alpha, v.Alpha); err != nil {
+		return fmt.Errorf("vk_alpha_1: %w", err)
+	}
+	if err := g2FromSnarkJS(&vk.G2.Beta, v.Beta); err != nil {
+		return fmt.Errorf("vk_beta_2: %w", err)
+	}
+	if err := g2FromSnarkJS(&vk.G2.Gamma, v.Gamma); err != nil {
+		return fmt.Errorf("vk_gamma_2: %w", err)
+	}
+	if err := g2FromSnarkJS(&vk.G2.Delta, v.Delta); err != nil {
+		return fmt.Errorf("vk_delta_2: %w", err)
+	}
+	vk.G1.K = make([]curve.G1Affine, len(v.IC))
+	for i := range v.IC {
+		if err := g1FromSnarkJS(&vk.G1.K[i], v.IC[i]); err != nil {
+			return fmt.Errorf("IC[%d]: %w", i, err)
+		}
+	}
+
+	return vk.Precompute()
+}
+
+// Precompute sets the elements of the verifying key which aren't serialized, e(α, β),
+// -[δ]2 and -[γ]2, for the keys filled from other formats
+func (vk *VerifyingKey) Precompute() error {
+	var err error
+	vk.e, err = curve.Pair([]curve.G1Affine{vk.G1.Alpha}, []curve.G2Affine{vk.G2.Beta})
+	if err != nil {
+		return err
+	}
+	vk.G2.deltaNeg.Neg(&vk.G2.Delta)
+	vk.G2.gammaNeg.Neg(&vk.G2.Gamma)
+	return nil
+}
+
+// ExportPublicSignals writes the public witness as the public.json of snarkjs
+func ExportPublicSignals(w io.Writer, publicWitness bn254witness.Witness) error {
+	res := make([]string, len(publicWitness))
+	for i := range publicWitness {
+		var b big.Int
+		publicWitness[i].ToBigIntRegular(&b)
+		res[i] = b.String()
+	}
+	return writeSnarkJS(w, res)
+}
+
+// ImportPublicSignals reads a public witness from the public.json of snarkjs
+func ImportPublicSignals(r io.Reader) (bn254witness.Witness, error) {
+	var signals []string
+	if err := readSnarkJS(r, &signals); err != nil {
+		return nil, err
+	}
+	res := make(bn254witness.Witness, len(signals))
+	for i, s := range signals {
+		v, err := parseSnarkJS(s, fr.Modulus())
+		if err != nil {
+			return nil, fmt.Errorf("signal %d: %w", i, err)
+		}
+		res[i].SetBigInt(v)
+	}
+	return res, nil
+}
+
+func writeSnarkJS(w io.Writer, v interface{}) error {
+	// snarkjs indents with one space
+	data, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func readSnarkJS(r io.Reader, v interface{}) error {
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnarkJS, err)
+	}
+	return nil
+}
+
+func checkSnarkJSHeader(protocol, c string) error {
+	if protocol != snarkJSProtocol {
+		return fmt.Errorf("%w: protocol %q, expected %q", ErrSnarkJS, protocol, snarkJSProtocol)
+	}
+	if c != snarkJSCurve {
+		return fmt.Errorf("%w: curve %q, expected %q", ErrSnarkJS, c, snarkJSCurve)
+	}
+	return nil
+}
+
+// parseSnarkJS parses the decimal number s, which must be lower than modulus
+func parseSnarkJS(s string, modulus *big.Int) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 || v.Cmp(modulus) >= 0 {
+		return nil, fmt.Errorf("%w: %q isn't a field element", ErrSnarkJS, s)
+	}
+	return v, nil
+}
+
+func fpToSnarkJS(e *fp.Element) string {
+	var b big.Int
+	e.ToBigIntRegular(&b)
+	return b.String()
+}
+
+func fpFromSnarkJS(e *fp.Element, s string) error {
+	v, err := parseSnarkJS(s, fp.Modulus())
+	if err != nil {
+		return err
+	}
+	e.SetBigInt(v)
+	return nil
+}
+
+func e2ToSnarkJS(e *curve.E2) []string {
+	return []string{fpToSnarkJS(&e.A0), fpToSnarkJS(&e.A1)}
+}
+
+func e2FromSnarkJS(e *curve.E2, s []string) error {
+	if len(s) != 2 {
+		return fmt.Errorf("%w: %d coordinates in an element of Fp2", ErrSnarkJS, len(s))
+	}
+	if err := fpFromSnarkJS(&e.A0, s[0]); err != nil {
+		return err
+	}
+	return fpFromSnarkJS(&e.A1, s[1])
+}
+
+// projectiveZ returns the projective coordinate z of a point of snarkjs, whose
+// coordinates are z[0] + z[1]·u: 0 at infinity, 1 otherwise
+func projectiveZ(z []string) (int, error) {
+	switch {
+	case len(z) == 1 && z[0] == "0", len(z) == 2 && z[0] == "0" && z[1] == "0":
+		return 0, nil
+	case len(z) == 1 && z[0] == "1", len(z) == 2 && z[0] == "1" && z[1] == "0":
+		return 1, nil
+	}
+	return 0, fmt.Errorf("%w: the point isn't affine", ErrSnarkJS)
+}
+
+func g1ToSnarkJS(p *curve.G1Affine) []string {
+	if p.IsInfinity() {
+		return []string{"0", "1", "0"}
+	}
+	return []string{fpToSnarkJS(&p.X), fpToSnarkJS(&p.Y), "1"}
+}
+
+func g1FromSnarkJS(p *curve.G1Affine, s []string) error {
+	if len(s) != 3 {
+		return fmt.Errorf("%w: %d coordinates in a point of G1", ErrSnarkJS, len(s))
+	}
+	switch z, err := projectiveZ(s[2:]); {
+	case err != nil:
+		return err
+	case z == 0:
+		p.X.SetZero()
+		p.Y.SetZero()
+		return nil
+	}
+	if err := fpFromSnarkJS(&p.X, s[0]); err != nil {
+		return err
+	}
+	if err := fpFromSnarkJS(&p.Y, s[1]); err != nil {
+		return err
+	}
+	if !p.IsOnCurve() || !p.IsInSubGroup() {
+		return fmt.Errorf("%w: the point isn't in G1", ErrSnarkJS)
+	}
+	return nil
+}
+
+func g2ToSnarkJS(p *curve.G2Affine) [][]string {
+	if p.IsInfinity() {
+		return [][]string{{"0", "0"}, {"1", "0"}, {"0", "0"}}
+	}
+	return [][]string{e2ToSnarkJS(&p.X), e2ToSnarkJS(&p.Y), {"1", "0"}}
+}
+
+func g2FromSnarkJS(p *curve.G2Affine, s [][]string) error {
+	if len(s) != 3 {
+		return fmt.Errorf("%w: %d coordinates in a point of G2", ErrSnarkJS, len(s))
+	}
+	switch z, err := projectiveZ(s[2]); {
+	case err != nil:
+		return err
+	case z == 0:
+		p.X.SetZero()
+		p.Y.SetZero()
+		return nil
+	}
+	if err := e2FromSnarkJS(&p.X, s[0]); err != nil {
+		return err
+	}
+	if err := e2FromSnarkJS(&p.Y, s[1]); err != nil {
+		return err
+	}
+	if !p.IsOnCurve() || !p.IsInSubGroup() {
+		return fmt.Errorf("%w: the point isn't in G2", ErrSnarkJS)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groth16
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark/internal/utils"
+)
+
+// ZFromSnarkJS returns pk.G1.Z from the H points of a .zkey of snarkjs, on domain.
+//
+// snarkjs commits to ab - c = h·(Xⁿ - 1) through its values on the odd powers of the
+// 2n-th root of unity g, with the points H[i] = [L_{2i+1}(τ)/δ]1 of the Lagrange basis of
+// the domain of size 2n, where gnark commits to the coefficients of h with the points
+// [τʲ·(τⁿ - 1)/δ]1, in bit-reversed order. As Xʲ·(Xⁿ - 1), of degree lower than 2n, is
+// zero on the even powers of g and -2·(g·ωⁱ)ʲ on g^{2i+1},
+//
+//	[τʲ·(τⁿ - 1)/δ]1 = -2·gʲ·Σᵢ ωⁱʲ·H[i]
+//
+// that is -2·gʲ times the DFT of H, which costs n/2·log(n) scalar multiplications.
+//
+// It returns an error if there aren't as many H points as the domain has elements.
+func ZFromSnarkJS(h []curve.G1Affine, domain *fft.Domain) ([]curve.G1Affine, error) {
+	n := len(h)
+	if uint64(n) != domain.Cardinality {
+		return nil, fmt.Errorf("%w: %d H points for a domain of size %d", ErrSnarkJS, n, domain.Cardinality)
+	}
+
+	// twiddles[i] = ωⁱ for the butterflies
+	twiddles := make([]big.Int, n/2)
+	var w fr.Element
+	w.SetOne()
+	for i := range twiddles {
+		w.ToBigIntRegular(&twiddles[i])
+		w.Mul(&w, &domain.Generator)
+	}
+
+	p := make([]curve.G1Jac, n)
+	utils.Parallelize(n, func(start, end int) {
+		for i := start; i < end; i++ {
+			p[i].FromAffine(&h[i])
+		}
+	})
+
+	// decimation in frequency, which leaves the DFT in bit-reversed order, the order of
+	// pk.G1.Z; the butterflies of a level touch distinct points
+	for m := n / 2; m >= 1; m >>= 1 {
+		stride := n / (2 * m)
+		utils.Parallelize(n/2, func(start, end int) {
+			var t curve.G1Jac
+			for k := start; k < end; k++ {
+				j := k % m
+				i := (k/m)*2*m + j
+				t.Set(&p[i])
+				p[i].AddAssign(&p[i+m])
+				t.SubAssign(&p[i+m])
+				if j != 0 {
+					t.ScalarMultiplication(&t, &twiddles[j*stride])
+				}
+				p[i+m].Set(&t)
+			}
+		})
+	}
+
+	// -2·gʲ, j being the bit-reversed index
+	g := fft.NewDomain(2 * domain.Cardinality).Generator
+	factors := make([]fr.Element, n)
+	factors[0].SetUint64(2)
+	factors[0].Neg(&factors[0])
+	for j := 1; j < n; j++ {
+		factors[j].Mul(&factors[j-1], &g)
+	}
+	nn := uint64(64 - bits.TrailingZeros64(uint64(n)))
+
+	res := make([]curve.G1Affine, n)
+	utils.Parallelize(n, func(start, end int) {
+		var s big.Int
+		for k := start; k < end; k++ {
+			factors[bits.Reverse64(uint64(k))>>nn].ToBigIntRegular(&s)
+			p[k].ScalarMultiplication(&p[k], &s)
+			res[k].FromJacobian(&p[k])
+		}
+	})
+	return res, nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pcs
+
+import (
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+)
+
+var (
+	_ Distributed = (*DKZG)(nil)
+	_ Univariate  = (*KZG)(nil)
+)
+
+// NewKZG returns the KZG pair: dkzg on X and kzg on Y, sharing the trapdoor of Y. Only
+// the first party and the verifier need srs.
+func NewKZG(dsrs *dkzg.SRS, srs *kzg.SRS) Scheme {
+	return Scheme{X: &DKZG{SRS: dsrs}, Y: &KZG{SRS: srs}}
+}
+
+// DKZG is the distributed KZG scheme of the gnark-crypto fork. Its digests are
+// *curve.G1Affine, and its proofs *dkzg.OpeningProof and *dkzg.BatchOpeningProof.
+type DKZG struct {
+	SRS *dkzg.SRS
+}
+
+func (s *DKZG) Commit(p []fr.Element, nbTasks ...int) (Digest, error) {
+	d, err := dkzg.Commit(p, s.SRS, nbTasks...)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s *DKZG) Open(p []fr.Element, point fr.Element) (OpeningProof, []fr.Element, error) {
+	proof, evals, err := dkzg.Open(p, point, s.SRS)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &proof, evals, nil
+}
+
+func (s *DKZG) BatchOpenSinglePoint(polys [][]fr.Element, digests []Digest, point fr.Element, hf hash.Hash) (BatchOpeningProof, [][]fr.Element, error) {
+	points, err := g1s(digests)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof, evals, err := dkzg.BatchOpenSinglePoint(polys, points, point, hf, s.SRS)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &proof, evals, nil
+}
+
+func (s *DKZG) FoldProof(digests []Digest, proof BatchOpeningProof, point fr.Element, hf hash.Hash) (OpeningProof, Digest, error) {
+	points, err := g1s(digests)
+	if err != nil {
+		return nil, nil, err
+	}
+	batch, ok := proof.(*dkzg.BatchOpeningProof)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T isn't a dkzg batch opening proof", proof)
+	}
+	folded, digest, err := dkzg.FoldProof(points, batch, point, hf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &folded, &digest, nil
+}
+
+func (s *DKZG) BatchVerifyMultiPoints(digests []Digest, proofs []OpeningProof, points []fr.Element) error {
+	g1Digests, err := g1s(digests)
+	if err != nil {
+		return err
+	}
+	dProofs := make([]dkzg.OpeningProof, len(proofs))
+	for i, proof := range proofs {
+		p, ok := proof.(*dkzg.OpeningProof)
+		if !ok {
+			return fmt.Errorf("proof %d: %T isn't a dkzg opening proof", i, proof)
+		}
+		dProofs[i] = *p
+	}
+	return dkzg.BatchVerifyMultiPoints(g1Digests, dProofs, points, s.SRS)
+}
+
+func (s *DKZG) Combine(digests []Digest, r fr.Element) (Digest, error) {
+	return combine(digests, r)
+}
+
+func (s *DKZG) PartialDigest(proof OpeningProof) Digest {
+	d := proof.(*dkzg.OpeningProof).ClaimedDigest
+	return &d
+}
+
+func (s *DKZG) PartialDigests(proof BatchOpeningProof) []Digest {
+	return digestsOf(proof.(*dkzg.BatchOpeningProof).ClaimedDigests)
+}
+
+func (s *DKZG) Transcript(proof BatchOpeningProof) []Digest {
+	batch := proof.(*dkzg.BatchOpeningProof)
+	return digestsOf(append([]curve.G1Affine{batch.H}, batch.ClaimedDigests...))
+}
+
+// KZG is the KZG scheme of gnark-crypto. Its digests are *curve.G1Affine, and its
+// proofs *kzg.OpeningProof and *kzg.BatchOpeningProof.
+type KZG struct {
+	SRS *kzg.SRS
+}
+
+func (s *KZG) Commit(p []fr.Element, nbTasks ...int) (Digest, error) {
+	d, err := kzg.Commit(p, s.SRS, nbTasks...)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s *KZG) Open(p []fr.Element, point fr.Element) (OpeningProof, error) {
+	proof, err := kzg.Open(p, point, s.SRS)
+	if err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+func (s *KZG) BatchOpenSinglePoint(polys [][]fr.Element, digests []Digest, point fr.Element, hf hash.Hash) (BatchOpeningProof, error) {
+	points, err := g1s(digests)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := kzg.BatchOpenSinglePoint(polys, points, point, hf, s.SRS)
+	if err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+func (s *KZG) FoldProof(digests []Digest, proof BatchOpeningProof, point fr.Element, hf hash.Hash) (OpeningProof, Digest, error) {
+	points, err := g1s(digests)
+	if err != nil {
+		return nil, nil, err
+	}
+	batch, ok := proof.(*kzg.BatchOpeningProof)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T isn't a kzg batch opening proof", proof)
+	}
+	folded, digest, err := kzg.FoldProof(points, batch, point, hf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &folded, &digest, nil
+}
+
+func (s *KZG) BatchVerifyMultiPoints(digests []Digest, proofs []OpeningProof, points []fr.Element) error {
+	g1Digests, err := g1s(digests)
+	if err != nil {
+		return err
+	}
+	kProofs := make([]kzg.OpeningProof, len(proofs))
+	for i, proof := range proofs {
+		p, ok := proof.(*kzg.OpeningProof)
+		if !ok {
+			return fmt.Errorf("proof %d: %T isn't a kzg opening proof", i, proof)
+		}
+		kProofs[i] = *p
+	}
+	return kzg.BatchVerifyMultiPoints(g1Digests, kProofs, points, s.SRS)
+}
+
+func (s *KZG) Combine(digests []Digest, r fr.Element) (Digest, error) {
+	return combine(digests, r)
+}
+
+func (s *KZG) ClaimedValue(proof OpeningProof) fr.Element {
+	return proof.(*kzg.OpeningProof).ClaimedValue
+}
+
+func (s *KZG) ClaimedValues(proof BatchOpeningProof) []fr.Element {
+	return proof.(*kzg.BatchOpeningProof).ClaimedValues
+}
+
+// g1s returns the points of KZG digests
+func g1s(digests []Digest) ([]curve.G1Affine, error) {
+	res := make([]curve.G1Affine, len(digests))
+	for i, d := range digests {
+		p, ok := d.(*curve.G1Affine)
+		if !ok {
+			return nil, fmt.Errorf("digest %d: %T isn't a KZG digest", i, d)
+		}
+		res[i] = *p
+	}
+	return res, nil
+}
+
+// digestsOf returns the KZG digests of points
+func digestsOf(points []curve.G1Affine) []Digest {
+	res := make([]Digest, len(points))
+	for i := range points {
+		p := points[i]
+		res[i] = &p
+	}
+	return res
+}
+
+// combine returns Σᵢ rⁱ·digests[i] for KZG digests, by Horner's rule
+func combine(digests []Digest, r fr.Element) (Digest, error) {
+	points, err := g1s(digests)
+	if err != nil {
+		return nil, err
+	}
+	var res curve.G1Affine
+	if len(points) == 0 {
+		return &res, nil
+	}
+	var bR big.Int
+	r.ToBigIntRegular(&bR)
+	res = points[len(points)-1]
+	for i := len(points) - 2; i >= 0; i-- {
+		res.ScalarMultiplication(&res, &bR)
+		res.Add(&res, &points[i])
+	}
+	return &res, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pcs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+)
+
+func randomPolynomials(nb, size int) [][]fr.Element {
+	res := make([][]fr.Element, nb)
+	for i := range res {
+		res[i] = make([]fr.Element, size)
+		for j := range res[i] {
+			res[i][j].SetRandom()
+		}
+	}
+	return res
+}
+
+func TestKZGCombine(t *testing.T) {
+	const size = 16
+	srs, err := kzg.NewSRS(size, new(big.Int).SetUint64(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheme := &KZG{SRS: srs}
+
+	polys := randomPolynomials(3, size)
+	digests := make([]Digest, len(polys))
+	for i := range polys {
+		if digests[i], err = scheme.Commit(polys[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// p₀ + r·p₁ + r²·p₂
+	var r fr.Element
+	r.SetRandom()
+	combined := make([]fr.Element, size)
+	for i := len(polys) - 1; i >= 0; i-- {
+		for j := range combined {
+			combined[j].Mul(&combined[j], &r).Add(&combined[j], &polys[i][j])
+		}
+	}
+	want, err := scheme.Commit(combined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := scheme.Combine(digests, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Marshal(), want.Marshal()) {
+		t.Fatal("the combined digest isn't the digest of the combined polynomials")
+	}
+}
+
+func TestKZGBatchOpening(t *testing.T) {
+	const size = 16
+	srs, err := kzg.NewSRS(size, new(big.Int).SetUint64(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scheme Univariate = &KZG{SRS: srs}
+
+	polys := randomPolynomials(4, size)
+	digests := make([]Digest, len(polys))
+	for i := range polys {
+		if digests[i], err = scheme.Commit(polys[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var point fr.Element
+	point.SetRandom()
+
+	batch, err := scheme.BatchOpenSinglePoint(polys, digests, point, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := scheme.ClaimedValues(batch)
+	for i := range polys {
+		var want fr.Element
+		for j := size - 1; j >= 0; j-- {
+			want.Mul(&want, &point).Add(&want, &polys[i][j])
+		}
+		if !values[i].Equal(&want) {
+			t.Fatalf("polynomial %d: wrong claimed value", i)
+		}
+	}
+
+	folded, foldedDigest, err := scheme.FoldProof(digests, batch, point, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	single, err := scheme.Open(polys[0], point)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scheme.BatchVerifyMultiPoints(
+		[]Digest{foldedDigest, digests[0]},
+		[]OpeningProof{folded, single},
+		[]fr.Element{point, point},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// a digest of another scheme is rejected
+	if err := scheme.BatchVerifyMultiPoints([]Digest{nil}, []OpeningProof{single}, []fr.Element{point}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
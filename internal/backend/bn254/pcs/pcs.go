@@ -0,0 +1,126 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pcs defines the polynomial commitment schemes of the Pianist provers.
+//
+// The provers commit to bivariate polynomials f(Y, X), party i holding the polynomial
+// f(ωⁱ, X) of X. A Distributed scheme commits to them collectively and partially opens
+// them on X = α, which leaves univariate polynomials f(Y, α), committed to in the
+// Univariate scheme and opened by the first party on Y = β. The two schemes of a Scheme
+// must agree: the partial digests of the Distributed scheme are digests of the
+// Univariate one.
+//
+// The protocols fold digests linearly (Combine), so that the digests must be
+// homomorphic, or defer the combination to the verification, as a scheme with Merkle
+// roots as digests would.
+//
+// Only piano commits through a Scheme. gpiano still calls dkzg and kzg directly: its
+// proofs and checkpoints serialize the digests as G1 points, and its parties open the
+// polynomials of Y together, which Univariate doesn't cover.
+package pcs
+
+import (
+	"hash"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// Digest is a commitment to a polynomial
+type Digest interface {
+	// Marshal returns the encoding of the digest bound to the Fiat-Shamir transcripts
+	Marshal() []byte
+}
+
+// OpeningProof is the proof of the evaluation of a committed polynomial at a point,
+// opaque to the protocols
+type OpeningProof interface{}
+
+// BatchOpeningProof is the proof of the evaluations of several committed polynomials
+// at the same point, opaque to the protocols
+type BatchOpeningProof interface{}
+
+// Scheme is a commitment scheme on each dimension of the bivariate polynomials
+type Scheme struct {
+	X Distributed
+	Y Univariate
+}
+
+// Distributed is a commitment scheme for the bivariate polynomials f(Y, X) spread over
+// the parties, party i holding f(ωⁱ, X) in canonical basis. All the parties call
+// Commit and the openings, the results are those of the first party.
+type Distributed interface {
+	// Commit returns the digest of f, nbTasks bounding the parallelism of each party
+	Commit(p []fr.Element, nbTasks ...int) (Digest, error)
+
+	// Open partially evaluates f at X = point. The first party gets the evaluations
+	// f(ωⁱ, point) of all the parties.
+	Open(p []fr.Element, point fr.Element) (OpeningProof, []fr.Element, error)
+
+	// BatchOpenSinglePoint partially evaluates the polynomials of digests at X = point,
+	// hf deriving the challenge folding them. The first party gets the evaluations
+	// of all the parties, polynomial by polynomial.
+	BatchOpenSinglePoint(polys [][]fr.Element, digests []Digest, point fr.Element, hf hash.Hash) (BatchOpeningProof, [][]fr.Element, error)
+
+	// FoldProof folds the batch opening proof of digests into the opening proof of
+	// a single digest, hf deriving the challenge as in BatchOpenSinglePoint
+	FoldProof(digests []Digest, proof BatchOpeningProof, point fr.Element, hf hash.Hash) (OpeningProof, Digest, error)
+
+	// BatchVerifyMultiPoints checks the opening proofs of digests at points
+	BatchVerifyMultiPoints(digests []Digest, proofs []OpeningProof, points []fr.Element) error
+
+	// Combine returns the digest of Σᵢ rⁱ·fᵢ given the digests of the fᵢ
+	Combine(digests []Digest, r fr.Element) (Digest, error)
+
+	// PartialDigest returns the digest of f(Y, point) in the Univariate scheme for an
+	// opening proof of f at X = point
+	PartialDigest(proof OpeningProof) Digest
+
+	// PartialDigests returns the digests of the fⱼ(Y, point) in the Univariate scheme
+	// for a batch opening proof of the fⱼ at X = point
+	PartialDigests(proof BatchOpeningProof) []Digest
+
+	// Transcript returns the digests of a batch opening proof bound to the Fiat-Shamir
+	// transcripts, the partial digests included
+	Transcript(proof BatchOpeningProof) []Digest
+}
+
+// Univariate is a commitment scheme for the polynomials of Y in canonical basis, which
+// only the first party handles
+type Univariate interface {
+	// Commit returns the digest of p, nbTasks bounding the parallelism
+	Commit(p []fr.Element, nbTasks ...int) (Digest, error)
+
+	// Open returns the proof of p(point)
+	Open(p []fr.Element, point fr.Element) (OpeningProof, error)
+
+	// BatchOpenSinglePoint returns the proof of the evaluations of polys at point, hf
+	// deriving the challenge folding them
+	BatchOpenSinglePoint(polys [][]fr.Element, digests []Digest, point fr.Element, hf hash.Hash) (BatchOpeningProof, error)
+
+	// FoldProof folds the batch opening proof of digests into the opening proof of
+	// a single digest, hf deriving the challenge as in BatchOpenSinglePoint
+	FoldProof(digests []Digest, proof BatchOpeningProof, point fr.Element, hf hash.Hash) (OpeningProof, Digest, error)
+
+	// BatchVerifyMultiPoints checks the opening proofs of digests at points
+	BatchVerifyMultiPoints(digests []Digest, proofs []OpeningProof, points []fr.Element) error
+
+	// Combine returns the digest of Σᵢ rⁱ·pᵢ given the digests of the pᵢ
+	Combine(digests []Digest, r fr.Element) (Digest, error)
+
+	// ClaimedValue returns the evaluation an opening proof claims
+	ClaimedValue(proof OpeningProof) fr.Element
+
+	// ClaimedValues returns the evaluations a batch opening proof claims
+	ClaimedValues(proof BatchOpeningProof) []fr.Element
+}
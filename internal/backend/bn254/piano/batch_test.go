@@ -0,0 +1,109 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package piano
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/internal/backend/bn254/cs"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+func TestBatchVerify(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &streamCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spr := ccs.(*cs.SparseR1CS)
+	tVariable := reflect.TypeOf((*frontend.Variable)(nil)).Elem()
+
+	const nbProofs = 4
+	fullWitnesses := make([]bn254witness.Witness, nbProofs)
+	publicWitnesses := make([]bn254witness.Witness, nbProofs)
+	for i := range fullWitnesses {
+		assignment := streamAssignment(uint64(i + 2))
+		if _, err := fullWitnesses[i].FromAssignment(assignment, tVariable, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := publicWitnesses[i].FromAssignment(assignment, tVariable, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pk, vk, err := Setup(spr, publicWitnesses[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proofs := make([]*Proof, nbProofs)
+	for i := range proofs {
+		if proofs[i], err = Prove(spr, pk, fullWitnesses[i], opt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := BatchVerify(proofs, vk, publicWitnesses); err != nil {
+		t.Fatal(err)
+	}
+
+	// the opening of Z on omegaX * alpha of another proof
+	const bad = 2
+	zShifted := *proofs[bad].PartialZShiftedProof.(*dkzg.OpeningProof)
+	zShifted.H = proofs[0].PartialZShiftedProof.(*dkzg.OpeningProof).H
+	tampered := *proofs[bad]
+	tampered.PartialZShiftedProof = &zShifted
+	if err := Verify(&tampered, vk, publicWitnesses[bad]); err == nil {
+		t.Fatal("tampered proof should not verify")
+	}
+
+	batch := append([]*Proof(nil), proofs...)
+	batch[bad] = &tampered
+	err = BatchVerify(batch, vk, publicWitnesses)
+	var batchErr *BatchVerifyError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a BatchVerifyError, got %v", err)
+	}
+	if batchErr.Index != bad {
+		t.Fatalf("BatchVerifyError reports proof %d, expected %d", batchErr.Index, bad)
+	}
+
+	// a commitment of another proof fails while replaying the transcript
+	tampered = *proofs[bad]
+	tampered.Z = proofs[0].Z
+	batch[bad] = &tampered
+	err = BatchVerify(batch, vk, publicWitnesses)
+	if !errors.As(err, &batchErr) || batchErr.Index != bad {
+		t.Fatalf("expected a BatchVerifyError on proof %d, got %v", bad, err)
+	}
+
+	if err := BatchVerify(proofs, vk, publicWitnesses[1:]); err == nil {
+		t.Fatal("batch verification should fail on a missing public witness")
+	}
+}
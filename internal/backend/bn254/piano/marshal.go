@@ -17,8 +17,21 @@
 package piano
 
 import (
+	"errors"
 	"fmt"
 	"io"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend/compiled"
+	"github.com/consensys/gnark/internal/backend/bn254/pcs"
+)
+
+// vkMagic prefixes the encodings of VerifyingKey, the version being its last byte
+const (
+	vkMagic   uint64 = 0x7069616e6f766b00 // "pianovk\x00"
+	vkVersion uint64 = 1
 )
 
 // WriteTo writes binary encoding of Proof to w
@@ -42,12 +55,123 @@ func (pk *ProvingKey) ReadFrom(r io.Reader) (int64, error) {
 
 }
 
-// WriteTo writes binary encoding of VerifyingKey to w
+// WriteTo writes binary encoding of VerifyingKey to w. The digests must be KZG digests,
+// and only the points of the SRS on Y the verifier needs are written: the SRS on X isn't,
+// see InitKZG.
 func (vk *VerifyingKey) WriteTo(w io.Writer) (n int64, err error) {
-	return 0, fmt.Errorf("not implemented")
+	enc := curve.NewEncoder(w)
+
+	digests := []pcs.Digest{vk.S[0], vk.S[1], vk.S[2], vk.Ql, vk.Qr, vk.Qm, vk.Qo, vk.Qk}
+	points := make([]curve.G1Affine, len(digests))
+	for i, d := range digests {
+		p, ok := d.(*curve.G1Affine)
+		if !ok {
+			return 0, fmt.Errorf("digest %d: %T isn't a KZG digest", i, d)
+		}
+		points[i] = *p
+	}
+
+	// the verifier needs [1]1, [1]2 and [τ]2 of the SRS on Y, which only the first
+	// party has
+	var srsG1 []curve.G1Affine
+	var srs *kzg.SRS
+	if y, ok := vk.PCS.Y.(*pcs.KZG); ok && y.SRS != nil {
+		srs = y.SRS
+		srsG1 = srs.G1[:1]
+	}
+
+	toEncode := []interface{}{
+		vkMagic | vkVersion,
+		vk.SizeY,
+		vk.SizeX,
+		&vk.SizeYInv,
+		&vk.SizeXInv,
+		&vk.Generator,
+		vk.NbPublicVariables,
+		&vk.CosetShift,
+		points,
+		uint64(vk.TranscriptHash),
+		srsG1,
+	}
+	if srs != nil {
+		toEncode = append(toEncode, &srs.G2[0], &srs.G2[1])
+	}
+
+	for _, v := range toEncode {
+		if err := enc.Encode(v); err != nil {
+			return enc.BytesWritten(), err
+		}
+	}
+
+	// fingerprint of the constraint system
+	n2, err := w.Write(vk.CircuitFingerprint[:])
+	return enc.BytesWritten() + int64(n2), err
 }
 
-// ReadFrom reads from binary representation in r into VerifyingKey
+// ReadFrom reads from binary representation in r into VerifyingKey. The scheme on X is
+// left nil, InitKZG setting it.
 func (vk *VerifyingKey) ReadFrom(r io.Reader) (int64, error) {
-	return 0, fmt.Errorf("not implemented")
+	dec := curve.NewDecoder(r)
+
+	var header uint64
+	if err := dec.Decode(&header); err != nil {
+		return dec.BytesRead(), err
+	}
+	if header&^0xff != vkMagic {
+		return dec.BytesRead(), errors.New("not a piano verifying key")
+	}
+	if version := header & 0xff; version == 0 || version > vkVersion {
+		return dec.BytesRead(), fmt.Errorf("unknown verifying key format version %d", version)
+	}
+
+	var (
+		points         []curve.G1Affine
+		transcriptHash uint64
+		srsG1          []curve.G1Affine
+	)
+	toDecode := []interface{}{
+		&vk.SizeY,
+		&vk.SizeX,
+		&vk.SizeYInv,
+		&vk.SizeXInv,
+		&vk.Generator,
+		&vk.NbPublicVariables,
+		&vk.CosetShift,
+		&points,
+		&transcriptHash,
+		&srsG1,
+	}
+
+	for _, v := range toDecode {
+		if err := dec.Decode(v); err != nil {
+			return dec.BytesRead(), err
+		}
+	}
+	if len(points) != 8 {
+		return dec.BytesRead(), fmt.Errorf("%d digests, expected 8", len(points))
+	}
+	vk.S[0], vk.S[1], vk.S[2] = &points[0], &points[1], &points[2]
+	vk.Ql, vk.Qr, vk.Qm, vk.Qo, vk.Qk = &points[3], &points[4], &points[5], &points[6], &points[7]
+	vk.TranscriptHash = backend.TranscriptHash(transcriptHash)
+
+	vk.PCS = pcs.Scheme{}
+	switch len(srsG1) {
+	case 0:
+	case 1:
+		srs := &kzg.SRS{G1: srsG1}
+		if err := dec.Decode(&srs.G2[0]); err != nil {
+			return dec.BytesRead(), err
+		}
+		if err := dec.Decode(&srs.G2[1]); err != nil {
+			return dec.BytesRead(), err
+		}
+		vk.PCS.Y = &pcs.KZG{SRS: srs}
+	default:
+		return dec.BytesRead(), fmt.Errorf("%d points of the SRS in G1, expected 1", len(srsG1))
+	}
+
+	// fingerprint of the constraint system
+	vk.CircuitFingerprint = compiled.Fingerprint{}
+	n, err := io.ReadFull(r, vk.CircuitFingerprint[:])
+	return dec.BytesRead() + int64(n), err
 }
@@ -24,7 +24,16 @@ import (
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 
 	"bytes"
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/internal/backend/bn254/cs"
+	"github.com/consensys/gnark/internal/backend/bn254/pcs"
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
 	"reflect"
 	"testing"
 )
@@ -36,14 +45,14 @@ func TestProvingKeySerialization(t *testing.T) {
 	vk.SizeXInv = fr.One()
 
 	_, _, g1gen, _ := curve.Generators()
-	vk.S[0] = g1gen
-	vk.S[1] = g1gen
-	vk.S[2] = g1gen
-	vk.Ql = g1gen
-	vk.Qr = g1gen
-	vk.Qm = g1gen
-	vk.Qo = g1gen
-	vk.Qk = g1gen
+	vk.S[0] = &g1gen
+	vk.S[1] = &g1gen
+	vk.S[2] = &g1gen
+	vk.Ql = &g1gen
+	vk.Qr = &g1gen
+	vk.Qm = &g1gen
+	vk.Qo = &g1gen
+	vk.Qk = &g1gen
 	vk.NbPublicVariables = 8000
 
 	// random pk
@@ -96,14 +105,20 @@ func TestVerifyingKeySerialization(t *testing.T) {
 	vk.SizeXInv = fr.One()
 
 	_, _, g1gen, _ := curve.Generators()
-	vk.S[0] = g1gen
-	vk.S[1] = g1gen
-	vk.S[2] = g1gen
-	vk.Ql = g1gen
-	vk.Qr = g1gen
-	vk.Qm = g1gen
-	vk.Qo = g1gen
-	vk.Qk = g1gen
+	vk.S[0] = &g1gen
+	vk.S[1] = &g1gen
+	vk.S[2] = &g1gen
+	vk.Ql = &g1gen
+	vk.Qr = &g1gen
+	vk.Qm = &g1gen
+	vk.Qo = &g1gen
+	vk.Qk = &g1gen
+	vk.TranscriptHash = backend.KECCAK256
+	vk.CircuitFingerprint[0] = 42
+
+	// only the points of the SRS on Y the verifier needs are serialized
+	_, _, _, g2gen := curve.Generators()
+	vk.PCS.Y = &pcs.KZG{SRS: &kzg.SRS{G1: []curve.G1Affine{g1gen}, G2: [2]curve.G2Affine{g2gen, g2gen}}}
 
 	var buf bytes.Buffer
 	written, err := vk.WriteTo(&buf)
@@ -126,3 +141,85 @@ func TestVerifyingKeySerialization(t *testing.T) {
 		t.Fatal("bytes written / read don't match")
 	}
 }
+
+func TestVerifyingKeyMalformed(t *testing.T) {
+	var vk VerifyingKey
+	_, _, g1gen, _ := curve.Generators()
+	vk.S[0], vk.S[1], vk.S[2] = &g1gen, &g1gen, &g1gen
+	vk.Ql, vk.Qr, vk.Qm, vk.Qo, vk.Qk = &g1gen, &g1gen, &g1gen, &g1gen, &g1gen
+
+	var buf bytes.Buffer
+	if _, err := vk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	var reconstructed VerifyingKey
+	if _, err := reconstructed.ReadFrom(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Fatal("reading a truncated fingerprint should fail")
+	}
+	other := append([]byte{}, data...)
+	other[0] ^= 1
+	if _, err := reconstructed.ReadFrom(bytes.NewReader(other)); err == nil {
+		t.Fatal("reading another header should fail")
+	}
+
+	// the digests are written as KZG digests only
+	vk.Qk = nil
+	if _, err := vk.WriteTo(&buf); err == nil {
+		t.Fatal("writing a missing digest should fail")
+	}
+}
+
+func TestVerifyingKeyRoundTrip(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &streamCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spr := ccs.(*cs.SparseR1CS)
+	tVariable := reflect.TypeOf((*frontend.Variable)(nil)).Elem()
+
+	var fullWitness, publicWitness bn254witness.Witness
+	assignment := streamAssignment(3)
+	if _, err := fullWitness.FromAssignment(assignment, tVariable, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := publicWitness.FromAssignment(assignment, tVariable, true); err != nil {
+		t.Fatal(err)
+	}
+
+	pk, vk, err := Setup(spr, publicWitness)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := Prove(spr, pk, fullWitness, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the key read back verifies once given the SRS on X again
+	var buf bytes.Buffer
+	if _, err := vk.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var reconstructed VerifyingKey
+	if _, err := reconstructed.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if reconstructed.Fingerprint() != spr.Fingerprint() {
+		t.Fatal("the serialization lost the fingerprint")
+	}
+	if err := reconstructed.InitKZG(vk.PCS.X.(*pcs.DKZG).SRS); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(proof, &reconstructed, publicWitness); err != nil {
+		t.Fatal(err)
+	}
+}
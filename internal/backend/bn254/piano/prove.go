@@ -19,25 +19,24 @@
 package piano
 
 import (
-	"crypto/sha256"
 	"fmt"
+	"hash"
 	"math/big"
 	"math/bits"
 	"runtime"
 	"time"
 
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
 	"github.com/consensys/gnark-crypto/fiat-shamir"
 	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/internal/backend/bn254/cs"
+	"github.com/consensys/gnark/internal/backend/bn254/pcs"
 	"github.com/consensys/gnark/internal/utils"
 	"github.com/consensys/gnark/logger"
 	"github.com/sunblaze-ucb/simpleMPI/mpi"
 
-	curve "github.com/consensys/gnark-crypto/ecc/bn254"
 	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
 )
 
@@ -49,33 +48,33 @@ var (
 type Proof struct {
 
 	// Commitments to the solution vectors
-	LRO [3]dkzg.Digest
+	LRO [3]pcs.Digest
 
 	// Commitment to Z, the permutation polynomial
-	Z dkzg.Digest
+	Z pcs.Digest
 
 	// Commitments to Hx1, Hx2, Hx3 such that
 	// Hx = Hx1 + (X**N) * Hx2 + (X**(2N)) * Hx3 and
 	// commitments to Hy1, Hy2, Hy3 such that
 	// Hy = Hy1 + (Y**M) * Hy2 + (Y**(2M)) * Hy3
-	Hx [3]dkzg.Digest
-	Hy [3]kzg.Digest
+	Hx [3]pcs.Digest
+	Hy [3]pcs.Digest
 
 	// Batch partially opening proof of
 	// foldedHx(Y, X) = Hx1(Y, X) + alpha*Hx2(Y, X) + (alpha**2)*Hx3(Y, X),
 	// L(Y, X), R(Y, X), O(Y, X), Ql(Y, X), Qr(Y, X), Qm(Y, X), Qo(Y, X),
 	// Qk(Y, X), S1(Y, X), S2(Y, X), S3(Y, X),
 	// Z(Y, X) on X = alpha
-	PartialBatchedProof dkzg.BatchOpeningProof
+	PartialBatchedProof pcs.BatchOpeningProof
 
 	// Opening partially proof of Z(Y, X) on X = omegaX*alpha
-	PartialZShiftedProof dkzg.OpeningProof
+	PartialZShiftedProof pcs.OpeningProof
 
 	// Batch opening proof of FoldedHx(Y, alpha), L(Y, alpha), R(Y, alpha), O(Y, alpha),
 	// Ql(Y, alpha), Qr(Y, alpha), Qm(Y, alpha), Qo(Y, alpha), Qk(Y, alpha),
 	// S1(Y, alpha), S2(Y, alpha), S3(Y, alpha), Z(Y, alpha), z(Y, mu*alpha),
 	// FoldedHy(Y) on Y = beta
-	BatchedProof kzg.BatchOpeningProof
+	BatchedProof pcs.BatchOpeningProof
 }
 
 // Prove from the public data
@@ -83,21 +82,107 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 	fmt.Println("Prover started")
 	log := logger.Logger().With().Str("curve", spr.CurveID().String()).Int("nbConstraints", len(spr.Constraints)).Str("backend", "piano").Logger()
 	start := time.Now()
+
+	inst, err := newInstance(spr, pk, newPrecomputed(pk), fullWitness, opt)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range inst.steps() {
+		if err := s.run(); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Debug().Dur("took", time.Since(start)).Msg("prover done")
+	return inst.proof, nil
+}
+
+// precomputed holds the tables of the prover which only depend on the proving key
+type precomputed struct {
+	// evaluationIDSmallDomain = getIDSmallDomain(&pk.Domain[0])
+	evaluationIDSmallDomain []fr.Element
+
+	// inverses of Xⁿ-1 on the coset of pk.Domain[1] and of Yᵐ-1 on the coset of globalDomain[1]
+	xnMinusOneBigInv, ymMinusOneBigInv []fr.Element
+}
+
+func newPrecomputed(pk *ProvingKey) *precomputed {
+	return &precomputed{
+		evaluationIDSmallDomain: getIDSmallDomain(&pk.Domain[0]),
+		xnMinusOneBigInv:        fr.BatchInvert(evaluateXnMinusOneBig(&pk.Domain[1], &pk.Domain[0])),
+		ymMinusOneBigInv:        fr.BatchInvert(evaluateXnMinusOneBig(globalDomain[1], globalDomain[0])),
+	}
+}
+
+// instance is the state of the proof of one witness
+type instance struct {
+	spr         *cs.SparseR1CS
+	pk          *ProvingKey
+	pre         *precomputed
+	fullWitness bn254witness.Witness
+	opt         backend.ProverConfig
+
+	hFunc hash.Hash
+	fs    fiatshamir.Transcript
+	proof *Proof
+
+	lSmallX, rSmallX, oSmallX                          []fr.Element
+	lCanonicalX, rCanonicalX, oCanonicalX, zCanonicalX []fr.Element
+	hx1, hx2, hx3                                      []fr.Element
+	gamma, eta, lambda, alpha                          fr.Element
+	zShiftedAlpha                                      []fr.Element
+	evalsXOnAlpha                                      [][]fr.Element
+}
+
+func newInstance(spr *cs.SparseR1CS, pk *ProvingKey, pre *precomputed, fullWitness bn254witness.Witness, opt backend.ProverConfig) (*instance, error) {
 	// pick a hash function that will be used to derive the challenges
-	hFunc := sha256.New()
+	hFunc, err := pk.Vk.TranscriptHash.New(ecc.BN254)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instance{
+		spr:         spr,
+		pk:          pk,
+		pre:         pre,
+		fullWitness: fullWitness,
+		opt:         opt,
+		hFunc:       hFunc,
+		// create a transcript manager to apply Fiat Shamir
+		fs:    fiatshamir.NewTranscript(hFunc, "gamma", "eta", "lambda", "alpha", "beta"),
+		proof: &Proof{},
+	}, nil
+}
+
+// step of the prover. Only the network steps exchange messages with the other parties,
+// all the parties must run them in the same order.
+type step struct {
+	network bool
+	run     func() error
+}
 
-	// create a transcript manager to apply Fiat Shamir
-	fs := fiatshamir.NewTranscript(hFunc, "gamma", "eta", "lambda", "alpha", "beta")
+// steps returns the steps of the prover, alternating local and network steps
+func (p *instance) steps() []step {
+	return []step{
+		{false, p.solve},
+		{true, p.commitLRO},
+		{false, p.computeZ},
+		{true, p.commitZ},
+		{false, p.computeQuotientX},
+		{true, p.openX},
+		{false, p.openY},
+	}
+}
 
-	// result
-	proof := &Proof{}
+// solve computes the solution and L, R, O in canonical basis
+func (p *instance) solve() error {
+	spr, pk := p.spr, p.pk
 
 	// compute the constraint system solution
-	var solution []fr.Element
-	var err error
-	if solution, err = spr.Solve(fullWitness, opt); err != nil {
-		if !opt.Force {
-			return nil, err
+	solution, err := spr.Solve(p.fullWitness, p.opt)
+	if err != nil {
+		if !p.opt.Force {
+			return err
 		} else {
 			// we need to fill solution with random values
 			var r fr.Element
@@ -112,127 +197,136 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 	fmt.Println("Solution computed")
 
 	// query L, R, O in Lagrange basis, not blinded
-	lSmallX, rSmallX, oSmallX := evaluateLROSmallDomainX(spr, pk, solution)
+	p.lSmallX, p.rSmallX, p.oSmallX = evaluateLROSmallDomainX(spr, pk, solution)
 
 	// save lL, lR, lO, and make a copy of them in
 	// canonical basis note that we allocate more capacity to reuse for blinded
 	// polynomials
-	lCanonicalX, rCanonicalX, oCanonicalX := computeLROCanonicalX(
-		lSmallX,
-		rSmallX,
-		oSmallX,
+	p.lCanonicalX, p.rCanonicalX, p.oCanonicalX = computeLROCanonicalX(
+		p.lSmallX,
+		p.rSmallX,
+		p.oSmallX,
 		&pk.Domain[0],
 	)
-	if err != nil {
-		return nil, err
-	}
+	return nil
+}
+
+// commitLRO commits to L, R, O and derives gamma and eta
+func (p *instance) commitLRO() error {
+	pk, proof := p.pk, p.proof
 
 	// compute kzg commitments of bcL, bcR and bcO
-	if err := commitToLRO(lCanonicalX, rCanonicalX, oCanonicalX, proof, pk.Vk.DKZGSRS); err != nil {
-		return nil, err
+	if err := commitToLRO(p.lCanonicalX, p.rCanonicalX, p.oCanonicalX, proof, pk.Vk.PCS.X); err != nil {
+		return err
 	}
 
 	// The first challenge is derived using the public data: the commitments to the permutation,
 	// the coefficients of the circuit, and the public inputs.
 	// derive gamma from the Comm(cL), Comm(cR), Comm(cO)
-	if err := bindPublicData(&fs, "gamma", *pk.Vk, fullWitness[:spr.NbPublicVariables]); err != nil {
-		return nil, err
+	if err := bindPublicData(&p.fs, "gamma", *pk.Vk, p.fullWitness[:p.spr.NbPublicVariables]); err != nil {
+		return err
 	}
-	gamma, err := deriveRandomness(&fs, "gamma", false, &proof.LRO[0], &proof.LRO[1], &proof.LRO[2])
+	var err error
+	p.gamma, err = deriveRandomness(&p.fs, "gamma", false, proof.LRO[:]...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Fiat Shamir this
-	eta, err := deriveRandomness(&fs, "eta", false)
-	if err != nil {
-		return nil, err
-	}
+	p.eta, err = deriveRandomness(&p.fs, "eta", false)
+	return err
+}
 
-	// compute Z, the permutation accumulator polynomial, in canonical basis
+// computeZ computes Z, the permutation accumulator polynomial, in canonical basis
+func (p *instance) computeZ() error {
 	// lL, lR, lO are NOT blinded
-	zCanonicalX, err := computeZCanonicalX(
-		lSmallX,
-		rSmallX,
-		oSmallX,
-		pk, eta, gamma,
+	var err error
+	p.zCanonicalX, err = computeZCanonicalX(
+		p.lSmallX,
+		p.rSmallX,
+		p.oSmallX,
+		p.pk, p.pre, p.eta, p.gamma,
 	)
-	if err != nil {
-		return nil, err
-	}
+	return err
+}
 
+// commitZ commits to Z and derives lambda
+func (p *instance) commitZ() error {
 	// commit to z
 	// note that we explicitly double the number of tasks for the multi exp
-	// in dkzg.Commit
+	// in the commitment
 	// this may add additional arithmetic operations, but with smaller tasks
 	// we ensure that this commitment is well parallelized, without having a
 	// "unbalanced task" making the rest of the code wait too long
-	if proof.Z, err = dkzg.Commit(zCanonicalX, pk.Vk.DKZGSRS, runtime.NumCPU()*2); err != nil {
-		return nil, err
+	var err error
+	if p.proof.Z, err = p.pk.Vk.PCS.X.Commit(p.zCanonicalX, runtime.NumCPU()*2); err != nil {
+		return err
 	}
 
 	// derive lambda from the Comm(L), Comm(R), Comm(O), Com(Z)
-	lambda, err := deriveRandomness(&fs, "lambda", false, &proof.Z)
-	if err != nil {
-		return nil, err
-	}
+	p.lambda, err = deriveRandomness(&p.fs, "lambda", false, p.proof.Z)
+	return err
+}
 
-	hx1, hx2, hx3 := computeQuotientCanonicalX(pk, lCanonicalX, rCanonicalX, oCanonicalX, zCanonicalX, eta, gamma, lambda)
+// computeQuotientX computes Hx in canonical basis
+func (p *instance) computeQuotientX() error {
+	p.hx1, p.hx2, p.hx3 = computeQuotientCanonicalX(p.pk, p.pre, p.lCanonicalX, p.rCanonicalX, p.oCanonicalX, p.zCanonicalX, p.eta, p.gamma, p.lambda)
+	return nil
+}
 
-	// print vector of hx1, hx2, hx3
+// openX commits to Hx, derives alpha and partially opens the polynomials on X = alpha
+func (p *instance) openX() error {
+	pk, proof := p.pk, p.proof
 
 	// compute kzg commitments of Hx1, Hx2 and Hx3
-	if err := commitToQuotientX(hx1, hx2, hx3, proof, pk.Vk.DKZGSRS); err != nil {
-		return nil, err
+	if err := commitToQuotientX(p.hx1, p.hx2, p.hx3, proof, pk.Vk.PCS.X); err != nil {
+		return err
 	}
 
 	// derive alpha
-	alpha, err := deriveRandomness(&fs, "alpha", false, &proof.Hx[0], &proof.Hx[1], &proof.Hx[2])
+	var err error
+	p.alpha, err = deriveRandomness(&p.fs, "alpha", false, proof.Hx[:]...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// open Z at mu*alpha
 	var alphaShifted fr.Element
-	alphaShifted.Mul(&alpha, &pk.Vk.Generator)
-	var zShiftedAlpha []fr.Element
-	proof.PartialZShiftedProof, zShiftedAlpha, err = dkzg.Open(
-		zCanonicalX,
+	alphaShifted.Mul(&p.alpha, &pk.Vk.Generator)
+	proof.PartialZShiftedProof, p.zShiftedAlpha, err = pk.Vk.PCS.X.Open(
+		p.zCanonicalX,
 		alphaShifted,
-		pk.Vk.DKZGSRS,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// foldedHDigest = Comm(Hx1) + (alpha**(N))*Comm(Hx2) + (alpha**(2(N)))*Comm(Hx3)
-	var bAlphaPowerN, bSize big.Int
+	var bSize big.Int
 	bSize.SetUint64(pk.Domain[0].Cardinality)
 	var alphaPowerN fr.Element
-	alphaPowerN.Exp(alpha, &bSize)
-	alphaPowerN.ToBigIntRegular(&bAlphaPowerN)
-	foldedHxDigest := proof.Hx[2]
-	foldedHxDigest.ScalarMultiplication(&foldedHxDigest, &bAlphaPowerN)
-	foldedHxDigest.Add(&foldedHxDigest, &proof.Hx[1])
-	foldedHxDigest.ScalarMultiplication(&foldedHxDigest, &bAlphaPowerN)
-	foldedHxDigest.Add(&foldedHxDigest, &proof.Hx[0])
+	alphaPowerN.Exp(p.alpha, &bSize)
+	foldedHxDigest, err := pk.Vk.PCS.X.Combine(proof.Hx[:], alphaPowerN)
+	if err != nil {
+		return err
+	}
 
 	// foldedHx = Hx1 + (alpha**(N))*Hx2 + (alpha**(2(N)))*Hx3
-	foldedHx := hx3
+	foldedHx := p.hx3
 	utils.Parallelize(len(foldedHx), func(start, end int) {
 		for i := start; i < end; i++ {
 			foldedHx[i].Mul(&foldedHx[i], &alphaPowerN)
-			foldedHx[i].Add(&foldedHx[i], &hx2[i])
+			foldedHx[i].Add(&foldedHx[i], &p.hx2[i])
 			foldedHx[i].Mul(&foldedHx[i], &alphaPowerN)
-			foldedHx[i].Add(&foldedHx[i], &hx1[i])
+			foldedHx[i].Add(&foldedHx[i], &p.hx1[i])
 		}
 	})
 
-	dkzgOpeningPolys := [][]fr.Element{
+	openingPolysX := [][]fr.Element{
 		foldedHx,
-		lCanonicalX,
-		rCanonicalX,
-		oCanonicalX,
+		p.lCanonicalX,
+		p.rCanonicalX,
+		p.oCanonicalX,
 		pk.Ql,
 		pk.Qr,
 		pk.Qm,
@@ -241,9 +335,9 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 		pk.S1Canonical,
 		pk.S2Canonical,
 		pk.S3Canonical,
-		zCanonicalX,
+		p.zCanonicalX,
 	}
-	dkzgDigests := []dkzg.Digest{
+	digestsX := []pcs.Digest{
 		foldedHxDigest,
 		proof.LRO[0],
 		proof.LRO[1],
@@ -260,42 +354,37 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 	}
 
 	// Batch open the first list of polynomials
-	var evalsXOnAlpha [][]fr.Element
-	proof.PartialBatchedProof, evalsXOnAlpha, err = dkzg.BatchOpenSinglePoint(
-		dkzgOpeningPolys,
-		dkzgDigests,
-		alpha,
-		hFunc,
-		pk.Vk.DKZGSRS,
+	proof.PartialBatchedProof, p.evalsXOnAlpha, err = pk.Vk.PCS.X.BatchOpenSinglePoint(
+		openingPolysX,
+		digestsX,
+		p.alpha,
+		p.hFunc,
 	)
+	return err
+}
 
-	if err != nil {
-		return nil, err
-	}
-
+// openY computes Hy, derives beta and opens the polynomials on Y = beta. Only the
+// first party has the partial openings on X = alpha, the other ones have nothing to do.
+func (p *instance) openY() error {
 	if mpi.SelfRank != 0 {
-		log.Debug().Dur("took", time.Since(start)).Msg("prover done")
-		if err != nil {
-			return nil, err
-		}
-
-		return proof, nil
+		return nil
 	}
+	pk, proof := p.pk, p.proof
 
 	// DBG check whether constraints are satisfied
 	if err := checkConstraintX(
 		pk,
-		evalsXOnAlpha,
-		zShiftedAlpha,
-		gamma,
-		eta,
-		lambda,
-		alpha,
+		p.evalsXOnAlpha,
+		p.zShiftedAlpha,
+		p.gamma,
+		p.eta,
+		p.lambda,
+		p.alpha,
 	); err != nil {
-		return nil, err
+		return err
 	}
 
-	polysCanonicalY := append(evalsXOnAlpha, zShiftedAlpha)
+	polysCanonicalY := append(p.evalsXOnAlpha, p.zShiftedAlpha)
 	for i := 0; i < len(polysCanonicalY); i++ {
 		globalDomain[0].FFTInverse(polysCanonicalY[i], fft.DIF)
 		fft.BitReverse(polysCanonicalY[i])
@@ -303,43 +392,34 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 
 	// compute Hy in canonical form
 	hyCanonical1, hyCanonical2, hyCanonical3 := computeQuotientCanonicalY(pk,
+		p.pre,
 		polysCanonicalY,
-		eta,
-		gamma,
-		lambda,
-		alpha,
+		p.eta,
+		p.gamma,
+		p.lambda,
+		p.alpha,
 	)
 
 	// compute kzg commitments of Hy1, Hy2 and Hy3
-	if err := commitToQuotientOnY(hyCanonical1, hyCanonical2, hyCanonical3, proof, globalSRS); err != nil {
-		return nil, err
+	if err := commitToQuotientOnY(hyCanonical1, hyCanonical2, hyCanonical3, proof, pk.Vk.PCS.Y); err != nil {
+		return err
 	}
 	// derive beta
-	ts := []*curve.G1Affine{
-		&proof.PartialBatchedProof.H,
-	}
-	for _, digest := range proof.PartialBatchedProof.ClaimedDigests {
-		ts = append(ts, &digest)
-	}
-	for _, digest := range proof.Hy {
-		ts = append(ts, &digest)
-	}
-	beta, err := deriveRandomness(&fs, "beta", true, ts...)
+	ts := append(pk.Vk.PCS.X.Transcript(proof.PartialBatchedProof), proof.Hy[:]...)
+	beta, err := deriveRandomness(&p.fs, "beta", true, ts...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// foldedHy = Hy1 + (beta**M)*Hy2 + (beta**(2M))*Hy3
-	var bBetaPowerM big.Int
+	var bSize big.Int
 	bSize.SetUint64(globalDomain[0].Cardinality)
 	var betaPowerM fr.Element
 	betaPowerM.Exp(beta, &bSize)
-	betaPowerM.ToBigIntRegular(&bBetaPowerM)
-	foldedHyDigest := proof.Hy[2]
-	foldedHyDigest.ScalarMultiplication(&foldedHyDigest, &bBetaPowerM)
-	foldedHyDigest.Add(&foldedHyDigest, &proof.Hy[1])
-	foldedHyDigest.ScalarMultiplication(&foldedHyDigest, &bBetaPowerM)
-	foldedHyDigest.Add(&foldedHyDigest, &proof.Hy[0])
+	foldedHyDigest, err := pk.Vk.PCS.Y.Combine(proof.Hy[:], betaPowerM)
+	if err != nil {
+		return err
+	}
 	foldedHy := hyCanonical3
 	utils.Parallelize(len(foldedHy), func(start, end int) {
 		for i := start; i < end; i++ {
@@ -352,33 +432,15 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 
 	polysCanonicalY = append(polysCanonicalY, foldedHy)
 
-	// evalsOnBeta := evalPolynomialsAtPoint(openingPolysCanonicalY, beta)
-	// DBG check whether constraints are satisfied
-	// if err := checkConstraintY(pk.Vk,
-	// 	evalsOnBeta,
-	// 	gamma,
-	// 	eta,
-	// 	lambda,
-	// 	alpha,
-	// 	beta,
-	// ); err != nil {
-	// 	return nil, err
-	// }
-
-	var digestsY []curve.G1Affine
-	digestsY = append(digestsY, proof.PartialBatchedProof.ClaimedDigests...)
-	digestsY = append(digestsY, proof.PartialZShiftedProof.ClaimedDigest, foldedHyDigest)
-	proof.BatchedProof, err = kzg.BatchOpenSinglePoint(
+	digestsY := pk.Vk.PCS.X.PartialDigests(proof.PartialBatchedProof)
+	digestsY = append(digestsY, pk.Vk.PCS.X.PartialDigest(proof.PartialZShiftedProof), foldedHyDigest)
+	proof.BatchedProof, err = pk.Vk.PCS.Y.BatchOpenSinglePoint(
 		polysCanonicalY,
 		digestsY,
 		beta,
-		hFunc,
-		globalSRS,
+		p.hFunc,
 	)
-	if err != nil {
-		return nil, err
-	}
-	return proof, nil
+	return err
 }
 
 // eval evaluates c at p
@@ -398,48 +460,48 @@ func evalPolynomialsAtPoint(polys [][]fr.Element, point fr.Element) []fr.Element
 	return res
 }
 
-func commitToLRO(bcl, bcr, bco []fr.Element, proof *Proof, srs *dkzg.SRS) error {
+func commitToLRO(bcl, bcr, bco []fr.Element, proof *Proof, scheme pcs.Distributed) error {
 	n := runtime.NumCPU() / 2
 	var err error
-	proof.LRO[0], err = dkzg.Commit(bcl, srs, n)
+	proof.LRO[0], err = scheme.Commit(bcl, n)
 	if err != nil {
 		return err
 	}
-	proof.LRO[1], err = dkzg.Commit(bcr, srs, n)
+	proof.LRO[1], err = scheme.Commit(bcr, n)
 	if err != nil {
 		return err
 	}
-	proof.LRO[2], err = dkzg.Commit(bco, srs, n)
+	proof.LRO[2], err = scheme.Commit(bco, n)
 	return err
 }
 
-func commitToQuotientX(h1, h2, h3 []fr.Element, proof *Proof, srs *dkzg.SRS) error {
+func commitToQuotientX(h1, h2, h3 []fr.Element, proof *Proof, scheme pcs.Distributed) error {
 	n := runtime.NumCPU() / 2
 	var err error
-	proof.Hx[0], err = dkzg.Commit(h1, srs, n)
+	proof.Hx[0], err = scheme.Commit(h1, n)
 	if err != nil {
 		return err
 	}
-	proof.Hx[1], err = dkzg.Commit(h2, srs, n)
+	proof.Hx[1], err = scheme.Commit(h2, n)
 	if err != nil {
 		return err
 	}
-	proof.Hx[2], err = dkzg.Commit(h3, srs, n)
+	proof.Hx[2], err = scheme.Commit(h3, n)
 	return err
 }
 
-func commitToQuotientOnY(h1, h2, h3 []fr.Element, proof *Proof, srs *kzg.SRS) error {
+func commitToQuotientOnY(h1, h2, h3 []fr.Element, proof *Proof, scheme pcs.Univariate) error {
 	n := runtime.NumCPU() / 2
 	var err error
-	proof.Hy[0], err = kzg.Commit(h1, srs, n)
+	proof.Hy[0], err = scheme.Commit(h1, n)
 	if err != nil {
 		return err
 	}
-	proof.Hy[1], err = kzg.Commit(h2, srs, n)
+	proof.Hy[1], err = scheme.Commit(h2, n)
 	if err != nil {
 		return err
 	}
-	proof.Hy[2], err = kzg.Commit(h3, srs, n)
+	proof.Hy[2], err = scheme.Commit(h3, n)
 	return err
 }
 
@@ -541,7 +603,7 @@ func evaluateLROSmallDomainX(spr *cs.SparseR1CS, pk *ProvingKey, solution []fr.E
 //							         (l(g**k)+eta*s1(g**k)+gamma)*(r(g**k)+eta*s2(g**k)+gamma)*(o(g**k)+eta*s3(g**k)+gamma)
 //
 //	* l, r, o are the solution in Lagrange basis, evaluated on the small domain
-func computeZCanonicalX(l, r, o []fr.Element, pk *ProvingKey, eta, gamma fr.Element) ([]fr.Element, error) {
+func computeZCanonicalX(l, r, o []fr.Element, pk *ProvingKey, pre *precomputed, eta, gamma fr.Element) ([]fr.Element, error) {
 	// note that z has more capacity has its memory is reused for z later on
 	z := make([]fr.Element, pk.Domain[0].Cardinality)
 	nbElmts := int(pk.Domain[0].Cardinality)
@@ -550,7 +612,7 @@ func computeZCanonicalX(l, r, o []fr.Element, pk *ProvingKey, eta, gamma fr.Elem
 	z[0].SetOne()
 	gInv[0].SetOne()
 
-	evaluationIDSmallDomain := getIDSmallDomain(&pk.Domain[0])
+	evaluationIDSmallDomain := pre.evaluationIDSmallDomain
 
 	utils.Parallelize(nbElmts-1, func(start, end int) {
 
@@ -617,7 +679,7 @@ func evaluateXnMinusOneBig(domainBig, domainSmall *fft.Domain) []fr.Element {
 // + lambda * (z(mu*X)*g1(X)*g2(X)*g3(X)-z(X)*f1(X)*f2(X)*f3(X))
 // + (lambda**2) * L0(X)*(z(X)-1)
 // = hx(X)Zn(X)
-func computeQuotientCanonicalX(pk *ProvingKey, lCanonicalX, rCanonicalX, oCanonicalX, zCanonicalX []fr.Element, eta, gamma, lambda fr.Element) ([]fr.Element, []fr.Element, []fr.Element) {
+func computeQuotientCanonicalX(pk *ProvingKey, pre *precomputed, lCanonicalX, rCanonicalX, oCanonicalX, zCanonicalX []fr.Element, eta, gamma, lambda fr.Element) ([]fr.Element, []fr.Element, []fr.Element) {
 	ratio := pk.Domain[1].Cardinality / pk.Domain[0].Cardinality
 
 	// Compute the power of domain[1].Generator with bit-reversed order.
@@ -709,8 +771,7 @@ func computeQuotientCanonicalX(pk *ProvingKey, lCanonicalX, rCanonicalX, oCanoni
 		})
 	}
 
-	XnMinusOneBig := evaluateXnMinusOneBig(&pk.Domain[1], &pk.Domain[0])
-	XnMinusOneBig = fr.BatchInvert(XnMinusOneBig)
+	XnMinusOneBig := pre.xnMinusOneBigInv
 	nn2 := uint64(64 - bits.TrailingZeros64(uint64(pk.Domain[1].Cardinality)))
 	utils.Parallelize(int(pk.Domain[1].Cardinality), func(start, end int) {
 		for _i := uint64(start); _i < uint64(end); _i++ {
@@ -735,7 +796,7 @@ func computeQuotientCanonicalX(pk *ProvingKey, lCanonicalX, rCanonicalX, oCanoni
 //	 - Z(Y, alpha)*F1(Y, alpha)*F2(Y, alpha)*F3(Y, alpha))
 // + lambda**2 * L0(alpha)*(Z(Y, alpha) - 1)
 // - Hx(Y, alpha)Z(X) = Hy(Y)Z(Y)
-func computeQuotientCanonicalY(pk *ProvingKey, polys [][]fr.Element, eta, gamma, lambda, alpha fr.Element) ([]fr.Element, []fr.Element, []fr.Element) {
+func computeQuotientCanonicalY(pk *ProvingKey, pre *precomputed, polys [][]fr.Element, eta, gamma, lambda, alpha fr.Element) ([]fr.Element, []fr.Element, []fr.Element) {
 	h := make([]fr.Element, globalDomain[1].Cardinality)
 	ratio := globalDomain[1].Cardinality / globalDomain[0].Cardinality
 
@@ -830,8 +891,7 @@ func computeQuotientCanonicalY(pk *ProvingKey, polys [][]fr.Element, eta, gamma,
 		})
 	}
 
-	evaluationYmMinusOneInverse := evaluateXnMinusOneBig(globalDomain[1], globalDomain[0])
-	evaluationYmMinusOneInverse = fr.BatchInvert(evaluationYmMinusOneInverse)
+	evaluationYmMinusOneInverse := pre.ymMinusOneBigInv
 	nn2 := uint64(64 - bits.TrailingZeros64(uint64(globalDomain[1].Cardinality)))
 	utils.Parallelize(int(globalDomain[1].Cardinality), func(start, end int) {
 		for _i := uint64(start); _i < uint64(end); _i++ {
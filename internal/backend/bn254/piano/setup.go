@@ -23,21 +23,33 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/comm"
+	"github.com/consensys/gnark/frontend/compiled"
 	"github.com/consensys/gnark/internal/backend/bn254/cs"
+	"github.com/consensys/gnark/internal/backend/bn254/pcs"
 	"github.com/sunblaze-ucb/simpleMPI/mpi"
 
 	dkzgg "github.com/consensys/gnark-crypto/dkzg"
 	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
 )
 
-var (
-	globalDomain [2]*fft.Domain
-	globalSRS    *kzg.SRS
-)
+var globalDomain [2]*fft.Domain
+
+// initGlobalDomain sets the domains of Y, one point per party
+func initGlobalDomain() {
+	globalDomain[0] = fft.NewDomain(mpi.WorldSize)
+	if mpi.WorldSize < 6 {
+		globalDomain[1] = fft.NewDomain(8 * mpi.WorldSize)
+	} else {
+		globalDomain[1] = fft.NewDomain(4 * mpi.WorldSize)
+	}
+}
 
 // ProvingKey stores the data needed to generate a proof:
 // * the commitment scheme
@@ -86,46 +98,37 @@ type VerifyingKey struct {
 	NbPublicVariables uint64
 
 	// Commitment scheme that is used for an instantiation of PLONK
-	DKZGSRS *dkzg.SRS
-	KZGSRS *kzg.SRS
+	PCS pcs.Scheme
 
 	// cosetShift generator of the coset on the small domain
 	CosetShift fr.Element
 
 	// S commitments to S1, S2, S3
-	S [3]kzg.Digest
+	S [3]pcs.Digest
 
 	// Commitments to ql, qr, qm, qo prepended with as many zeroes (ones for l) as there are public inputs.
 	// In particular Qk is not complete.
-	Ql, Qr, Qm, Qo, Qk kzg.Digest
+	Ql, Qr, Qm, Qo, Qk pcs.Digest
+
+	// TranscriptHash is the hash function of the Fiat-Shamir transcript
+	TranscriptHash backend.TranscriptHash
+
+	// CircuitFingerprint is the fingerprint of the constraint system each party proves, zero if
+	// the key wasn't set up from a constraint system
+	CircuitFingerprint compiled.Fingerprint
 }
 
-// Setup sets proving and verifying keys
+// Setup sets proving and verifying keys, committing with the KZG pair whose trapdoors
+// it draws
 func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey, *VerifyingKey, error) {
-	globalDomain[0] = fft.NewDomain(mpi.WorldSize)
-	if mpi.WorldSize < 6 {
-		globalDomain[1] = fft.NewDomain(8 * mpi.WorldSize)
-	} else {
-		globalDomain[1] = fft.NewDomain(4 * mpi.WorldSize)
-	}
+	initGlobalDomain()
 
 	one := fr.One()
-
-	var pk ProvingKey
-	var vk VerifyingKey
-
-	// The verifying key shares data with the proving key
-	pk.Vk = &vk
-
-	nbConstraints := len(spr.Constraints)
-
-	// fft domains
-	sizeSystem := uint64(nbConstraints + spr.NbPublicVariables) // spr.NbPublicVariables is for the placeholder constraints
-	pk.Domain[0] = *fft.NewDomain(sizeSystem)
-	pk.Vk.CosetShift.Set(&pk.Domain[0].FrMultiplicativeGen)
+	sizeX := ecc.NextPowerOfTwo(uint64(len(spr.Constraints) + spr.NbPublicVariables))
 
 	var t, s *big.Int
 	var err error
+	var srs *kzg.SRS
 	if mpi.SelfRank == 0 {
 		for {
 			t, err = rand.Int(rand.Reader, spr.CurveID().ScalarField())
@@ -145,7 +148,7 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 			}
 			var ele fr.Element
 			ele.SetBigInt(s)
-			if !ele.Exp(ele, big.NewInt(int64(pk.Domain[0].Cardinality))).Equal(&one) {
+			if !ele.Exp(ele, big.NewInt(int64(sizeX))).Equal(&one) {
 				break
 			}
 		}
@@ -153,44 +156,81 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 		tByteLen := (t.BitLen() + 7) / 8
 		sByteLen := (s.BitLen() + 7) / 8
 		for i := uint64(1); i < mpi.WorldSize; i++ {
-			if err := mpi.SendBytes([]byte{byte(tByteLen)}, i); err != nil {
+			if err := comm.Default.Transport.Send([]byte{byte(tByteLen)}, i); err != nil {
 				return nil, nil, err
 			}
-			if err := mpi.SendBytes(t.Bytes(), i); err != nil {
+			if err := comm.Default.Transport.Send(t.Bytes(), i); err != nil {
 				return nil, nil, err
 			}
-			if err := mpi.SendBytes([]byte{byte(sByteLen)}, i); err != nil {
+			if err := comm.Default.Transport.Send([]byte{byte(sByteLen)}, i); err != nil {
 				return nil, nil, err
 			}
-			if err := mpi.SendBytes(s.Bytes(), i); err != nil {
+			if err := comm.Default.Transport.Send(s.Bytes(), i); err != nil {
 				return nil, nil, err
 			}
 		}
-		globalSRS, err = kzg.NewSRS(globalDomain[0].Cardinality, t)
+		srs, err = kzg.NewSRS(globalDomain[0].Cardinality, t)
 		if err != nil {
 			return nil, nil, err
 		}
 	} else {
-		tByteLen, err := mpi.ReceiveBytes(1, 0)
+		tByteLen, err := comm.Default.Transport.Receive(1, 0)
 		if err != nil {
 			return nil, nil, err
 		}
-		tbytes, err := mpi.ReceiveBytes(uint64(tByteLen[0]), 0)
+		tbytes, err := comm.Default.Transport.Receive(uint64(tByteLen[0]), 0)
 		if err != nil {
 			return nil, nil, err
 		}
 		t = new(big.Int).SetBytes(tbytes)
-		sByteLen, err := mpi.ReceiveBytes(1, 0)
+		sByteLen, err := comm.Default.Transport.Receive(1, 0)
 		if err != nil {
 			return nil, nil, err
 		}
-		sbytes, err := mpi.ReceiveBytes(uint64(sByteLen[0]), 0)
+		sbytes, err := comm.Default.Transport.Receive(uint64(sByteLen[0]), 0)
 		if err != nil {
 			return nil, nil, err
 		}
 		s = new(big.Int).SetBytes(sbytes)
 	}
-	vk.KZGSRS = globalSRS
+
+	dkzgSRS, err := dkzg.NewSRS(sizeX+3, []*big.Int{t, s}, &globalDomain[0].Generator)
+	if err != nil {
+		return nil, nil, err
+	}
+	return SetupPCS(spr, publicWitness, pcs.NewKZG(dkzgSRS, srs))
+}
+
+// SetupSRS sets proving and verifying keys committing with the KZG pair of the given SRS,
+// which must share the trapdoor of Y. Only the first party needs srs. dsrs is computed
+// from the trapdoor of Y, so that srs coming from a Powers of Tau ceremony doesn't make
+// the keys any more trusted than whoever computed dsrs.
+func SetupSRS(spr *cs.SparseR1CS, publicWitness bn254witness.Witness, dsrs *dkzg.SRS, srs *kzg.SRS) (*ProvingKey, *VerifyingKey, error) {
+	initGlobalDomain()
+	if mpi.SelfRank == 0 && (srs == nil || uint64(len(srs.G1)) < globalDomain[0].Cardinality) {
+		return nil, nil, errors.New("kzg srs is too small")
+	}
+	return SetupPCS(spr, publicWitness, pcs.NewKZG(dsrs, srs))
+}
+
+// SetupPCS sets proving and verifying keys committing with the given scheme, whose
+// parameters must fit the circuit
+func SetupPCS(spr *cs.SparseR1CS, publicWitness bn254witness.Witness, scheme pcs.Scheme) (*ProvingKey, *VerifyingKey, error) {
+	initGlobalDomain()
+
+	var pk ProvingKey
+	var vk VerifyingKey
+
+	// The verifying key shares data with the proving key
+	pk.Vk = &vk
+	vk.PCS = scheme
+
+	nbConstraints := len(spr.Constraints)
+
+	// fft domains
+	sizeSystem := uint64(nbConstraints + spr.NbPublicVariables) // spr.NbPublicVariables is for the placeholder constraints
+	pk.Domain[0] = *fft.NewDomain(sizeSystem)
+	pk.Vk.CosetShift.Set(&pk.Domain[0].FrMultiplicativeGen)
 
 	// h, the quotient polynomial is of degree 3(n+1)+2, so it's in a 3(n+2) dim vector space,
 	// the domain is the next power of 2 superior to 3(n+2). 4*domainNum is enough in all cases
@@ -208,14 +248,6 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 	vk.Generator.Set(&pk.Domain[0].Generator)
 	vk.NbPublicVariables = uint64(spr.NbPublicVariables)
 
-	dkzgSRS, err := dkzg.NewSRS(vk.SizeX+3, []*big.Int{t, s}, &globalDomain[0].Generator)
-	if err != nil {
-		return nil, nil, err
-	}
-	if err := pk.InitKZG(dkzgSRS); err != nil {
-		return nil, nil, err
-	}
-
 	// public polynomials corresponding to constraints: [ placholders | constraints | assertions ]
 	pk.Ql = make([]fr.Element, pk.Domain[0].Cardinality)
 	pk.Qr = make([]fr.Element, pk.Domain[0].Cardinality)
@@ -259,31 +291,34 @@ func Setup(spr *cs.SparseR1CS, publicWitness bn254witness.Witness) (*ProvingKey,
 	ccomputePermutationPolynomials(&pk)
 
 	// Commit to the polynomials to set up the verifying key
-	if vk.Ql, err = dkzg.Commit(pk.Ql, vk.DKZGSRS); err != nil {
+	var err error
+	if vk.Ql, err = vk.PCS.X.Commit(pk.Ql); err != nil {
 		return nil, nil, err
 	}
-	if vk.Qr, err = dkzg.Commit(pk.Qr, vk.DKZGSRS); err != nil {
+	if vk.Qr, err = vk.PCS.X.Commit(pk.Qr); err != nil {
 		return nil, nil, err
 	}
-	if vk.Qm, err = dkzg.Commit(pk.Qm, vk.DKZGSRS); err != nil {
+	if vk.Qm, err = vk.PCS.X.Commit(pk.Qm); err != nil {
 		return nil, nil, err
 	}
-	if vk.Qo, err = dkzg.Commit(pk.Qo, vk.DKZGSRS); err != nil {
+	if vk.Qo, err = vk.PCS.X.Commit(pk.Qo); err != nil {
 		return nil, nil, err
 	}
-	if vk.Qk, err = dkzg.Commit(pk.Qk, vk.DKZGSRS); err != nil {
+	if vk.Qk, err = vk.PCS.X.Commit(pk.Qk); err != nil {
 		return nil, nil, err
 	}
-	if vk.S[0], err = dkzg.Commit(pk.S1Canonical, vk.DKZGSRS); err != nil {
+	if vk.S[0], err = vk.PCS.X.Commit(pk.S1Canonical); err != nil {
 		return nil, nil, err
 	}
-	if vk.S[1], err = dkzg.Commit(pk.S2Canonical, vk.DKZGSRS); err != nil {
+	if vk.S[1], err = vk.PCS.X.Commit(pk.S2Canonical); err != nil {
 		return nil, nil, err
 	}
-	if vk.S[2], err = dkzg.Commit(pk.S3Canonical, vk.DKZGSRS); err != nil {
+	if vk.S[2], err = vk.PCS.X.Commit(pk.S3Canonical); err != nil {
 		return nil, nil, err
 	}
 
+	vk.CircuitFingerprint = spr.Fingerprint()
+
 	return &pk, &vk, nil
 
 }
@@ -402,7 +437,7 @@ func getIDSmallDomain(domain *fft.Domain) []fr.Element {
 	return res
 }
 
-// InitKZG inits pk.Vk.KZG using pk.Domain[0] cardinality and provided SRS
+// InitKZG inits the dkzg scheme of pk.Vk on X using pk.Domain[0] cardinality and provided SRS
 //
 // This should be used after deserializing a ProvingKey
 // as pk.Vk.KZG is NOT serialized
@@ -410,7 +445,7 @@ func (pk *ProvingKey) InitKZG(srs dkzgg.SRS) error {
 	return pk.Vk.InitKZG(srs)
 }
 
-// InitKZG inits vk.KZG using provided SRS
+// InitKZG inits the dkzg scheme of vk on X using provided SRS
 //
 // This should be used after deserializing a VerifyingKey
 // as vk.KZG is NOT serialized
@@ -422,7 +457,7 @@ func (vk *VerifyingKey) InitKZG(srs dkzgg.SRS) error {
 	if len(_srs.G1) < int(vk.SizeX) {
 		return errors.New("dkzg srs is too small")
 	}
-	vk.DKZGSRS = _srs
+	vk.PCS.X = &pcs.DKZG{SRS: _srs}
 
 	return nil
 }
@@ -432,6 +467,11 @@ func (vk *VerifyingKey) NbPublicWitness() int {
 	return int(vk.NbPublicVariables)
 }
 
+// Fingerprint returns the fingerprint of the constraint system each party proves
+func (vk *VerifyingKey) Fingerprint() compiled.Fingerprint {
+	return vk.CircuitFingerprint
+}
+
 // VerifyingKey returns pk.Vk
 func (pk *ProvingKey) VerifyingKey() interface{} {
 	return pk.Vk
@@ -0,0 +1,217 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package piano
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/internal/backend/bn254/cs"
+	"github.com/consensys/gnark/logger"
+
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+// pipelineDepth is the number of instances ProveMany proves at the same time
+const pipelineDepth = 2
+
+var errPipelineAborted = errors.New("an earlier proof of the stream failed")
+
+// ProveResult is the proof of a witness of ProveMany, or the error which prevented it
+type ProveResult struct {
+	Proof *Proof
+	Err   error
+}
+
+// ProveMany proves every witness received on witnesses with the same circuit, and sends
+// the results on the returned channel in the order of the witnesses. The channel is
+// closed after witnesses is closed and the last result was sent. As pk holds the public
+// inputs, the witnesses only differ by their secret inputs.
+//
+// Two instances are in flight at any time: while instance k runs its quotient and opening
+// phases, instance k+1 is solved and its witness is committed, and the tables which only
+// depend on pk are computed once for the whole stream. All the parties must receive the
+// same number of witnesses. As the network rounds of the instances are interleaved in a
+// fixed order, the result of the last witness is only sent once witnesses is closed.
+// After a failure, the remaining witnesses are drained and fail as well.
+func ProveMany(spr *cs.SparseR1CS, pk *ProvingKey, witnesses <-chan bn254witness.Witness, opt backend.ProverConfig) <-chan ProveResult {
+	log := logger.Logger().With().Str("curve", spr.CurveID().String()).Int("nbConstraints", len(spr.Constraints)).Str("backend", "piano").Logger()
+	results := make(chan ProveResult, pipelineDepth)
+	pending := make(chan chan ProveResult, pipelineDepth)
+
+	// forward the results in order
+	go func() {
+		for res := range pending {
+			results <- <-res
+		}
+		close(results)
+	}()
+
+	go func() {
+		defer close(pending)
+		start := time.Now()
+		pre := newPrecomputed(pk)
+		seq := newSequencer()
+		slots := make(chan struct{}, pipelineDepth)
+
+		k := 0
+		for {
+			slots <- struct{}{}
+			fullWitness, ok := <-witnesses
+			if !ok {
+				// instance k won't commit to its witness before the openings of instance k-1
+				seq.skip(networkTurn(k, 1))
+				break
+			}
+			res := make(chan ProveResult, 1)
+			pending <- res
+			go func(k int) {
+				defer func() { <-slots }()
+				proof, err := proveInstance(spr, pk, pre, fullWitness, opt, seq, k)
+				if err != nil {
+					seq.abort(err)
+				}
+				res <- ProveResult{Proof: proof, Err: err}
+			}(k)
+			k++
+		}
+
+		took := time.Since(start)
+		log.Info().Int("nbProofs", k).Dur("took", took).
+			Float64("proofsPerMinute", float64(k)/took.Minutes()).
+			Msg("stream of proofs done")
+	}()
+
+	return results
+}
+
+// proveInstance runs the steps of instance k, taking its turn for the network steps
+func proveInstance(spr *cs.SparseR1CS, pk *ProvingKey, pre *precomputed, fullWitness bn254witness.Witness, opt backend.ProverConfig, seq *sequencer, k int) (*Proof, error) {
+	inst, err := newInstance(spr, pk, pre, fullWitness, opt)
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range inst.steps() {
+		if !s.network {
+			if err := s.run(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		turn := networkTurn(k, i)
+		if err := seq.wait(turn); err != nil {
+			return nil, err
+		}
+		err := s.run()
+		seq.done(turn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return inst.proof, nil
+}
+
+// networkTurn returns the position of the network step i of instance k in the order
+//
+//	commitLRO(0), commitZ(0), commitLRO(1), openX(0), commitZ(1), commitLRO(2), openX(1), ...
+//
+// so that the witness of instance k+1 is committed while instance k computes its
+// quotient, and Z of instance k+1 is computed while instance k opens its polynomials.
+func networkTurn(k, i int) int {
+	switch i {
+	case 1: // commitLRO
+		if k == 0 {
+			return 0
+		}
+		return 3*k - 1
+	case 3: // commitZ
+		return 3*k + 1
+	case 5: // openX
+		return 3*k + 3
+	default:
+		panic("not a network step")
+	}
+}
+
+// sequencer runs the network steps of the instances one at a time, in the order of
+// their turns, which is the same on all the parties
+type sequencer struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	next    int
+	skipped map[int]bool
+	err     error
+}
+
+func newSequencer() *sequencer {
+	s := &sequencer{skipped: make(map[int]bool)}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// wait blocks until it is the given turn, or the pipeline was aborted
+func (s *sequencer) wait(turn int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.next != turn && s.err == nil {
+		s.cond.Wait()
+	}
+	if s.err != nil {
+		return errPipelineAborted
+	}
+	return nil
+}
+
+// done ends the given turn
+func (s *sequencer) done(turn int) {
+	s.mu.Lock()
+	if s.next == turn {
+		s.advance()
+	}
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// skip marks a turn no instance will take
+func (s *sequencer) skip(turn int) {
+	s.mu.Lock()
+	if s.next == turn {
+		s.advance()
+	} else {
+		s.skipped[turn] = true
+	}
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+func (s *sequencer) advance() {
+	s.next++
+	for s.skipped[s.next] {
+		delete(s.skipped, s.next)
+		s.next++
+	}
+}
+
+// abort wakes up and fails all the waiting instances
+func (s *sequencer) abort(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
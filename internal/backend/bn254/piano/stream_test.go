@@ -0,0 +1,229 @@
+// Copyright 2023 Tianyi Liu and Tiancheng Xie
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package piano
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/internal/backend/bn254/cs"
+	"github.com/sunblaze-ucb/simpleMPI/mpi"
+
+	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
+)
+
+func TestNetworkTurns(t *testing.T) {
+	for _, n := range []int{1, 2, 5} {
+		seq := newSequencer()
+		seq.skip(networkTurn(n, 1))
+
+		// run the network steps of the instances concurrently and record their order
+		var mu sync.Mutex
+		var order []int
+		var wg sync.WaitGroup
+		for k := 0; k < n; k++ {
+			for _, i := range []int{1, 3, 5} {
+				wg.Add(1)
+				go func(k, i int) {
+					defer wg.Done()
+					turn := networkTurn(k, i)
+					if err := seq.wait(turn); err != nil {
+						t.Error(err)
+						return
+					}
+					mu.Lock()
+					order = append(order, turn)
+					mu.Unlock()
+					seq.done(turn)
+				}(k, i)
+			}
+		}
+		wg.Wait()
+
+		if len(order) != 3*n {
+			t.Fatalf("%d instances: got %d network steps", n, len(order))
+		}
+		for j := 1; j < len(order); j++ {
+			if order[j] <= order[j-1] {
+				t.Fatalf("%d instances: network steps out of order %v", n, order)
+			}
+		}
+	}
+
+	// an abort wakes up the waiting instances
+	seq := newSequencer()
+	errs := make(chan error)
+	go func() { errs <- seq.wait(1) }()
+	time.Sleep(10 * time.Millisecond)
+	seq.abort(errPipelineAborted)
+	if err := <-errs; err == nil {
+		t.Fatal("wait should fail after an abort")
+	}
+}
+
+// piano binds the public inputs in the proving key, so the witnesses of a stream only
+// differ by their secret inputs
+type streamCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable
+}
+
+func (circuit *streamCircuit) Define(api frontend.API) error {
+	x := circuit.X
+	for i := 0; i < 100; i++ {
+		x = api.Add(api.Mul(x, x), circuit.X)
+	}
+	api.AssertIsEqual(x, circuit.Y)
+	return nil
+}
+
+func streamAssignment(x uint64) *streamCircuit {
+	var X, y fr.Element
+	X.SetUint64(x)
+	y.Set(&X)
+	for i := 0; i < 100; i++ {
+		y.Square(&y).Add(&y, &X)
+	}
+	return &streamCircuit{X: x, Y: y}
+}
+
+func TestProveMany(t *testing.T) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &streamCircuit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spr := ccs.(*cs.SparseR1CS)
+	tVariable := reflect.TypeOf((*frontend.Variable)(nil)).Elem()
+
+	const nbProofs = 4
+	fullWitnesses := make([]bn254witness.Witness, nbProofs)
+	publicWitnesses := make([]bn254witness.Witness, nbProofs)
+	for i := range fullWitnesses {
+		assignment := streamAssignment(uint64(i + 2))
+		if _, err := fullWitnesses[i].FromAssignment(assignment, tVariable, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := publicWitnesses[i].FromAssignment(assignment, tVariable, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pk, vk, err := Setup(spr, publicWitnesses[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	witnesses := make(chan bn254witness.Witness)
+	go func() {
+		for _, w := range fullWitnesses {
+			witnesses <- w
+		}
+		close(witnesses)
+	}()
+
+	i := 0
+	for res := range ProveMany(spr, pk, witnesses, opt) {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		if err := Verify(res.Proof, vk, publicWitnesses[i]); err != nil {
+			t.Fatalf("proof %d: %v", i, err)
+		}
+
+		// the proofs are the ones of Prove
+		expected, err := Prove(spr, pk, fullWitnesses[i], opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res.Proof, expected) {
+			t.Fatalf("proof %d differs from the proof of Prove", i)
+		}
+		i++
+	}
+	if i != nbProofs {
+		t.Fatalf("got %d proofs, expected %d", i, nbProofs)
+	}
+}
+
+// BenchmarkProveMany reports the throughput of ProveMany and of consecutive calls to Prove
+func BenchmarkProveMany(b *testing.B) {
+	mpi.WorldSize = 1
+	mpi.SelfRank = 0
+
+	ccs, err := frontend.Compile(ecc.BN254, scs.NewBuilder, &streamCircuit{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	spr := ccs.(*cs.SparseR1CS)
+	tVariable := reflect.TypeOf((*frontend.Variable)(nil)).Elem()
+	var fullWitness, publicWitness bn254witness.Witness
+	if _, err := fullWitness.FromAssignment(streamAssignment(2), tVariable, false); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := publicWitness.FromAssignment(streamAssignment(2), tVariable, true); err != nil {
+		b.Fatal(err)
+	}
+	pk, _, err := Setup(spr, publicWitness)
+	if err != nil {
+		b.Fatal(err)
+	}
+	opt, err := backend.NewProverConfig()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	const nbProofs = 8
+	b.Run("prove", func(b *testing.B) {
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < nbProofs; j++ {
+				if _, err := Prove(spr, pk, fullWitness, opt); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(b.N*nbProofs)/time.Since(start).Minutes(), "proofs/min")
+	})
+	b.Run("prove-many", func(b *testing.B) {
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			witnesses := make(chan bn254witness.Witness, nbProofs)
+			for j := 0; j < nbProofs; j++ {
+				witnesses <- fullWitness
+			}
+			close(witnesses)
+			for res := range ProveMany(spr, pk, witnesses, opt) {
+				if res.Err != nil {
+					b.Fatal(res.Err)
+				}
+			}
+		}
+		b.ReportMetric(float64(b.N*nbProofs)/time.Since(start).Minutes(), "proofs/min")
+	})
+}
@@ -19,7 +19,6 @@
 package piano
 
 import (
-	"crypto/sha256"
 	"fmt"
 	"math/big"
 	"runtime/debug"
@@ -28,22 +27,132 @@ import (
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/sunblaze-ucb/simpleMPI/mpi"
 
-	curve "github.com/consensys/gnark-crypto/ecc/bn254"
 	bn254witness "github.com/consensys/gnark/internal/backend/bn254/witness"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark-crypto/fiat-shamir"
+	"github.com/consensys/gnark/backend/comm"
 	"github.com/consensys/gnark/logger"
 
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/dkzg"
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr/kzg"
+	"github.com/consensys/gnark/internal/backend/bn254/pcs"
 )
 
 func Verify(proof *Proof, vk *VerifyingKey, publicWitness bn254witness.Witness) error {
 	log := logger.Logger().With().Str("curve", "bn254").Str("backend", "piano").Logger()
 	start := time.Now()
 
+	claims, err := foldClaims(proof, vk, publicWitness)
+	if err != nil {
+		return err
+	}
+	if err := claims.verify(vk); err != nil {
+		return err
+	}
+
+	log.Debug().Dur("took", time.Since(start)).Msg("verifier done")
+
+	return nil
+}
+
+// BatchVerifyError is returned by BatchVerify when the batch doesn't pass.
+// Index is the position in the batch of the first proof that fails on its own.
+type BatchVerifyError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchVerifyError) Error() string {
+	return fmt.Sprintf("proof %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchVerifyError) Unwrap() error {
+	return e.Err
+}
+
+// BatchVerify verifies several proofs generated for the same verifying key.
+//
+// The transcripts and the constraints on Y = beta are checked proof by proof,
+// while the openings of all the proofs are folded with verifier randomness into
+// a single batch verification on X and a single one on Y. When the batch doesn't pass,
+// the openings are checked proof by proof to find out which one is wrong.
+func BatchVerify(proofs []*Proof, vk *VerifyingKey, publicWitnesses []bn254witness.Witness) error {
+	log := logger.Logger().With().Str("curve", "bn254").Str("backend", "piano").Int("nbProofs", len(proofs)).Logger()
+	start := time.Now()
+
+	if len(proofs) != len(publicWitnesses) {
+		return fmt.Errorf("got %d proofs but %d public witnesses", len(proofs), len(publicWitnesses))
+	}
+	if len(proofs) == 0 {
+		return nil
+	}
+
+	claims := make([]*openingClaims, len(proofs))
+	var batch openingClaims
+	for i := range proofs {
+		var err error
+		if claims[i], err = foldClaims(proofs[i], vk, publicWitnesses[i]); err != nil {
+			return &BatchVerifyError{Index: i, Err: err}
+		}
+		batch.append(claims[i])
+	}
+
+	if err := batch.verify(vk); err != nil {
+		for i := range claims {
+			if err := claims[i].verify(vk); err != nil {
+				return &BatchVerifyError{Index: i, Err: err}
+			}
+		}
+		return err
+	}
+
+	log.Debug().Dur("took", time.Since(start)).Msg("batch verifier done")
+
+	return nil
+}
+
+// openingClaims stores the opening claims of a proof which remain to be checked
+// with pairings once the transcript and the constraints on Y = beta are checked.
+type openingClaims struct {
+	// claims on X = alpha, X = omegaX * alpha
+	digestsX []pcs.Digest
+	proofsX  []pcs.OpeningProof
+	pointsX  []fr.Element
+
+	// claims on Y = beta
+	digestsY []pcs.Digest
+	proofsY  []pcs.OpeningProof
+	pointsY  []fr.Element
+}
+
+// append adds the claims of o to c
+func (c *openingClaims) append(o *openingClaims) {
+	c.digestsX = append(c.digestsX, o.digestsX...)
+	c.proofsX = append(c.proofsX, o.proofsX...)
+	c.pointsX = append(c.pointsX, o.pointsX...)
+	c.digestsY = append(c.digestsY, o.digestsY...)
+	c.proofsY = append(c.proofsY, o.proofsY...)
+	c.pointsY = append(c.pointsY, o.pointsY...)
+}
+
+// verify checks the claims with one batch verification on X and one on Y
+func (c *openingClaims) verify(vk *VerifyingKey) error {
+	if err := vk.PCS.X.BatchVerifyMultiPoints(c.digestsX, c.proofsX, c.pointsX); err != nil {
+		return fmt.Errorf("failed to batch verify on X = alpha: %v", err)
+	}
+	if err := vk.PCS.Y.BatchVerifyMultiPoints(c.digestsY, c.proofsY, c.pointsY); err != nil {
+		return fmt.Errorf("failed to batch verify on Y = beta: %v", err)
+	}
+	return nil
+}
+
+// foldClaims replays the transcript of the proof, checks the constraints on
+// Y = beta and folds the batch opening proofs into single opening claims.
+func foldClaims(proof *Proof, vk *VerifyingKey, publicWitness bn254witness.Witness) (*openingClaims, error) {
 	// pick a hash function to derive the challenge (the same as in the prover)
-	hFunc := sha256.New()
+	hFunc, err := vk.TranscriptHash.New(ecc.BN254)
+	if err != nil {
+		return nil, err
+	}
 
 	// transcript to derive the challenge
 	fs := fiatshamir.NewTranscript(hFunc, "gamma", "eta", "lambda", "alpha", "beta")
@@ -52,49 +161,44 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bn254witness.Witness)
 	// the coefficients of the circuit, and the public inputs.
 	// derive gamma from the Comm(blinded cl), Comm(blinded cr), Comm(blinded co)
 	if err := bindPublicData(&fs, "gamma", *vk, publicWitness); err != nil {
-		return err
+		return nil, err
 	}
-	gamma, err := deriveRandomness(&fs, "gamma", true, &proof.LRO[0], &proof.LRO[1], &proof.LRO[2])
+	gamma, err := deriveRandomness(&fs, "gamma", true, proof.LRO[:]...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// derive eta from Comm(l), Comm(r), Comm(o)
 	eta, err := deriveRandomness(&fs, "eta", true)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// derive lambda from Comm(l), Comm(r), Comm(o), Com(Z)
-	lambda, err := deriveRandomness(&fs, "lambda", true, &proof.Z)
+	lambda, err := deriveRandomness(&fs, "lambda", true, proof.Z)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// derive alpha, the point of evaluation
-	alpha, err := deriveRandomness(&fs, "alpha", true, &proof.Hx[0], &proof.Hx[1], &proof.Hx[2])
+	alpha, err := deriveRandomness(&fs, "alpha", true, proof.Hx[:]...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// evaluation of Z=Xⁿ⁻¹ at α
-	var alphaPowerN, zalpha fr.Element
+	var alphaPowerN fr.Element
 	var bExpo big.Int
-	one := fr.One()
 	bExpo.SetUint64(vk.SizeX)
 	alphaPowerN.Exp(alpha, &bExpo)
-	zalpha.Sub(&alphaPowerN, &one)
 
 	// compute the folded commitment to H: Comm(h₁) + αᵐ*Comm(h₂) + α²⁽ᵐ⁾*Comm(h₃)
-	var alphaNBigInt big.Int
-	alphaPowerN.ToBigIntRegular(&alphaNBigInt)
-	foldedHxDigest := proof.Hx[2]
-	foldedHxDigest.ScalarMultiplication(&foldedHxDigest, &alphaNBigInt)
-	foldedHxDigest.Add(&foldedHxDigest, &proof.Hx[1])
-	foldedHxDigest.ScalarMultiplication(&foldedHxDigest, &alphaNBigInt)
-	foldedHxDigest.Add(&foldedHxDigest, &proof.Hx[0])
-
-	foldedPartialProof, foldedPartialDigest, err := dkzg.FoldProof(
-		[]dkzg.Digest{
+	foldedHxDigest, err := vk.PCS.X.Combine(proof.Hx[:], alphaPowerN)
+	if err != nil {
+		return nil, err
+	}
+
+	foldedPartialProof, foldedPartialDigest, err := vk.PCS.X.FoldProof(
+		[]pcs.Digest{
 			foldedHxDigest,
 			proof.LRO[0],
 			proof.LRO[1],
@@ -109,81 +213,56 @@ func Verify(proof *Proof, vk *VerifyingKey, publicWitness bn254witness.Witness)
 			vk.S[2],
 			proof.Z,
 		},
-		&proof.PartialBatchedProof,
+		proof.PartialBatchedProof,
 		alpha,
 		hFunc)
 
 	if err != nil {
-		return fmt.Errorf("failed to fold proof on X = alpha: %v", err)
+		return nil, fmt.Errorf("failed to fold proof on X = alpha: %v", err)
 	}
-	// Batch verify
 	var shiftedalpha fr.Element
 	shiftedalpha.Mul(&alpha, &vk.Generator)
-	err = dkzg.BatchVerifyMultiPoints(
-		[]dkzg.Digest{
-			foldedPartialDigest,
-			proof.Z,
-		},
-		[]dkzg.OpeningProof{
-			foldedPartialProof,
-			proof.PartialZShiftedProof,
-		},
-		[]fr.Element{
-			alpha,
-			shiftedalpha,
-		},
-		vk.DKZGSRS,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to batch verify on X = alpha: %v", err)
-	}
 
 	// derive beta
-	ts := []*curve.G1Affine{
-		&proof.PartialBatchedProof.H,
-	}
-	for _, digest := range proof.PartialBatchedProof.ClaimedDigests {
-		ts = append(ts, &digest)
-	}
-	for _, digest := range proof.Hy {
-		ts = append(ts, &digest)
-	}
+	ts := append(vk.PCS.X.Transcript(proof.PartialBatchedProof), proof.Hy[:]...)
 	beta, err := deriveRandomness(&fs, "beta", true, ts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := checkConstraintY(vk, proof.BatchedProof.ClaimedValues, gamma, eta, lambda, alpha, beta); err != nil {
-		return err
+	if err := checkConstraintY(vk, vk.PCS.Y.ClaimedValues(proof.BatchedProof), gamma, eta, lambda, alpha, beta); err != nil {
+		return nil, err
 	}
 	// foldedHy = Hy1 + (beta**M)*Hy2 + (beta**(2M))*Hy3
-	var bBetaPowerM, bSize big.Int
+	var bSize big.Int
 	bSize.SetUint64(vk.SizeY)
 	var betaPowerM fr.Element
 	betaPowerM.Exp(beta, &bSize)
-	betaPowerM.ToBigIntRegular(&bBetaPowerM)
-	foldedHyDigest := proof.Hy[2]                                      // Hy3
-	foldedHyDigest.ScalarMultiplication(&foldedHyDigest, &bBetaPowerM) // (beta**M)*Hy3
-	foldedHyDigest.Add(&foldedHyDigest, &proof.Hy[1])                  // (beta**M)*Hy3 + Hy2
-	foldedHyDigest.ScalarMultiplication(&foldedHyDigest, &bBetaPowerM) // (beta**(2M))*Hy3 + (beta**M)*Hy2
-	foldedHyDigest.Add(&foldedHyDigest, &proof.Hy[0])                  // (beta**(2M))*Hy3 + (beta**M)*Hy2 + Hy1
-
-	if err := kzg.BatchVerifySinglePoint(
-		append(proof.PartialBatchedProof.ClaimedDigests,
-			proof.PartialZShiftedProof.ClaimedDigest,
+	foldedHyDigest, err := vk.PCS.Y.Combine(proof.Hy[:], betaPowerM)
+	if err != nil {
+		return nil, err
+	}
+
+	foldedProof, foldedDigest, err := vk.PCS.Y.FoldProof(
+		append(vk.PCS.X.PartialDigests(proof.PartialBatchedProof),
+			vk.PCS.X.PartialDigest(proof.PartialZShiftedProof),
 			foldedHyDigest,
 		),
-		&proof.BatchedProof,
-		beta, // not consistent with the prover
-		hFunc,
-		vk.KZGSRS,
-	); err != nil {
-		return err
+		proof.BatchedProof,
+		beta,
+		hFunc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fold proof on Y = beta: %v", err)
 	}
 
-	log.Debug().Dur("took", time.Since(start)).Msg("verifier done")
-
-	return err
+	return &openingClaims{
+		digestsX: []pcs.Digest{foldedPartialDigest, proof.Z},
+		proofsX:  []pcs.OpeningProof{foldedPartialProof, proof.PartialZShiftedProof},
+		pointsX:  []fr.Element{alpha, shiftedalpha},
+		digestsY: []pcs.Digest{foldedDigest},
+		proofsY:  []pcs.OpeningProof{foldedProof},
+		pointsY:  []fr.Element{beta},
+	}, nil
 }
 
 // unpack unpacks evaluations from an array
@@ -225,14 +304,12 @@ func bindPublicData(fs *fiatshamir.Transcript, challenge string, vk VerifyingKey
 	return nil
 }
 
-func deriveRandomness(fs *fiatshamir.Transcript, challenge string, notSend bool, points ...*curve.G1Affine) (fr.Element, error) {
+func deriveRandomness(fs *fiatshamir.Transcript, challenge string, notSend bool, digests ...pcs.Digest) (fr.Element, error) {
 	if mpi.SelfRank == 0 {
-		var buf [curve.SizeOfG1AffineUncompressed]byte
 		var r fr.Element
 
-		for _, p := range points {
-			buf = p.RawBytes()
-			if err := fs.Bind(challenge, buf[:]); err != nil {
+		for _, d := range digests {
+			if err := fs.Bind(challenge, d.Marshal()); err != nil {
 				fmt.Println("deriveRandomness", challenge, "err", err)
 				fmt.Println("Stack", string(debug.Stack()))
 				return r, err
@@ -250,16 +327,18 @@ func deriveRandomness(fs *fiatshamir.Transcript, challenge string, notSend bool,
 			return r, nil
 		}
 		sendBuf := r.Bytes()
-		for i := 1; i < int(mpi.WorldSize); i++ {
-			mpi.SendBytes(sendBuf[:], uint64(i))
+		if _, err := comm.Default.Broadcast(sendBuf[:], fr.Bytes); err != nil {
+			return r, err
 		}
+		comm.Default.NextRound()
 		return r, nil
 	} else {
 		var r fr.Element
-		recvBuf, err := mpi.ReceiveBytes(fr.Bytes, 0)
+		recvBuf, err := comm.Default.Broadcast(nil, fr.Bytes)
 		if err != nil {
 			return r, err
 		}
+		comm.Default.NextRound()
 		r.SetBytes(recvBuf)
 		return r, nil
 	}
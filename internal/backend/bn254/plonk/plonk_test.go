@@ -87,6 +87,41 @@ func referenceCircuit() (frontend.CompiledConstraintSystem, frontend.Circuit, *k
 	return ccs, &good, srs
 }
 
+func TestTranscriptHash(t *testing.T) {
+	ccs, _solution, srs := referenceCircuit()
+	fullWitness := bn254witness.Witness{}
+	if _, err := fullWitness.FromAssignment(_solution, tVariable, false); err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := bn254witness.Witness{}
+	if _, err := publicWitness.FromAssignment(_solution, tVariable, true); err != nil {
+		t.Fatal(err)
+	}
+
+	pk, vk, err := bn254plonk.Setup(ccs.(*cs.SparseR1CS), srs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := []backend.TranscriptHash{backend.SHA256, backend.KECCAK256, backend.MIMC}
+	for _, h := range hashes {
+		vk.TranscriptHash = h
+		proof, err := bn254plonk.Prove(ccs.(*cs.SparseR1CS), pk, fullWitness, backend.ProverConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bn254plonk.Verify(proof, vk, publicWitness); err != nil {
+			t.Fatalf("%s: %v", h, err)
+		}
+
+		// the proof doesn't verify with another transcript hash
+		vk.TranscriptHash = hashes[(int(h)+1)%len(hashes)]
+		if err := bn254plonk.Verify(proof, vk, publicWitness); err == nil {
+			t.Fatalf("%s: proof verified with transcript hash %s", h, vk.TranscriptHash)
+		}
+	}
+}
+
 func BenchmarkSetup(b *testing.B) {
 	ccs, _, srs := referenceCircuit()
 
@@ -121,6 +156,38 @@ func BenchmarkProver(b *testing.B) {
 	}
 }
 
+// BenchmarkProverPipeline compares the pipelined prover to the same steps run sequentially
+func BenchmarkProverPipeline(b *testing.B) {
+	ccs, _solution, srs := referenceCircuit()
+	fullWitness := bn254witness.Witness{}
+	_, err := fullWitness.FromAssignment(_solution, tVariable, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pk, _, err := bn254plonk.Setup(ccs.(*cs.SparseR1CS), srs)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	provers := []struct {
+		name  string
+		prove func(*cs.SparseR1CS, *bn254plonk.ProvingKey, bn254witness.Witness, backend.ProverConfig) (*bn254plonk.Proof, error)
+	}{
+		{"pipelined", bn254plonk.Prove},
+		{"sequential", bn254plonk.ProveSequential},
+	}
+	for _, p := range provers {
+		b.Run(p.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := p.prove(ccs.(*cs.SparseR1CS), pk, fullWitness, backend.ProverConfig{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkVerifier(b *testing.B) {
 	ccs, _solution, srs := referenceCircuit()
 	fullWitness := bn254witness.Witness{}
@@ -19,7 +19,6 @@
 package plonk
 
 import (
-	"crypto/sha256"
 	"math/big"
 	"math/bits"
 	"runtime"
@@ -39,6 +38,7 @@ import (
 
 	"github.com/consensys/gnark-crypto/fiat-shamir"
 	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/internal/utils"
 	"github.com/consensys/gnark/logger"
 )
@@ -62,12 +62,29 @@ type Proof struct {
 }
 
 // Prove from the public data
+//
+// The computation and commitment of Z, and the derivation of alpha, run concurrently
+// with the evaluation of the other parts of the quotient on the cosets of the big domain,
+// and the linearized polynomial is computed while h is folded.
 func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness, opt backend.ProverConfig) (*Proof, error) {
+	return prove(spr, pk, fullWitness, opt, true)
+}
+
+// proveSequential runs the steps of Prove one after the other, it is the reference
+// of the pipelined prover in the benchmarks.
+func proveSequential(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness, opt backend.ProverConfig) (*Proof, error) {
+	return prove(spr, pk, fullWitness, opt, false)
+}
+
+func prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness, opt backend.ProverConfig, pipelined bool) (*Proof, error) {
 
 	log := logger.Logger().With().Str("curve", spr.CurveID().String()).Int("nbConstraints", len(spr.Constraints)).Str("backend", "plonk").Logger()
 	start := time.Now()
 	// pick a hash function that will be used to derive the challenges
-	hFunc := sha256.New()
+	hFunc, err := pk.Vk.TranscriptHash.New(ecc.BN254)
+	if err != nil {
+		return nil, err
+	}
 
 	// create a transcript manager to apply Fiat Shamir
 	fs := fiatshamir.NewTranscript(hFunc, "gamma", "beta", "alpha", "zeta")
@@ -75,9 +92,23 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 	// result
 	proof := &Proof{}
 
+	// async runs f in a goroutine if the prover is pipelined, and returns a function
+	// waiting for f to return
+	async := func(f func()) func() {
+		if !pipelined {
+			f()
+			return func() {}
+		}
+		done := make(chan struct{})
+		go func() {
+			f()
+			close(done)
+		}()
+		return func() { <-done }
+	}
+
 	// compute the constraint system solution
 	var solution []fr.Element
-	var err error
 	if solution, err = spr.Solve(fullWitness, opt); err != nil {
 		if !opt.Force {
 			return nil, err
@@ -133,37 +164,49 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 	// compute Z, the permutation accumulator polynomial, in canonical basis
 	// ll, lr, lo are NOT blinded
 	var blindedZCanonical []fr.Element
-	blindedZCanonical, err = computeBlindedZCanonical(
-		evaluationLDomainSmall,
-		evaluationRDomainSmall,
-		evaluationODomainSmall,
-		pk, beta, gamma)
-	if err != nil {
-		return nil, err
-	}
+	var alpha fr.Element
+	var errZ error
+	waitZ := async(func() {
+		blindedZCanonical, errZ = computeBlindedZCanonical(
+			evaluationLDomainSmall,
+			evaluationRDomainSmall,
+			evaluationODomainSmall,
+			pk, beta, gamma)
+		if errZ != nil {
+			return
+		}
 
-	// commit to the blinded version of z
-	// note that we explicitly double the number of tasks for the multi exp in kzg.Commit
-	// this may add additional arithmetic operations, but with smaller tasks
-	// we ensure that this commitment is well parallelized, without having a "unbalanced task" making
-	// the rest of the code wait too long.
-	if proof.Z, err = kzg.Commit(blindedZCanonical, pk.Vk.KZGSRS, runtime.NumCPU()*2); err != nil {
-		return nil, err
-	}
+		// commit to the blinded version of z
+		// note that we explicitly double the number of tasks for the multi exp in kzg.Commit
+		// this may add additional arithmetic operations, but with smaller tasks
+		// we ensure that this commitment is well parallelized, without having a "unbalanced task" making
+		// the rest of the code wait too long.
+		if proof.Z, errZ = kzg.Commit(blindedZCanonical, pk.Vk.KZGSRS, runtime.NumCPU()*2); errZ != nil {
+			return
+		}
 
-	alpha, err := deriveRandomness(&fs, "alpha", &proof.Z)
-	if err != nil {
-		return nil, err
-	}
+		// derive alpha from the Comm(l), Comm(r), Comm(o), Com(Z)
+		alpha, errZ = deriveRandomness(&fs, "alpha", &proof.Z)
+	})
 
+	// compute qk in canonical basis, completed with the public inputs
 	qkCompletedCanonical := make([]fr.Element, pk.Domain[0].Cardinality)
 	copy(qkCompletedCanonical, fullWitness[:spr.NbPublicVariables])
 	copy(qkCompletedCanonical[spr.NbPublicVariables:], pk.LQk[spr.NbPublicVariables:])
 	pk.Domain[0].FFTInverse(qkCompletedCanonical, fft.DIF)
 	fft.BitReverse(qkCompletedCanonical)
 
+	// evaluate the gate constraint and the products of the permutation constraint,
+	// which don't depend on z, on the cosets of the big domain
+	constraintsInd, constraintsF, constraintsG := evaluateConstraintsDomainBigBitReversed(pk, blindedLCanonical, blindedRCanonical, blindedOCanonical, qkCompletedCanonical, beta, gamma)
+
+	waitZ()
+	if errZ != nil {
+		return nil, errZ
+	}
+
 	// compute h in canonical form
-	h1, h2, h3 := computeQuotientCanonical(pk, blindedLCanonical, blindedRCanonical, blindedOCanonical, blindedZCanonical, qkCompletedCanonical, beta, gamma, alpha)
+	h1, h2, h3 := computeQuotientCanonical(pk, constraintsInd, constraintsF, constraintsG, blindedZCanonical, alpha)
 
 	// compute kzg commitments of h1, h2 and h3
 	if err := commitToQuotient(h1, h2, h3, proof, pk.Vk.KZGSRS); err != nil {
@@ -177,9 +220,10 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 	}
 
 	// compute evaluations of (blinded version of) l, r, o, z at zeta
-	blzeta := eval(blindedLCanonical, zeta)
-	brzeta := eval(blindedRCanonical, zeta)
-	bozeta := eval(blindedOCanonical, zeta)
+	var blzeta, brzeta, bozeta fr.Element
+	waitL := async(func() { blzeta = eval(blindedLCanonical, zeta) })
+	waitR := async(func() { brzeta = eval(blindedRCanonical, zeta) })
+	waitO := async(func() { bozeta = eval(blindedOCanonical, zeta) })
 
 	// open blinded Z at zeta*z
 	var zetaShifted fr.Element
@@ -201,25 +245,29 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 		linearizedPolynomialDigest    curve.G1Affine
 		errLPoly                      error
 	)
-	linearizedPolynomialCanonical = computeLinearizedPolynomial(
-		blzeta,
-		brzeta,
-		bozeta,
-		alpha,
-		beta,
-		gamma,
-		zeta,
-		bzuzeta,
-		blindedZCanonical,
-		pk,
-	)
+	waitLPoly := async(func() {
+		// compute the linearization polynomial r at zeta (goal: save committing separately to z, ql, qr, qm, qo, k)
+		waitL()
+		waitR()
+		waitO()
+		linearizedPolynomialCanonical = computeLinearizedPolynomial(
+			blzeta,
+			brzeta,
+			bozeta,
+			alpha,
+			beta,
+			gamma,
+			zeta,
+			bzuzeta,
+			blindedZCanonical,
+			pk,
+		)
+
+		// TODO this commitment is only necessary to derive the challenge, we should
+		// be able to avoid doing it and get the challenge in another way
+		linearizedPolynomialDigest, errLPoly = kzg.Commit(linearizedPolynomialCanonical, pk.Vk.KZGSRS)
+	})
 
-	// TODO this commitment is only necessary to derive the challenge, we should
-	// be able to avoid doing it and get the challenge in another way
-	linearizedPolynomialDigest, errLPoly = kzg.Commit(linearizedPolynomialCanonical, pk.Vk.KZGSRS)
-	if errLPoly != nil {
-		return nil, errLPoly
-	}
 	// foldedHDigest = Comm(h1) + ζᵐ⁺²*Comm(h2) + ζ²⁽ᵐ⁺²⁾*Comm(h3)
 	var bZetaPowerm, bSize big.Int
 	bSize.SetUint64(pk.Domain[0].Cardinality)
@@ -243,6 +291,11 @@ func Prove(spr *cs.SparseR1CS, pk *ProvingKey, fullWitness bn254witness.Witness,
 		}
 	})
 
+	waitLPoly()
+	if errLPoly != nil {
+		return nil, errLPoly
+	}
+
 	// Batch open the first list of polynomials
 	proof.BatchedProof, err = kzg.BatchOpenSinglePoint(
 		[][]fr.Element{
@@ -495,12 +548,13 @@ func evaluateXnMinusOneDomainBigCoset(domainBig, domainSmall *fft.Domain) []fr.E
 	return res
 }
 
-// computeQuotientCanonical computes h in canonical form, split as h1+X^mh2+X²mh3 such that
+// evaluateConstraintsDomainBigBitReversed evaluates, on the cosets of the big domain in
+// bit-reversed order, the parts of the numerator of h that don't depend on z:
 //
-// ql(X)L(X)+qr(X)R(X)+qm(X)L(X)R(X)+qo(X)O(X)+k(X) + α.(z(μX)*g₁(X)*g₂(X)*g₃(X)-z(X)*f₁(X)*f₂(X)*f₃(X)) + α²*L₁(X)*(Z(X)-1)= h(X)Z(X)
-//
-// constraintInd, constraintOrdering are evaluated on the big domain (coset).
-func computeQuotientCanonical(pk *ProvingKey, lCanonicalX, rCanonicalX, oCanonicalX, zCanonicalX, qkCompletedCanonical []fr.Element, eta, gamma, lambda fr.Element) ([]fr.Element, []fr.Element, []fr.Element) {
+// * the gate constraint ql(X)L(X)+qr(X)R(X)+qm(X)L(X)R(X)+qo(X)O(X)+k(X)
+// * f₁(X)*f₂(X)*f₃(X) = (l(X)+β*X+γ)*(r(X)+β*u*X+γ)*(o(X)+β*u²*X+γ)
+// * g₁(X)*g₂(X)*g₃(X) = (l(X)+β*s1(X)+γ)*(r(X)+β*s2(X)+γ)*(o(X)+β*s3(X)+γ)
+func evaluateConstraintsDomainBigBitReversed(pk *ProvingKey, lCanonicalX, rCanonicalX, oCanonicalX, qkCompletedCanonical []fr.Element, beta, gamma fr.Element) (constraintsInd, constraintsF, constraintsG []fr.Element) {
 	ratio := pk.Domain[1].Cardinality / pk.Domain[0].Cardinality
 
 	// Compute the power of domain[1].Generator with bit-reversed order.
@@ -514,26 +568,18 @@ func computeQuotientCanonical(pk *ProvingKey, lCanonicalX, rCanonicalX, oCanonic
 	// Variables needed in permutation constraint.
 	n := pk.Domain[0].Cardinality
 	nn := uint64(64 - bits.TrailingZeros64(uint64(pk.Domain[0].Cardinality)))
-	var cosetShiftEta, cosetShiftSquareEta fr.Element
-	cosetShiftEta.Mul(&pk.Vk.CosetShift, &eta)
-	cosetShiftSquareEta.Mul(&cosetShiftEta, &pk.Vk.CosetShift)
+	var cosetShiftBeta, cosetShiftSquareBeta fr.Element
+	cosetShiftBeta.Mul(&pk.Vk.CosetShift, &beta)
+	cosetShiftSquareBeta.Mul(&cosetShiftBeta, &pk.Vk.CosetShift)
 
-	var one fr.Element
-	one.SetOne()
-	Lag0 := make([]fr.Element, pk.Domain[0].Cardinality)
-	for i := 0; i < int(pk.Domain[0].Cardinality); i++ {
-		Lag0[i].Set(&pk.Domain[0].CardinalityInv)
-	}
-
-	h := make([]fr.Element, pk.Domain[1].Cardinality)
+	constraintsInd = make([]fr.Element, pk.Domain[1].Cardinality)
+	constraintsF = make([]fr.Element, pk.Domain[1].Cardinality)
+	constraintsG = make([]fr.Element, pk.Domain[1].Cardinality)
 	for _j := 0; _j < int(ratio); _j++ {
 		// Compute FFT part for each polynomial.
-		lag0 := pk.Domain[0].FFTPart(Lag0, fft.DIF, factorsBR[_j], true)
-
 		s1 := pk.Domain[0].FFTPart(pk.S1Canonical, fft.DIF, factorsBR[_j], true)
 		s2 := pk.Domain[0].FFTPart(pk.S2Canonical, fft.DIF, factorsBR[_j], true)
 		s3 := pk.Domain[0].FFTPart(pk.S3Canonical, fft.DIF, factorsBR[_j], true)
-		z := pk.Domain[0].FFTPart(zCanonicalX, fft.DIF, factorsBR[_j], true)
 
 		ql := pk.Domain[0].FFTPart(pk.Ql, fft.DIF, factorsBR[_j], true)
 		qr := pk.Domain[0].FFTPart(pk.Qr, fft.DIF, factorsBR[_j], true)
@@ -544,7 +590,7 @@ func computeQuotientCanonical(pk *ProvingKey, lCanonicalX, rCanonicalX, oCanonic
 		l := pk.Domain[0].FFTPart(lCanonicalX, fft.DIF, factorsBR[_j], true)
 		r := pk.Domain[0].FFTPart(rCanonicalX, fft.DIF, factorsBR[_j], true)
 		o := pk.Domain[0].FFTPart(oCanonicalX, fft.DIF, factorsBR[_j], true)
-	
+
 		hStart := uint64(_j) * n
 		utils.Parallelize(int(n), func(start, end int) {
 			var f, g [3]fr.Element
@@ -553,41 +599,94 @@ func computeQuotientCanonical(pk *ProvingKey, lCanonicalX, rCanonicalX, oCanonic
 			ID.Exp(pk.Domain[0].Generator, big.NewInt(int64(start))).
 				Mul(&ID, &factorsBR[_j]).
 				Mul(&ID, &pk.Domain[1].FrMultiplicativeGen)
-			
+
 			for i := uint64(start); i < uint64(end); i++ {
 				_i := bits.Reverse64(uint64(i)) >> nn
-				_is := bits.Reverse64(uint64((i + 1)) & (n - 1)) >> nn
-
-				// Compute permutation constraints L0(X)*(z(X)-1)
-				h[hStart + _i].Sub(&z[_i], &one).Mul(&h[hStart + _i], &lag0[_i])
-				
-				// Compute permutation constraints z(mu*X)*g1(X)*g2(X)*g3(X) - z(X)*f1(X)*f2(X)*f3(X)
-				f[0].Mul(&ID, &eta).Add(&f[0], &l[_i]).Add(&f[0], &gamma)
-				f[1].Mul(&ID, &cosetShiftEta).Add(&f[1], &r[_i]).Add(&f[1], &gamma)
-				f[2].Mul(&ID, &cosetShiftSquareEta).Add(&f[2], &o[_i]).Add(&f[2], &gamma)
 
-				g[0].Mul(&s1[_i], &eta).Add(&g[0], &l[_i]).Add(&g[0], &gamma)
-				g[1].Mul(&s2[_i], &eta).Add(&g[1], &r[_i]).Add(&g[1], &gamma)
-				g[2].Mul(&s3[_i], &eta).Add(&g[2], &o[_i]).Add(&g[2], &gamma)
+				// Compute the products of the permutation constraint
+				f[0].Mul(&ID, &beta).Add(&f[0], &l[_i]).Add(&f[0], &gamma)
+				f[1].Mul(&ID, &cosetShiftBeta).Add(&f[1], &r[_i]).Add(&f[1], &gamma)
+				f[2].Mul(&ID, &cosetShiftSquareBeta).Add(&f[2], &o[_i]).Add(&f[2], &gamma)
 
-				f[0].Mul(&f[0], &f[1]).Mul(&f[0], &f[2]).Mul(&f[0], &z[_i])
-				g[0].Mul(&g[0], &g[1]).Mul(&g[0], &g[2]).Mul(&g[0], &z[_is])
+				g[0].Mul(&s1[_i], &beta).Add(&g[0], &l[_i]).Add(&g[0], &gamma)
+				g[1].Mul(&s2[_i], &beta).Add(&g[1], &r[_i]).Add(&g[1], &gamma)
+				g[2].Mul(&s3[_i], &beta).Add(&g[2], &o[_i]).Add(&g[2], &gamma)
 
-				f[0].Sub(&g[0], &f[0])
-				h[hStart + _i].Mul(&h[hStart + _i], &lambda).Add(&h[hStart + _i], &f[0])
+				constraintsF[hStart+_i].Mul(&f[0], &f[1]).Mul(&constraintsF[hStart+_i], &f[2])
+				constraintsG[hStart+_i].Mul(&g[0], &g[1]).Mul(&constraintsG[hStart+_i], &g[2])
 				ID.Mul(&ID, &pk.Domain[0].Generator)
 
 				// Compute gate constraint
 				t1.Mul(&qm[_i], &r[_i])
 				t1.Add(&t1, &ql[_i])
 				t1.Mul(&t1, &l[_i])
-	
+
 				t0.Mul(&qr[_i], &r[_i])
 				t0.Add(&t0, &t1)
-	
+
 				t1.Mul(&qo[_i], &o[_i])
-				t0.Add(&t0, &t1).Add(&t0, &qk[_i])
-				h[hStart + _i].Mul(&h[hStart + _i], &lambda).Add(&h[hStart + _i], &t0)
+				constraintsInd[hStart+_i].Add(&t0, &t1).Add(&constraintsInd[hStart+_i], &qk[_i])
+			}
+		})
+	}
+
+	return
+}
+
+// computeQuotientCanonical computes h in canonical form, split as h1+X^mh2+X²mh3 such that
+//
+// ql(X)L(X)+qr(X)R(X)+qm(X)L(X)R(X)+qo(X)O(X)+k(X) + α.(z(μX)*g₁(X)*g₂(X)*g₃(X)-z(X)*f₁(X)*f₂(X)*f₃(X)) + α²*L₁(X)*(Z(X)-1)= h(X)Z(X)
+//
+// constraintsInd, constraintsF, constraintsG are evaluated on the big domain (coset), see
+// evaluateConstraintsDomainBigBitReversed.
+func computeQuotientCanonical(pk *ProvingKey, constraintsInd, constraintsF, constraintsG, zCanonicalX []fr.Element, alpha fr.Element) ([]fr.Element, []fr.Element, []fr.Element) {
+	ratio := pk.Domain[1].Cardinality / pk.Domain[0].Cardinality
+
+	// Compute the power of domain[1].Generator with bit-reversed order.
+	factorsBR := make([]fr.Element, ratio)
+	factorsBR[0].SetOne()
+	for i := 1; i < int(ratio); i++ {
+		factorsBR[i].Mul(&factorsBR[i-1], &pk.Domain[1].Generator)
+	}
+	fft.BitReverse(factorsBR)
+
+	n := pk.Domain[0].Cardinality
+	nn := uint64(64 - bits.TrailingZeros64(uint64(pk.Domain[0].Cardinality)))
+
+	var one fr.Element
+	one.SetOne()
+	Lag0 := make([]fr.Element, pk.Domain[0].Cardinality)
+	for i := 0; i < int(pk.Domain[0].Cardinality); i++ {
+		Lag0[i].Set(&pk.Domain[0].CardinalityInv)
+	}
+
+	// h reuses the memory of constraintsInd
+	h := constraintsInd
+	for _j := 0; _j < int(ratio); _j++ {
+		// Compute FFT part for each polynomial.
+		lag0 := pk.Domain[0].FFTPart(Lag0, fft.DIF, factorsBR[_j], true)
+		z := pk.Domain[0].FFTPart(zCanonicalX, fft.DIF, factorsBR[_j], true)
+
+		hStart := uint64(_j) * n
+		utils.Parallelize(int(n), func(start, end int) {
+			var t0, t1 fr.Element
+			for i := uint64(start); i < uint64(end); i++ {
+				_i := bits.Reverse64(uint64(i)) >> nn
+				_is := bits.Reverse64(uint64((i + 1)) & (n - 1)) >> nn
+
+				// Compute permutation constraints L0(X)*(z(X)-1)
+				t0.Sub(&z[_i], &one).Mul(&t0, &lag0[_i])
+
+				// Compute permutation constraints z(mu*X)*g1(X)*g2(X)*g3(X) - z(X)*f1(X)*f2(X)*f3(X)
+				t1.Mul(&constraintsF[hStart+_i], &z[_i])
+				t0.Mul(&t0, &alpha)
+				t0.Sub(&t0, &t1)
+				t1.Mul(&constraintsG[hStart+_i], &z[_is])
+				t0.Add(&t0, &t1)
+
+				// add the gate constraint
+				t0.Mul(&t0, &alpha)
+				h[hStart+_i].Add(&h[hStart+_i], &t0)
 			}
 		})
 	}
@@ -598,14 +697,14 @@ func computeQuotientCanonical(pk *ProvingKey, lCanonicalX, rCanonicalX, oCanonic
 	utils.Parallelize(int(pk.Domain[1].Cardinality), func(start, end int) {
 		for _i := uint64(start); _i < uint64(end); _i++ {
 			i := bits.Reverse64(_i) >> nn2
-			h[_i].Mul(&h[_i], &XnMinusOneBig[i % ratio])
+			h[_i].Mul(&h[_i], &XnMinusOneBig[i%ratio])
 		}
 	})
 	pk.Domain[1].FFTInverse(h, fft.DIT, true)
 
 	h1 := h[:n]
-	h2 := h[n: 2*n]
-	h3 := h[2*n: 3*n]
+	h2 := h[n : 2*n]
+	h3 := h[2*n : 3*n]
 
 	return h1, h2, h3
 }
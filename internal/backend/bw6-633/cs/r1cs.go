@@ -493,3 +493,48 @@ func (cs *R1CS) ReadFrom(r io.Reader) (int64, error) {
 
 	return int64(decoder.NumBytesRead()), nil
 }
+
+// WriteJSON writes the R1CS in the portable JSON layout of compiled.R1CS.WriteJSON
+func (cs *R1CS) WriteJSON(w io.Writer) error {
+	return cs.R1CS.WriteJSON(w, coefficientsToBigInt(cs.Coefficients))
+}
+
+// Fingerprint returns the canonical hash of the R1CS, see compiled.Fingerprint
+func (cs *R1CS) Fingerprint() compiled.Fingerprint {
+	return cs.R1CS.Fingerprint(coefficientsToBigInt(cs.Coefficients))
+}
+
+// ReadJSON reads the R1CS from the portable JSON layout of compiled.R1CS.ReadJSON
+func (cs *R1CS) ReadJSON(r io.Reader) error {
+	coefficients, err := cs.R1CS.ReadJSON(r)
+	if err != nil {
+		return err
+	}
+	if cs.R1CS.CurveID != ecc.BW6_633 {
+		return fmt.Errorf("constraint system of %s, expected %s", cs.R1CS.CurveID, ecc.BW6_633)
+	}
+	cs.Coefficients, err = coefficientsFromBigInt(coefficients)
+	return err
+}
+
+// coefficientsToBigInt returns the coefficients in regular form
+func coefficientsToBigInt(coefficients []fr.Element) []big.Int {
+	res := make([]big.Int, len(coefficients))
+	for i := range coefficients {
+		coefficients[i].ToBigIntRegular(&res[i])
+	}
+	return res
+}
+
+// coefficientsFromBigInt returns the coefficients read from a JSON constraint system,
+// which must be in the field
+func coefficientsFromBigInt(coefficients []big.Int) ([]fr.Element, error) {
+	res := make([]fr.Element, len(coefficients))
+	for i := range coefficients {
+		if coefficients[i].Cmp(fr.Modulus()) >= 0 {
+			return nil, fmt.Errorf("coefficient %d out of the field", i)
+		}
+		res[i].SetBigInt(&coefficients[i])
+	}
+	return res, nil
+}
@@ -87,6 +87,41 @@ func referenceCircuit() (frontend.CompiledConstraintSystem, frontend.Circuit, *k
 	return ccs, &good, srs
 }
 
+func TestTranscriptHash(t *testing.T) {
+	ccs, _solution, srs := referenceCircuit()
+	fullWitness := bw6_633witness.Witness{}
+	if _, err := fullWitness.FromAssignment(_solution, tVariable, false); err != nil {
+		t.Fatal(err)
+	}
+	publicWitness := bw6_633witness.Witness{}
+	if _, err := publicWitness.FromAssignment(_solution, tVariable, true); err != nil {
+		t.Fatal(err)
+	}
+
+	pk, vk, err := bw6_633plonk.Setup(ccs.(*cs.SparseR1CS), srs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := []backend.TranscriptHash{backend.SHA256, backend.KECCAK256, backend.MIMC}
+	for _, h := range hashes {
+		vk.TranscriptHash = h
+		proof, err := bw6_633plonk.Prove(ccs.(*cs.SparseR1CS), pk, fullWitness, backend.ProverConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bw6_633plonk.Verify(proof, vk, publicWitness); err != nil {
+			t.Fatalf("%s: %v", h, err)
+		}
+
+		// the proof doesn't verify with another transcript hash
+		vk.TranscriptHash = hashes[(int(h)+1)%len(hashes)]
+		if err := bw6_633plonk.Verify(proof, vk, publicWitness); err == nil {
+			t.Fatalf("%s: proof verified with transcript hash %s", h, vk.TranscriptHash)
+		}
+	}
+}
+
 func BenchmarkSetup(b *testing.B) {
 	ccs, _, srs := referenceCircuit()
 
@@ -465,7 +465,8 @@ var InverseE12Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error
 }
 
 func init() {
-	hint.Register(InverseE12Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls12377.InverseE12Hint", 1, InverseE12Hint)
+	hint.RegisterAlias(InverseE12Hint, "github.com/consensys/gnark/std/algebra/fields_bls12377.glob..func1", "github.com/consensys/gnark/std/algebra/fields_bls12377.init.func1")
 }
 
 // Inverse e12 elmts
@@ -538,7 +539,8 @@ var DivE12Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error {
 }
 
 func init() {
-	hint.Register(DivE12Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls12377.DivE12Hint", 1, DivE12Hint)
+	hint.RegisterAlias(DivE12Hint, "github.com/consensys/gnark/std/algebra/fields_bls12377.glob..func2", "github.com/consensys/gnark/std/algebra/fields_bls12377.init.func2")
 }
 
 // DivUnchecked e12 elmts
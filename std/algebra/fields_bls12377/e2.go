@@ -155,7 +155,8 @@ var InverseE2Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error
 }
 
 func init() {
-	hint.Register(InverseE2Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls12377.InverseE2Hint", 1, InverseE2Hint)
+	hint.RegisterAlias(InverseE2Hint, "github.com/consensys/gnark/std/algebra/fields_bls12377.glob..func3", "github.com/consensys/gnark/std/algebra/fields_bls12377.init.func3")
 }
 
 // Inverse e2 elmts
@@ -197,7 +198,8 @@ var DivE2Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error {
 }
 
 func init() {
-	hint.Register(DivE2Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls12377.DivE2Hint", 1, DivE2Hint)
+	hint.RegisterAlias(DivE2Hint, "github.com/consensys/gnark/std/algebra/fields_bls12377.glob..func4", "github.com/consensys/gnark/std/algebra/fields_bls12377.init.func4")
 }
 
 // DivUnchecked e2 elmts
@@ -199,7 +199,8 @@ var DivE6Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error {
 }
 
 func init() {
-	hint.Register(DivE6Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls12377.DivE6Hint", 1, DivE6Hint)
+	hint.RegisterAlias(DivE6Hint, "github.com/consensys/gnark/std/algebra/fields_bls12377.glob..func5", "github.com/consensys/gnark/std/algebra/fields_bls12377.init.func5")
 }
 
 // DivUnchecked e6 elmts
@@ -247,7 +248,8 @@ var InverseE6Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error
 }
 
 func init() {
-	hint.Register(InverseE6Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls12377.InverseE6Hint", 1, InverseE6Hint)
+	hint.RegisterAlias(InverseE6Hint, "github.com/consensys/gnark/std/algebra/fields_bls12377.glob..func6", "github.com/consensys/gnark/std/algebra/fields_bls12377.init.func6")
 }
 
 // Inverse e6 elmts
@@ -161,7 +161,8 @@ var DivE2Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error {
 }
 
 func init() {
-	hint.Register(DivE2Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls24315.DivE2Hint", 1, DivE2Hint)
+	hint.RegisterAlias(DivE2Hint, "github.com/consensys/gnark/std/algebra/fields_bls24315.glob..func3", "github.com/consensys/gnark/std/algebra/fields_bls24315.init.func3")
 }
 
 // DivUnchecked e2 elmts
@@ -200,7 +201,8 @@ var InverseE2Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error
 }
 
 func init() {
-	hint.Register(InverseE2Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls24315.InverseE2Hint", 1, InverseE2Hint)
+	hint.RegisterAlias(InverseE2Hint, "github.com/consensys/gnark/std/algebra/fields_bls24315.glob..func4", "github.com/consensys/gnark/std/algebra/fields_bls24315.init.func4")
 }
 
 // Inverse e2 elmts
@@ -448,7 +448,8 @@ var InverseE24Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error
 }
 
 func init() {
-	hint.Register(InverseE24Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls24315.InverseE24Hint", 1, InverseE24Hint)
+	hint.RegisterAlias(InverseE24Hint, "github.com/consensys/gnark/std/algebra/fields_bls24315.glob..func5", "github.com/consensys/gnark/std/algebra/fields_bls24315.init.func5")
 }
 
 // Inverse e24 elmts
@@ -558,7 +559,8 @@ var DivE24Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error {
 }
 
 func init() {
-	hint.Register(DivE24Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls24315.DivE24Hint", 1, DivE24Hint)
+	hint.RegisterAlias(DivE24Hint, "github.com/consensys/gnark/std/algebra/fields_bls24315.glob..func6", "github.com/consensys/gnark/std/algebra/fields_bls24315.init.func6")
 }
 
 // DivUnchecked e24 elmts
@@ -166,7 +166,8 @@ var DivE4Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error {
 }
 
 func init() {
-	hint.Register(DivE4Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls24315.DivE4Hint", 1, DivE4Hint)
+	hint.RegisterAlias(DivE4Hint, "github.com/consensys/gnark/std/algebra/fields_bls24315.glob..func7", "github.com/consensys/gnark/std/algebra/fields_bls24315.init.func7")
 }
 
 // DivUnchecked e4 elmts
@@ -209,7 +210,8 @@ var InverseE4Hint = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) error
 }
 
 func init() {
-	hint.Register(InverseE4Hint)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/fields_bls24315.InverseE4Hint", 1, InverseE4Hint)
+	hint.RegisterAlias(InverseE4Hint, "github.com/consensys/gnark/std/algebra/fields_bls24315.glob..func8", "github.com/consensys/gnark/std/algebra/fields_bls24315.init.func8")
 }
 
 // Inverse e4 elmts
@@ -228,7 +228,8 @@ var DecomposeScalar = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) erro
 }
 
 func init() {
-	hint.Register(DecomposeScalar)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/sw_bls12377.DecomposeScalar", 1, DecomposeScalar)
+	hint.RegisterAlias(DecomposeScalar, "github.com/consensys/gnark/std/algebra/sw_bls12377.glob..func1", "github.com/consensys/gnark/std/algebra/sw_bls12377.init.func1")
 }
 
 // varScalarMul sets P = [s] Q and returns P.
@@ -228,7 +228,8 @@ var DecomposeScalar = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) erro
 }
 
 func init() {
-	hint.Register(DecomposeScalar)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/sw_bls24315.DecomposeScalar", 1, DecomposeScalar)
+	hint.RegisterAlias(DecomposeScalar, "github.com/consensys/gnark/std/algebra/sw_bls24315.glob..func1", "github.com/consensys/gnark/std/algebra/sw_bls24315.init.func1")
 }
 
 // varScalarMul sets P = [s] Q and returns P.
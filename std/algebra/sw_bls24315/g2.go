@@ -243,7 +243,8 @@ var DecomposeScalarG2 = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) er
 }
 
 func init() {
-	hint.Register(DecomposeScalarG2)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/sw_bls24315.DecomposeScalarG2", 1, DecomposeScalarG2)
+	hint.RegisterAlias(DecomposeScalarG2, "github.com/consensys/gnark/std/algebra/sw_bls24315.glob..func2", "github.com/consensys/gnark/std/algebra/sw_bls24315.init.func2")
 }
 
 // varScalarMul sets P = [s] Q and returns P.
@@ -82,7 +82,8 @@ var DecomposeScalar = func(curve ecc.ID, inputs []*big.Int, res []*big.Int) erro
 }
 
 func init() {
-	hint.Register(DecomposeScalar)
+	hint.RegisterNamed("github.com/consensys/gnark/std/algebra/twistededwards.DecomposeScalar", 1, DecomposeScalar)
+	hint.RegisterAlias(DecomposeScalar, "github.com/consensys/gnark/std/algebra/twistededwards.glob..func1", "github.com/consensys/gnark/std/algebra/twistededwards.init.func1")
 }
 
 // ScalarMul computes the scalar multiplication of a point on a twisted Edwards curve
@@ -21,8 +21,10 @@ import (
 	"fmt"
 	"runtime/debug"
 
+	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/hash"
+	"github.com/consensys/gnark/std/hash/mimc"
 )
 
 // errChallengeNotFound is returned when a wrong challenge name is provided.
@@ -69,6 +71,23 @@ func NewTranscript(api frontend.API, h hash.Hash, challengesID ...string) Transc
 	return t
 }
 
+// NewTranscriptFromHash returns a new transcript deriving the same challenges as the
+// native transcripts of the PlonK, piano and gpiano backends with the transcript hash h,
+// provided the values are bound as field elements, in the same order. Only the
+// SNARK-friendly transcript hashes are supported.
+func NewTranscriptFromHash(api frontend.API, h backend.TranscriptHash, challengesID ...string) (Transcript, error) {
+	switch h {
+	case backend.MIMC:
+		hSnark, err := mimc.NewMiMC(api)
+		if err != nil {
+			return Transcript{}, err
+		}
+		return NewTranscript(api, &hSnark, challengesID...), nil
+	default:
+		return Transcript{}, fmt.Errorf("transcript hash %s is not supported in circuits", h)
+	}
+}
+
 // Bind binds the challenge to value. A challenge can be binded to an
 // arbitrary number of values, but the order in which the binded values
 // are added is important. Once a challenge is computed, it cannot be
@@ -23,6 +23,7 @@ import (
 	"github.com/consensys/gnark-crypto/ecc"
 	fiatshamir "github.com/consensys/gnark-crypto/fiat-shamir"
 	"github.com/consensys/gnark-crypto/hash"
+	"github.com/consensys/gnark/backend"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/scs"
 	"github.com/consensys/gnark/std/hash/mimc"
@@ -149,6 +150,76 @@ func TestFiatShamir(t *testing.T) {
 
 }
 
+type TranscriptHashCircuit struct {
+	Bindings   [2][3]frontend.Variable `gnark:",public"`
+	Challenges [2]frontend.Variable    `gnark:",secret"`
+}
+
+func (circuit *TranscriptHashCircuit) Define(api frontend.API) error {
+	ts, err := NewTranscriptFromHash(api, backend.MIMC, "gamma", "beta")
+	if err != nil {
+		return err
+	}
+	if err := ts.Bind("gamma", circuit.Bindings[0][:]); err != nil {
+		return err
+	}
+	if err := ts.Bind("beta", circuit.Bindings[1][:]); err != nil {
+		return err
+	}
+
+	gamma, err := ts.ComputeChallenge("gamma")
+	if err != nil {
+		return err
+	}
+	beta, err := ts.ComputeChallenge("beta")
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsEqual(gamma, circuit.Challenges[0])
+	api.AssertIsEqual(beta, circuit.Challenges[1])
+
+	return nil
+}
+
+// the native MiMC transcript hash pads the names of the challenges, so that they
+// don't need to be as long as a field element
+func TestTranscriptHash(t *testing.T) {
+	assert := test.NewAssert(t)
+
+	for _, curveID := range []ecc.ID{ecc.BN254, ecc.BLS12_377, ecc.BLS12_381, ecc.BLS24_315, ecc.BW6_761, ecc.BW6_633} {
+		h, err := backend.MIMC.New(curveID)
+		assert.NoError(err)
+		names := [2]string{"gamma", "beta"}
+		ts := fiatshamir.NewTranscript(h, names[:]...)
+
+		var witness TranscriptHashCircuit
+		buf := make([]byte, (curveID.ScalarField().BitLen()+7)/8)
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 3; j++ {
+				var b big.Int
+				b.SetUint64(uint64(i*3 + j + 1))
+				witness.Bindings[i][j] = b
+				assert.NoError(ts.Bind(names[i], b.FillBytes(buf)))
+			}
+		}
+
+		gamma, err := ts.ComputeChallenge("gamma")
+		assert.NoError(err)
+		beta, err := ts.ComputeChallenge("beta")
+		assert.NoError(err)
+		witness.Challenges[0] = gamma
+		witness.Challenges[1] = beta
+
+		assert.SolvingSucceeded(&TranscriptHashCircuit{}, &witness, test.WithCurves(curveID))
+	}
+
+	for _, h := range []backend.TranscriptHash{backend.SHA256, backend.KECCAK256} {
+		_, err := NewTranscriptFromHash(nil, h)
+		assert.Error(err)
+	}
+}
+
 func BenchmarkCompile(b *testing.B) {
 	// create an empty cs
 	var circuit FiatShamirCircuit
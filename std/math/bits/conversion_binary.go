@@ -10,8 +10,8 @@ import (
 
 func init() {
 	// register hints
-	hint.Register(IthBit)
-	hint.Register(NBits)
+	hint.RegisterNamed("github.com/consensys/gnark/std/math/bits.IthBit", 1, IthBit)
+	hint.RegisterNamed("github.com/consensys/gnark/std/math/bits.NBits", 1, NBits)
 }
 
 // ToBinary is an alias of ToBase(api, Binary, v, opts)
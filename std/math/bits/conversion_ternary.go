@@ -14,7 +14,8 @@ import (
 var NTrits = nTrits
 
 func init() {
-	hint.Register(NTrits)
+	hint.RegisterNamed("github.com/consensys/gnark/std/math/bits.NTrits", 1, NTrits)
+	hint.RegisterAlias(NTrits, "github.com/consensys/gnark/std/math/bits.nTrits")
 }
 
 // ToTernary is an alias of ToBase(api, Ternary, v, opts...)
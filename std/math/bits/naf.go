@@ -14,7 +14,8 @@ import (
 var NNAF = nNaf
 
 func init() {
-	hint.Register(NNAF)
+	hint.RegisterNamed("github.com/consensys/gnark/std/math/bits.NNAF", 1, NNAF)
+	hint.RegisterAlias(NNAF, "github.com/consensys/gnark/std/math/bits.nNaf")
 }
 
 // ToNAF returns the NAF decomposition of given input.
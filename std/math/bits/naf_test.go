@@ -1,9 +1,14 @@
 package bits_test
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/hint"
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	cs_bn254 "github.com/consensys/gnark/internal/backend/bn254/cs"
 	"github.com/consensys/gnark/std/math/bits"
 	"github.com/consensys/gnark/test"
 )
@@ -30,3 +35,50 @@ func TestToNAF(t *testing.T) {
 	assert := test.NewAssert(t)
 	assert.ProverSucceeded(&toNAFCircuit{}, &toNAFCircuit{A: 13, B0: 1, B1: 0, B2: -1, B3: 0, B4: 1})
 }
+
+// TestLegacyHintNames solves constraint systems serialized before NNAF and NTrits were
+// named, whose hints refer to them by the names of the functions they hold
+func TestLegacyHintNames(t *testing.T) {
+	for legacy, test := range map[string]struct {
+		hintFn              hint.Function
+		circuit, assignment frontend.Circuit
+	}{
+		"github.com/consensys/gnark/std/math/bits.nNaf":   {bits.NNAF, &toNAFCircuit{}, &toNAFCircuit{A: 13, B0: 1, B1: 0, B2: -1, B3: 0, B4: 1}},
+		"github.com/consensys/gnark/std/math/bits.nTrits": {bits.NTrits, &toTernaryCircuit{}, &toTernaryCircuit{A: 5, T0: 2, T1: 1, T2: 0}},
+	} {
+		ccs, err := frontend.Compile(ecc.BN254, r1cs.NewBuilder, test.circuit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the hints and the dependencies as the constraint system had them
+		cs := ccs.(*cs_bn254.R1CS)
+		id, legacyID := hint.UUID(test.hintFn), hint.NameID(legacy)
+		if _, ok := cs.MHintsDependencies[id]; !ok {
+			t.Fatalf("%s: the constraint system doesn't depend on %s", legacy, hint.Name(test.hintFn))
+		}
+		for _, h := range cs.MHints {
+			if h.ID == id {
+				h.ID, h.Name = legacyID, legacy
+			}
+		}
+		delete(cs.MHintsDependencies, id)
+		cs.MHintsDependencies[legacyID] = legacy
+
+		var buf bytes.Buffer
+		if _, err := cs.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var read cs_bn254.R1CS
+		if _, err := read.ReadFrom(&buf); err != nil {
+			t.Fatalf("%s: %v", legacy, err)
+		}
+		witness, err := frontend.NewWitness(test.assignment, ecc.BN254)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := read.IsSolved(witness); err != nil {
+			t.Fatalf("%s: %v", legacy, err)
+		}
+	}
+}